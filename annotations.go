@@ -0,0 +1,67 @@
+package gophpparser
+
+import (
+	"regexp"
+	"strings"
+)
+
+// Annotation is a TODO-style marker found in a comment
+type Annotation struct {
+	Marker  string `json:"marker"`  // TODO, FIXME, HACK or XXX
+	Message string `json:"message"` // Text following the marker
+	Line    int    `json:"line"`
+	Column  int    `json:"column"`
+}
+
+var annotationPattern = regexp.MustCompile(`\b(TODO|FIXME|HACK|XXX)\b:?\s*(.*)`)
+
+// Annotations returns the TODO, FIXME, HACK and XXX markers in the
+// program's comments, in source order. A comment may hold several markers,
+// one per line. The source is lexed again so that comments the parser
+// skipped, such as docblocks on class members or comments inside
+// expressions, are included; a program without source, e.g. one read by
+// FromJSON, only has its statement-level comments.
+func (sp *SemanticProgram) Annotations() []Annotation {
+	var annotations []Annotation
+	for _, comment := range sp.commentTokens() {
+		for i, line := range strings.Split(comment.Literal, "\n") {
+			match := annotationPattern.FindStringSubmatchIndex(line)
+			if match == nil {
+				continue
+			}
+
+			column := match[2] + 1
+			if i == 0 {
+				column += comment.Column - 1
+			}
+
+			message := strings.TrimSpace(strings.TrimSuffix(strings.TrimSpace(line[match[4]:]), "*/"))
+			annotations = append(annotations, Annotation{
+				Marker:  line[match[2]:match[3]],
+				Message: message,
+				Line:    comment.Line + i,
+				Column:  column,
+			})
+		}
+	}
+	return annotations
+}
+
+// commentTokens returns every comment and docblock token in the source
+func (sp *SemanticProgram) commentTokens() []Token {
+	var comments []Token
+	if sp.Program == nil || sp.Program.source == "" {
+		for _, comment := range sp.Comments {
+			comments = append(comments, comment.Token)
+		}
+		return comments
+	}
+
+	l := New(sp.Program.source)
+	for tok := l.NextToken(); tok.Type != EOF; tok = l.NextToken() {
+		if tok.Type == COMMENT || tok.Type == DOCBLOCK {
+			comments = append(comments, tok)
+		}
+	}
+	return comments
+}
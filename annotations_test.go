@@ -0,0 +1,47 @@
+package gophpparser
+
+import "testing"
+
+func TestAnnotations(t *testing.T) {
+	phpCode := `<?php
+$total = 0; // TODO: fix this
+/*
+ * FIXME handle negative totals
+ */
+function add($a, $b) {
+    // Nothing to do here
+    return $a + $b;
+}
+
+class Cart {
+    /**
+     * HACK: totals are cached
+     */
+    public function total() {
+        return $this->sum(/* XXX rounding */ 2);
+    }
+}
+?>`
+
+	semanticProgram, err := ParseWithSemantics(phpCode, "todo.php")
+	if err != nil {
+		t.Fatalf("Failed to parse with semantics: %v", err)
+	}
+
+	annotations := semanticProgram.Annotations()
+	expected := []Annotation{
+		{Marker: "TODO", Message: "fix this", Line: 2, Column: 16},
+		{Marker: "FIXME", Message: "handle negative totals", Line: 4, Column: 4},
+		{Marker: "HACK", Message: "totals are cached", Line: 13, Column: 8},
+		{Marker: "XXX", Message: "rounding", Line: 16, Column: 30},
+	}
+
+	if len(annotations) != len(expected) {
+		t.Fatalf("expected %d annotations, got=%d (%+v)", len(expected), len(annotations), annotations)
+	}
+	for i, want := range expected {
+		if annotations[i] != want {
+			t.Errorf("annotation %d wrong. expected=%+v, got=%+v", i, want, annotations[i])
+		}
+	}
+}
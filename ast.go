@@ -2,12 +2,15 @@ package gophpparser
 
 import (
 	"encoding/json"
+	"reflect"
+	"strings"
 )
 
 type Node interface {
 	String() string
 	TokenLiteral() string
 	Type() string
+	Kind() NodeKind
 }
 
 type Statement interface {
@@ -22,6 +25,11 @@ type Expression interface {
 
 type Program struct {
 	Statements []Statement `json:"statements"`
+
+	// source and spans record where each top-level statement came from so
+	// ReparseRange can reuse the statements an edit did not touch
+	source string
+	spans  []statementSpan
 }
 
 func (p *Program) TokenLiteral() string {
@@ -63,6 +71,69 @@ func (v *Variable) TokenLiteral() string { return v.Token.Literal }
 func (v *Variable) String() string       { return "$" + v.Name }
 func (v *Variable) Type() string         { return "Variable" }
 
+// Parameter is a single parameter in a function, method, closure or arrow
+// function signature. Token is the parameter's variable token.
+type Parameter struct {
+	Token      Token        `json:"token"`
+	Name       string       `json:"name"`
+	Attributes []*Attribute `json:"attributes,omitempty"`
+	Visibility string       `json:"visibility,omitempty"` // Set for a promoted constructor parameter
+	Readonly   bool         `json:"readonly,omitempty"`
+	TypeHint   *TypeHint    `json:"type_hint,omitempty"`
+	ByRef      bool         `json:"by_ref,omitempty"`
+	Variadic   bool         `json:"variadic,omitempty"`
+	Default    Expression   `json:"default,omitempty"`
+}
+
+// Promoted reports whether the parameter also declares a property, as in
+// a constructor's public int $x
+func (p *Parameter) Promoted() bool {
+	return p.Visibility != "" || p.Readonly
+}
+
+func (p *Parameter) String() string {
+	out := ""
+	for _, attribute := range p.Attributes {
+		out += attribute.String() + " "
+	}
+	if p.Visibility != "" {
+		out += p.Visibility + " "
+	}
+	if p.Readonly {
+		out += "readonly "
+	}
+	if p.TypeHint != nil {
+		out += p.TypeHint.String() + " "
+	}
+	if p.ByRef {
+		out += "&"
+	}
+	if p.Variadic {
+		out += "..."
+	}
+	out += "$" + p.Name
+	if p.Default != nil {
+		out += " = " + p.Default.String()
+	}
+	return out
+}
+
+// Attribute is a single attribute such as #[Route("/users")]. Attributes
+// grouped in one #[...] are recorded separately.
+type Attribute struct {
+	Token     Token        `json:"token"`
+	Name      *Identifier  `json:"name"`
+	Arguments []Expression `json:"arguments,omitempty"`
+}
+
+func (a *Attribute) String() string {
+	out := "#[" + a.Name.String()
+	if a.Arguments != nil {
+		out += "(" + joinExpressions(a.Arguments) + ")"
+	}
+	return out + "]"
+}
+
 type IntegerLiteral struct {
 	Token Token `json:"token"`
 	Value int64 `json:"value"`
@@ -148,16 +219,36 @@ func (es *ExpressionStatement) String() string {
 }
 func (es *ExpressionStatement) Type() string { return "ExpressionStatement" }
 
+// EmptyStatement is a lone semicolon
+type EmptyStatement struct {
+	Token Token `json:"token"`
+}
+
+func (es *EmptyStatement) statementNode()       {}
+func (es *EmptyStatement) TokenLiteral() string { return es.Token.Literal }
+func (es *EmptyStatement) String() string       { return ";" }
+func (es *EmptyStatement) Type() string         { return "EmptyStatement" }
+
+// AssignmentExpression assigns Value to either a plain variable (Name) or,
+// for property, static property and array element writes, to Target.
 type AssignmentExpression struct {
-	Token Token      `json:"token"`
-	Name  *Variable  `json:"name"`
-	Value Expression `json:"value"`
+	Token  Token      `json:"token"`
+	Name   *Variable  `json:"name,omitempty"`
+	Target Expression `json:"target,omitempty"`
+	Value  Expression `json:"value"`
 }
 
 func (ae *AssignmentExpression) expressionNode()      {}
 func (ae *AssignmentExpression) TokenLiteral() string { return ae.Token.Literal }
 func (ae *AssignmentExpression) String() string {
-	return ae.Name.String() + " = " + ae.Value.String()
+	operator := " = "
+	if ae.Token.Type != ASSIGN && ae.Token.Literal != "" {
+		operator = " " + ae.Token.Literal + " "
+	}
+	if ae.Name == nil {
+		return ae.Target.String() + operator + ae.Value.String()
+	}
+	return ae.Name.String() + operator + ae.Value.String()
 }
 func (ae *AssignmentExpression) Type() string { return "AssignmentExpression" }
 
@@ -189,11 +280,15 @@ func (pe *PrefixExpression) String() string {
 func (pe *PrefixExpression) Type() string { return "PrefixExpression" }
 
 type FunctionDeclaration struct {
-	Token      Token           `json:"token"`
-	Name       *Identifier     `json:"name"`
-	Parameters []*Variable     `json:"parameters"`
-	ReturnType Expression      `json:"return_type,omitempty"`
-	Body       *BlockStatement `json:"body"`
+	Token           Token           `json:"token"`
+	ReturnsRef      bool            `json:"returns_ref,omitempty"`
+	Name            *Identifier     `json:"name"`
+	Parameters      []*Parameter    `json:"parameters"`
+	ReturnType      Expression      `json:"return_type,omitempty"`
+	Body            *BlockStatement `json:"body"`
+	IsGenerator     bool            `json:"is_generator,omitempty"`
+	LeadingComments []string        `json:"leading_comments,omitempty"`
+	DocBlock        string          `json:"doc_block,omitempty"`
 }
 
 func (fd *FunctionDeclaration) statementNode()       {}
@@ -206,7 +301,11 @@ func (fd *FunctionDeclaration) String() string {
 		}
 		params += p.String()
 	}
-	out := "function " + fd.Name.String() + "(" + params + ")"
+	out := "function "
+	if fd.ReturnsRef {
+		out += "&"
+	}
+	out += fd.Name.String() + "(" + params + ")"
 	if fd.ReturnType != nil {
 		out += ": " + fd.ReturnType.String()
 	}
@@ -251,9 +350,21 @@ type IfStatement struct {
 	Token       Token           `json:"token"`
 	Condition   Expression      `json:"condition"`
 	Consequence *BlockStatement `json:"consequence"`
+	ElseIfs     []*ElseIf       `json:"elseifs,omitempty"`
 	Alternative *BlockStatement `json:"alternative"`
 }
 
+// ElseIf is an elseif (or "else if") branch of an IfStatement.
+type ElseIf struct {
+	Token       Token           `json:"token"`
+	Condition   Expression      `json:"condition"`
+	Consequence *BlockStatement `json:"consequence"`
+}
+
+func (ei *ElseIf) String() string {
+	return "elseif" + ei.Condition.String() + " " + ei.Consequence.String()
+}
+
 func (ifs *IfStatement) statementNode()       {}
 func (ifs *IfStatement) TokenLiteral() string { return ifs.Token.Literal }
 func (ifs *IfStatement) String() string {
@@ -272,6 +383,9 @@ func (ifs *IfStatement) String() string {
 	} else {
 		out += "<nil consequence>"
 	}
+	for _, elseIf := range ifs.ElseIfs {
+		out += elseIf.String()
+	}
 	if ifs.Alternative != nil {
 		out += "else " + ifs.Alternative.String()
 	}
@@ -298,6 +412,100 @@ func (es *EchoStatement) String() string {
 }
 func (es *EchoStatement) Type() string { return "EchoStatement" }
 
+type UnsetStatement struct {
+	Token     Token        `json:"token"`
+	Arguments []Expression `json:"arguments"`
+}
+
+func (us *UnsetStatement) statementNode()       {}
+func (us *UnsetStatement) TokenLiteral() string { return us.Token.Literal }
+func (us *UnsetStatement) String() string {
+	return "unset(" + joinExpressions(us.Arguments) + ");"
+}
+func (us *UnsetStatement) Type() string { return "UnsetStatement" }
+
+// GlobalStatement imports global variables into a function scope
+type GlobalStatement struct {
+	Token     Token       `json:"token"`
+	Variables []*Variable `json:"variables"`
+}
+
+func (gs *GlobalStatement) statementNode()       {}
+func (gs *GlobalStatement) TokenLiteral() string { return gs.Token.Literal }
+func (gs *GlobalStatement) String() string {
+	names := []string{}
+	for _, v := range gs.Variables {
+		names = append(names, v.String())
+	}
+	return "global " + strings.Join(names, ", ") + ";"
+}
+func (gs *GlobalStatement) Type() string { return "GlobalStatement" }
+
+// StaticVariableStatement declares function variables that keep their
+// value between calls
+type StaticVariableStatement struct {
+	Token     Token             `json:"token"`
+	Variables []*StaticVariable `json:"variables"`
+}
+
+// StaticVariable is one variable of a static declaration. Default is nil
+// when no initial value is given.
+type StaticVariable struct {
+	Variable *Variable  `json:"variable"`
+	Default  Expression `json:"default,omitempty"`
+}
+
+func (sv *StaticVariable) String() string {
+	if sv.Default == nil {
+		return sv.Variable.String()
+	}
+	return sv.Variable.String() + " = " + sv.Default.String()
+}
+
+func (ss *StaticVariableStatement) statementNode()       {}
+func (ss *StaticVariableStatement) TokenLiteral() string { return ss.Token.Literal }
+func (ss *StaticVariableStatement) String() string {
+	variables := []string{}
+	for _, v := range ss.Variables {
+		variables = append(variables, v.String())
+	}
+	return "static " + strings.Join(variables, ", ") + ";"
+}
+func (ss *StaticVariableStatement) Type() string { return "StaticVariableStatement" }
+
+type IssetExpression struct {
+	Token     Token        `json:"token"`
+	Arguments []Expression `json:"arguments"`
+}
+
+func (ie *IssetExpression) expressionNode()      {}
+func (ie *IssetExpression) TokenLiteral() string { return ie.Token.Literal }
+func (ie *IssetExpression) String() string {
+	return "isset(" + joinExpressions(ie.Arguments) + ")"
+}
+func (ie *IssetExpression) Type() string { return "IssetExpression" }
+
+type EmptyExpression struct {
+	Token     Token        `json:"token"`
+	Arguments []Expression `json:"arguments"`
+}
+
+func (ee *EmptyExpression) expressionNode()      {}
+func (ee *EmptyExpression) TokenLiteral() string { return ee.Token.Literal }
+func (ee *EmptyExpression) String() string {
+	return "empty(" + joinExpressions(ee.Arguments) + ")"
+}
+func (ee *EmptyExpression) Type() string { return "EmptyExpression" }
+
+// joinExpressions renders expressions as a comma-separated list
+func joinExpressions(expressions []Expression) string {
+	parts := make([]string, len(expressions))
+	for i, expr := range expressions {
+		parts[i] = expr.String()
+	}
+	return strings.Join(parts, ", ")
+}
+
 type CallExpression struct {
 	Token     Token        `json:"token"`
 	Function  Expression   `json:"function"`
@@ -352,6 +560,25 @@ func (fs *ForStatement) String() string {
 }
 func (fs *ForStatement) Type() string { return "ForStatement" }
 
+// CommaExpression is a comma-separated list of expressions in a for loop
+// clause, as in for ($i = 0, $j = 10; ...). A clause holding a single
+// expression is not wrapped.
+type CommaExpression struct {
+	Token       Token        `json:"token"`
+	Expressions []Expression `json:"expressions"`
+}
+
+func (ce *CommaExpression) expressionNode()      {}
+func (ce *CommaExpression) TokenLiteral() string { return ce.Token.Literal }
+func (ce *CommaExpression) String() string {
+	var out []string
+	for _, expr := range ce.Expressions {
+		out = append(out, expr.String())
+	}
+	return strings.Join(out, ", ")
+}
+func (ce *CommaExpression) Type() string { return "CommaExpression" }
+
 type IndexExpression struct {
 	Token Token      `json:"token"`
 	Left  Expression `json:"left"`
@@ -361,7 +588,11 @@ type IndexExpression struct {
 func (ie *IndexExpression) expressionNode()      {}
 func (ie *IndexExpression) TokenLiteral() string { return ie.Token.Literal }
 func (ie *IndexExpression) String() string {
-	return "(" + ie.Left.String() + "[" + ie.Index.String() + "])"
+	index := ""
+	if ie.Index != nil {
+		index = ie.Index.String()
+	}
+	return "(" + ie.Left.String() + "[" + index + "])"
 }
 func (ie *IndexExpression) Type() string { return "IndexExpression" }
 
@@ -391,12 +622,53 @@ func (ws *WhileStatement) String() string {
 }
 func (ws *WhileStatement) Type() string { return "WhileStatement" }
 
+type SwitchStatement struct {
+	Token   Token         `json:"token"`
+	Subject Expression    `json:"subject"`
+	Cases   []*SwitchCase `json:"cases"`
+}
+
+func (ss *SwitchStatement) statementNode()       {}
+func (ss *SwitchStatement) TokenLiteral() string { return ss.Token.Literal }
+func (ss *SwitchStatement) String() string {
+	out := "switch (" + ss.Subject.String() + ") { "
+	for _, c := range ss.Cases {
+		out += c.String() + " "
+	}
+	return out + "}"
+}
+func (ss *SwitchStatement) Type() string { return "SwitchStatement" }
+
+// SwitchCase is a single case or default label of a switch statement.
+// The default label has no condition.
+type SwitchCase struct {
+	Token     Token       `json:"token"`
+	Condition Expression  `json:"condition,omitempty"`
+	Body      []Statement `json:"body"`
+}
+
+func (sc *SwitchCase) IsDefault() bool { return sc.Condition == nil }
+
+func (sc *SwitchCase) String() string {
+	out := "default:"
+	if !sc.IsDefault() {
+		out = "case " + sc.Condition.String() + ":"
+	}
+	for _, s := range sc.Body {
+		out += " " + s.String()
+	}
+	return out
+}
+
+// ForeachStatement iterates over Array. Each element is bound either to
+// Value or, when destructured, to the variables in ValuePattern.
 type ForeachStatement struct {
-	Token Token           `json:"token"`
-	Array Expression      `json:"array"`
-	Key   *Variable       `json:"key"`
-	Value *Variable       `json:"value"`
-	Body  *BlockStatement `json:"body"`
+	Token        Token           `json:"token"`
+	Array        Expression      `json:"array"`
+	Key          *Variable       `json:"key"`
+	Value        *Variable       `json:"value,omitempty"`
+	ValuePattern Expression      `json:"value_pattern,omitempty"`
+	Body         *BlockStatement `json:"body"`
 }
 
 func (fs *ForeachStatement) statementNode()       {}
@@ -406,7 +678,12 @@ func (fs *ForeachStatement) String() string {
 	if fs.Key != nil {
 		out += fs.Key.String() + " => "
 	}
-	out += fs.Value.String() + ") " + fs.Body.String()
+	if fs.ValuePattern != nil {
+		out += fs.ValuePattern.String()
+	} else {
+		out += fs.Value.String()
+	}
+	out += ") " + fs.Body.String()
 	return out
 }
 func (fs *ForeachStatement) Type() string { return "ForeachStatement" }
@@ -465,6 +742,59 @@ func (aal *AssociativeArrayLiteral) String() string {
 }
 func (aal *AssociativeArrayLiteral) Type() string { return "AssociativeArrayLiteral" }
 
+// ListExpression is the target of a destructuring assignment, written as
+// list(...) or in the short [...] form. Skipped elements are nil items.
+type ListExpression struct {
+	Token Token       `json:"token"`
+	Items []*ListItem `json:"items"`
+}
+
+// ListItem is one element of a ListExpression. Key is nil unless the
+// element is keyed; Value is an assignable expression or a nested list.
+type ListItem struct {
+	Key   Expression `json:"key,omitempty"`
+	Value Expression `json:"value"`
+}
+
+func (li *ListItem) String() string {
+	if li == nil {
+		return ""
+	}
+	if li.Key != nil {
+		return li.Key.String() + " => " + li.Value.String()
+	}
+	return li.Value.String()
+}
+
+func (le *ListExpression) expressionNode()      {}
+func (le *ListExpression) TokenLiteral() string { return le.Token.Literal }
+func (le *ListExpression) String() string {
+	items := []string{}
+	for _, item := range le.Items {
+		items = append(items, item.String())
+	}
+	if le.Token.Type == LBRACKET {
+		return "[" + strings.Join(items, ", ") + "]"
+	}
+	return "list(" + strings.Join(items, ", ") + ")"
+}
+func (le *ListExpression) Type() string { return "ListExpression" }
+
+// DestructuringAssignment assigns the elements of Source to the targets
+// of a list
+type DestructuringAssignment struct {
+	Token   Token           `json:"token"`
+	Targets *ListExpression `json:"targets"`
+	Source  Expression      `json:"source"`
+}
+
+func (da *DestructuringAssignment) expressionNode()      {}
+func (da *DestructuringAssignment) TokenLiteral() string { return da.Token.Literal }
+func (da *DestructuringAssignment) String() string {
+	return da.Targets.String() + " = " + da.Source.String()
+}
+func (da *DestructuringAssignment) Type() string { return "DestructuringAssignment" }
+
 type InterpolatedString struct {
 	Token Token        `json:"token"`
 	Parts []Expression `json:"parts"`
@@ -475,7 +805,12 @@ func (is *InterpolatedString) TokenLiteral() string { return is.Token.Literal }
 func (is *InterpolatedString) String() string {
 	out := "\""
 	for _, part := range is.Parts {
-		out += part.String()
+		switch part.(type) {
+		case *StringLiteral, *Variable:
+			out += part.String()
+		default:
+			out += "{" + part.String() + "}"
+		}
 	}
 	out += "\""
 	return out
@@ -483,20 +818,30 @@ func (is *InterpolatedString) String() string {
 func (is *InterpolatedString) Type() string { return "InterpolatedString" }
 
 type ClassDeclaration struct {
-	Token      Token                  `json:"token"`
-	Name       *Identifier            `json:"name"`
-	SuperClass *Identifier            `json:"super_class,omitempty"`
-	Interfaces []*Identifier          `json:"interfaces,omitempty"`
-	TraitUses  []*TraitUse            `json:"trait_uses,omitempty"`
-	Properties []*PropertyDeclaration `json:"properties"`
-	Methods    []*MethodDeclaration   `json:"methods"`
-	Constants  []*ConstantDeclaration `json:"constants,omitempty"`
+	Token           Token                  `json:"token"`
+	Abstract        bool                   `json:"abstract,omitempty"`
+	Final           bool                   `json:"final,omitempty"`
+	Name            *Identifier            `json:"name"`
+	SuperClass      *Identifier            `json:"super_class,omitempty"`
+	Interfaces      []*Identifier          `json:"interfaces,omitempty"`
+	TraitUses       []*TraitUse            `json:"trait_uses,omitempty"`
+	Properties      []*PropertyDeclaration `json:"properties"`
+	Methods         []*MethodDeclaration   `json:"methods"`
+	Constants       []*ConstantDeclaration `json:"constants,omitempty"`
+	LeadingComments []string               `json:"leading_comments,omitempty"`
+	DocBlock        string                 `json:"doc_block,omitempty"`
 }
 
 func (cd *ClassDeclaration) statementNode()       {}
 func (cd *ClassDeclaration) TokenLiteral() string { return cd.Token.Literal }
 func (cd *ClassDeclaration) String() string {
 	out := "class " + cd.Name.String()
+	if cd.Final {
+		out = "final " + out
+	}
+	if cd.Abstract {
+		out = "abstract " + out
+	}
 	if cd.SuperClass != nil {
 		out += " extends " + cd.SuperClass.String()
 	}
@@ -528,11 +873,15 @@ func (cd *ClassDeclaration) String() string {
 func (cd *ClassDeclaration) Type() string { return "ClassDeclaration" }
 
 type PropertyDeclaration struct {
-	Token      Token      `json:"token"`
-	Visibility string     `json:"visibility"`
-	Static     bool       `json:"static"`
-	Name       *Variable  `json:"name"`
-	Value      Expression `json:"value,omitempty"`
+	Token           Token      `json:"token"`
+	Visibility      string     `json:"visibility"`
+	Static          bool       `json:"static"`
+	Readonly        bool       `json:"readonly,omitempty"`
+	PropertyType    *TypeHint  `json:"property_type,omitempty"`
+	Name            *Variable  `json:"name"`
+	Value           Expression `json:"value,omitempty"`
+	LeadingComments []string   `json:"leading_comments,omitempty"`
+	DocBlock        string     `json:"doc_block,omitempty"`
 }
 
 func (pd *PropertyDeclaration) statementNode()       {}
@@ -542,6 +891,12 @@ func (pd *PropertyDeclaration) String() string {
 	if pd.Static {
 		out += " static"
 	}
+	if pd.Readonly {
+		out += " readonly"
+	}
+	if pd.PropertyType != nil {
+		out += " " + pd.PropertyType.String()
+	}
 	out += " " + pd.Name.String()
 	if pd.Value != nil {
 		out += " = " + pd.Value.String()
@@ -552,22 +907,39 @@ func (pd *PropertyDeclaration) String() string {
 func (pd *PropertyDeclaration) Type() string { return "PropertyDeclaration" }
 
 type MethodDeclaration struct {
-	Token      Token           `json:"token"`
-	Visibility string          `json:"visibility"`
-	Static     bool            `json:"static"`
-	Name       *Identifier     `json:"name"`
-	Parameters []*Variable     `json:"parameters"`
-	Body       *BlockStatement `json:"body"`
+	Token           Token           `json:"token"`
+	Abstract        bool            `json:"abstract,omitempty"`
+	Final           bool            `json:"final,omitempty"`
+	Visibility      string          `json:"visibility"`
+	Static          bool            `json:"static"`
+	ReturnsRef      bool            `json:"returns_ref,omitempty"`
+	Name            *Identifier     `json:"name"`
+	Parameters      []*Parameter    `json:"parameters"`
+	ReturnType      Expression      `json:"return_type,omitempty"`
+	Body            *BlockStatement `json:"body"`
+	IsGenerator     bool            `json:"is_generator,omitempty"`
+	LeadingComments []string        `json:"leading_comments,omitempty"`
+	DocBlock        string          `json:"doc_block,omitempty"`
 }
 
 func (md *MethodDeclaration) statementNode()       {}
 func (md *MethodDeclaration) TokenLiteral() string { return md.Token.Literal }
 func (md *MethodDeclaration) String() string {
 	out := md.Visibility
+	if md.Final {
+		out = "final " + out
+	}
+	if md.Abstract {
+		out = "abstract " + out
+	}
 	if md.Static {
 		out += " static"
 	}
-	out += " function " + md.Name.String() + "("
+	out += " function "
+	if md.ReturnsRef {
+		out += "&"
+	}
+	out += md.Name.String() + "("
 	params := ""
 	for i, p := range md.Parameters {
 		if i > 0 {
@@ -575,7 +947,14 @@ func (md *MethodDeclaration) String() string {
 		}
 		params += p.String()
 	}
-	out += params + ") " + md.Body.String()
+	out += params + ")"
+	if md.ReturnType != nil {
+		out += ": " + md.ReturnType.String()
+	}
+	if md.Body == nil {
+		return out + ";"
+	}
+	out += " " + md.Body.String()
 	return out
 }
 func (md *MethodDeclaration) Type() string { return "MethodDeclaration" }
@@ -599,10 +978,10 @@ func (id *InterfaceDeclaration) String() string {
 func (id *InterfaceDeclaration) Type() string { return "InterfaceDeclaration" }
 
 type InterfaceMethod struct {
-	Token      Token       `json:"token"`
-	Visibility string      `json:"visibility"`
-	Name       *Identifier `json:"name"`
-	Parameters []*Variable `json:"parameters"`
+	Token      Token        `json:"token"`
+	Visibility string       `json:"visibility"`
+	Name       *Identifier  `json:"name"`
+	Parameters []*Parameter `json:"parameters"`
 }
 
 func (im *InterfaceMethod) statementNode()       {}
@@ -622,6 +1001,7 @@ func (im *InterfaceMethod) Type() string { return "InterfaceMethod" }
 type TraitDeclaration struct {
 	Token      Token                  `json:"token"`
 	Name       *Identifier            `json:"name"`
+	Constants  []*ConstantDeclaration `json:"constants,omitempty"`
 	Properties []*PropertyDeclaration `json:"properties"`
 	Methods    []*MethodDeclaration   `json:"methods"`
 }
@@ -630,6 +1010,9 @@ func (td *TraitDeclaration) statementNode()       {}
 func (td *TraitDeclaration) TokenLiteral() string { return td.Token.Literal }
 func (td *TraitDeclaration) String() string {
 	out := "trait " + td.Name.String() + " {"
+	for _, constant := range td.Constants {
+		out += constant.String()
+	}
 	for _, prop := range td.Properties {
 		out += prop.String()
 	}
@@ -660,6 +1043,65 @@ func (tu *TraitUse) String() string {
 }
 func (tu *TraitUse) Type() string { return "TraitUse" }
 
+type EnumDeclaration struct {
+	Token       Token                  `json:"token"`
+	Name        *Identifier            `json:"name"`
+	BackingType *TypeHint              `json:"backing_type,omitempty"`
+	Interfaces  []*Identifier          `json:"interfaces,omitempty"`
+	TraitUses   []*TraitUse            `json:"trait_uses,omitempty"`
+	Cases       []*EnumCase            `json:"cases"`
+	Constants   []*ConstantDeclaration `json:"constants,omitempty"`
+	Methods     []*MethodDeclaration   `json:"methods"`
+}
+
+func (ed *EnumDeclaration) statementNode()       {}
+func (ed *EnumDeclaration) TokenLiteral() string { return ed.Token.Literal }
+func (ed *EnumDeclaration) String() string {
+	out := "enum " + ed.Name.String()
+	if ed.BackingType != nil {
+		out += ": " + ed.BackingType.String()
+	}
+	if len(ed.Interfaces) > 0 {
+		interfaces := []string{}
+		for _, iface := range ed.Interfaces {
+			interfaces = append(interfaces, iface.String())
+		}
+		out += " implements " + strings.Join(interfaces, ", ")
+	}
+	out += " {"
+	for _, traitUse := range ed.TraitUses {
+		out += traitUse.String()
+	}
+	for _, enumCase := range ed.Cases {
+		out += enumCase.String()
+	}
+	for _, constant := range ed.Constants {
+		out += constant.String()
+	}
+	for _, method := range ed.Methods {
+		out += method.String()
+	}
+	out += "}"
+	return out
+}
+func (ed *EnumDeclaration) Type() string { return "EnumDeclaration" }
+
+type EnumCase struct {
+	Token Token       `json:"token"`
+	Name  *Identifier `json:"name"`
+	Value Expression  `json:"value,omitempty"`
+}
+
+func (ec *EnumCase) statementNode()       {}
+func (ec *EnumCase) TokenLiteral() string { return ec.Token.Literal }
+func (ec *EnumCase) String() string {
+	if ec.Value != nil {
+		return "case " + ec.Name.String() + " = " + ec.Value.String() + ";"
+	}
+	return "case " + ec.Name.String() + ";"
+}
+func (ec *EnumCase) Type() string { return "EnumCase" }
+
 type ConstantDeclaration struct {
 	Token      Token       `json:"token"`
 	Visibility string      `json:"visibility"`
@@ -699,11 +1141,15 @@ type ObjectAccessExpression struct {
 	Token    Token      `json:"token"`
 	Object   Expression `json:"object"`
 	Property Expression `json:"property"`
+	Nullsafe bool       `json:"nullsafe,omitempty"` // ?-> instead of ->
 }
 
 func (oae *ObjectAccessExpression) expressionNode()      {}
 func (oae *ObjectAccessExpression) TokenLiteral() string { return oae.Token.Literal }
 func (oae *ObjectAccessExpression) String() string {
+	if oae.Nullsafe {
+		return oae.Object.String() + "?->" + oae.Property.String()
+	}
 	return oae.Object.String() + "->" + oae.Property.String()
 }
 func (oae *ObjectAccessExpression) Type() string { return "ObjectAccessExpression" }
@@ -773,20 +1219,32 @@ func (ts *TryStatement) String() string {
 func (ts *TryStatement) Type() string { return "TryStatement" }
 
 type CatchClause struct {
-	Token         Token           `json:"token"`
-	ExceptionType *Identifier     `json:"exception_type"`
-	Variable      *Variable       `json:"variable"`
-	Body          *BlockStatement `json:"body"`
+	Token          Token           `json:"token"`
+	ExceptionType  *Identifier     `json:"-"` // First of ExceptionTypes, kept for older callers
+	ExceptionTypes []*Identifier   `json:"exception_types,omitempty"`
+	Variable       *Variable       `json:"variable,omitempty"` // Nil for catch (Exception)
+	Body           *BlockStatement `json:"body"`
 }
 
 func (cc *CatchClause) statementNode()       {}
 func (cc *CatchClause) TokenLiteral() string { return cc.Token.Literal }
 func (cc *CatchClause) String() string {
 	out := " catch ("
-	if cc.ExceptionType != nil {
-		out += cc.ExceptionType.String() + " "
+	if len(cc.ExceptionTypes) > 0 {
+		for i, exceptionType := range cc.ExceptionTypes {
+			if i > 0 {
+				out += " | "
+			}
+			out += exceptionType.String()
+		}
+		out += " "
+	}
+	if cc.Variable != nil {
+		out += cc.Variable.String()
+	} else {
+		out = strings.TrimSuffix(out, " ")
 	}
-	out += cc.Variable.String() + ") " + cc.Body.String()
+	out += ") " + cc.Body.String()
 	return out
 }
 func (cc *CatchClause) Type() string { return "CatchClause" }
@@ -867,6 +1325,50 @@ func (re *RequireExpression) String() string {
 }
 func (re *RequireExpression) Type() string { return "RequireExpression" }
 
+type PrintExpression struct {
+	Token Token      `json:"token"`
+	Value Expression `json:"value"`
+}
+
+func (pe *PrintExpression) expressionNode()      {}
+func (pe *PrintExpression) TokenLiteral() string { return pe.Token.Literal }
+func (pe *PrintExpression) String() string       { return "print " + pe.Value.String() }
+func (pe *PrintExpression) Type() string         { return "PrintExpression" }
+
+// TypeHint is a declared type on a property, parameter or return value.
+// Union types (A|B) list their members in Union and leave Name empty.
+type TypeHint struct {
+	Token        Token       `json:"token"`
+	Name         string      `json:"name,omitempty"`
+	Nullable     bool        `json:"nullable,omitempty"`
+	Union        []*TypeHint `json:"union,omitempty"`
+	Intersection []*TypeHint `json:"intersection,omitempty"`
+}
+
+func (th *TypeHint) expressionNode()      {}
+func (th *TypeHint) TokenLiteral() string { return th.Token.Literal }
+func (th *TypeHint) String() string {
+	if len(th.Union) > 0 {
+		members := []string{}
+		for _, member := range th.Union {
+			members = append(members, member.String())
+		}
+		return strings.Join(members, "|")
+	}
+	if len(th.Intersection) > 0 {
+		members := []string{}
+		for _, member := range th.Intersection {
+			members = append(members, member.String())
+		}
+		return strings.Join(members, "&")
+	}
+	if th.Nullable {
+		return "?" + th.Name
+	}
+	return th.Name
+}
+func (th *TypeHint) Type() string { return "TypeHint" }
+
 type NullableType struct {
 	Token    Token      `json:"token"`
 	BaseType Expression `json:"base_type"`
@@ -877,13 +1379,30 @@ func (nt *NullableType) TokenLiteral() string { return nt.Token.Literal }
 func (nt *NullableType) String() string       { return "?" + nt.BaseType.String() }
 func (nt *NullableType) Type() string         { return "NullableType" }
 
+// ClosureUse is a variable captured by a closure's use clause, as in
+// use ($a, &$b). Token is the variable's token.
+type ClosureUse struct {
+	Token Token  `json:"token"`
+	Name  string `json:"name"`
+	ByRef bool   `json:"by_ref,omitempty"`
+}
+
+func (cu *ClosureUse) String() string {
+	if cu.ByRef {
+		return "&$" + cu.Name
+	}
+	return "$" + cu.Name
+}
+
 type AnonymousFunction struct {
-	Token      Token           `json:"token"`
-	Static     bool            `json:"static,omitempty"`
-	Parameters []*Variable     `json:"parameters"`
-	UseClause  []*Variable     `json:"use_clause,omitempty"`
-	ReturnType Expression      `json:"return_type,omitempty"`
-	Body       *BlockStatement `json:"body"`
+	Token       Token           `json:"token"`
+	Static      bool            `json:"static,omitempty"`
+	ReturnsRef  bool            `json:"returns_ref,omitempty"`
+	Parameters  []*Parameter    `json:"parameters"`
+	UseClause   []*ClosureUse   `json:"use_clause,omitempty"`
+	ReturnType  Expression      `json:"return_type,omitempty"`
+	Body        *BlockStatement `json:"body"`
+	IsGenerator bool            `json:"is_generator,omitempty"`
 }
 
 func (af *AnonymousFunction) expressionNode()      {}
@@ -901,7 +1420,11 @@ func (af *AnonymousFunction) String() string {
 	if af.Static {
 		out += "static "
 	}
-	out += "function(" + params + ")"
+	out += "function"
+	if af.ReturnsRef {
+		out += "&"
+	}
+	out += "(" + params + ")"
 
 	if af.ReturnType != nil {
 		out += ": " + af.ReturnType.String()
@@ -968,17 +1491,147 @@ func (ye *YieldExpression) Type() string { return "YieldExpression" }
 type TernaryExpression struct {
 	Token      Token      `json:"token"`
 	Condition  Expression `json:"condition"`
-	TrueValue  Expression `json:"true_value"`
+	TrueValue  Expression `json:"true_value"` // nil for the short form $a ?: $b
 	FalseValue Expression `json:"false_value"`
 }
 
 func (te *TernaryExpression) expressionNode()      {}
 func (te *TernaryExpression) TokenLiteral() string { return te.Token.Literal }
 func (te *TernaryExpression) String() string {
+	if te.TrueValue == nil {
+		return "(" + te.Condition.String() + " ?: " + te.FalseValue.String() + ")"
+	}
 	return "(" + te.Condition.String() + " ? " + te.TrueValue.String() + " : " + te.FalseValue.String() + ")"
 }
 func (te *TernaryExpression) Type() string { return "TernaryExpression" }
 
+type CoalesceExpression struct {
+	Token Token      `json:"token"`
+	Left  Expression `json:"left"`
+	Right Expression `json:"right"`
+}
+
+func (ce *CoalesceExpression) expressionNode()      {}
+func (ce *CoalesceExpression) TokenLiteral() string { return ce.Token.Literal }
+func (ce *CoalesceExpression) String() string {
+	return "(" + ce.Left.String() + " ?? " + ce.Right.String() + ")"
+}
+func (ce *CoalesceExpression) Type() string { return "CoalesceExpression" }
+
+type CloneExpression struct {
+	Token   Token      `json:"token"`
+	Operand Expression `json:"operand"`
+}
+
+func (ce *CloneExpression) expressionNode()      {}
+func (ce *CloneExpression) TokenLiteral() string { return ce.Token.Literal }
+func (ce *CloneExpression) String() string {
+	return "(clone " + ce.Operand.String() + ")"
+}
+func (ce *CloneExpression) Type() string { return "CloneExpression" }
+
+type InstanceofExpression struct {
+	Token Token      `json:"token"`
+	Left  Expression `json:"left"`
+	Class Expression `json:"class"` // Usually an Identifier; may be a variable
+}
+
+func (ie *InstanceofExpression) expressionNode()      {}
+func (ie *InstanceofExpression) TokenLiteral() string { return ie.Token.Literal }
+func (ie *InstanceofExpression) String() string {
+	return "(" + ie.Left.String() + " instanceof " + ie.Class.String() + ")"
+}
+func (ie *InstanceofExpression) Type() string { return "InstanceofExpression" }
+
+type ThrowExpression struct {
+	Token      Token      `json:"token"`
+	Expression Expression `json:"expression"`
+}
+
+func (te *ThrowExpression) expressionNode()      {}
+func (te *ThrowExpression) TokenLiteral() string { return te.Token.Literal }
+func (te *ThrowExpression) String() string {
+	return "throw " + te.Expression.String()
+}
+func (te *ThrowExpression) Type() string { return "ThrowExpression" }
+
+type ArrowFunction struct {
+	Token      Token        `json:"token"`
+	Static     bool         `json:"static,omitempty"`
+	ReturnsRef bool         `json:"returns_ref,omitempty"`
+	Parameters []*Parameter `json:"parameters"`
+	ReturnType Expression   `json:"return_type,omitempty"`
+	Body       Expression   `json:"body"`
+}
+
+func (af *ArrowFunction) expressionNode()      {}
+func (af *ArrowFunction) TokenLiteral() string { return af.Token.Literal }
+func (af *ArrowFunction) String() string {
+	params := ""
+	for i, p := range af.Parameters {
+		if i > 0 {
+			params += ", "
+		}
+		params += p.String()
+	}
+
+	out := ""
+	if af.Static {
+		out += "static "
+	}
+	out += "fn"
+	if af.ReturnsRef {
+		out += "&"
+	}
+	out += "(" + params + ")"
+
+	if af.ReturnType != nil {
+		out += ": " + af.ReturnType.String()
+	}
+
+	out += " => " + af.Body.String()
+	return out
+}
+func (af *ArrowFunction) Type() string { return "ArrowFunction" }
+
+type MatchExpression struct {
+	Token   Token       `json:"token"`
+	Subject Expression  `json:"subject"`
+	Arms    []*MatchArm `json:"arms"`
+}
+
+// MatchArm is a single "conditions => body" arm of a match expression.
+// The default arm has no conditions.
+type MatchArm struct {
+	Conditions []Expression `json:"conditions,omitempty"`
+	Body       Expression   `json:"body"`
+}
+
+func (ma *MatchArm) IsDefault() bool { return len(ma.Conditions) == 0 }
+
+func (ma *MatchArm) String() string {
+	out := "default"
+	if !ma.IsDefault() {
+		conditions := []string{}
+		for _, c := range ma.Conditions {
+			conditions = append(conditions, c.String())
+		}
+		out = strings.Join(conditions, ", ")
+	}
+	return out + " => " + ma.Body.String()
+}
+
+func (me *MatchExpression) expressionNode()      {}
+func (me *MatchExpression) TokenLiteral() string { return me.Token.Literal }
+func (me *MatchExpression) String() string {
+	arms := []string{}
+	for _, arm := range me.Arms {
+		arms = append(arms, arm.String())
+	}
+	return "match (" + me.Subject.String() + ") { " + strings.Join(arms, ", ") + " }"
+}
+func (me *MatchExpression) Type() string { return "MatchExpression" }
+
 type DeclareStatement struct {
 	Token      Token                    `json:"token"`
 	Directives map[string]Expression    `json:"directives"`
@@ -1008,6 +1661,18 @@ func (ds *DeclareStatement) String() string {
 func (ds *DeclareStatement) Type() string { return "DeclareStatement" }
 
 func ToJSON(node Node) ([]byte, error) {
+	return ToJSONWithOptions(node, JSONOptions{})
+}
+
+// JSONOptions configures ToJSONWithOptions
+type JSONOptions struct {
+	// Positions adds a "position" object with the line, column and byte
+	// offset of its token to every node that has a token
+	Positions bool
+}
+
+// ToJSONWithOptions serializes a node like ToJSON, configured by options
+func ToJSONWithOptions(node Node, options JSONOptions) ([]byte, error) {
 	data := map[string]any{
 		"type": node.Type(),
 	}
@@ -1032,7 +1697,11 @@ func ToJSON(node Node) ([]byte, error) {
 	case *ExpressionStatement:
 		data["expression"] = n.Expression
 	case *AssignmentExpression:
-		data["name"] = n.Name
+		if n.Name != nil {
+			data["name"] = n.Name
+		} else {
+			data["target"] = n.Target
+		}
 		data["value"] = n.Value
 	case *InfixExpression:
 		data["left"] = n.Left
@@ -1042,9 +1711,24 @@ func ToJSON(node Node) ([]byte, error) {
 		data["operator"] = n.Operator
 		data["right"] = n.Right
 	case *FunctionDeclaration:
+		if n.ReturnsRef {
+			data["returns_ref"] = n.ReturnsRef
+		}
 		data["name"] = n.Name
 		data["parameters"] = n.Parameters
+		if n.ReturnType != nil {
+			data["return_type"] = n.ReturnType
+		}
 		data["body"] = n.Body
+		if n.IsGenerator {
+			data["is_generator"] = n.IsGenerator
+		}
+		if len(n.LeadingComments) > 0 {
+			data["leading_comments"] = n.LeadingComments
+		}
+		if n.DocBlock != "" {
+			data["doc_block"] = n.DocBlock
+		}
 	case *ReturnStatement:
 		data["return_value"] = n.ReturnValue
 	case *BlockStatement:
@@ -1052,11 +1736,29 @@ func ToJSON(node Node) ([]byte, error) {
 	case *IfStatement:
 		data["condition"] = n.Condition
 		data["consequence"] = n.Consequence
+		if len(n.ElseIfs) > 0 {
+			data["elseifs"] = n.ElseIfs
+		}
 		if n.Alternative != nil {
 			data["alternative"] = n.Alternative
 		}
 	case *EchoStatement:
 		data["values"] = n.Values
+	case *UnsetStatement:
+		data["arguments"] = n.Arguments
+	case *GlobalStatement:
+		data["variables"] = n.Variables
+	case *ListExpression:
+		data["items"] = n.Items
+	case *DestructuringAssignment:
+		data["targets"] = n.Targets
+		data["source"] = n.Source
+	case *StaticVariableStatement:
+		data["variables"] = n.Variables
+	case *IssetExpression:
+		data["arguments"] = n.Arguments
+	case *EmptyExpression:
+		data["arguments"] = n.Arguments
 	case *CallExpression:
 		data["function"] = n.Function
 		data["arguments"] = n.Arguments
@@ -1076,12 +1778,19 @@ func ToJSON(node Node) ([]byte, error) {
 	case *WhileStatement:
 		data["condition"] = n.Condition
 		data["body"] = n.Body
+	case *SwitchStatement:
+		data["subject"] = n.Subject
+		data["cases"] = n.Cases
 	case *ForeachStatement:
 		data["array"] = n.Array
 		if n.Key != nil {
 			data["key"] = n.Key
 		}
-		data["value"] = n.Value
+		if n.ValuePattern != nil {
+			data["value_pattern"] = n.ValuePattern
+		} else {
+			data["value"] = n.Value
+		}
 		data["body"] = n.Body
 	case *BreakStatement:
 		if n.Level != nil {
@@ -1096,6 +1805,12 @@ func ToJSON(node Node) ([]byte, error) {
 	case *InterpolatedString:
 		data["parts"] = n.Parts
 	case *ClassDeclaration:
+		if n.Abstract {
+			data["abstract"] = n.Abstract
+		}
+		if n.Final {
+			data["final"] = n.Final
+		}
 		data["name"] = n.Name
 		if n.SuperClass != nil {
 			data["super_class"] = n.SuperClass
@@ -1111,25 +1826,67 @@ func ToJSON(node Node) ([]byte, error) {
 		if len(n.Constants) > 0 {
 			data["constants"] = n.Constants
 		}
+		if len(n.LeadingComments) > 0 {
+			data["leading_comments"] = n.LeadingComments
+		}
+		if n.DocBlock != "" {
+			data["doc_block"] = n.DocBlock
+		}
 	case *PropertyDeclaration:
 		data["visibility"] = n.Visibility
 		data["static"] = n.Static
+		if n.Readonly {
+			data["readonly"] = n.Readonly
+		}
+		if n.PropertyType != nil {
+			data["property_type"] = n.PropertyType
+		}
 		data["name"] = n.Name
 		if n.Value != nil {
 			data["value"] = n.Value
 		}
+		if len(n.LeadingComments) > 0 {
+			data["leading_comments"] = n.LeadingComments
+		}
+		if n.DocBlock != "" {
+			data["doc_block"] = n.DocBlock
+		}
 	case *MethodDeclaration:
+		if n.Abstract {
+			data["abstract"] = n.Abstract
+		}
+		if n.Final {
+			data["final"] = n.Final
+		}
 		data["visibility"] = n.Visibility
 		data["static"] = n.Static
+		if n.ReturnsRef {
+			data["returns_ref"] = n.ReturnsRef
+		}
 		data["name"] = n.Name
 		data["parameters"] = n.Parameters
+		if n.ReturnType != nil {
+			data["return_type"] = n.ReturnType
+		}
 		data["body"] = n.Body
+		if n.IsGenerator {
+			data["is_generator"] = n.IsGenerator
+		}
+		if len(n.LeadingComments) > 0 {
+			data["leading_comments"] = n.LeadingComments
+		}
+		if n.DocBlock != "" {
+			data["doc_block"] = n.DocBlock
+		}
 	case *NewExpression:
 		data["class_name"] = n.ClassName
 		data["arguments"] = n.Arguments
 	case *ObjectAccessExpression:
 		data["object"] = n.Object
 		data["property"] = n.Property
+		if n.Nullsafe {
+			data["nullsafe"] = n.Nullsafe
+		}
 	case *StaticAccessExpression:
 		data["class"] = n.Class
 		data["property"] = n.Property
@@ -1147,10 +1904,12 @@ func ToJSON(node Node) ([]byte, error) {
 			data["finally"] = n.Finally
 		}
 	case *CatchClause:
-		if n.ExceptionType != nil {
-			data["exception_type"] = n.ExceptionType
+		if len(n.ExceptionTypes) > 0 {
+			data["exception_types"] = n.ExceptionTypes
+		}
+		if n.Variable != nil {
+			data["variable"] = n.Variable
 		}
-		data["variable"] = n.Variable
 		data["body"] = n.Body
 	case *ThrowStatement:
 		data["expression"] = n.Expression
@@ -1166,12 +1925,30 @@ func ToJSON(node Node) ([]byte, error) {
 	case *RequireExpression:
 		data["path"] = n.Path
 		data["once"] = n.Once
+	case *PrintExpression:
+		data["value"] = n.Value
+	case *TypeHint:
+		if n.Name != "" {
+			data["name"] = n.Name
+		}
+		if n.Nullable {
+			data["nullable"] = n.Nullable
+		}
+		if len(n.Intersection) > 0 {
+			data["intersection"] = n.Intersection
+		}
+		if len(n.Union) > 0 {
+			data["union"] = n.Union
+		}
 	case *NullableType:
 		data["base_type"] = n.BaseType
 	case *AnonymousFunction:
 		if n.Static {
 			data["static"] = n.Static
 		}
+		if n.ReturnsRef {
+			data["returns_ref"] = n.ReturnsRef
+		}
 		data["parameters"] = n.Parameters
 		if len(n.UseClause) > 0 {
 			data["use_clause"] = n.UseClause
@@ -1180,6 +1957,9 @@ func ToJSON(node Node) ([]byte, error) {
 			data["return_type"] = n.ReturnType
 		}
 		data["body"] = n.Body
+		if n.IsGenerator {
+			data["is_generator"] = n.IsGenerator
+		}
 	case *NamespacedIdentifier:
 		data["namespace"] = n.Namespace
 		data["name"] = n.Name
@@ -1199,18 +1979,73 @@ func ToJSON(node Node) ([]byte, error) {
 		data["parameters"] = n.Parameters
 	case *TraitDeclaration:
 		data["name"] = n.Name
+		if len(n.Constants) > 0 {
+			data["constants"] = n.Constants
+		}
 		data["properties"] = n.Properties
 		data["methods"] = n.Methods
 	case *TraitUse:
 		data["traits"] = n.Traits
+	case *EnumDeclaration:
+		data["name"] = n.Name
+		if n.BackingType != nil {
+			data["backing_type"] = n.BackingType
+		}
+		if len(n.Interfaces) > 0 {
+			data["interfaces"] = n.Interfaces
+		}
+		if len(n.TraitUses) > 0 {
+			data["trait_uses"] = n.TraitUses
+		}
+		data["cases"] = n.Cases
+		if len(n.Constants) > 0 {
+			data["constants"] = n.Constants
+		}
+		data["methods"] = n.Methods
+	case *EnumCase:
+		data["name"] = n.Name
+		if n.Value != nil {
+			data["value"] = n.Value
+		}
 	case *ConstantDeclaration:
 		data["visibility"] = n.Visibility
 		data["name"] = n.Name
 		data["value"] = n.Value
+	case *ThrowExpression:
+		data["expression"] = n.Expression
+	case *ArrowFunction:
+		if n.Static {
+			data["static"] = n.Static
+		}
+		if n.ReturnsRef {
+			data["returns_ref"] = n.ReturnsRef
+		}
+		data["parameters"] = n.Parameters
+		if n.ReturnType != nil {
+			data["return_type"] = n.ReturnType
+		}
+		data["body"] = n.Body
+	case *MatchExpression:
+		data["subject"] = n.Subject
+		data["arms"] = n.Arms
 	case *TernaryExpression:
 		data["condition"] = n.Condition
-		data["true_value"] = n.TrueValue
+		if n.TrueValue != nil {
+			data["true_value"] = n.TrueValue
+		} else {
+			data["short"] = true
+		}
 		data["false_value"] = n.FalseValue
+	case *CoalesceExpression:
+		data["left"] = n.Left
+		data["right"] = n.Right
+	case *InstanceofExpression:
+		data["left"] = n.Left
+		data["class"] = n.Class
+	case *CloneExpression:
+		data["operand"] = n.Operand
+	case *CommaExpression:
+		data["expressions"] = n.Expressions
 	case *DeclareStatement:
 		data["directives"] = n.Directives
 		if n.Body != nil {
@@ -1218,5 +2053,13 @@ func ToJSON(node Node) ([]byte, error) {
 		}
 	}
 
+	// Nested nodes carry their own type so FromJSON can rebuild them
+	for key, value := range data {
+		data[key] = encodeValue(reflect.ValueOf(value), options)
+	}
+	if position, ok := nodePosition(node); ok && options.Positions {
+		data["position"] = position
+	}
+
 	return json.MarshalIndent(data, "", "  ")
 }
@@ -0,0 +1,101 @@
+package gophpparser
+
+import "strings"
+
+// builtinClasses lists PHP's built-in classes and interfaces that code may
+// reference without declaring them. Keys are lowercase because PHP class
+// names are case-insensitive.
+var builtinClasses = map[string]*Symbol{}
+
+func init() {
+	classes := []string{
+		"stdClass", "Closure", "Generator", "WeakMap", "WeakReference",
+		"ArrayObject", "ArrayIterator", "SplStack", "SplQueue", "SplObjectStorage",
+		"DateTime", "DateTimeImmutable", "DateInterval", "DatePeriod", "DateTimeZone",
+		"Exception", "ErrorException", "Error", "TypeError", "ValueError",
+		"ArithmeticError", "DivisionByZeroError", "ArgumentCountError",
+		"AssertionError", "CompileError", "ParseError", "UnhandledMatchError",
+		"JsonException", "LogicException", "BadFunctionCallException",
+		"BadMethodCallException", "DomainException", "InvalidArgumentException",
+		"LengthException", "OutOfRangeException", "RuntimeException",
+		"OutOfBoundsException", "OverflowException", "RangeException",
+		"UnderflowException", "UnexpectedValueException",
+	}
+	interfaces := []string{
+		"Throwable", "Traversable", "Iterator", "IteratorAggregate",
+		"ArrayAccess", "Countable", "Serializable", "JsonSerializable",
+		"Stringable", "DateTimeInterface", "UnitEnum", "BackedEnum",
+	}
+
+	for _, name := range classes {
+		registerBuiltinClass(name, CLASS_SYMBOL)
+	}
+	for _, name := range interfaces {
+		registerBuiltinClass(name, INTERFACE_SYMBOL)
+	}
+}
+
+func registerBuiltinClass(name string, symbolType SymbolType) {
+	builtinClasses[strings.ToLower(name)] = &Symbol{
+		Name:           name,
+		FullyQualified: name,
+		Type:           symbolType,
+		BuiltIn:        true,
+	}
+}
+
+// lookupBuiltinClass returns the built-in class or interface with the given
+// global name, or nil if PHP does not provide one.
+func lookupBuiltinClass(name string, symbolType SymbolType) *Symbol {
+	symbol, exists := builtinClasses[strings.ToLower(strings.TrimPrefix(name, "\\"))]
+	if !exists || symbol.Type != symbolType {
+		return nil
+	}
+	return symbol
+}
+
+// builtinTypes lists the type names that are not classes, along with the
+// relative class names whose meaning depends on the enclosing class.
+var builtinTypes = map[string]bool{
+	"int": true, "float": true, "string": true, "bool": true, "array": true,
+	"object": true, "callable": true, "iterable": true, "mixed": true,
+	"void": true, "never": true, "null": true, "false": true, "true": true,
+	"self": true, "static": true, "parent": true,
+}
+
+// isBuiltinTypeName reports whether a type name in a type hint is a
+// built-in type rather than a reference to a class.
+func isBuiltinTypeName(name string) bool {
+	return builtinTypes[strings.ToLower(name)]
+}
+
+// builtinExceptionParents maps each built-in throwable class to the class
+// or interface it directly extends, keyed and valued in lowercase
+var builtinExceptionParents = map[string]string{
+	"exception":                "throwable",
+	"error":                    "throwable",
+	"errorexception":           "exception",
+	"typeerror":                "error",
+	"valueerror":               "error",
+	"arithmeticerror":          "error",
+	"divisionbyzeroerror":      "arithmeticerror",
+	"argumentcounterror":       "typeerror",
+	"assertionerror":           "error",
+	"compileerror":             "error",
+	"parseerror":               "compileerror",
+	"unhandledmatcherror":      "error",
+	"jsonexception":            "exception",
+	"logicexception":           "exception",
+	"badfunctioncallexception": "logicexception",
+	"badmethodcallexception":   "badfunctioncallexception",
+	"domainexception":          "logicexception",
+	"invalidargumentexception": "logicexception",
+	"lengthexception":          "logicexception",
+	"outofrangeexception":      "logicexception",
+	"runtimeexception":         "exception",
+	"outofboundsexception":     "runtimeexception",
+	"overflowexception":        "runtimeexception",
+	"rangeexception":           "runtimeexception",
+	"underflowexception":       "runtimeexception",
+	"unexpectedvalueexception": "runtimeexception",
+}
@@ -0,0 +1,57 @@
+package gophpparser
+
+// CallArgs returns the arguments of a call in source order, or nil for a
+// nil call
+func CallArgs(call *CallExpression) []Expression {
+	if call == nil {
+		return nil
+	}
+	return call.Arguments
+}
+
+// StringArg returns the value of the i-th argument when it is a plain
+// string literal. Interpolated strings and any other expression report
+// false, since their value is only known at run time.
+func (ce *CallExpression) StringArg(i int) (string, bool) {
+	if i < 0 || i >= len(ce.Arguments) {
+		return "", false
+	}
+	literal, ok := ce.Arguments[i].(*StringLiteral)
+	if !ok {
+		return "", false
+	}
+	return literal.Value, true
+}
+
+// CalleeName returns the name a call invokes as written: strlen for a
+// function call, Route::get for a static method call and $router->get for
+// a method call on a variable. A method called on any other expression is
+// reported as ->get. The name is empty when it is computed at run time, as
+// in $handler() or $obj->$method().
+func (ce *CallExpression) CalleeName() string {
+	switch callee := ce.Function.(type) {
+	case *Identifier:
+		return callee.Value
+	case *StaticAccessExpression:
+		class, ok := callee.Class.(*Identifier)
+		method, isName := callee.Property.(*Identifier)
+		if !ok || !isName {
+			return ""
+		}
+		return class.Value + "::" + method.Value
+	case *ObjectAccessExpression:
+		method, ok := callee.Property.(*Identifier)
+		if !ok {
+			return ""
+		}
+		operator := "->"
+		if callee.Nullsafe {
+			operator = "?->"
+		}
+		if object, ok := callee.Object.(*Variable); ok {
+			return object.String() + operator + method.Value
+		}
+		return operator + method.Value
+	}
+	return ""
+}
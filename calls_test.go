@@ -0,0 +1,66 @@
+package gophpparser
+
+import "testing"
+
+func TestCallHelpers(t *testing.T) {
+	input := `<?php
+Route::get('/users/{id}', $handler);
+strlen("name");
+$router?->post("/save", fn() => 1);
+$this->container()->get($id);
+$handler("/{$id}");
+$obj->$method();
+?>`
+
+	l := New(input)
+	p := NewParser(l)
+	program := p.ParseProgram()
+
+	if len(p.Errors()) != 0 {
+		t.Errorf("parser has %d errors", len(p.Errors()))
+		for _, err := range p.Errors() {
+			t.Errorf("parser error: %q", err)
+		}
+		return
+	}
+
+	tests := []struct {
+		callee    string
+		arguments int
+		path      string // Value of the first argument when a plain string
+	}{
+		{"Route::get", 2, "/users/{id}"},
+		{"strlen", 1, "name"},
+		{"$router?->post", 2, "/save"},
+		{"->get", 1, ""},
+		{"", 1, ""},
+		{"", 0, ""},
+	}
+
+	if len(program.Statements) != len(tests) {
+		t.Fatalf("program.Statements does not contain %d statements. got=%d", len(tests), len(program.Statements))
+	}
+
+	for i, tt := range tests {
+		stmt := program.Statements[i].(*ExpressionStatement)
+		call, ok := stmt.Expression.(*CallExpression)
+		if !ok {
+			t.Fatalf("statement %d is not *CallExpression. got=%T", i, stmt.Expression)
+		}
+
+		if got := call.CalleeName(); got != tt.callee {
+			t.Errorf("statement %d: CalleeName() wrong. expected=%q, got=%q", i, tt.callee, got)
+		}
+		if got := len(CallArgs(call)); got != tt.arguments {
+			t.Errorf("statement %d: expected %d arguments. got=%d", i, tt.arguments, got)
+		}
+		path, ok := call.StringArg(0)
+		if ok != (tt.path != "") || path != tt.path {
+			t.Errorf("statement %d: StringArg(0) wrong. expected=%q, got=%q (ok=%t)", i, tt.path, path, ok)
+		}
+	}
+
+	if CallArgs(nil) != nil {
+		t.Errorf("CallArgs(nil) should be nil")
+	}
+}
@@ -0,0 +1,305 @@
+package gophpparser
+
+import (
+	"math"
+	"strconv"
+	"strings"
+)
+
+// EvalConstExpr evaluates a constant expression built from literals, known
+// constants and the arithmetic, bitwise and concatenation operators. consts
+// maps constant names to their values and may be nil. The result is an
+// int64, float64, string, bool or nil, following PHP's conversion rules;
+// integer arithmetic that overflows gives a float64, as it does in PHP.
+// The ok flag is false when the expression is not constant, references an
+// unknown constant, divides by zero or mixes types PHP would reject.
+func EvalConstExpr(expr Expression, consts map[string]any) (any, bool) {
+	switch e := expr.(type) {
+	case *IntegerLiteral:
+		return e.Value, true
+	case *FloatLiteral:
+		return e.Value, true
+	case *StringLiteral:
+		return e.Value, true
+	case *BooleanLiteral:
+		return e.Value, true
+	case *NullLiteral:
+		return nil, true
+	case *Identifier:
+		value, exists := consts[e.Value]
+		return value, exists
+	case *PrefixExpression:
+		right, ok := EvalConstExpr(e.Right, consts)
+		if !ok {
+			return nil, false
+		}
+		return evalConstPrefix(e.Operator, right)
+	case *InfixExpression:
+		left, ok := EvalConstExpr(e.Left, consts)
+		if !ok {
+			return nil, false
+		}
+		right, ok := EvalConstExpr(e.Right, consts)
+		if !ok {
+			return nil, false
+		}
+		return evalConstInfix(e.Operator, left, right)
+	}
+
+	return nil, false
+}
+
+func evalConstPrefix(operator string, right any) (any, bool) {
+	switch operator {
+	case "!":
+		return !constToBool(right), true
+	case "-":
+		n, ok := constToNumber(right)
+		if !ok {
+			return nil, false
+		}
+		if i, isInt := n.(int64); isInt && i != math.MinInt64 {
+			return -i, true
+		}
+		return -constToFloat(n), true
+	case "~":
+		i, ok := right.(int64)
+		if !ok {
+			return nil, false
+		}
+		return ^i, true
+	}
+
+	return nil, false
+}
+
+func evalConstInfix(operator string, left, right any) (any, bool) {
+	switch operator {
+	case ".":
+		l, ok := constToString(left)
+		if !ok {
+			return nil, false
+		}
+		r, ok := constToString(right)
+		if !ok {
+			return nil, false
+		}
+		return l + r, true
+	case "|", "&", "^", "<<", ">>", "%":
+		l, ok := constToInt(left)
+		if !ok {
+			return nil, false
+		}
+		r, ok := constToInt(right)
+		if !ok {
+			return nil, false
+		}
+		switch operator {
+		case "|":
+			return l | r, true
+		case "&":
+			return l & r, true
+		case "^":
+			return l ^ r, true
+		case "<<":
+			if r < 0 {
+				return nil, false
+			}
+			return l << r, true
+		case ">>":
+			if r < 0 {
+				return nil, false
+			}
+			return l >> r, true
+		default:
+			if r == 0 {
+				return nil, false
+			}
+			return l % r, true
+		}
+	case "+", "-", "*", "/", "**":
+		l, ok := constToNumber(left)
+		if !ok {
+			return nil, false
+		}
+		r, ok := constToNumber(right)
+		if !ok {
+			return nil, false
+		}
+
+		li, lIsInt := l.(int64)
+		ri, rIsInt := r.(int64)
+		if lIsInt && rIsInt {
+			switch operator {
+			case "+":
+				if sum := li + ri; (sum > li) == (ri > 0) {
+					return sum, true
+				}
+				return float64(li) + float64(ri), true
+			case "-":
+				if difference := li - ri; (difference < li) == (ri > 0) {
+					return difference, true
+				}
+				return float64(li) - float64(ri), true
+			case "*":
+				if product, ok := multiplyInts(li, ri); ok {
+					return product, true
+				}
+				return float64(li) * float64(ri), true
+			case "**":
+				if power, ok := powInts(li, ri); ok {
+					return power, true
+				}
+				return math.Pow(float64(li), float64(ri)), true
+			default:
+				if ri == 0 {
+					return nil, false
+				}
+				// Integer division only stays an integer when exact and in
+				// range; PHP_INT_MIN / -1 overflows to a float
+				if li%ri == 0 && !(li == math.MinInt64 && ri == -1) {
+					return li / ri, true
+				}
+				return float64(li) / float64(ri), true
+			}
+		}
+
+		lf, rf := constToFloat(l), constToFloat(r)
+		switch operator {
+		case "+":
+			return lf + rf, true
+		case "-":
+			return lf - rf, true
+		case "*":
+			return lf * rf, true
+		case "**":
+			return math.Pow(lf, rf), true
+		default:
+			if rf == 0 {
+				return nil, false
+			}
+			return lf / rf, true
+		}
+	}
+
+	return nil, false
+}
+
+// multiplyInts multiplies two integers, reporting false on overflow
+func multiplyInts(a, b int64) (int64, bool) {
+	if a == 0 || b == 0 {
+		return 0, true
+	}
+	product := a * b
+	if product/b != a || (a == -1 && b == math.MinInt64) || (b == -1 && a == math.MinInt64) {
+		return 0, false
+	}
+	return product, true
+}
+
+// powInts raises base to a non-negative integer exponent, reporting false
+// when the exponent is negative or the result overflows
+func powInts(base, exponent int64) (int64, bool) {
+	if exponent < 0 {
+		return 0, false
+	}
+	switch {
+	case exponent == 0:
+		return 1, true
+	case base == 0 || base == 1:
+		return base, true
+	case base == -1:
+		if exponent%2 == 0 {
+			return 1, true
+		}
+		return -1, true
+	}
+	// Any other base overflows within 64 multiplications
+	result := int64(1)
+	for ; exponent > 0; exponent-- {
+		var ok bool
+		if result, ok = multiplyInts(result, base); !ok {
+			return 0, false
+		}
+	}
+	return result, true
+}
+
+// constToNumber converts a value to int64 or float64 as PHP arithmetic
+// would. Non-numeric strings are rejected rather than silently becoming 0.
+func constToNumber(value any) (any, bool) {
+	switch v := value.(type) {
+	case int64, float64:
+		return v, true
+	case bool:
+		if v {
+			return int64(1), true
+		}
+		return int64(0), true
+	case nil:
+		return int64(0), true
+	case string:
+		s := strings.TrimSpace(v)
+		if i, err := strconv.ParseInt(s, 10, 64); err == nil {
+			return i, true
+		}
+		if f, err := strconv.ParseFloat(s, 64); err == nil {
+			return f, true
+		}
+	}
+
+	return nil, false
+}
+
+func constToInt(value any) (int64, bool) {
+	n, ok := constToNumber(value)
+	if !ok {
+		return 0, false
+	}
+	if f, isFloat := n.(float64); isFloat {
+		return int64(f), true
+	}
+	return n.(int64), true
+}
+
+func constToFloat(number any) float64 {
+	if i, isInt := number.(int64); isInt {
+		return float64(i)
+	}
+	return number.(float64)
+}
+
+func constToString(value any) (string, bool) {
+	switch v := value.(type) {
+	case string:
+		return v, true
+	case int64:
+		return strconv.FormatInt(v, 10), true
+	case float64:
+		return strconv.FormatFloat(v, 'G', 14, 64), true
+	case bool:
+		if v {
+			return "1", true
+		}
+		return "", true
+	case nil:
+		return "", true
+	}
+
+	return "", false
+}
+
+func constToBool(value any) bool {
+	switch v := value.(type) {
+	case bool:
+		return v
+	case int64:
+		return v != 0
+	case float64:
+		return v != 0
+	case string:
+		return v != "" && v != "0"
+	}
+
+	return false
+}
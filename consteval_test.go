@@ -0,0 +1,75 @@
+package gophpparser
+
+import "testing"
+
+func parseConstValue(t *testing.T, input string) Expression {
+	l := New("<?php " + input)
+	p := NewParser(l)
+	program := p.ParseProgram()
+
+	if len(p.Errors()) != 0 {
+		t.Fatalf("parser errors for %q: %v", input, p.Errors())
+	}
+
+	constant, ok := program.Statements[0].(*ConstantDeclaration)
+	if !ok {
+		t.Fatalf("program.Statements[0] is not *ConstantDeclaration. got=%T",
+			program.Statements[0])
+	}
+
+	return constant.Value
+}
+
+func TestEvalConstExpr(t *testing.T) {
+	consts := map[string]any{"FLAG_A": int64(1), "FLAG_B": int64(2)}
+
+	tests := []struct {
+		input    string
+		expected any
+	}{
+		{"const X = 1 << 4;", int64(16)},
+		{"const X = 2 + 3 * 4;", int64(14)},
+		{"const X = 'a' . 'b';", "ab"},
+		{"const X = FLAG_A | FLAG_B;", int64(3)},
+		{"const X = 7 / 2;", 3.5},
+		{"const X = 'v' . 2;", "v2"},
+		{"const X = ~0;", int64(-1)},
+		{"const X = 2 ** 3;", int64(8)},
+		{"const X = (-1) ** 5;", int64(-1)},
+		{"const X = 2 ** -1;", 0.5},
+		{"const X = 2 ** 63;", 9223372036854775808.0},
+		{"const X = 9223372036854775807 + 1;", 9223372036854775808.0},
+		{"const X = -9223372036854775807 - 2;", -9223372036854775809.0},
+		{"const X = (-9223372036854775807 - 1) / -1;", 9223372036854775808.0},
+		{"const X = 4611686018427387904 * 2;", 9223372036854775808.0},
+		{"const X = 3037000500 * 3037000500;", 9223372037000250000.0},
+	}
+
+	for _, tt := range tests {
+		value, ok := EvalConstExpr(parseConstValue(t, tt.input), consts)
+		if !ok {
+			t.Errorf("%q could not be evaluated", tt.input)
+			continue
+		}
+		if value != tt.expected {
+			t.Errorf("%q evaluated wrong. want=%v (%T), got=%v (%T)",
+				tt.input, tt.expected, tt.expected, value, value)
+		}
+	}
+}
+
+func TestEvalConstExprFailures(t *testing.T) {
+	tests := []string{
+		"const X = 1 / 0;",
+		"const X = 5 % 0;",
+		"const X = 'abc' + 1;",
+		"const X = UNKNOWN + 1;",
+		"const X = $var + 1;",
+	}
+
+	for _, input := range tests {
+		if value, ok := EvalConstExpr(parseConstValue(t, input), nil); ok {
+			t.Errorf("%q should not evaluate, got=%v", input, value)
+		}
+	}
+}
@@ -68,7 +68,7 @@ func DebugParsePHP(input string) *DebugParseErrors {
 	debug.ParsingErrors = parser.Errors()
 	
 	// Analyze missing prefix functions
-	debug.analyzeMissingPrefixFunctions()
+	debug.analyzeMissingPrefixFunctions(parser.StructuredErrors())
 	
 	return debug
 }
@@ -254,23 +254,85 @@ func getTokenTypeName(tokenType TokenType) string {
 		return "COMMENT"
 	case DOCBLOCK:
 		return "DOCBLOCK"
+	case BIT_AND:
+		return "BIT_AND"
+	case BIT_OR:
+		return "BIT_OR"
+	case BIT_XOR:
+		return "BIT_XOR"
+	case BIT_NOT:
+		return "BIT_NOT"
+	case SHIFT_LEFT:
+		return "SHIFT_LEFT"
+	case SHIFT_RIGHT:
+		return "SHIFT_RIGHT"
+	case READONLY:
+		return "READONLY"
+	case ENUM:
+		return "ENUM"
+	case CASE:
+		return "CASE"
+	case AT:
+		return "AT"
+	case SWITCH:
+		return "SWITCH"
+	case DEFAULT:
+		return "DEFAULT"
+	case POW:
+		return "POW"
+	case POW_ASSIGN:
+		return "POW_ASSIGN"
+	case ELLIPSIS:
+		return "ELLIPSIS"
+	case ATTRIBUTE_START:
+		return "ATTRIBUTE_START"
+	case PLUS_ASSIGN:
+		return "PLUS_ASSIGN"
+	case MINUS_ASSIGN:
+		return "MINUS_ASSIGN"
+	case MULTIPLY_ASSIGN:
+		return "MULTIPLY_ASSIGN"
+	case DIVIDE_ASSIGN:
+		return "DIVIDE_ASSIGN"
+	case CONCAT_ASSIGN:
+		return "CONCAT_ASSIGN"
+	case MODULO_ASSIGN:
+		return "MODULO_ASSIGN"
+	case BIT_AND_ASSIGN:
+		return "BIT_AND_ASSIGN"
+	case BIT_OR_ASSIGN:
+		return "BIT_OR_ASSIGN"
+	case BIT_XOR_ASSIGN:
+		return "BIT_XOR_ASSIGN"
+	case SHIFT_LEFT_ASSIGN:
+		return "SHIFT_LEFT_ASSIGN"
+	case SHIFT_RIGHT_ASSIGN:
+		return "SHIFT_RIGHT_ASSIGN"
+	case LOGICAL_AND:
+		return "LOGICAL_AND"
+	case LOGICAL_OR:
+		return "LOGICAL_OR"
+	case LOGICAL_XOR:
+		return "LOGICAL_XOR"
 	default:
 		return fmt.Sprintf("UNKNOWN_TOKEN(%d)", int(tokenType))
 	}
 }
 
 // analyzeMissingPrefixFunctions identifies which prefix functions are missing
-func (d *DebugParseErrors) analyzeMissingPrefixFunctions() {
+// by matching each unexpected-token error to the token at its position
+func (d *DebugParseErrors) analyzeMissingPrefixFunctions(errors []*ParseError) {
 	missingPrefixes := make(map[string]bool)
 	
-	for _, errMsg := range d.ParsingErrors {
-		if strings.Contains(errMsg, "no prefix parse function for") {
-			// Extract the token type from error message
-			start := strings.Index(errMsg, "no prefix parse function for ") + len("no prefix parse function for ")
-			end := strings.Index(errMsg[start:], " found")
-			if end != -1 {
-				tokenType := errMsg[start : start+end]
-				missingPrefixes[tokenType] = true
+	for _, err := range errors {
+		// Only the bare "unexpected 'x'" form comes from a missing prefix function
+		if !strings.HasPrefix(err.Message, "unexpected '") || !strings.HasSuffix(err.Message, "'") {
+			continue
+		}
+		for _, token := range d.Tokens {
+			if token.Line == err.Line && token.Column == err.Column {
+				missingPrefixes[token.TypeName] = true
+				break
 			}
 		}
 	}
@@ -334,7 +396,7 @@ func (d *DebugParseErrors) GetMostCommonErrors() map[string]int {
 	errorCounts := make(map[string]int)
 	
 	for _, err := range d.ParsingErrors {
-		if strings.Contains(err, "no prefix parse function") {
+		if strings.HasPrefix(err, "unexpected '") {
 			errorCounts["missing_prefix_function"]++
 		} else if strings.Contains(err, "expected next token") {
 			errorCounts["unexpected_token"]++
@@ -8,6 +8,9 @@ type ParseError struct {
 	Message string
 	Line    int
 	Column  int
+
+	// text is how Parser.Errors reports the error
+	text string
 }
 
 func (e *ParseError) Error() string {
@@ -0,0 +1,103 @@
+package gophpparser
+
+import (
+	"errors"
+	"reflect"
+	"strings"
+)
+
+// FlatNode is one node of a flattened AST. Children point at their parent
+// by ID instead of being nested, which suits graph databases and diff tools.
+type FlatNode struct {
+	ID         int            `json:"id"`
+	ParentID   int            `json:"parent_id"` // 0 for the root
+	Kind       string         `json:"kind"`
+	Attributes map[string]any `json:"attributes,omitempty"` // Scalar fields only
+}
+
+// Flatten lists the nodes of the AST rooted at root in depth-first order.
+// IDs are assigned in that order starting at 1, so flattening the same tree
+// twice gives the same IDs. Attributes hold the node's scalar fields under
+// their JSON names, plus the line and column of its token.
+func Flatten(root Node) ([]FlatNode, error) {
+	if isNilNode(root) {
+		return nil, errors.New("cannot flatten a nil node")
+	}
+
+	var nodes []FlatNode
+	var flatten func(node Node, parentID int)
+	flatten = func(node Node, parentID int) {
+		id := len(nodes) + 1
+		nodes = append(nodes, FlatNode{
+			ID:         id,
+			ParentID:   parentID,
+			Kind:       node.Type(),
+			Attributes: scalarAttributes(node),
+		})
+		for _, child := range childNodes(node) {
+			flatten(child, id)
+		}
+	}
+	flatten(root, 0)
+
+	return nodes, nil
+}
+
+// childNodes returns the direct children of node in the order Walk visits them
+func childNodes(node Node) []Node {
+	var children []Node
+	Walk(node, func(n Node) bool {
+		if n == node {
+			return true
+		}
+		children = append(children, n)
+		return false
+	})
+	return children
+}
+
+// scalarAttributes collects the string, bool and numeric fields of a node
+// keyed by their JSON names. Zero values of omitempty fields are left out.
+func scalarAttributes(node Node) map[string]any {
+	value := reflect.ValueOf(node)
+	if value.Kind() == reflect.Pointer {
+		value = value.Elem()
+	}
+	if value.Kind() != reflect.Struct {
+		return nil
+	}
+
+	attributes := map[string]any{}
+	if field := value.FieldByName("Token"); field.IsValid() {
+		if token, ok := field.Interface().(Token); ok && token.Line > 0 {
+			attributes["line"] = token.Line
+			attributes["column"] = token.Column
+		}
+	}
+
+	for i := 0; i < value.NumField(); i++ {
+		field := value.Type().Field(i)
+		tag := field.Tag.Get("json")
+		if !field.IsExported() || tag == "" || tag == "-" || field.Type == reflect.TypeOf(Token{}) {
+			continue
+		}
+
+		name, options, _ := strings.Cut(tag, ",")
+		fieldValue := value.Field(i)
+		switch fieldValue.Kind() {
+		case reflect.String, reflect.Bool,
+			reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+			reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64,
+			reflect.Float32, reflect.Float64:
+			if strings.Contains(options, "omitempty") && fieldValue.IsZero() {
+				continue
+			}
+			attributes[name] = fieldValue.Interface()
+		}
+	}
+
+	if len(attributes) == 0 {
+		return nil
+	}
+	return attributes
+}
@@ -0,0 +1,58 @@
+package gophpparser
+
+import "testing"
+
+func TestFlatten(t *testing.T) {
+	program, err := Parse("<?php $a = 1 + 2; echo $a; ?>")
+	if err != nil {
+		t.Fatalf("Parse failed: %v", err)
+	}
+
+	nodes, err := Flatten(program)
+	if err != nil {
+		t.Fatalf("Flatten failed: %v", err)
+	}
+
+	expected := []struct {
+		kind     string
+		parentID int
+	}{
+		{"Program", 0},
+		{"ExpressionStatement", 1},
+		{"AssignmentExpression", 2},
+		{"Variable", 3},
+		{"InfixExpression", 3},
+		{"IntegerLiteral", 5},
+		{"IntegerLiteral", 5},
+		{"EchoStatement", 1},
+		{"Variable", 8},
+	}
+
+	if len(nodes) != len(expected) {
+		t.Fatalf("expected %d nodes, got=%d (%v)", len(expected), len(nodes), nodes)
+	}
+	for i, want := range expected {
+		node := nodes[i]
+		if node.ID != i+1 {
+			t.Errorf("nodes[%d].ID wrong. expected=%d, got=%d", i, i+1, node.ID)
+		}
+		if node.Kind != want.kind || node.ParentID != want.parentID {
+			t.Errorf("nodes[%d] wrong. expected %s under %d, got %s under %d",
+				i, want.kind, want.parentID, node.Kind, node.ParentID)
+		}
+	}
+
+	if nodes[4].Attributes["operator"] != "+" {
+		t.Errorf("infix operator attribute wrong. got=%v", nodes[4].Attributes)
+	}
+	if nodes[5].Attributes["value"] != int64(1) {
+		t.Errorf("integer value attribute wrong. got=%v", nodes[5].Attributes)
+	}
+	if nodes[8].Attributes["name"] != "a" || nodes[8].Attributes["line"] != 1 {
+		t.Errorf("variable attributes wrong. got=%v", nodes[8].Attributes)
+	}
+
+	if _, err := Flatten(nil); err == nil {
+		t.Errorf("expected an error flattening a nil node")
+	}
+}
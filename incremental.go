@@ -0,0 +1,124 @@
+package gophpparser
+
+import (
+	"fmt"
+	"strings"
+)
+
+// statementSpan is the byte range of a top-level statement in the source it
+// was parsed from. It runs up to the start of the following token, so the
+// whitespace after a statement belongs to it.
+type statementSpan struct {
+	start int
+	end   int
+}
+
+// ReparseRange parses source after an edit to prev, a program previously
+// returned by Parse or ParseProgram. source is the complete new text and
+// [changedStart, changedEnd) is the byte range of the replacement text in it.
+//
+// Only the top-level statements touched by the edit are re-parsed; the others
+// are reused from prev. Statements after the edit are reused only when the
+// edit did not add or remove lines, so their line numbers stay correct, and
+// when the edit changed the length of the source they are copied with the
+// byte offsets of their tokens moved to match it. prev is never modified.
+// Whenever the edit cannot be isolated this way, the whole source is parsed.
+func ReparseRange(prev *Program, source string, changedStart, changedEnd int) (*Program, error) {
+	if prev == nil || len(prev.spans) == 0 || len(prev.spans) != len(prev.Statements) {
+		return Parse(source)
+	}
+	if changedStart < 0 || changedStart > changedEnd || changedEnd > len(source) {
+		return nil, fmt.Errorf("invalid changed range [%d, %d) for source of length %d", changedStart, changedEnd, len(source))
+	}
+
+	// Map the end of the edit back into the previous source and make sure
+	// the text around the edit is really unchanged
+	delta := len(source) - len(prev.source)
+	oldEnd := changedEnd - delta
+	if oldEnd < changedStart || oldEnd > len(prev.source) ||
+		prev.source[:changedStart] != source[:changedStart] ||
+		prev.source[oldEnd:] != source[changedEnd:] {
+		return Parse(source)
+	}
+
+	// Edits before the first statement may touch the opening tag
+	if changedStart < prev.spans[0].start {
+		return Parse(source)
+	}
+
+	first := -1
+	for i, span := range prev.spans {
+		if span.end >= changedStart {
+			first = i
+			break
+		}
+	}
+	if first == -1 {
+		return Parse(source)
+	}
+
+	// Later statements keep their positions only if no lines moved; any
+	// statement sharing the edit's last line may still shift columns
+	last := len(prev.spans) - 1
+	oldText := prev.source[changedStart:oldEnd]
+	newText := source[changedStart:changedEnd]
+	if strings.Count(oldText, "\n") == strings.Count(newText, "\n") {
+		lineEnd := len(prev.source)
+		if i := strings.IndexByte(prev.source[oldEnd:], '\n'); i >= 0 {
+			lineEnd = oldEnd + i
+		}
+		for last > first && prev.spans[last].start > lineEnd {
+			last--
+		}
+	}
+
+	// The final statement's span stops short of a closing tag, so re-parse
+	// through the end of the source when it is included
+	reStart := prev.spans[first].start
+	reEnd := prev.spans[last].end + delta
+	if last == len(prev.spans)-1 {
+		reEnd = len(source)
+	}
+	if reEnd < changedEnd {
+		return Parse(source)
+	}
+
+	p := NewParser(newLexerAt(source, reStart))
+	statements, spans := p.parseTopLevelStatements(reEnd)
+	if len(p.Errors()) > 0 {
+		return nil, fmt.Errorf("parser errors:\n%s", strings.Join(p.Errors(), "\n"))
+	}
+
+	// The re-parsed statements must stop exactly where the reused ones
+	// begin, otherwise the edit changed how the following code groups
+	if last < len(prev.spans)-1 && p.curToken.Position != reEnd {
+		return Parse(source)
+	}
+	if last == len(prev.spans)-1 && !p.curTokenIs(EOF) {
+		return Parse(source)
+	}
+
+	program := &Program{source: source}
+	program.Statements = make([]Statement, 0, first+len(statements)+len(prev.Statements)-last-1)
+	program.spans = make([]statementSpan, 0, cap(program.Statements))
+
+	program.Statements = append(program.Statements, prev.Statements[:first]...)
+	program.spans = append(program.spans, prev.spans[:first]...)
+
+	program.Statements = append(program.Statements, statements...)
+	program.spans = append(program.spans, spans...)
+
+	for i := last + 1; i < len(prev.Statements); i++ {
+		stmt := prev.Statements[i]
+		if delta != 0 {
+			stmt = shiftedCopy(stmt, delta).(Statement)
+		}
+		program.Statements = append(program.Statements, stmt)
+		program.spans = append(program.spans, statementSpan{
+			start: prev.spans[i].start + delta,
+			end:   prev.spans[i].end + delta,
+		})
+	}
+
+	return program, nil
+}
@@ -0,0 +1,142 @@
+package gophpparser
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestReparseRangeReusesUnaffectedStatements(t *testing.T) {
+	before := `<?php
+$a = 1;
+$b = 2;
+function greet($name) {
+    return $name;
+}
+?>`
+
+	prev, err := Parse(before)
+	if err != nil {
+		t.Fatalf("Failed to parse: %v", err)
+	}
+
+	// Replace "2" with "40 + 2" on the second statement
+	changedStart := strings.Index(before, "2;")
+	after := before[:changedStart] + "40 + 2" + before[changedStart+1:]
+	changedEnd := changedStart + len("40 + 2")
+
+	program, err := ReparseRange(prev, after, changedStart, changedEnd)
+	if err != nil {
+		t.Fatalf("ReparseRange failed: %v", err)
+	}
+
+	if len(program.Statements) != 3 {
+		t.Fatalf("program.Statements does not contain 3 statements. got=%d",
+			len(program.Statements))
+	}
+
+	if program.Statements[0] != prev.Statements[0] {
+		t.Errorf("statement before the edit was not reused")
+	}
+	// The edit lengthened the source, so the statement after it is reused
+	// as a shifted copy
+	if program.Statements[2] == prev.Statements[2] || program.Statements[2].String() != prev.Statements[2].String() {
+		t.Errorf("statement after the edit was not reused as a copy")
+	}
+	if program.Statements[1] == prev.Statements[1] {
+		t.Errorf("edited statement was not re-parsed")
+	}
+
+	full, err := Parse(after)
+	if err != nil {
+		t.Fatalf("Failed to parse edited source: %v", err)
+	}
+	if program.String() != full.String() {
+		t.Errorf("incremental result differs from full parse.\ngot=%q\nwant=%q",
+			program.String(), full.String())
+	}
+
+	// A second edit on the updated program adds a line, so everything from
+	// the edit onwards is re-parsed
+	insertAt := strings.Index(after, "40 + 2;") + len("40 + 2;")
+	edited := after[:insertAt] + "\n$c = 3;" + after[insertAt:]
+
+	program2, err := ReparseRange(program, edited, insertAt, insertAt+len("\n$c = 3;"))
+	if err != nil {
+		t.Fatalf("ReparseRange failed: %v", err)
+	}
+
+	if len(program2.Statements) != 4 {
+		t.Fatalf("program2.Statements does not contain 4 statements. got=%d",
+			len(program2.Statements))
+	}
+	if program2.Statements[0] != program.Statements[0] {
+		t.Errorf("statement before the second edit was not reused")
+	}
+
+	fn, ok := program2.Statements[3].(*FunctionDeclaration)
+	if !ok {
+		t.Fatalf("program2.Statements[3] is not *FunctionDeclaration. got=%T",
+			program2.Statements[3])
+	}
+	if fn.Token.Line != 5 {
+		t.Errorf("function line not updated after inserted line. got=%d, want=5", fn.Token.Line)
+	}
+}
+
+func TestReparseRangeShiftsReusedTokens(t *testing.T) {
+	before := `<?php
+$a = 1;
+function greet($name) {
+    return "Hi " . $name;
+}
+?>`
+
+	prev, err := Parse(before)
+	if err != nil {
+		t.Fatalf("Failed to parse: %v", err)
+	}
+
+	// Lengthen the first statement without adding a line
+	changedStart := strings.Index(before, "1;")
+	after := before[:changedStart] + "1000" + before[changedStart+1:]
+	changedEnd := changedStart + len("1000")
+
+	prevStart, prevEnd := SourceRange(prev.Statements[1])
+
+	program, err := ReparseRange(prev, after, changedStart, changedEnd)
+	if err != nil {
+		t.Fatalf("ReparseRange failed: %v", err)
+	}
+	if program.Statements[1] == prev.Statements[1] {
+		t.Fatalf("shifted statement shares its node with prev")
+	}
+	if start, end := SourceRange(prev.Statements[1]); start != prevStart || end != prevEnd {
+		t.Errorf("prev statement range changed. got=[%d, %d), want=[%d, %d)", start, end, prevStart, prevEnd)
+	}
+	if got := before[prevStart:prevEnd]; !strings.HasPrefix(got, "function greet") {
+		t.Errorf("prev statement no longer matches the old source. got=%q", got)
+	}
+
+	full, err := Parse(after)
+	if err != nil {
+		t.Fatalf("Failed to parse edited source: %v", err)
+	}
+
+	gotStart, gotEnd := SourceRange(program.Statements[1])
+	wantStart, wantEnd := SourceRange(full.Statements[1])
+	if gotStart != wantStart || gotEnd != wantEnd {
+		t.Errorf("reused statement range wrong. got=[%d, %d), want=[%d, %d)", gotStart, gotEnd, wantStart, wantEnd)
+	}
+
+	got, err := ToJSONWithOptions(program.Statements[1], JSONOptions{Positions: true})
+	if err != nil {
+		t.Fatalf("ToJSONWithOptions failed: %v", err)
+	}
+	want, err := ToJSONWithOptions(full.Statements[1], JSONOptions{Positions: true})
+	if err != nil {
+		t.Fatalf("ToJSONWithOptions failed: %v", err)
+	}
+	if string(got) != string(want) {
+		t.Errorf("reused statement JSON differs from full parse.\ngot=%s\nwant=%s", got, want)
+	}
+}
@@ -0,0 +1,323 @@
+package gophpparser
+
+import (
+	"strconv"
+	"strings"
+)
+
+// parseInterpolatedString splits the current double-quoted STRING token
+// into literal segments and embedded expressions. It supports the simple
+// $var, $var[key] and $var->prop forms as well as {$expr} and ${name}.
+// A string with nothing to interpolate, such as "Price: \$5", is returned
+// as a plain StringLiteral.
+func (p *Parser) parseInterpolatedString() Expression {
+	s := newInterpolationScanner(p, p.curToken)
+	parts := s.scan()
+
+	hasExpression := false
+	for _, part := range parts {
+		if _, ok := part.(*StringLiteral); !ok {
+			hasExpression = true
+			break
+		}
+	}
+	if !hasExpression {
+		return &StringLiteral{Token: p.curToken, Value: unquoteString(p.curToken.Literal, '"')}
+	}
+
+	return &InterpolatedString{Token: p.curToken, Parts: parts}
+}
+
+// interpolationScanner walks the contents of a double-quoted string. It
+// works on offsets into the parser's input so embedded expressions can be
+// lexed in place and keep their source positions.
+type interpolationScanner struct {
+	p      *Parser
+	source string
+	start  int // offset of the first byte after the opening quote
+	end    int // offset of the closing quote
+	pos    int
+
+	literal      strings.Builder
+	literalStart int
+	parts        []Expression
+}
+
+func newInterpolationScanner(p *Parser, tok Token) *interpolationScanner {
+	source, start := p.l.input, tok.Position+1
+	if strings.HasPrefix(source[min(tok.Position, len(source)):], "<<<") {
+		// A heredoc body starts on the line after its label
+		start = tok.Position + strings.IndexByte(source[tok.Position:], '\n') + 1
+	}
+	if start+len(tok.Literal) > len(source) || source[start:start+len(tok.Literal)] != tok.Literal {
+		// The token did not come from this input; scan the literal alone
+		source, start = tok.Literal, 0
+	}
+
+	return &interpolationScanner{
+		p:            p,
+		source:       source,
+		start:        start,
+		end:          start + len(tok.Literal),
+		pos:          start,
+		literalStart: start,
+	}
+}
+
+func (s *interpolationScanner) scan() []Expression {
+	for s.pos < s.end {
+		ch := s.source[s.pos]
+		switch {
+		case ch == '\\' && s.pos+1 < s.end:
+			// Escapes are decoded with the rest of the literal segment
+			s.literal.WriteString(s.source[s.pos : s.pos+2])
+			s.pos += 2
+		case ch == '{' && s.peek(1) == '$':
+			s.flushLiteral()
+			s.scanBraced(s.pos+1, s.pos)
+		case ch == '$' && s.peek(1) == '{':
+			s.flushLiteral()
+			s.scanDollarBraced()
+		case ch == '$' && isLetter(s.peek(1)):
+			s.flushLiteral()
+			s.scanSimple()
+		default:
+			s.literal.WriteByte(ch)
+			s.pos++
+		}
+	}
+	s.flushLiteral()
+	return s.parts
+}
+
+func (s *interpolationScanner) peek(offset int) byte {
+	if s.pos+offset >= s.end {
+		return 0
+	}
+	return s.source[s.pos+offset]
+}
+
+// flushLiteral adds the pending literal text as a StringLiteral part
+func (s *interpolationScanner) flushLiteral() {
+	if s.literal.Len() > 0 {
+		raw := s.literal.String()
+		s.parts = append(s.parts, &StringLiteral{Token: s.token(STRING, raw, s.literalStart), Value: unquoteString(raw, '"')})
+		s.literal.Reset()
+	}
+	s.literalStart = s.pos
+}
+
+// token builds a token whose position matches what the lexer would report
+// for a token starting at offset
+func (s *interpolationScanner) token(tokenType TokenType, literal string, offset int) Token {
+	before := s.source[:offset]
+	return Token{
+		Type:     tokenType,
+		Literal:  literal,
+		Line:     1 + strings.Count(before, "\n"),
+		Column:   offset - strings.LastIndex(before, "\n"),
+		Position: offset,
+		End:      offset + len(literal),
+	}
+}
+
+// scanName reads an identifier starting at the current position
+func (s *interpolationScanner) scanName() string {
+	start := s.pos
+	for s.pos < s.end && (isLetter(s.source[s.pos]) || isDigit(s.source[s.pos])) {
+		s.pos++
+	}
+	return s.source[start:s.pos]
+}
+
+// scanSimple handles $var, $var[key], $var->prop and $var?->prop
+func (s *interpolationScanner) scanSimple() {
+	varStart := s.pos
+	s.pos++ // skip '$'
+	name := s.scanName()
+	var expr Expression = &Variable{Token: s.token(VARIABLE, "$"+name, varStart), Name: name}
+
+	switch {
+	case s.peek(0) == '[':
+		if index := s.scanSimpleIndex(expr); index != nil {
+			expr = index
+		}
+	case s.peek(0) == '-' && s.peek(1) == '>' && isLetter(s.peek(2)):
+		arrow := s.token(OBJECT_ACCESS, "->", s.pos)
+		s.pos += 2
+		propStart := s.pos
+		prop := s.scanName()
+		expr = &ObjectAccessExpression{Token: arrow, Object: expr,
+			Property: &Identifier{Token: s.token(IDENT, prop, propStart), Value: prop}}
+	case s.peek(0) == '?' && s.peek(1) == '-' && s.peek(2) == '>' && isLetter(s.peek(3)):
+		arrow := s.token(QUESTION_ARROW, "?->", s.pos)
+		s.pos += 3
+		propStart := s.pos
+		prop := s.scanName()
+		expr = &ObjectAccessExpression{Token: arrow, Object: expr, Nullsafe: true,
+			Property: &Identifier{Token: s.token(IDENT, prop, propStart), Value: prop}}
+	}
+
+	s.parts = append(s.parts, expr)
+	s.literalStart = s.pos
+}
+
+// scanSimpleIndex reads the [key] after a simple variable, where key is an
+// integer, a bare string key or a variable. It leaves the position alone
+// and returns nil when the brackets do not hold one of those.
+func (s *interpolationScanner) scanSimpleIndex(left Expression) Expression {
+	open := s.pos
+	s.pos++ // skip '['
+
+	var index Expression
+	keyStart := s.pos
+	switch ch := s.peek(0); {
+	case isDigit(ch) || ch == '-' && isDigit(s.peek(1)):
+		s.pos++
+		for isDigit(s.peek(0)) {
+			s.pos++
+		}
+		literal := s.source[keyStart:s.pos]
+		value, err := strconv.ParseInt(literal, 10, 64)
+		if err != nil {
+			s.pos = open
+			return nil
+		}
+		index = &IntegerLiteral{Token: s.token(INT, literal, keyStart), Value: value}
+	case ch == '$' && isLetter(s.peek(1)):
+		s.pos++
+		name := s.scanName()
+		index = &Variable{Token: s.token(VARIABLE, "$"+name, keyStart), Name: name}
+	case isLetter(ch):
+		key := s.scanName()
+		index = &StringLiteral{Token: s.token(STRING, key, keyStart), Value: key}
+	}
+
+	if index == nil || s.peek(0) != ']' {
+		s.pos = open
+		return nil
+	}
+	s.pos++ // skip ']'
+
+	return &IndexExpression{Token: s.token(LBRACKET, "[", open), Left: left, Index: index}
+}
+
+// scanBraced parses the expression between the braces of {$expr}, where
+// exprStart is the offset of the '$' and open the offset of the '{'
+func (s *interpolationScanner) scanBraced(exprStart, open int) {
+	closing := s.matchingBrace(open)
+	if closing < 0 {
+		// An unterminated brace is kept as literal text
+		s.literal.WriteByte('{')
+		s.pos = open + 1
+		return
+	}
+
+	if expr := s.parseEmbedded(exprStart, closing); expr != nil {
+		s.parts = append(s.parts, expr)
+	}
+	s.pos = closing + 1
+	s.literalStart = s.pos
+}
+
+// scanDollarBraced handles ${name}, a variable, ${name[expr]}, an element
+// of that variable, and ${expr}, a variable variable named by the
+// expression's value
+func (s *interpolationScanner) scanDollarBraced() {
+	dollar := s.pos
+	open := s.pos + 1
+	closing := s.matchingBrace(open)
+	if closing < 0 {
+		s.literal.WriteByte('$')
+		s.pos++
+		return
+	}
+
+	inner := s.source[open+1 : closing]
+	name := inner
+	if i := strings.IndexByte(inner, '['); i > 0 {
+		name = inner[:i]
+	}
+	if name == "" || !isLetter(name[0]) || !isIdentifier(name) {
+		name = ""
+	}
+
+	switch {
+	case name != "" && name == inner:
+		s.parts = append(s.parts, &Variable{Token: s.token(VARIABLE, "$"+inner, dollar), Name: inner})
+	case name != "":
+		if expr := s.parseEmbedded(open+1, closing); expr != nil {
+			s.parts = append(s.parts, s.indexedVariable(expr, name, dollar))
+		}
+	default:
+		if expr := s.parseEmbedded(open+1, closing); expr != nil {
+			s.parts = append(s.parts, &PrefixExpression{Token: s.token(VARIABLE_VAR, "$", dollar), Operator: "$", Right: expr})
+		}
+	}
+	s.pos = closing + 1
+	s.literalStart = s.pos
+}
+
+// indexedVariable turns the name[expr] parsed from ${name[expr]} into an
+// index of the variable $name. Anything else stays a variable variable.
+func (s *interpolationScanner) indexedVariable(expr Expression, name string, dollar int) Expression {
+	if index, ok := expr.(*IndexExpression); ok {
+		if ident, ok := index.Left.(*Identifier); ok && ident.Value == name {
+			index.Left = &Variable{Token: s.token(VARIABLE, "$"+name, dollar), Name: name}
+			return index
+		}
+	}
+	return &PrefixExpression{Token: s.token(VARIABLE_VAR, "$", dollar), Operator: "$", Right: expr}
+}
+
+// matchingBrace returns the offset of the '}' closing the '{' at open, or
+// -1 if the string ends first. Braces inside quoted keys are ignored.
+func (s *interpolationScanner) matchingBrace(open int) int {
+	depth := 0
+	var quote byte
+	for i := open; i < s.end; i++ {
+		ch := s.source[i]
+		switch {
+		case quote != 0:
+			if ch == '\\' {
+				i++
+			} else if ch == quote {
+				quote = 0
+			}
+		case ch == '\'' || ch == '"':
+			quote = ch
+		case ch == '{':
+			depth++
+		case ch == '}':
+			depth--
+			if depth == 0 {
+				return i
+			}
+		}
+	}
+	return -1
+}
+
+// parseEmbedded parses source[start:end] as a single expression with a
+// parser of its own, so positions in errors and nodes match the file
+func (s *interpolationScanner) parseEmbedded(start, end int) Expression {
+	sub := NewParser(newLexerAt(s.source[:end], start))
+	sub.MaxDepth = s.p.MaxDepth
+
+	expr := sub.parseExpression(LOWEST)
+	if expr != nil && !sub.peekTokenIs(EOF) {
+		sub.peekError(EOF)
+	}
+
+	s.p.errors = append(s.p.errors, sub.errors...)
+	return expr
+}
+
+func isIdentifier(name string) bool {
+	for i := 0; i < len(name); i++ {
+		if !isLetter(name[i]) && !isDigit(name[i]) {
+			return false
+		}
+	}
+	return true
+}
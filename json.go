@@ -0,0 +1,315 @@
+package gophpparser
+
+import (
+	"encoding/json"
+	"fmt"
+	"reflect"
+	"strings"
+)
+
+// jsonNodeTypes maps the "type" discriminator written by ToJSON to a
+// constructor for the concrete node it names
+var jsonNodeTypes = map[string]func() Node{
+	"Program":                 func() Node { return &Program{} },
+	"Identifier":              func() Node { return &Identifier{} },
+	"Variable":                func() Node { return &Variable{} },
+	"IntegerLiteral":          func() Node { return &IntegerLiteral{} },
+	"FloatLiteral":            func() Node { return &FloatLiteral{} },
+	"StringLiteral":           func() Node { return &StringLiteral{} },
+	"BooleanLiteral":          func() Node { return &BooleanLiteral{} },
+	"NullLiteral":             func() Node { return &NullLiteral{} },
+	"MagicConstant":           func() Node { return &MagicConstant{} },
+	"Comment":                 func() Node { return &Comment{} },
+	"ExpressionStatement":     func() Node { return &ExpressionStatement{} },
+	"AssignmentExpression":    func() Node { return &AssignmentExpression{} },
+	"InfixExpression":         func() Node { return &InfixExpression{} },
+	"PrefixExpression":        func() Node { return &PrefixExpression{} },
+	"FunctionDeclaration":     func() Node { return &FunctionDeclaration{} },
+	"ReturnStatement":         func() Node { return &ReturnStatement{} },
+	"BlockStatement":          func() Node { return &BlockStatement{} },
+	"IfStatement":             func() Node { return &IfStatement{} },
+	"EchoStatement":           func() Node { return &EchoStatement{} },
+	"UnsetStatement":          func() Node { return &UnsetStatement{} },
+	"GlobalStatement":         func() Node { return &GlobalStatement{} },
+	"ListExpression":          func() Node { return &ListExpression{} },
+	"DestructuringAssignment": func() Node { return &DestructuringAssignment{} },
+	"StaticVariableStatement": func() Node { return &StaticVariableStatement{} },
+	"IssetExpression":         func() Node { return &IssetExpression{} },
+	"EmptyExpression":         func() Node { return &EmptyExpression{} },
+	"CallExpression":          func() Node { return &CallExpression{} },
+	"ArrayLiteral":            func() Node { return &ArrayLiteral{} },
+	"ForStatement":            func() Node { return &ForStatement{} },
+	"IndexExpression":         func() Node { return &IndexExpression{} },
+	"PostfixExpression":       func() Node { return &PostfixExpression{} },
+	"WhileStatement":          func() Node { return &WhileStatement{} },
+	"SwitchStatement":         func() Node { return &SwitchStatement{} },
+	"ForeachStatement":        func() Node { return &ForeachStatement{} },
+	"BreakStatement":          func() Node { return &BreakStatement{} },
+	"ContinueStatement":       func() Node { return &ContinueStatement{} },
+	"AssociativeArrayLiteral": func() Node { return &AssociativeArrayLiteral{} },
+	"InterpolatedString":      func() Node { return &InterpolatedString{} },
+	"ClassDeclaration":        func() Node { return &ClassDeclaration{} },
+	"PropertyDeclaration":     func() Node { return &PropertyDeclaration{} },
+	"MethodDeclaration":       func() Node { return &MethodDeclaration{} },
+	"NewExpression":           func() Node { return &NewExpression{} },
+	"ObjectAccessExpression":  func() Node { return &ObjectAccessExpression{} },
+	"StaticAccessExpression":  func() Node { return &StaticAccessExpression{} },
+	"NamespaceDeclaration":    func() Node { return &NamespaceDeclaration{} },
+	"UseStatement":            func() Node { return &UseStatement{} },
+	"TryStatement":            func() Node { return &TryStatement{} },
+	"CatchClause":             func() Node { return &CatchClause{} },
+	"ThrowStatement":          func() Node { return &ThrowStatement{} },
+	"IncludeStatement":        func() Node { return &IncludeStatement{} },
+	"RequireStatement":        func() Node { return &RequireStatement{} },
+	"IncludeExpression":       func() Node { return &IncludeExpression{} },
+	"RequireExpression":       func() Node { return &RequireExpression{} },
+	"PrintExpression":         func() Node { return &PrintExpression{} },
+	"TypeHint":                func() Node { return &TypeHint{} },
+	"NullableType":            func() Node { return &NullableType{} },
+	"AnonymousFunction":       func() Node { return &AnonymousFunction{} },
+	"NamespacedIdentifier":    func() Node { return &NamespacedIdentifier{} },
+	"YieldExpression":         func() Node { return &YieldExpression{} },
+	"InterfaceDeclaration":    func() Node { return &InterfaceDeclaration{} },
+	"InterfaceMethod":         func() Node { return &InterfaceMethod{} },
+	"TraitDeclaration":        func() Node { return &TraitDeclaration{} },
+	"TraitUse":                func() Node { return &TraitUse{} },
+	"EnumDeclaration":         func() Node { return &EnumDeclaration{} },
+	"EnumCase":                func() Node { return &EnumCase{} },
+	"ConstantDeclaration":     func() Node { return &ConstantDeclaration{} },
+	"ThrowExpression":         func() Node { return &ThrowExpression{} },
+	"ArrowFunction":           func() Node { return &ArrowFunction{} },
+	"MatchExpression":         func() Node { return &MatchExpression{} },
+	"TernaryExpression":       func() Node { return &TernaryExpression{} },
+	"CoalesceExpression":      func() Node { return &CoalesceExpression{} },
+	"InstanceofExpression":    func() Node { return &InstanceofExpression{} },
+	"CloneExpression":         func() Node { return &CloneExpression{} },
+	"DeclareStatement":        func() Node { return &DeclareStatement{} },
+	"CommaExpression":         func() Node { return &CommaExpression{} },
+	"EmptyStatement":          func() Node { return &EmptyStatement{} },
+}
+
+var (
+	tokenType = reflect.TypeOf(Token{})
+	nodeType  = reflect.TypeOf((*Node)(nil)).Elem()
+)
+
+// FromJSON rebuilds a program from the output of ToJSON. Nodes are
+// recreated from the "type" field at each level, so the result has the same
+// concrete types, tokens and fields as the program that was serialized.
+func FromJSON(data []byte) (*Program, error) {
+	node, err := decodeNode(data)
+	if err != nil {
+		return nil, err
+	}
+	program, ok := node.(*Program)
+	if !ok {
+		return nil, fmt.Errorf("expected a Program at the top level, got %s", node.Type())
+	}
+	return program, nil
+}
+
+// decodeNode reads the "type" field of a JSON object and decodes the
+// object into a new node of that type
+func decodeNode(data []byte) (Node, error) {
+	var header struct {
+		Type string `json:"type"`
+	}
+	if err := json.Unmarshal(data, &header); err != nil {
+		return nil, err
+	}
+	if header.Type == "" {
+		return nil, fmt.Errorf("node is missing its type: %s", data)
+	}
+	newNode, ok := jsonNodeTypes[header.Type]
+	if !ok {
+		return nil, fmt.Errorf("unknown node type %q", header.Type)
+	}
+
+	node := newNode()
+	if err := decodeValue(data, reflect.ValueOf(node).Elem()); err != nil {
+		return nil, fmt.Errorf("decoding %s: %w", header.Type, err)
+	}
+	return node, nil
+}
+
+// decodeValue decodes JSON into target, creating nodes for every field
+// whose declared type is an interface
+func decodeValue(data []byte, target reflect.Value) error {
+	if string(data) == "null" {
+		target.Set(reflect.Zero(target.Type()))
+		return nil
+	}
+
+	switch target.Kind() {
+	case reflect.Interface:
+		node, err := decodeNode(data)
+		if err != nil {
+			return err
+		}
+		value := reflect.ValueOf(node)
+		if !value.Type().AssignableTo(target.Type()) {
+			return fmt.Errorf("%s cannot be used as %s", node.Type(), target.Type())
+		}
+		target.Set(value)
+	case reflect.Pointer:
+		value := reflect.New(target.Type().Elem())
+		if err := decodeValue(data, value.Elem()); err != nil {
+			return err
+		}
+		target.Set(value)
+	case reflect.Slice:
+		var items []json.RawMessage
+		if err := json.Unmarshal(data, &items); err != nil {
+			return err
+		}
+		slice := reflect.MakeSlice(target.Type(), len(items), len(items))
+		for i, item := range items {
+			if err := decodeValue(item, slice.Index(i)); err != nil {
+				return err
+			}
+		}
+		target.Set(slice)
+	case reflect.Map:
+		var items map[string]json.RawMessage
+		if err := json.Unmarshal(data, &items); err != nil {
+			return err
+		}
+		m := reflect.MakeMapWithSize(target.Type(), len(items))
+		for key, item := range items {
+			value := reflect.New(target.Type().Elem()).Elem()
+			if err := decodeValue(item, value); err != nil {
+				return err
+			}
+			m.SetMapIndex(reflect.ValueOf(key), value)
+		}
+		target.Set(m)
+	case reflect.Struct:
+		if target.Type() == tokenType {
+			return json.Unmarshal(data, target.Addr().Interface())
+		}
+		var fields map[string]json.RawMessage
+		if err := json.Unmarshal(data, &fields); err != nil {
+			return err
+		}
+		for i := 0; i < target.NumField(); i++ {
+			name, _, ok := jsonField(target.Type().Field(i))
+			if !ok {
+				continue
+			}
+			if raw, present := fields[name]; present {
+				if err := decodeValue(raw, target.Field(i)); err != nil {
+					return fmt.Errorf("field %s: %w", name, err)
+				}
+			}
+		}
+		// ExceptionType is not serialized; restore it from the full list
+		if clause, ok := target.Addr().Interface().(*CatchClause); ok && len(clause.ExceptionTypes) > 0 {
+			clause.ExceptionType = clause.ExceptionTypes[0]
+		}
+	default:
+		return json.Unmarshal(data, target.Addr().Interface())
+	}
+	return nil
+}
+
+// encodeValue converts a value held by a node into the form ToJSON writes,
+// following the json tags of each struct and adding a "type" field to
+// every node so FromJSON can tell which node to rebuild
+func encodeValue(value reflect.Value, options JSONOptions) any {
+	switch value.Kind() {
+	case reflect.Invalid:
+		return nil
+	case reflect.Interface, reflect.Pointer:
+		if value.IsNil() {
+			return nil
+		}
+		encoded := encodeValue(value.Elem(), options)
+		if fields, ok := encoded.(map[string]any); ok && value.Type().Implements(nodeType) {
+			node := value.Interface().(Node)
+			fields["type"] = node.Type()
+			if position, ok := nodePosition(node); ok && options.Positions {
+				fields["position"] = position
+			}
+		}
+		return encoded
+	case reflect.Slice:
+		if value.IsNil() {
+			return nil
+		}
+		items := make([]any, value.Len())
+		for i := range items {
+			items[i] = encodeValue(value.Index(i), options)
+		}
+		return items
+	case reflect.Map:
+		if value.IsNil() {
+			return nil
+		}
+		items := make(map[string]any, value.Len())
+		iter := value.MapRange()
+		for iter.Next() {
+			items[iter.Key().String()] = encodeValue(iter.Value(), options)
+		}
+		return items
+	case reflect.Struct:
+		if value.Type() == tokenType {
+			return value.Interface()
+		}
+		fields := map[string]any{}
+		for i := 0; i < value.NumField(); i++ {
+			name, omitEmpty, ok := jsonField(value.Type().Field(i))
+			if !ok || (omitEmpty && isEmptyJSONValue(value.Field(i))) {
+				continue
+			}
+			fields[name] = encodeValue(value.Field(i), options)
+		}
+		return fields
+	}
+	return value.Interface()
+}
+
+// jsonPosition is the "position" object ToJSONWithOptions adds to nodes
+type jsonPosition struct {
+	Line   int `json:"line"`
+	Column int `json:"column"`
+	Offset int `json:"offset"`
+}
+
+// nodePosition returns where the token of a node starts. Nodes without a
+// token, such as Program, report false.
+func nodePosition(node Node) (jsonPosition, bool) {
+	tok, ok := nodeToken(node)
+	if !ok {
+		return jsonPosition{}, false
+	}
+	return jsonPosition{Line: tok.Line, Column: tok.Column, Offset: tok.Position}, true
+}
+
+// jsonField returns the JSON name of a struct field and whether it is
+// omitted when empty. Unexported fields and fields tagged "-" report false.
+func jsonField(field reflect.StructField) (name string, omitEmpty bool, ok bool) {
+	if !field.IsExported() {
+		return "", false, false
+	}
+	tag := field.Tag.Get("json")
+	if tag == "-" {
+		return "", false, false
+	}
+	name, options, _ := strings.Cut(tag, ",")
+	if name == "" {
+		name = field.Name
+	}
+	return name, options == "omitempty", true
+}
+
+// isEmptyJSONValue reports whether encoding/json would drop the value from
+// a field tagged omitempty
+func isEmptyJSONValue(value reflect.Value) bool {
+	switch value.Kind() {
+	case reflect.Array, reflect.Map, reflect.Slice, reflect.String:
+		return value.Len() == 0
+	case reflect.Interface, reflect.Pointer:
+		return value.IsNil()
+	}
+	return value.IsZero()
+}
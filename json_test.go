@@ -0,0 +1,242 @@
+package gophpparser
+
+import (
+	"encoding/json"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestFromJSONRoundTrip(t *testing.T) {
+	input := `<?php
+declare(strict_types=1);
+namespace App\Models;
+use App\Contracts\Shape as ShapeContract;
+
+final class Circle extends Shape implements ShapeContract {
+    use HasName;
+    const PI = 3.14;
+    public readonly ?float $radius = null;
+    private static array $cache = ['a' => 1, 'b' => [2, 3]];
+
+    public function __construct(private int|float $r, string ...$tags) {
+        parent::__construct();
+    }
+
+    public function area(): float {
+        return self::PI * $this->r ** 2;
+    }
+}
+
+interface Named {
+    public function name(): string;
+}
+
+trait HasName {
+    public function name(): string { return static::class; }
+}
+
+enum Suit: string {
+    case Hearts = 'H';
+    case Spades = 'S';
+}
+
+function &gen(iterable $items, $limit = 10) {
+    static $count = 0;
+    global $config;
+    foreach ($items as $key => [$a, $b]) {
+        if ($a === null) {
+            continue;
+        } elseif ($b instanceof Circle) {
+            break 1;
+        } else {
+            yield $key => $a;
+        }
+    }
+    for ($i = 0; $i < $limit; $i++) {
+        $count += $i;
+    }
+    while (!empty($items)) {
+        unset($items[0], $config['x']);
+    }
+    if (isset($config['x'])) {
+        $config = null;
+    }
+    switch ($count) {
+        case 1:
+            echo "one {$config['x']} $count", PHP_EOL;
+            break;
+        default:
+            print 'many';
+    }
+    try {
+        $r = match (true) {
+            $count > 5, $count < 0 => throw new \RuntimeException("bad"),
+            default => clone $config,
+        };
+    } catch (LogicException | TypeError $e) {
+        throw $e;
+    } finally {
+        $x = $r?->value ?? -1;
+    }
+    $f = static fn($v) => $v * 2;
+    $g = function ($v) use ($f, &$count) { return $f($v) ? $v : 0; };
+    [$p, $q] = [1, 2];
+    require_once __DIR__ . '/bootstrap.php';
+    return $g;
+}
+?>`
+
+	l := New(input)
+	p := NewParser(l)
+	program := p.ParseProgram()
+
+	if len(p.Errors()) != 0 {
+		t.Errorf("parser has %d errors", len(p.Errors()))
+		for _, err := range p.Errors() {
+			t.Errorf("parser error: %q", err)
+		}
+		return
+	}
+
+	assertJSONRoundTrip(t, program)
+}
+
+func TestFromJSONRoundTripTestFiles(t *testing.T) {
+	files, err := filepath.Glob("testfiles/*.php")
+	if err != nil {
+		t.Fatalf("glob failed: %v", err)
+	}
+
+	for _, file := range files {
+		program, err := Parsefile(file)
+		if err != nil {
+			continue
+		}
+		t.Run(filepath.Base(file), func(t *testing.T) {
+			assertJSONRoundTrip(t, program)
+		})
+	}
+}
+
+func assertJSONRoundTrip(t *testing.T, program *Program) {
+	t.Helper()
+
+	data, err := ToJSON(program)
+	if err != nil {
+		t.Fatalf("ToJSON failed: %v", err)
+	}
+	decoded, err := FromJSON(data)
+	if err != nil {
+		t.Fatalf("FromJSON failed: %v", err)
+	}
+
+	if decoded.String() != program.String() {
+		t.Errorf("String() differs after round trip.\nexpected=%s\ngot=%s", program.String(), decoded.String())
+	}
+
+	again, err := ToJSON(decoded)
+	if err != nil {
+		t.Fatalf("ToJSON of decoded program failed: %v", err)
+	}
+	if string(again) != string(data) {
+		t.Errorf("JSON differs after round trip.\nexpected=%s\ngot=%s", data, again)
+	}
+}
+
+func TestFromJSONNodeTypes(t *testing.T) {
+	for name, newNode := range jsonNodeTypes {
+		if got := newNode().Type(); got != name {
+			t.Errorf("jsonNodeTypes[%q] builds a %s", name, got)
+		}
+	}
+}
+
+func TestFromJSONErrors(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected string
+	}{
+		{`{"statements": []}`, "node is missing its type"},
+		{`{"type": "Mystery"}`, `unknown node type "Mystery"`},
+		{`{"type": "Identifier", "value": "x"}`, "expected a Program at the top level, got Identifier"},
+		{`{"type": "Program", "statements": [{"type": "Identifier"}]}`, "Identifier cannot be used as gophpparser.Statement"},
+		{`{"type": "Program"`, "unexpected end of JSON input"},
+	}
+
+	for _, tt := range tests {
+		_, err := FromJSON([]byte(tt.input))
+		if err == nil {
+			t.Errorf("input %s: expected an error", tt.input)
+			continue
+		}
+		if !strings.Contains(err.Error(), tt.expected) {
+			t.Errorf("input %s: expected error containing %q. got=%q", tt.input, tt.expected, err.Error())
+		}
+	}
+}
+
+func TestToJSONWithPositions(t *testing.T) {
+	input := `<?php
+$name = 'world';
+
+echo "hello $name";
+`
+
+	program, err := Parse(input)
+	if err != nil {
+		t.Fatalf("failed to parse: %v", err)
+	}
+
+	data, err := ToJSONWithOptions(program.Statements[1], JSONOptions{Positions: true})
+	if err != nil {
+		t.Fatalf("ToJSONWithOptions failed: %v", err)
+	}
+
+	var echo struct {
+		Type     string `json:"type"`
+		Position struct {
+			Line   int `json:"line"`
+			Column int `json:"column"`
+			Offset int `json:"offset"`
+		} `json:"position"`
+		Values []struct {
+			Position *struct {
+				Line int `json:"line"`
+			} `json:"position"`
+		} `json:"values"`
+	}
+	if err := json.Unmarshal(data, &echo); err != nil {
+		t.Fatalf("failed to read JSON: %v", err)
+	}
+
+	if echo.Type != "EchoStatement" {
+		t.Fatalf("expected an EchoStatement. got=%s", echo.Type)
+	}
+	if echo.Position.Line != 4 || echo.Position.Column != 1 || echo.Position.Offset != strings.Index(input, "echo") {
+		t.Errorf("wrong position for echo. got=%+v", echo.Position)
+	}
+	if len(echo.Values) != 1 || echo.Values[0].Position == nil || echo.Values[0].Position.Line != 4 {
+		t.Errorf("nested value is missing its position. got=%s", data)
+	}
+
+	plain, err := ToJSON(program)
+	if err != nil {
+		t.Fatalf("ToJSON failed: %v", err)
+	}
+	if strings.Contains(string(plain), `"position"`) {
+		t.Errorf("ToJSON should not include positions. got=%s", plain)
+	}
+
+	data, err = ToJSONWithOptions(program, JSONOptions{Positions: true})
+	if err != nil {
+		t.Fatalf("ToJSONWithOptions failed: %v", err)
+	}
+	decoded, err := FromJSON(data)
+	if err != nil {
+		t.Fatalf("FromJSON failed on JSON with positions: %v", err)
+	}
+	if decoded.String() != program.String() {
+		t.Errorf("String() differs after round trip.\nexpected=%s\ngot=%s", program.String(), decoded.String())
+	}
+}
@@ -1,6 +1,9 @@
 package gophpparser
 
-import "strings"
+import (
+	"strconv"
+	"strings"
+)
 
 type Lexer struct {
 	input        string
@@ -11,12 +14,35 @@ type Lexer struct {
 	column       int
 }
 
+// utf8BOM is the byte order mark some editors write at the start of UTF-8 files
+const utf8BOM = "\xEF\xBB\xBF"
+
+// New returns a lexer over input, which is assumed to be UTF-8. A leading
+// byte order mark is skipped so it cannot hide the opening <?php tag;
+// token positions still refer to byte offsets in input.
 func New(input string) *Lexer {
 	l := &Lexer{
 		input:  input,
 		line:   1,
 		column: 0,
 	}
+	if strings.HasPrefix(input, utf8BOM) {
+		l.readPosition = len(utf8BOM)
+	}
+	l.readChar()
+	return l
+}
+
+// newLexerAt returns a lexer over input that starts reading at the byte
+// offset, with line and column information matching a lexer that started
+// at the beginning of input.
+func newLexerAt(input string, offset int) *Lexer {
+	l := &Lexer{
+		input:        input,
+		readPosition: offset,
+		line:         1 + strings.Count(input[:offset], "\n"),
+		column:       offset - strings.LastIndex(input[:offset], "\n") - 1,
+	}
 	l.readChar()
 	return l
 }
@@ -57,6 +83,9 @@ func (l *Lexer) NextToken() Token {
 
 	l.skipWhitespace()
 
+	// Byte offset of the token in the input
+	start := min(l.position, len(l.input))
+
 	switch l.ch {
 	case '=':
 		if l.peekChar() == '=' {
@@ -82,26 +111,60 @@ func (l *Lexer) NextToken() Token {
 			ch := l.ch
 			l.readChar()
 			tok = Token{Type: INCREMENT, Literal: string(ch) + string(l.ch), Line: l.line, Column: l.column}
+		} else if l.peekChar() == '=' {
+			ch := l.ch
+			l.readChar()
+			tok = Token{Type: PLUS_ASSIGN, Literal: string(ch) + string(l.ch), Line: l.line, Column: l.column}
 		} else {
 			tok = newToken(PLUS, l.ch, l.line, l.column)
 		}
 	case '-':
-		if l.peekChar() == '>' {
+		if l.peekChar() == '-' {
+			ch := l.ch
+			l.readChar()
+			tok = Token{Type: DECREMENT, Literal: string(ch) + string(l.ch), Line: l.line, Column: l.column}
+		} else if l.peekChar() == '>' {
 			ch := l.ch
 			l.readChar()
 			tok = Token{Type: OBJECT_ACCESS, Literal: string(ch) + string(l.ch), Line: l.line, Column: l.column}
+		} else if l.peekChar() == '=' {
+			ch := l.ch
+			l.readChar()
+			tok = Token{Type: MINUS_ASSIGN, Literal: string(ch) + string(l.ch), Line: l.line, Column: l.column}
 		} else {
 			tok = newToken(MINUS, l.ch, l.line, l.column)
 		}
 	case '*':
-		tok = newToken(MULTIPLY, l.ch, l.line, l.column)
+		if l.peekChar() == '*' {
+			ch := l.ch
+			l.readChar()
+			if l.peekChar() == '=' {
+				secondCh := l.ch
+				l.readChar()
+				tok = Token{Type: POW_ASSIGN, Literal: string(ch) + string(secondCh) + string(l.ch), Line: l.line, Column: l.column}
+			} else {
+				tok = Token{Type: POW, Literal: string(ch) + string(l.ch), Line: l.line, Column: l.column}
+			}
+		} else if l.peekChar() == '=' {
+			ch := l.ch
+			l.readChar()
+			tok = Token{Type: MULTIPLY_ASSIGN, Literal: string(ch) + string(l.ch), Line: l.line, Column: l.column}
+		} else {
+			tok = newToken(MULTIPLY, l.ch, l.line, l.column)
+		}
 	case '/':
+		// Comments are positioned at their start, since they may span lines.
+		// Reading one leaves the lexer just past it, so the token ends
+		// there: before the newline of a line comment, after the */ of a
+		// block comment.
 		if l.peekChar() == '/' {
+			tok.Line, tok.Column = l.line, l.column
 			tok.Type = COMMENT
 			tok.Literal = l.readLineComment()
-			tok.Line = l.line
-			tok.Column = l.column
+			tok.Position, tok.End = start, l.position
+			return tok
 		} else if l.peekChar() == '*' {
+			tok.Line, tok.Column = l.line, l.column
 			comment := l.readBlockComment()
 			if strings.HasPrefix(comment, "/**") {
 				tok.Type = DOCBLOCK
@@ -109,15 +172,47 @@ func (l *Lexer) NextToken() Token {
 				tok.Type = COMMENT
 			}
 			tok.Literal = comment
-			tok.Line = l.line
-			tok.Column = l.column
+			tok.Position, tok.End = start, min(l.position, len(l.input))
+			return tok
+		} else if l.peekChar() == '=' {
+			ch := l.ch
+			l.readChar()
+			tok = Token{Type: DIVIDE_ASSIGN, Literal: string(ch) + string(l.ch), Line: l.line, Column: l.column}
 		} else {
 			tok = newToken(DIVIDE, l.ch, l.line, l.column)
 		}
+	case '#':
+		// Shell-style line comment; #[ starts an attribute instead
+		if l.peekChar() != '[' {
+			tok.Line, tok.Column = l.line, l.column
+			tok.Type = COMMENT
+			tok.Literal = l.readLineComment()
+			tok.Position, tok.End = start, l.position
+			return tok
+		} else {
+			l.readChar()
+			tok = Token{Type: ATTRIBUTE_START, Literal: "#[", Line: l.line, Column: l.column}
+		}
 	case '%':
-		tok = newToken(MODULO, l.ch, l.line, l.column)
+		if l.peekChar() == '=' {
+			ch := l.ch
+			l.readChar()
+			tok = Token{Type: MODULO_ASSIGN, Literal: string(ch) + string(l.ch), Line: l.line, Column: l.column}
+		} else {
+			tok = newToken(MODULO, l.ch, l.line, l.column)
+		}
 	case '.':
-		tok = newToken(CONCAT, l.ch, l.line, l.column)
+		if l.peekChar() == '.' && l.peekCharAt(1) == '.' {
+			l.readChar()
+			l.readChar()
+			tok = Token{Type: ELLIPSIS, Literal: "...", Line: l.line, Column: l.column}
+		} else if l.peekChar() == '=' {
+			ch := l.ch
+			l.readChar()
+			tok = Token{Type: CONCAT_ASSIGN, Literal: string(ch) + string(l.ch), Line: l.line, Column: l.column}
+		} else {
+			tok = newToken(CONCAT, l.ch, l.line, l.column)
+		}
 	case '!':
 		if l.peekChar() == '=' {
 			ch := l.ch
@@ -134,15 +229,29 @@ func (l *Lexer) NextToken() Token {
 			tok = newToken(NOT, l.ch, l.line, l.column)
 		}
 	case '<':
-		if l.peekChar() == '=' && l.peekCharAt(1) == '>' {
-			ch := l.ch
+		if l.peekChar() == '<' && l.peekCharAt(1) == '<' && l.heredocLabelAhead() {
+			tok.Type = STRING
+			tok.Literal = l.readHeredoc()
+			tok.Line = l.line
+			tok.Column = l.column
+		} else if l.peekChar() == '=' && l.peekCharAt(1) == '>' {
+			// The column is where the operator starts
+			column := l.column
 			l.readChar()
 			l.readChar()
-			tok = Token{Type: SPACESHIP, Literal: string(ch) + "=>", Line: l.line, Column: l.column}
+			tok = Token{Type: SPACESHIP, Literal: "<=>", Line: l.line, Column: column}
 		} else if l.peekChar() == '=' {
 			ch := l.ch
 			l.readChar()
 			tok = Token{Type: LTE, Literal: string(ch) + string(l.ch), Line: l.line, Column: l.column}
+		} else if l.peekChar() == '<' && l.peekCharAt(1) == '=' {
+			l.readChar()
+			l.readChar()
+			tok = Token{Type: SHIFT_LEFT_ASSIGN, Literal: "<<=", Line: l.line, Column: l.column}
+		} else if l.peekChar() == '<' {
+			ch := l.ch
+			l.readChar()
+			tok = Token{Type: SHIFT_LEFT, Literal: string(ch) + string(l.ch), Line: l.line, Column: l.column}
 		} else if l.peekChar() == '?' {
 			ch := l.ch
 			l.readChar()
@@ -170,6 +279,14 @@ func (l *Lexer) NextToken() Token {
 			ch := l.ch
 			l.readChar()
 			tok = Token{Type: GTE, Literal: string(ch) + string(l.ch), Line: l.line, Column: l.column}
+		} else if l.peekChar() == '>' && l.peekCharAt(1) == '=' {
+			l.readChar()
+			l.readChar()
+			tok = Token{Type: SHIFT_RIGHT_ASSIGN, Literal: ">>=", Line: l.line, Column: l.column}
+		} else if l.peekChar() == '>' {
+			ch := l.ch
+			l.readChar()
+			tok = Token{Type: SHIFT_RIGHT, Literal: string(ch) + string(l.ch), Line: l.line, Column: l.column}
 		} else {
 			tok = newToken(GT, l.ch, l.line, l.column)
 		}
@@ -178,17 +295,37 @@ func (l *Lexer) NextToken() Token {
 			ch := l.ch
 			l.readChar()
 			tok = Token{Type: AND, Literal: string(ch) + string(l.ch), Line: l.line, Column: l.column}
+		} else if l.peekChar() == '=' {
+			ch := l.ch
+			l.readChar()
+			tok = Token{Type: BIT_AND_ASSIGN, Literal: string(ch) + string(l.ch), Line: l.line, Column: l.column}
 		} else {
-			tok = newToken(ILLEGAL, l.ch, l.line, l.column)
+			tok = newToken(BIT_AND, l.ch, l.line, l.column)
 		}
 	case '|':
 		if l.peekChar() == '|' {
 			ch := l.ch
 			l.readChar()
 			tok = Token{Type: OR, Literal: string(ch) + string(l.ch), Line: l.line, Column: l.column}
+		} else if l.peekChar() == '=' {
+			ch := l.ch
+			l.readChar()
+			tok = Token{Type: BIT_OR_ASSIGN, Literal: string(ch) + string(l.ch), Line: l.line, Column: l.column}
 		} else {
-			tok = newToken(ILLEGAL, l.ch, l.line, l.column)
+			tok = newToken(BIT_OR, l.ch, l.line, l.column)
+		}
+	case '^':
+		if l.peekChar() == '=' {
+			ch := l.ch
+			l.readChar()
+			tok = Token{Type: BIT_XOR_ASSIGN, Literal: string(ch) + string(l.ch), Line: l.line, Column: l.column}
+		} else {
+			tok = newToken(BIT_XOR, l.ch, l.line, l.column)
 		}
+	case '~':
+		tok = newToken(BIT_NOT, l.ch, l.line, l.column)
+	case '@':
+		tok = newToken(AT, l.ch, l.line, l.column)
 	case '?':
 		if l.peekChar() == '>' {
 			ch := l.ch
@@ -243,6 +380,8 @@ func (l *Lexer) NextToken() Token {
 		tok.Literal = "$" + l.readIdentifier()
 		tok.Line = l.line
 		tok.Column = l.column
+		tok.Position = start
+		tok.End = min(l.position, len(l.input))
 		return tok
 	case ':':
 		if l.peekChar() == ':' {
@@ -265,18 +404,24 @@ func (l *Lexer) NextToken() Token {
 			tok.Column = l.column
 			tok.Literal = l.readIdentifier()
 			tok.Type = LookupIdent(tok.Literal)
+			tok.Position = start
+			tok.End = min(l.position, len(l.input))
 			return tok
 		} else if isDigit(l.ch) {
 			tok.Type, tok.Literal = l.readNumber()
 			tok.Line = l.line
 			tok.Column = l.column
+			tok.Position = start
+			tok.End = min(l.position, len(l.input))
 			return tok
 		} else {
 			tok = newToken(ILLEGAL, l.ch, l.line, l.column)
 		}
 	}
 
+	tok.Position = start
 	l.readChar()
+	tok.End = min(l.position, len(l.input))
 	return tok
 }
 
@@ -298,18 +443,36 @@ func (l *Lexer) readNumber() (TokenType, string) {
 	position := l.position
 	tokenType := INT
 
-	for isDigit(l.ch) {
+	// Underscores are read along with the digits and checked by the parser,
+	// so a misplaced one is reported instead of starting an identifier
+	for isDigit(l.ch) || l.ch == '_' {
 		l.readChar()
 	}
 
 	if l.ch == '.' && isDigit(l.peekChar()) {
 		tokenType = FLOAT
 		l.readChar()
-		for isDigit(l.ch) {
+		for isDigit(l.ch) || l.ch == '_' {
 			l.readChar()
 		}
 	}
 
+	// Exponent, as in 1.5e10 or 2E-3. An exponent without digits, as in 1e
+	// or 1e_3, is kept in the literal for the parser to reject.
+	if l.ch == 'e' || l.ch == 'E' {
+		tokenType = FLOAT
+		l.readChar()
+		if (l.ch == '+' || l.ch == '-') && isDigit(l.peekChar()) {
+			l.readChar()
+		}
+	}
+
+	// Letters running on from the digits belong to the literal too: the
+	// prefix and digits of 0x1F, 0b101 or 0o17, or a malformed suffix
+	for isLetter(l.ch) || isDigit(l.ch) {
+		l.readChar()
+	}
+
 	return tokenType, l.input[position:l.position]
 }
 
@@ -327,6 +490,205 @@ func (l *Lexer) readString(delimiter byte) string {
 	return l.input[position:l.position]
 }
 
+// heredocLabelAhead reports whether the "<<<" at the current position
+// opens a heredoc or nowdoc, that is, whether it is followed by a label
+// (optionally quoted) and the end of the line.
+func (l *Lexer) heredocLabelAhead() bool {
+	_, _, bodyStart := l.heredocHeader()
+	return bodyStart >= 0
+}
+
+// heredocHeader parses the "<<<LABEL" line at the current position. It
+// returns the label, the quote around it (0 for a bare heredoc label) and
+// the offset where the body starts, or -1 if this is not a heredoc header.
+func (l *Lexer) heredocHeader() (string, byte, int) {
+	i := l.position + 3
+	for i < len(l.input) && (l.input[i] == ' ' || l.input[i] == '\t') {
+		i++
+	}
+
+	var quote byte
+	if i < len(l.input) && (l.input[i] == '\'' || l.input[i] == '"') {
+		quote = l.input[i]
+		i++
+	}
+
+	labelStart := i
+	for i < len(l.input) && (isLetter(l.input[i]) || isDigit(l.input[i])) {
+		i++
+	}
+	label := l.input[labelStart:i]
+	if label == "" || !isLetter(label[0]) {
+		return "", 0, -1
+	}
+
+	if quote != 0 {
+		if i >= len(l.input) || l.input[i] != quote {
+			return "", 0, -1
+		}
+		i++
+	}
+	if i < len(l.input) && l.input[i] == '\r' {
+		i++
+	}
+	if i >= len(l.input) || l.input[i] != '\n' {
+		return "", 0, -1
+	}
+
+	return label, quote, i + 1
+}
+
+// readHeredoc reads a heredoc or nowdoc body up to its closing label and
+// leaves the lexer on the label's last character. As in PHP 7.3+, the
+// closing label may be indented and that indentation is removed from every
+// line of the body.
+func (l *Lexer) readHeredoc() string {
+	label, _, bodyStart := l.heredocHeader()
+
+	body, end := "", len(l.input)-1
+	for lineStart := bodyStart; lineStart < len(l.input); {
+		lineEnd := strings.IndexByte(l.input[lineStart:], '\n')
+		if lineEnd < 0 {
+			lineEnd = len(l.input)
+		} else {
+			lineEnd += lineStart
+		}
+
+		line := l.input[lineStart:lineEnd]
+		trimmed := strings.TrimLeft(line, " \t")
+		if strings.HasPrefix(trimmed, label) && (len(trimmed) == len(label) || !isLetter(trimmed[len(label)]) && !isDigit(trimmed[len(label)])) {
+			indent := len(line) - len(trimmed)
+			if lineStart > bodyStart {
+				body = strings.TrimSuffix(l.input[bodyStart:lineStart-1], "\r")
+			}
+			body = removeHeredocIndent(body, indent)
+			end = lineStart + indent + len(label) - 1
+			break
+		}
+
+		if lineEnd == len(l.input) {
+			// Unterminated: the rest of the input is the body
+			body = l.input[bodyStart:]
+			break
+		}
+		lineStart = lineEnd + 1
+	}
+
+	for l.position < end && l.ch != 0 {
+		l.readChar()
+	}
+	return body
+}
+
+// removeHeredocIndent strips up to indent leading spaces or tabs from each
+// line of a heredoc body
+func removeHeredocIndent(body string, indent int) string {
+	if indent == 0 {
+		return body
+	}
+
+	lines := strings.Split(body, "\n")
+	for i, line := range lines {
+		n := 0
+		for n < indent && n < len(line) && (line[n] == ' ' || line[n] == '\t') {
+			n++
+		}
+		lines[i] = line[n:]
+	}
+	return strings.Join(lines, "\n")
+}
+
+// unquoteString decodes the escape sequences in the raw contents of a
+// string literal. Single-quoted strings only unescape \\ and \'; double-quoted
+// strings also support \n, \t, \$, octal, \x and \u{} escapes. Unknown
+// escapes are kept as written, as PHP does.
+func unquoteString(raw string, quote byte) string {
+	if !strings.Contains(raw, "\\") {
+		return raw
+	}
+
+	var out strings.Builder
+	for i := 0; i < len(raw); i++ {
+		if raw[i] != '\\' || i+1 == len(raw) {
+			out.WriteByte(raw[i])
+			continue
+		}
+
+		next := raw[i+1]
+		if quote == '\'' {
+			if next == '\\' || next == '\'' {
+				out.WriteByte(next)
+				i++
+			} else {
+				out.WriteByte('\\')
+			}
+			continue
+		}
+
+		switch next {
+		case 'n':
+			out.WriteByte('\n')
+		case 't':
+			out.WriteByte('\t')
+		case 'r':
+			out.WriteByte('\r')
+		case 'v':
+			out.WriteByte('\v')
+		case 'e':
+			out.WriteByte(0x1b)
+		case 'f':
+			out.WriteByte('\f')
+		case '\\', '$', '"':
+			out.WriteByte(next)
+		case '0', '1', '2', '3', '4', '5', '6', '7':
+			end := i + 1
+			for end < len(raw) && end < i+4 && raw[end] >= '0' && raw[end] <= '7' {
+				end++
+			}
+			value, _ := strconv.ParseUint(raw[i+1:end], 8, 16)
+			out.WriteByte(byte(value))
+			i = end - 1
+			continue
+		case 'x':
+			end := i + 2
+			for end < len(raw) && end < i+4 && isHexDigit(raw[end]) {
+				end++
+			}
+			if end == i+2 {
+				out.WriteByte('\\')
+				continue
+			}
+			value, _ := strconv.ParseUint(raw[i+2:end], 16, 8)
+			out.WriteByte(byte(value))
+			i = end - 1
+			continue
+		case 'u':
+			closing := strings.IndexByte(raw[i:], '}')
+			if i+2 >= len(raw) || raw[i+2] != '{' || closing < 0 {
+				out.WriteByte('\\')
+				continue
+			}
+			value, err := strconv.ParseUint(raw[i+3:i+closing], 16, 32)
+			if err != nil {
+				out.WriteByte('\\')
+				continue
+			}
+			out.WriteRune(rune(value))
+			i += closing
+			continue
+		default:
+			out.WriteByte('\\')
+			continue
+		}
+		i++
+	}
+	return out.String()
+}
+
+func isHexDigit(ch byte) bool {
+	return isDigit(ch) || 'a' <= ch && ch <= 'f' || 'A' <= ch && ch <= 'F'
+}
+
 func isLetter(ch byte) bool {
 	return 'a' <= ch && ch <= 'z' || 'A' <= ch && ch <= 'Z' || ch == '_' || ch > 127
 }
@@ -387,10 +749,6 @@ func (l *Lexer) readBlockComment() string {
 			l.readChar() // read '/'
 			break
 		}
-		if l.ch == '\n' {
-			l.line++
-			l.column = 0
-		}
 		l.readChar()
 	}
 	return l.input[position:l.position]
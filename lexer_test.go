@@ -0,0 +1,270 @@
+package gophpparser
+
+import "testing"
+
+func TestSpaceshipToken(t *testing.T) {
+	input := `<?php $a <=> $b;`
+
+	tests := []struct {
+		expectedType    TokenType
+		expectedLiteral string
+	}{
+		{PHP_OPEN, "<?php"},
+		{VARIABLE, "$a"},
+		{SPACESHIP, "<=>"},
+		{VARIABLE, "$b"},
+		{SEMICOLON, ";"},
+		{EOF, ""},
+	}
+
+	l := New(input)
+
+	for i, tt := range tests {
+		tok := l.NextToken()
+
+		if tok.Type != tt.expectedType {
+			t.Fatalf("tests[%d] - tokentype wrong. expected=%q, got=%q",
+				i, tt.expectedType, tok.Type)
+		}
+		if tok.Literal != tt.expectedLiteral {
+			t.Fatalf("tests[%d] - literal wrong. expected=%q, got=%q",
+				i, tt.expectedLiteral, tok.Literal)
+		}
+
+		// The operator is reported at its first character
+		if tok.Type == SPACESHIP {
+			if tok.Line != 1 || tok.Column != 10 {
+				t.Errorf("tests[%d] - position wrong. expected=1:10, got=%d:%d",
+					i, tok.Line, tok.Column)
+			}
+			if input[tok.Position:tok.End] != "<=>" {
+				t.Errorf("tests[%d] - span wrong. got=%q", i, input[tok.Position:tok.End])
+			}
+		}
+	}
+}
+
+func TestUnquoteString(t *testing.T) {
+	tests := []struct {
+		raw      string
+		quote    byte
+		expected string
+	}{
+		{`a\nb`, '"', "a\nb"},
+		{`tab\there`, '"', "tab\there"},
+		{`say \"hi\"`, '"', `say "hi"`},
+		{`back\\slash`, '"', `back\slash`},
+		{`\$price`, '"', "$price"},
+		{`\101\x42\u{1F600}`, '"', "AB\U0001F600"},
+		{`keep \q and \x`, '"', `keep \q and \x`},
+		{`a\nb`, '\'', `a\nb`},
+		{`it\'s`, '\'', "it's"},
+		{`back\\slash`, '\'', `back\slash`},
+	}
+
+	for _, tt := range tests {
+		if got := unquoteString(tt.raw, tt.quote); got != tt.expected {
+			t.Errorf("unquoteString(%q, %q) wrong. expected=%q, got=%q", tt.raw, tt.quote, tt.expected, got)
+		}
+	}
+}
+
+func TestHeredocTokens(t *testing.T) {
+	input := `<?php
+$a = <<<EOT
+  Hello $name
+    indented
+  EOT;
+$b = <<<'EOT'
+raw $var
+EOT;
+$c = 1 << 2;`
+
+	tests := []struct {
+		expectedType    TokenType
+		expectedLiteral string
+		expectedLine    int
+	}{
+		{PHP_OPEN, "<?php", 1},
+		{VARIABLE, "$a", 2},
+		{ASSIGN, "=", 2},
+		{STRING, "Hello $name\n  indented", 5},
+		{SEMICOLON, ";", 5},
+		{VARIABLE, "$b", 6},
+		{ASSIGN, "=", 6},
+		{STRING, "raw $var", 8},
+		{SEMICOLON, ";", 8},
+		{VARIABLE, "$c", 9},
+		{ASSIGN, "=", 9},
+		{INT, "1", 9},
+		{SHIFT_LEFT, "<<", 9},
+		{INT, "2", 9},
+		{SEMICOLON, ";", 9},
+		{EOF, "", 9},
+	}
+
+	l := New(input)
+
+	for i, tt := range tests {
+		tok := l.NextToken()
+
+		if tok.Type != tt.expectedType {
+			t.Fatalf("tests[%d] - tokentype wrong. expected=%q, got=%q",
+				i, tt.expectedType, tok.Type)
+		}
+		if tok.Literal != tt.expectedLiteral {
+			t.Fatalf("tests[%d] - literal wrong. expected=%q, got=%q",
+				i, tt.expectedLiteral, tok.Literal)
+		}
+		if tok.Line != tt.expectedLine {
+			t.Errorf("tests[%d] - line wrong. expected=%d, got=%d",
+				i, tt.expectedLine, tok.Line)
+		}
+	}
+}
+
+func TestCompoundAssignmentTokens(t *testing.T) {
+	input := `+= -= *= /= .= %= &= |= ^= <<= >>= **= ??= -> ++`
+
+	tests := []struct {
+		expectedType    TokenType
+		expectedLiteral string
+	}{
+		{PLUS_ASSIGN, "+="},
+		{MINUS_ASSIGN, "-="},
+		{MULTIPLY_ASSIGN, "*="},
+		{DIVIDE_ASSIGN, "/="},
+		{CONCAT_ASSIGN, ".="},
+		{MODULO_ASSIGN, "%="},
+		{BIT_AND_ASSIGN, "&="},
+		{BIT_OR_ASSIGN, "|="},
+		{BIT_XOR_ASSIGN, "^="},
+		{SHIFT_LEFT_ASSIGN, "<<="},
+		{SHIFT_RIGHT_ASSIGN, ">>="},
+		{POW_ASSIGN, "**="},
+		{QUESTION_QUESTION_ASSIGN, "??="},
+		{OBJECT_ACCESS, "->"},
+		{INCREMENT, "++"},
+		{EOF, ""},
+	}
+
+	l := New(input)
+
+	for i, tt := range tests {
+		tok := l.NextToken()
+
+		if tok.Type != tt.expectedType {
+			t.Fatalf("tests[%d] - tokentype wrong. expected=%q, got=%q",
+				i, tt.expectedType, tok.Type)
+		}
+		if tok.Literal != tt.expectedLiteral {
+			t.Fatalf("tests[%d] - literal wrong. expected=%q, got=%q",
+				i, tt.expectedLiteral, tok.Literal)
+		}
+	}
+}
+
+func TestDecrementToken(t *testing.T) {
+	// -- is read greedily, so $a--1 is $a-- followed by 1, as in PHP;
+	// spaces keep the two minus signs apart
+	input := `$a--1 $b - -1 --$c`
+
+	tests := []struct {
+		expectedType    TokenType
+		expectedLiteral string
+	}{
+		{VARIABLE, "$a"},
+		{DECREMENT, "--"},
+		{INT, "1"},
+		{VARIABLE, "$b"},
+		{MINUS, "-"},
+		{MINUS, "-"},
+		{INT, "1"},
+		{DECREMENT, "--"},
+		{VARIABLE, "$c"},
+		{EOF, ""},
+	}
+
+	l := New(input)
+
+	for i, tt := range tests {
+		tok := l.NextToken()
+
+		if tok.Type != tt.expectedType {
+			t.Fatalf("tests[%d] - tokentype wrong. expected=%q, got=%q",
+				i, tt.expectedType, tok.Type)
+		}
+		if tok.Literal != tt.expectedLiteral {
+			t.Fatalf("tests[%d] - literal wrong. expected=%q, got=%q",
+				i, tt.expectedLiteral, tok.Literal)
+		}
+	}
+}
+
+func TestTokenByteOffsets(t *testing.T) {
+	input := "<?php\n$name = \"Ann\" . 'B';\necho strlen($name) >= 10;"
+
+	tests := []struct {
+		expectedType     TokenType
+		expectedPosition int
+		expectedEnd      int
+	}{
+		{PHP_OPEN, 0, 5},
+		{VARIABLE, 6, 11},
+		{ASSIGN, 12, 13},
+		{STRING, 14, 19},
+		{CONCAT, 20, 21},
+		{STRING, 22, 25},
+		{SEMICOLON, 25, 26},
+		{ECHO, 27, 31},
+		{IDENT, 32, 38},
+		{LPAREN, 38, 39},
+		{VARIABLE, 39, 44},
+		{RPAREN, 44, 45},
+		{GTE, 46, 48},
+		{INT, 49, 51},
+		{SEMICOLON, 51, 52},
+		{EOF, 52, 52},
+	}
+
+	l := New(input)
+
+	for i, tt := range tests {
+		tok := l.NextToken()
+
+		if tok.Type != tt.expectedType {
+			t.Fatalf("tests[%d] - tokentype wrong. expected=%q, got=%q",
+				i, tt.expectedType, tok.Type)
+		}
+		if tok.Position != tt.expectedPosition || tok.End != tt.expectedEnd {
+			t.Errorf("tests[%d] - offsets wrong. expected=%d-%d, got=%d-%d",
+				i, tt.expectedPosition, tt.expectedEnd, tok.Position, tok.End)
+		}
+	}
+}
+
+func TestCommentTokenSpans(t *testing.T) {
+	input := "<?php // line\n/* block */$a; # shell\n"
+
+	expected := []string{"// line", "/* block */", "# shell"}
+	var got []string
+
+	l := New(input)
+	for tok := l.NextToken(); tok.Type != EOF; tok = l.NextToken() {
+		if tok.Type == VARIABLE && tok.Literal != "$a" {
+			t.Errorf("token after a block comment lost its first character. got=%q", tok.Literal)
+		}
+		if tok.Type == COMMENT {
+			got = append(got, input[tok.Position:tok.End])
+		}
+	}
+
+	if len(got) != len(expected) {
+		t.Fatalf("expected %d comments. got=%q", len(expected), got)
+	}
+	for i, want := range expected {
+		if got[i] != want {
+			t.Errorf("comment %d spans wrong. expected=%q, got=%q", i, want, got[i])
+		}
+	}
+}
@@ -71,6 +71,36 @@ func TestSpecificMagicConstants(t *testing.T) {
 			phpCode:  `<?php echo __DIR__; ?>`,
 			expected: "__DIR__",
 		},
+		{
+			name:     "__LINE__ constant",
+			phpCode:  `<?php echo __LINE__; ?>`,
+			expected: "__LINE__",
+		},
+		{
+			name:     "__FUNCTION__ constant",
+			phpCode:  `<?php echo __FUNCTION__; ?>`,
+			expected: "__FUNCTION__",
+		},
+		{
+			name:     "__CLASS__ constant",
+			phpCode:  `<?php echo __CLASS__; ?>`,
+			expected: "__CLASS__",
+		},
+		{
+			name:     "__TRAIT__ constant",
+			phpCode:  `<?php echo __TRAIT__; ?>`,
+			expected: "__TRAIT__",
+		},
+		{
+			name:     "__METHOD__ constant",
+			phpCode:  `<?php echo __METHOD__; ?>`,
+			expected: "__METHOD__",
+		},
+		{
+			name:     "__NAMESPACE__ constant",
+			phpCode:  `<?php echo __NAMESPACE__; ?>`,
+			expected: "__NAMESPACE__",
+		},
 	}
 
 	for _, tt := range tests {
@@ -0,0 +1,73 @@
+package gophpparser
+
+// Metrics summarizes the size and shape of a parsed file
+type Metrics struct {
+	LogicalLines int            `json:"logical_lines"` // Lines holding code other than comments
+	NodeCounts   map[string]int `json:"node_counts"`   // Statements and expressions by node kind
+	MaxNesting   int            `json:"max_nesting"`   // Deepest nesting of blocks
+	Functions    int            `json:"functions"`
+	Classes      int            `json:"classes"`
+	Methods      int            `json:"methods"`
+}
+
+// Metrics computes size and complexity metrics for the program
+func (sp *SemanticProgram) Metrics() *Metrics {
+	metrics := &Metrics{
+		LogicalLines: countLogicalLines(sp.Program.source),
+		NodeCounts:   map[string]int{},
+		MaxNesting:   blockNesting(sp.Program),
+	}
+
+	Walk(sp.Program, func(node Node) bool {
+		switch node.(type) {
+		case *Program:
+			return true
+		case *FunctionDeclaration:
+			metrics.Functions++
+		case *ClassDeclaration:
+			metrics.Classes++
+		case *MethodDeclaration:
+			metrics.Methods++
+		}
+		metrics.NodeCounts[node.Kind().String()]++
+		return true
+	})
+
+	return metrics
+}
+
+// countLogicalLines counts the distinct lines that hold a token other than
+// a comment or the PHP open and close tags
+func countLogicalLines(source string) int {
+	lines := map[int]bool{}
+	l := New(source)
+	for tok := l.NextToken(); tok.Type != EOF; tok = l.NextToken() {
+		switch tok.Type {
+		case COMMENT, DOCBLOCK, PHP_OPEN, PHP_CLOSE:
+			continue
+		}
+		lines[tok.Line] = true
+	}
+	return len(lines)
+}
+
+// blockNesting returns how deeply blocks nest within node, counting node
+// itself when it is a block
+func blockNesting(node Node) int {
+	deepest := 0
+	Walk(node, func(child Node) bool {
+		if child == node {
+			return true
+		}
+		if block, ok := child.(*BlockStatement); ok {
+			deepest = max(deepest, blockNesting(block))
+			return false
+		}
+		return true
+	})
+
+	if _, ok := node.(*BlockStatement); ok {
+		return deepest + 1
+	}
+	return deepest
+}
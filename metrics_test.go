@@ -0,0 +1,58 @@
+package gophpparser
+
+import "testing"
+
+func TestMetrics(t *testing.T) {
+	phpCode := `<?php
+// Helpers for totals
+function total($items) {
+    $sum = 0;
+    foreach ($items as $item) {
+        if ($item > 0) {
+            $sum = $sum + $item;
+        }
+    }
+    return $sum;
+}
+
+class Cart {
+    /** Items in the cart */
+    private $items = [];
+
+    public function add($item) {
+        $this->items[] = $item;
+    }
+}
+?>`
+
+	semanticProgram, err := ParseWithSemantics(phpCode, "cart.php")
+	if err != nil {
+		t.Fatalf("Failed to parse with semantics: %v", err)
+	}
+
+	metrics := semanticProgram.Metrics()
+
+	if metrics.LogicalLines != 15 {
+		t.Errorf("LogicalLines wrong. expected=15, got=%d", metrics.LogicalLines)
+	}
+	if metrics.MaxNesting != 3 {
+		t.Errorf("MaxNesting wrong. expected=3, got=%d", metrics.MaxNesting)
+	}
+	if metrics.Functions != 1 || metrics.Classes != 1 || metrics.Methods != 1 {
+		t.Errorf("declaration counts wrong. got functions=%d, classes=%d, methods=%d",
+			metrics.Functions, metrics.Classes, metrics.Methods)
+	}
+
+	expectedCounts := map[string]int{
+		"ForeachStatement":     1,
+		"IfStatement":          1,
+		"ReturnStatement":      1,
+		"AssignmentExpression": 3,
+		"Comment":              1,
+	}
+	for kind, want := range expectedCounts {
+		if metrics.NodeCounts[kind] != want {
+			t.Errorf("NodeCounts[%q] wrong. expected=%d, got=%d", kind, want, metrics.NodeCounts[kind])
+		}
+	}
+}
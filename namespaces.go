@@ -0,0 +1,113 @@
+package gophpparser
+
+// fileDeclarations holds the namespaces and class-like declarations found by
+// scanDeclarations.
+type fileDeclarations struct {
+	namespaces []string
+	// classes holds the fully qualified names of declared classes,
+	// interfaces, traits and enums
+	classes []string
+}
+
+// FileNamespaces returns the namespaces declared in a PHP file, in the order
+// they are declared. It only lexes the source instead of parsing it, which
+// makes it cheap enough to run over a whole project, e.g. to validate
+// autoloader mappings. Both `namespace X;` and `namespace X { }` are
+// recognised; an unnamed `namespace { }` block is reported as "".
+func FileNamespaces(source string) ([]string, error) {
+	decls, err := scanDeclarations(source)
+	if err != nil {
+		return nil, err
+	}
+	return decls.namespaces, nil
+}
+
+// scanDeclarations lexes source and collects its namespace and class-like
+// declarations without building an AST.
+func scanDeclarations(source string) (*fileDeclarations, error) {
+	l := New(source)
+	decls := &fileDeclarations{namespaces: []string{}, classes: []string{}}
+	current := ""
+	depth := 0
+	// namespaceDepth is the brace depth of declarations directly inside the
+	// current namespace: 1 for the braced form, 0 otherwise
+	namespaceDepth := 0
+	prev := Token{}
+
+	for tok := l.NextToken(); tok.Type != EOF; prev, tok = tok, l.NextToken() {
+		switch tok.Type {
+		case LBRACE:
+			depth++
+		case RBRACE:
+			depth--
+		case CLASS, INTERFACE, TRAIT, ENUM:
+			// Skip Foo::class and anything nested in a function or class
+			if prev.Type == STATIC_ACCESS || depth != namespaceDepth {
+				continue
+			}
+
+			next := l.NextToken()
+			if isNameToken(next) {
+				name := next.Literal
+				if current != "" {
+					name = current + "\\" + name
+				}
+				decls.classes = append(decls.classes, name)
+			}
+			prev, tok = tok, next
+			if next.Type == LBRACE {
+				depth++
+			}
+		case NAMESPACE:
+			// Namespace declarations only appear at the top level
+			if depth > 0 {
+				continue
+			}
+
+			next := l.NextToken()
+			if next.Type == NAMESPACE_SEPARATOR {
+				// namespace\foo() is a relative name, not a declaration
+				continue
+			}
+
+			name := ""
+			for isNameToken(next) {
+				name += next.Literal
+				next = l.NextToken()
+				if next.Type != NAMESPACE_SEPARATOR {
+					break
+				}
+				name += "\\"
+				next = l.NextToken()
+				if !isNameToken(next) {
+					return nil, &ParseError{Message: "expected name after '\\' in namespace declaration", Line: next.Line, Column: next.Column}
+				}
+			}
+
+			switch next.Type {
+			case SEMICOLON:
+				if name == "" {
+					return nil, &ParseError{Message: "expected namespace name", Line: next.Line, Column: next.Column}
+				}
+				namespaceDepth = 0
+			case LBRACE:
+				depth++
+				namespaceDepth = 1
+			default:
+				return nil, &ParseError{Message: "expected ';' or '{' after namespace name, got " + next.Type.String(), Line: next.Line, Column: next.Column}
+			}
+
+			decls.namespaces = append(decls.namespaces, name)
+			current = name
+			prev, tok = tok, next
+		}
+	}
+
+	return decls, nil
+}
+
+// isNameToken reports whether tok can be part of a qualified name. Keywords
+// are allowed since PHP accepts them as namespace segments.
+func isNameToken(tok Token) bool {
+	return tok.Type != STRING && tok.Literal != "" && isLetter(tok.Literal[0])
+}
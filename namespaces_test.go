@@ -0,0 +1,52 @@
+package gophpparser
+
+import "testing"
+
+func TestFileNamespaces(t *testing.T) {
+	source := `<?php
+namespace App\Models {
+    class User {
+        public function save() {}
+    }
+}
+
+namespace App\Http\Controllers {
+    class UserController {}
+}
+`
+
+	namespaces, err := FileNamespaces(source)
+	if err != nil {
+		t.Fatalf("FileNamespaces failed: %v", err)
+	}
+
+	expected := []string{"App\\Models", "App\\Http\\Controllers"}
+	if len(namespaces) != len(expected) {
+		t.Fatalf("expected %d namespaces, got=%d (%v)", len(expected), len(namespaces), namespaces)
+	}
+
+	for i, want := range expected {
+		if namespaces[i] != want {
+			t.Errorf("namespaces[%d] wrong. want=%q, got=%q", i, want, namespaces[i])
+		}
+	}
+}
+
+func TestFileNamespacesSemicolonForm(t *testing.T) {
+	source := `<?php
+namespace App\Services;
+
+use App\Models\User;
+
+$x = namespace\helper();
+`
+
+	namespaces, err := FileNamespaces(source)
+	if err != nil {
+		t.Fatalf("FileNamespaces failed: %v", err)
+	}
+
+	if len(namespaces) != 1 || namespaces[0] != "App\\Services" {
+		t.Errorf("expected [App\\Services], got=%v", namespaces)
+	}
+}
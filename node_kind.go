@@ -0,0 +1,256 @@
+package gophpparser
+
+// NodeKind identifies the concrete type of an AST node. It mirrors the
+// string returned by Type() but is cheaper to switch on.
+type NodeKind int
+
+const (
+	ILLEGAL_NODE NodeKind = iota
+	PROGRAM_NODE
+	IDENTIFIER_NODE
+	VARIABLE_NODE
+	INTEGER_LITERAL_NODE
+	FLOAT_LITERAL_NODE
+	STRING_LITERAL_NODE
+	BOOLEAN_LITERAL_NODE
+	NULL_LITERAL_NODE
+	MAGIC_CONSTANT_NODE
+	COMMENT_NODE
+	EXPRESSION_STATEMENT_NODE
+	ASSIGNMENT_EXPRESSION_NODE
+	INFIX_EXPRESSION_NODE
+	PREFIX_EXPRESSION_NODE
+	FUNCTION_DECLARATION_NODE
+	RETURN_STATEMENT_NODE
+	BLOCK_STATEMENT_NODE
+	IF_STATEMENT_NODE
+	ECHO_STATEMENT_NODE
+	CALL_EXPRESSION_NODE
+	ARRAY_LITERAL_NODE
+	FOR_STATEMENT_NODE
+	INDEX_EXPRESSION_NODE
+	POSTFIX_EXPRESSION_NODE
+	WHILE_STATEMENT_NODE
+	FOREACH_STATEMENT_NODE
+	BREAK_STATEMENT_NODE
+	CONTINUE_STATEMENT_NODE
+	ASSOCIATIVE_ARRAY_LITERAL_NODE
+	INTERPOLATED_STRING_NODE
+	CLASS_DECLARATION_NODE
+	PROPERTY_DECLARATION_NODE
+	METHOD_DECLARATION_NODE
+	INTERFACE_DECLARATION_NODE
+	INTERFACE_METHOD_NODE
+	TRAIT_DECLARATION_NODE
+	TRAIT_USE_NODE
+	ENUM_DECLARATION_NODE
+	ENUM_CASE_NODE
+	CONSTANT_DECLARATION_NODE
+	NEW_EXPRESSION_NODE
+	OBJECT_ACCESS_EXPRESSION_NODE
+	STATIC_ACCESS_EXPRESSION_NODE
+	NAMESPACE_DECLARATION_NODE
+	USE_STATEMENT_NODE
+	TRY_STATEMENT_NODE
+	CATCH_CLAUSE_NODE
+	THROW_STATEMENT_NODE
+	INCLUDE_STATEMENT_NODE
+	REQUIRE_STATEMENT_NODE
+	INCLUDE_EXPRESSION_NODE
+	REQUIRE_EXPRESSION_NODE
+	PRINT_EXPRESSION_NODE
+	TYPE_HINT_NODE
+	NULLABLE_TYPE_NODE
+	ANONYMOUS_FUNCTION_NODE
+	NAMESPACED_IDENTIFIER_NODE
+	YIELD_EXPRESSION_NODE
+	TERNARY_EXPRESSION_NODE
+	THROW_EXPRESSION_NODE
+	ARROW_FUNCTION_NODE
+	MATCH_EXPRESSION_NODE
+	DECLARE_STATEMENT_NODE
+	SEMANTIC_NEW_EXPRESSION_NODE
+	SEMANTIC_CALL_EXPRESSION_NODE
+	SEMANTIC_IDENTIFIER_NODE
+	SEMANTIC_STATIC_ACCESS_NODE
+	SWITCH_STATEMENT_NODE
+	COALESCE_EXPRESSION_NODE
+	INSTANCEOF_EXPRESSION_NODE
+	UNSET_STATEMENT_NODE
+	ISSET_EXPRESSION_NODE
+	EMPTY_EXPRESSION_NODE
+	CLONE_EXPRESSION_NODE
+	GLOBAL_STATEMENT_NODE
+	STATIC_VARIABLE_STATEMENT_NODE
+	LIST_EXPRESSION_NODE
+	DESTRUCTURING_ASSIGNMENT_NODE
+	COMMA_EXPRESSION_NODE
+	EMPTY_STATEMENT_NODE
+)
+
+var nodeKindNames = map[NodeKind]string{
+	PROGRAM_NODE:                   "Program",
+	IDENTIFIER_NODE:                "Identifier",
+	VARIABLE_NODE:                  "Variable",
+	INTEGER_LITERAL_NODE:           "IntegerLiteral",
+	FLOAT_LITERAL_NODE:             "FloatLiteral",
+	STRING_LITERAL_NODE:            "StringLiteral",
+	BOOLEAN_LITERAL_NODE:           "BooleanLiteral",
+	NULL_LITERAL_NODE:              "NullLiteral",
+	MAGIC_CONSTANT_NODE:            "MagicConstant",
+	COMMENT_NODE:                   "Comment",
+	EXPRESSION_STATEMENT_NODE:      "ExpressionStatement",
+	ASSIGNMENT_EXPRESSION_NODE:     "AssignmentExpression",
+	INFIX_EXPRESSION_NODE:          "InfixExpression",
+	PREFIX_EXPRESSION_NODE:         "PrefixExpression",
+	FUNCTION_DECLARATION_NODE:      "FunctionDeclaration",
+	RETURN_STATEMENT_NODE:          "ReturnStatement",
+	BLOCK_STATEMENT_NODE:           "BlockStatement",
+	IF_STATEMENT_NODE:              "IfStatement",
+	ECHO_STATEMENT_NODE:            "EchoStatement",
+	CALL_EXPRESSION_NODE:           "CallExpression",
+	ARRAY_LITERAL_NODE:             "ArrayLiteral",
+	FOR_STATEMENT_NODE:             "ForStatement",
+	INDEX_EXPRESSION_NODE:          "IndexExpression",
+	POSTFIX_EXPRESSION_NODE:        "PostfixExpression",
+	WHILE_STATEMENT_NODE:           "WhileStatement",
+	FOREACH_STATEMENT_NODE:         "ForeachStatement",
+	BREAK_STATEMENT_NODE:           "BreakStatement",
+	CONTINUE_STATEMENT_NODE:        "ContinueStatement",
+	ASSOCIATIVE_ARRAY_LITERAL_NODE: "AssociativeArrayLiteral",
+	INTERPOLATED_STRING_NODE:       "InterpolatedString",
+	CLASS_DECLARATION_NODE:         "ClassDeclaration",
+	PROPERTY_DECLARATION_NODE:      "PropertyDeclaration",
+	METHOD_DECLARATION_NODE:        "MethodDeclaration",
+	INTERFACE_DECLARATION_NODE:     "InterfaceDeclaration",
+	INTERFACE_METHOD_NODE:          "InterfaceMethod",
+	TRAIT_DECLARATION_NODE:         "TraitDeclaration",
+	TRAIT_USE_NODE:                 "TraitUse",
+	ENUM_DECLARATION_NODE:          "EnumDeclaration",
+	ENUM_CASE_NODE:                 "EnumCase",
+	CONSTANT_DECLARATION_NODE:      "ConstantDeclaration",
+	NEW_EXPRESSION_NODE:            "NewExpression",
+	OBJECT_ACCESS_EXPRESSION_NODE:  "ObjectAccessExpression",
+	STATIC_ACCESS_EXPRESSION_NODE:  "StaticAccessExpression",
+	NAMESPACE_DECLARATION_NODE:     "NamespaceDeclaration",
+	USE_STATEMENT_NODE:             "UseStatement",
+	TRY_STATEMENT_NODE:             "TryStatement",
+	CATCH_CLAUSE_NODE:              "CatchClause",
+	THROW_STATEMENT_NODE:           "ThrowStatement",
+	INCLUDE_STATEMENT_NODE:         "IncludeStatement",
+	REQUIRE_STATEMENT_NODE:         "RequireStatement",
+	INCLUDE_EXPRESSION_NODE:        "IncludeExpression",
+	REQUIRE_EXPRESSION_NODE:        "RequireExpression",
+	PRINT_EXPRESSION_NODE:          "PrintExpression",
+	TYPE_HINT_NODE:                 "TypeHint",
+	NULLABLE_TYPE_NODE:             "NullableType",
+	ANONYMOUS_FUNCTION_NODE:        "AnonymousFunction",
+	NAMESPACED_IDENTIFIER_NODE:     "NamespacedIdentifier",
+	YIELD_EXPRESSION_NODE:          "YieldExpression",
+	TERNARY_EXPRESSION_NODE:        "TernaryExpression",
+	THROW_EXPRESSION_NODE:          "ThrowExpression",
+	ARROW_FUNCTION_NODE:            "ArrowFunction",
+	MATCH_EXPRESSION_NODE:          "MatchExpression",
+	DECLARE_STATEMENT_NODE:         "DeclareStatement",
+	SEMANTIC_NEW_EXPRESSION_NODE:   "SemanticNewExpression",
+	SEMANTIC_CALL_EXPRESSION_NODE:  "SemanticCallExpression",
+	SEMANTIC_IDENTIFIER_NODE:       "SemanticIdentifier",
+	SEMANTIC_STATIC_ACCESS_NODE:    "SemanticStaticAccess",
+	SWITCH_STATEMENT_NODE:          "SwitchStatement",
+	COALESCE_EXPRESSION_NODE:       "CoalesceExpression",
+	INSTANCEOF_EXPRESSION_NODE:     "InstanceofExpression",
+	UNSET_STATEMENT_NODE:           "UnsetStatement",
+	ISSET_EXPRESSION_NODE:          "IssetExpression",
+	EMPTY_EXPRESSION_NODE:          "EmptyExpression",
+	CLONE_EXPRESSION_NODE:          "CloneExpression",
+	GLOBAL_STATEMENT_NODE:          "GlobalStatement",
+	STATIC_VARIABLE_STATEMENT_NODE: "StaticVariableStatement",
+	LIST_EXPRESSION_NODE:           "ListExpression",
+	DESTRUCTURING_ASSIGNMENT_NODE:  "DestructuringAssignment",
+	COMMA_EXPRESSION_NODE:          "CommaExpression",
+	EMPTY_STATEMENT_NODE:           "EmptyStatement",
+}
+
+func (k NodeKind) String() string {
+	if name, ok := nodeKindNames[k]; ok {
+		return name
+	}
+	return "unknown"
+}
+
+func (p *Program) Kind() NodeKind                   { return PROGRAM_NODE }
+func (i *Identifier) Kind() NodeKind                { return IDENTIFIER_NODE }
+func (v *Variable) Kind() NodeKind                  { return VARIABLE_NODE }
+func (il *IntegerLiteral) Kind() NodeKind           { return INTEGER_LITERAL_NODE }
+func (fl *FloatLiteral) Kind() NodeKind             { return FLOAT_LITERAL_NODE }
+func (sl *StringLiteral) Kind() NodeKind            { return STRING_LITERAL_NODE }
+func (bl *BooleanLiteral) Kind() NodeKind           { return BOOLEAN_LITERAL_NODE }
+func (nl *NullLiteral) Kind() NodeKind              { return NULL_LITERAL_NODE }
+func (mc *MagicConstant) Kind() NodeKind            { return MAGIC_CONSTANT_NODE }
+func (c *Comment) Kind() NodeKind                   { return COMMENT_NODE }
+func (es *ExpressionStatement) Kind() NodeKind      { return EXPRESSION_STATEMENT_NODE }
+func (ae *AssignmentExpression) Kind() NodeKind     { return ASSIGNMENT_EXPRESSION_NODE }
+func (ie *InfixExpression) Kind() NodeKind          { return INFIX_EXPRESSION_NODE }
+func (pe *PrefixExpression) Kind() NodeKind         { return PREFIX_EXPRESSION_NODE }
+func (fd *FunctionDeclaration) Kind() NodeKind      { return FUNCTION_DECLARATION_NODE }
+func (rs *ReturnStatement) Kind() NodeKind          { return RETURN_STATEMENT_NODE }
+func (bs *BlockStatement) Kind() NodeKind           { return BLOCK_STATEMENT_NODE }
+func (ifs *IfStatement) Kind() NodeKind             { return IF_STATEMENT_NODE }
+func (es *EchoStatement) Kind() NodeKind            { return ECHO_STATEMENT_NODE }
+func (ce *CallExpression) Kind() NodeKind           { return CALL_EXPRESSION_NODE }
+func (al *ArrayLiteral) Kind() NodeKind             { return ARRAY_LITERAL_NODE }
+func (fs *ForStatement) Kind() NodeKind             { return FOR_STATEMENT_NODE }
+func (ie *IndexExpression) Kind() NodeKind          { return INDEX_EXPRESSION_NODE }
+func (pe *PostfixExpression) Kind() NodeKind        { return POSTFIX_EXPRESSION_NODE }
+func (ws *WhileStatement) Kind() NodeKind           { return WHILE_STATEMENT_NODE }
+func (fs *ForeachStatement) Kind() NodeKind         { return FOREACH_STATEMENT_NODE }
+func (bs *BreakStatement) Kind() NodeKind           { return BREAK_STATEMENT_NODE }
+func (cs *ContinueStatement) Kind() NodeKind        { return CONTINUE_STATEMENT_NODE }
+func (aal *AssociativeArrayLiteral) Kind() NodeKind { return ASSOCIATIVE_ARRAY_LITERAL_NODE }
+func (is *InterpolatedString) Kind() NodeKind       { return INTERPOLATED_STRING_NODE }
+func (cd *ClassDeclaration) Kind() NodeKind         { return CLASS_DECLARATION_NODE }
+func (pd *PropertyDeclaration) Kind() NodeKind      { return PROPERTY_DECLARATION_NODE }
+func (md *MethodDeclaration) Kind() NodeKind        { return METHOD_DECLARATION_NODE }
+func (id *InterfaceDeclaration) Kind() NodeKind     { return INTERFACE_DECLARATION_NODE }
+func (im *InterfaceMethod) Kind() NodeKind          { return INTERFACE_METHOD_NODE }
+func (td *TraitDeclaration) Kind() NodeKind         { return TRAIT_DECLARATION_NODE }
+func (tu *TraitUse) Kind() NodeKind                 { return TRAIT_USE_NODE }
+func (ed *EnumDeclaration) Kind() NodeKind          { return ENUM_DECLARATION_NODE }
+func (ec *EnumCase) Kind() NodeKind                 { return ENUM_CASE_NODE }
+func (cd *ConstantDeclaration) Kind() NodeKind      { return CONSTANT_DECLARATION_NODE }
+func (ne *NewExpression) Kind() NodeKind            { return NEW_EXPRESSION_NODE }
+func (oae *ObjectAccessExpression) Kind() NodeKind  { return OBJECT_ACCESS_EXPRESSION_NODE }
+func (sae *StaticAccessExpression) Kind() NodeKind  { return STATIC_ACCESS_EXPRESSION_NODE }
+func (nd *NamespaceDeclaration) Kind() NodeKind     { return NAMESPACE_DECLARATION_NODE }
+func (us *UseStatement) Kind() NodeKind             { return USE_STATEMENT_NODE }
+func (ts *TryStatement) Kind() NodeKind             { return TRY_STATEMENT_NODE }
+func (cc *CatchClause) Kind() NodeKind              { return CATCH_CLAUSE_NODE }
+func (ts *ThrowStatement) Kind() NodeKind           { return THROW_STATEMENT_NODE }
+func (is *IncludeStatement) Kind() NodeKind         { return INCLUDE_STATEMENT_NODE }
+func (rs *RequireStatement) Kind() NodeKind         { return REQUIRE_STATEMENT_NODE }
+func (ie *IncludeExpression) Kind() NodeKind        { return INCLUDE_EXPRESSION_NODE }
+func (re *RequireExpression) Kind() NodeKind        { return REQUIRE_EXPRESSION_NODE }
+func (pe *PrintExpression) Kind() NodeKind          { return PRINT_EXPRESSION_NODE }
+func (th *TypeHint) Kind() NodeKind                 { return TYPE_HINT_NODE }
+func (nt *NullableType) Kind() NodeKind             { return NULLABLE_TYPE_NODE }
+func (af *AnonymousFunction) Kind() NodeKind        { return ANONYMOUS_FUNCTION_NODE }
+func (ni *NamespacedIdentifier) Kind() NodeKind     { return NAMESPACED_IDENTIFIER_NODE }
+func (ye *YieldExpression) Kind() NodeKind          { return YIELD_EXPRESSION_NODE }
+func (te *TernaryExpression) Kind() NodeKind        { return TERNARY_EXPRESSION_NODE }
+func (te *ThrowExpression) Kind() NodeKind          { return THROW_EXPRESSION_NODE }
+func (af *ArrowFunction) Kind() NodeKind            { return ARROW_FUNCTION_NODE }
+func (me *MatchExpression) Kind() NodeKind          { return MATCH_EXPRESSION_NODE }
+func (ds *DeclareStatement) Kind() NodeKind         { return DECLARE_STATEMENT_NODE }
+func (ss *SwitchStatement) Kind() NodeKind          { return SWITCH_STATEMENT_NODE }
+func (ce *CoalesceExpression) Kind() NodeKind       { return COALESCE_EXPRESSION_NODE }
+func (ie *InstanceofExpression) Kind() NodeKind     { return INSTANCEOF_EXPRESSION_NODE }
+func (us *UnsetStatement) Kind() NodeKind           { return UNSET_STATEMENT_NODE }
+func (ie *IssetExpression) Kind() NodeKind          { return ISSET_EXPRESSION_NODE }
+func (ee *EmptyExpression) Kind() NodeKind          { return EMPTY_EXPRESSION_NODE }
+func (ce *CloneExpression) Kind() NodeKind          { return CLONE_EXPRESSION_NODE }
+func (gs *GlobalStatement) Kind() NodeKind          { return GLOBAL_STATEMENT_NODE }
+func (ss *StaticVariableStatement) Kind() NodeKind  { return STATIC_VARIABLE_STATEMENT_NODE }
+func (le *ListExpression) Kind() NodeKind           { return LIST_EXPRESSION_NODE }
+func (da *DestructuringAssignment) Kind() NodeKind  { return DESTRUCTURING_ASSIGNMENT_NODE }
+func (ce *CommaExpression) Kind() NodeKind          { return COMMA_EXPRESSION_NODE }
+func (es *EmptyStatement) Kind() NodeKind           { return EMPTY_STATEMENT_NODE }
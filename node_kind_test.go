@@ -0,0 +1,122 @@
+package gophpparser
+
+import "testing"
+
+func TestNodeKind(t *testing.T) {
+	tests := []struct {
+		node         Node
+		expectedKind NodeKind
+	}{
+		{&Program{}, PROGRAM_NODE},
+		{&Identifier{}, IDENTIFIER_NODE},
+		{&Variable{}, VARIABLE_NODE},
+		{&IntegerLiteral{}, INTEGER_LITERAL_NODE},
+		{&FloatLiteral{}, FLOAT_LITERAL_NODE},
+		{&StringLiteral{}, STRING_LITERAL_NODE},
+		{&BooleanLiteral{}, BOOLEAN_LITERAL_NODE},
+		{&NullLiteral{}, NULL_LITERAL_NODE},
+		{&MagicConstant{}, MAGIC_CONSTANT_NODE},
+		{&Comment{}, COMMENT_NODE},
+		{&ExpressionStatement{}, EXPRESSION_STATEMENT_NODE},
+		{&AssignmentExpression{}, ASSIGNMENT_EXPRESSION_NODE},
+		{&InfixExpression{}, INFIX_EXPRESSION_NODE},
+		{&PrefixExpression{}, PREFIX_EXPRESSION_NODE},
+		{&FunctionDeclaration{}, FUNCTION_DECLARATION_NODE},
+		{&ReturnStatement{}, RETURN_STATEMENT_NODE},
+		{&BlockStatement{}, BLOCK_STATEMENT_NODE},
+		{&IfStatement{}, IF_STATEMENT_NODE},
+		{&EchoStatement{}, ECHO_STATEMENT_NODE},
+		{&CallExpression{}, CALL_EXPRESSION_NODE},
+		{&ArrayLiteral{}, ARRAY_LITERAL_NODE},
+		{&ForStatement{}, FOR_STATEMENT_NODE},
+		{&IndexExpression{}, INDEX_EXPRESSION_NODE},
+		{&PostfixExpression{}, POSTFIX_EXPRESSION_NODE},
+		{&WhileStatement{}, WHILE_STATEMENT_NODE},
+		{&ForeachStatement{}, FOREACH_STATEMENT_NODE},
+		{&BreakStatement{}, BREAK_STATEMENT_NODE},
+		{&ContinueStatement{}, CONTINUE_STATEMENT_NODE},
+		{&AssociativeArrayLiteral{}, ASSOCIATIVE_ARRAY_LITERAL_NODE},
+		{&InterpolatedString{}, INTERPOLATED_STRING_NODE},
+		{&ClassDeclaration{}, CLASS_DECLARATION_NODE},
+		{&PropertyDeclaration{}, PROPERTY_DECLARATION_NODE},
+		{&MethodDeclaration{}, METHOD_DECLARATION_NODE},
+		{&InterfaceDeclaration{}, INTERFACE_DECLARATION_NODE},
+		{&InterfaceMethod{}, INTERFACE_METHOD_NODE},
+		{&TraitDeclaration{}, TRAIT_DECLARATION_NODE},
+		{&TraitUse{}, TRAIT_USE_NODE},
+		{&EnumDeclaration{}, ENUM_DECLARATION_NODE},
+		{&EnumCase{}, ENUM_CASE_NODE},
+		{&ConstantDeclaration{}, CONSTANT_DECLARATION_NODE},
+		{&NewExpression{}, NEW_EXPRESSION_NODE},
+		{&ObjectAccessExpression{}, OBJECT_ACCESS_EXPRESSION_NODE},
+		{&StaticAccessExpression{}, STATIC_ACCESS_EXPRESSION_NODE},
+		{&NamespaceDeclaration{}, NAMESPACE_DECLARATION_NODE},
+		{&UseStatement{}, USE_STATEMENT_NODE},
+		{&TryStatement{}, TRY_STATEMENT_NODE},
+		{&CatchClause{}, CATCH_CLAUSE_NODE},
+		{&ThrowStatement{}, THROW_STATEMENT_NODE},
+		{&IncludeStatement{}, INCLUDE_STATEMENT_NODE},
+		{&RequireStatement{}, REQUIRE_STATEMENT_NODE},
+		{&IncludeExpression{}, INCLUDE_EXPRESSION_NODE},
+		{&RequireExpression{}, REQUIRE_EXPRESSION_NODE},
+		{&PrintExpression{}, PRINT_EXPRESSION_NODE},
+		{&TypeHint{}, TYPE_HINT_NODE},
+		{&NullableType{}, NULLABLE_TYPE_NODE},
+		{&AnonymousFunction{}, ANONYMOUS_FUNCTION_NODE},
+		{&NamespacedIdentifier{}, NAMESPACED_IDENTIFIER_NODE},
+		{&YieldExpression{}, YIELD_EXPRESSION_NODE},
+		{&TernaryExpression{}, TERNARY_EXPRESSION_NODE},
+		{&ThrowExpression{}, THROW_EXPRESSION_NODE},
+		{&ArrowFunction{}, ARROW_FUNCTION_NODE},
+		{&MatchExpression{}, MATCH_EXPRESSION_NODE},
+		{&DeclareStatement{}, DECLARE_STATEMENT_NODE},
+		{&SemanticNewExpression{}, SEMANTIC_NEW_EXPRESSION_NODE},
+		{&SemanticCallExpression{}, SEMANTIC_CALL_EXPRESSION_NODE},
+		{&SemanticIdentifier{}, SEMANTIC_IDENTIFIER_NODE},
+		{&SemanticStaticAccess{}, SEMANTIC_STATIC_ACCESS_NODE},
+		{&SwitchStatement{}, SWITCH_STATEMENT_NODE},
+		{&CoalesceExpression{}, COALESCE_EXPRESSION_NODE},
+		{&InstanceofExpression{}, INSTANCEOF_EXPRESSION_NODE},
+		{&UnsetStatement{}, UNSET_STATEMENT_NODE},
+		{&IssetExpression{}, ISSET_EXPRESSION_NODE},
+		{&EmptyExpression{}, EMPTY_EXPRESSION_NODE},
+		{&CloneExpression{}, CLONE_EXPRESSION_NODE},
+		{&GlobalStatement{}, GLOBAL_STATEMENT_NODE},
+		{&StaticVariableStatement{}, STATIC_VARIABLE_STATEMENT_NODE},
+		{&ListExpression{}, LIST_EXPRESSION_NODE},
+		{&DestructuringAssignment{}, DESTRUCTURING_ASSIGNMENT_NODE},
+		{&CommaExpression{}, COMMA_EXPRESSION_NODE},
+		{&EmptyStatement{}, EMPTY_STATEMENT_NODE},
+	}
+
+	for _, tt := range tests {
+		if tt.node.Kind() != tt.expectedKind {
+			t.Errorf("%T.Kind() wrong. expected=%s, got=%s", tt.node, tt.expectedKind, tt.node.Kind())
+		}
+		if tt.node.Kind().String() != tt.node.Type() {
+			t.Errorf("%T.Kind().String() does not match Type(). kind=%q, type=%q",
+				tt.node, tt.node.Kind().String(), tt.node.Type())
+		}
+	}
+}
+
+func TestNodeKindSwitch(t *testing.T) {
+	program, err := Parse("<?php $a = 1; echo $a; ?>")
+	if err != nil {
+		t.Fatalf("Parse failed: %v", err)
+	}
+
+	expected := []NodeKind{EXPRESSION_STATEMENT_NODE, ECHO_STATEMENT_NODE}
+	if len(program.Statements) != len(expected) {
+		t.Fatalf("program.Statements does not contain %d statements. got=%d", len(expected), len(program.Statements))
+	}
+	for i, stmt := range program.Statements {
+		if stmt.Kind() != expected[i] {
+			t.Errorf("statement %d kind wrong. expected=%s, got=%s", i, expected[i], stmt.Kind())
+		}
+	}
+
+	if NodeKind(-1).String() != "unknown" {
+		t.Errorf("unknown kind should stringify as unknown. got=%q", NodeKind(-1).String())
+	}
+}
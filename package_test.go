@@ -0,0 +1,20 @@
+package gophpparser_test
+
+import (
+	"testing"
+
+	"github.com/buildwithhp/gophpparser"
+)
+
+func TestPackageCompiles(t *testing.T) {
+	if gophpparser.ILLEGAL.String() != "ILLEGAL" {
+		t.Errorf("ILLEGAL.String() wrong. got=%q", gophpparser.ILLEGAL.String())
+	}
+
+	if tok := gophpparser.LookupIdent("function"); tok != gophpparser.FUNCTION {
+		t.Errorf("LookupIdent(\"function\") wrong. got=%s", tok)
+	}
+	if tok := gophpparser.LookupIdent("foo"); tok != gophpparser.IDENT {
+		t.Errorf("LookupIdent(\"foo\") wrong. got=%s", tok)
+	}
+}
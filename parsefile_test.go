@@ -201,4 +201,41 @@ func ExampleParsefile() {
 
 	// Use the program
 	_ = program.Statements
-}
\ No newline at end of file
+}
+
+func TestParsefileWithByteOrderMark(t *testing.T) {
+	content := "\xEF\xBB\xBF<?php\n$name = \"John\";\necho $name;\n?>"
+
+	tmpFile, err := os.CreateTemp("", "bom_*.php")
+	if err != nil {
+		t.Fatalf("Failed to create temp file: %v", err)
+	}
+	defer os.Remove(tmpFile.Name())
+
+	if _, err := tmpFile.WriteString(content); err != nil {
+		t.Fatalf("Failed to write to temp file: %v", err)
+	}
+	tmpFile.Close()
+
+	program, err := Parsefile(tmpFile.Name())
+	if err != nil {
+		t.Fatalf("Failed to parse file with BOM: %v", err)
+	}
+
+	if len(program.Statements) != 2 {
+		t.Fatalf("Expected 2 statements, got %d: %q", len(program.Statements), program.String())
+	}
+	if _, ok := program.Statements[0].(*ExpressionStatement); !ok {
+		t.Errorf("First statement is not *ExpressionStatement. got=%T", program.Statements[0])
+	}
+
+	tok := New(content).NextToken()
+	if tok.Type != PHP_OPEN {
+		t.Errorf("First token should be PHP_OPEN, got %s (%q)", tok.Type, tok.Literal)
+	}
+	// The BOM shifts byte offsets but not the line and column
+	plain := New(content[3:]).NextToken()
+	if tok.Line != plain.Line || tok.Column != plain.Column || tok.Position != plain.Position+3 {
+		t.Errorf("PHP_OPEN position wrong. line=%d, column=%d, position=%d", tok.Line, tok.Column, tok.Position)
+	}
+}
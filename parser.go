@@ -10,18 +10,33 @@ import (
 const (
 	_ int = iota
 	LOWEST
+	WORD_OR     // or
+	WORD_XOR    // xor
+	WORD_AND    // and
+	ASSIGNMENT  // =
 	TERNARY     // ? :
+	COALESCE    // ??
+	BITWISE_OR  // |
+	BITWISE_XOR // ^
+	BITWISE_AND // &
 	EQUALS      // ==
 	LESSGREATER // > or <
+	SHIFT       // << or >>
 	SUM         // +
 	PRODUCT     // *
 	PREFIX      // -X or !X
+	TYPE_CHECK  // X instanceof Y, above PREFIX so !X instanceof Y is !(X instanceof Y)
+	POWER       // X ** Y, above PREFIX so -X ** Y is -(X ** Y)
 	CALL        // myFunction(X)
 )
 
 var precedences = map[TokenType]int{
+	LOGICAL_OR:               WORD_OR,
+	LOGICAL_XOR:              WORD_XOR,
+	LOGICAL_AND:              WORD_AND,
+	ASSIGN:                   ASSIGNMENT,
 	QUESTION:                 TERNARY,
-	QUESTION_QUESTION:        EQUALS,
+	QUESTION_QUESTION:        COALESCE,
 	QUESTION_QUESTION_ASSIGN: EQUALS,
 	QUESTION_ARROW:           CALL,
 	EQ:                       EQUALS,
@@ -33,15 +48,37 @@ var precedences = map[TokenType]int{
 	LTE:                      LESSGREATER,
 	GTE:                      LESSGREATER,
 	SPACESHIP:                LESSGREATER,
+	BIT_OR:                   BITWISE_OR,
+	BIT_XOR:                  BITWISE_XOR,
+	BIT_AND:                  BITWISE_AND,
+	SHIFT_LEFT:               SHIFT,
+	SHIFT_RIGHT:              SHIFT,
 	PLUS:                     SUM,
 	MINUS:                    SUM,
 	CONCAT:                   SUM,
 	DIVIDE:                   PRODUCT,
 	MULTIPLY:                 PRODUCT,
 	MODULO:                   PRODUCT,
+	POW:                      POWER,
+	POW_ASSIGN:               ASSIGNMENT,
+	PLUS_ASSIGN:              ASSIGNMENT,
+	MINUS_ASSIGN:             ASSIGNMENT,
+	MULTIPLY_ASSIGN:          ASSIGNMENT,
+	DIVIDE_ASSIGN:            ASSIGNMENT,
+	CONCAT_ASSIGN:            ASSIGNMENT,
+	MODULO_ASSIGN:            ASSIGNMENT,
+	BIT_AND_ASSIGN:           ASSIGNMENT,
+	BIT_OR_ASSIGN:            ASSIGNMENT,
+	BIT_XOR_ASSIGN:           ASSIGNMENT,
+	SHIFT_LEFT_ASSIGN:        ASSIGNMENT,
+	SHIFT_RIGHT_ASSIGN:       ASSIGNMENT,
+	INSTANCEOF:               TYPE_CHECK,
 	LPAREN:                   CALL,
+	LBRACKET:                 CALL,
 	OBJECT_ACCESS:            CALL,
 	STATIC_ACCESS:            CALL,
+	INCREMENT:                CALL,
+	DECREMENT:                CALL,
 }
 
 type (
@@ -55,7 +92,41 @@ type Parser struct {
 	curToken  Token
 	peekToken Token
 
-	errors []string
+	// heldComments are the comments skipped after the current '{' or ':'.
+	// Whether they start a statement list is only known once the construct
+	// is parsed, so keepComments puts them back where one does.
+	heldComments []Token
+	// queuedTokens are read before the lexer is asked for more
+	queuedTokens []Token
+
+	// errors holds every error in the order it was found, each with the
+	// position of the token it is about
+	errors []*ParseError
+	// recovering is set by a syntax error and cleared once parsing has
+	// skipped to the next statement. Errors in between are follow-on errors
+	// of the first and are not recorded.
+	recovering bool
+
+	// sawYield records whether a yield was parsed in the current function body
+	sawYield bool
+
+	// MaxDepth limits how deeply expressions may nest; 0 means no limit.
+	// Left-associative operator chains such as $a . $b . $c are parsed
+	// iteratively and do not count against it.
+	MaxDepth int
+	depth    int
+
+	// bestEffort drops top-level statements that failed to parse instead
+	// of keeping their partial nodes
+	bestEffort bool
+
+	// AttachComments records the comments written directly before function,
+	// class, method and property declarations on those declarations
+	AttachComments bool
+	// leadingComments are the comments directly before the current token,
+	// looking past modifiers; pendingComments collects them as they are read
+	leadingComments []Token
+	pendingComments []Token
 
 	prefixParseFns map[TokenType]prefixParseFn
 	infixParseFns  map[TokenType]infixParseFn
@@ -64,7 +135,7 @@ type Parser struct {
 func NewParser(l *Lexer) *Parser {
 	p := &Parser{
 		l:      l,
-		errors: []string{},
+		errors: []*ParseError{},
 	}
 
 	p.prefixParseFns = make(map[TokenType]prefixParseFn)
@@ -81,18 +152,29 @@ func NewParser(l *Lexer) *Parser {
 	p.registerPrefix(MINUS, p.parsePrefixExpression)
 	p.registerPrefix(INCREMENT, p.parsePrefixExpression)
 	p.registerPrefix(DECREMENT, p.parsePrefixExpression)
+	p.registerPrefix(BIT_NOT, p.parsePrefixExpression)
+	p.registerPrefix(AT, p.parsePrefixExpression)
+	p.registerPrefix(LIST, p.parseListExpression)
 	p.registerPrefix(NEW, p.parseNewExpression)
 	p.registerPrefix(FUNCTION, p.parseAnonymousFunction)
 	p.registerPrefix(STATIC, p.parseStaticFunction)
 	p.registerPrefix(YIELD, p.parseYieldExpression)
+	p.registerPrefix(THROW, p.parseThrowExpression)
+	p.registerPrefix(ARROW_FUNCTION, p.parseArrowFunction)
+	p.registerPrefix(MATCH, p.parseMatchExpression)
 	p.registerPrefix(LPAREN, p.parseGroupedExpression)
 	p.registerPrefix(LBRACKET, p.parseArrayLiteral)
+	p.registerPrefix(ARRAY, p.parseArrayFunction)
+	p.registerPrefix(ISSET, p.parseIssetExpression)
+	p.registerPrefix(EMPTY, p.parseEmptyExpression)
+	p.registerPrefix(CLONE, p.parseCloneExpression)
 	p.registerPrefix(NAMESPACE_SEPARATOR, p.parseNamespacedIdentifier)
 	p.registerPrefix(QUESTION, p.parseTernaryOrNullable)
 	p.registerPrefix(INCLUDE, p.parseIncludeExpression)
 	p.registerPrefix(INCLUDE_ONCE, p.parseIncludeExpression)
 	p.registerPrefix(REQUIRE, p.parseRequireExpression)
 	p.registerPrefix(REQUIRE_ONCE, p.parseRequireExpression)
+	p.registerPrefix(PRINT, p.parsePrintExpression)
 	// Add prefix functions for operators that might appear in unexpected contexts
 	p.registerPrefix(MULTIPLY, p.parseUnexpectedToken)
 	p.registerPrefix(DIVIDE, p.parseUnexpectedToken)
@@ -114,11 +196,32 @@ func NewParser(l *Lexer) *Parser {
 	p.registerInfix(LTE, p.parseInfixExpression)
 	p.registerInfix(GTE, p.parseInfixExpression)
 	p.registerInfix(SPACESHIP, p.parseInfixExpression)
+	p.registerInfix(BIT_AND, p.parseInfixExpression)
+	p.registerInfix(BIT_OR, p.parseInfixExpression)
+	p.registerInfix(BIT_XOR, p.parseInfixExpression)
+	p.registerInfix(SHIFT_LEFT, p.parseInfixExpression)
+	p.registerInfix(SHIFT_RIGHT, p.parseInfixExpression)
 	p.registerInfix(AND, p.parseInfixExpression)
 	p.registerInfix(OR, p.parseInfixExpression)
+	p.registerInfix(LOGICAL_AND, p.parseInfixExpression)
+	p.registerInfix(LOGICAL_OR, p.parseInfixExpression)
+	p.registerInfix(LOGICAL_XOR, p.parseInfixExpression)
 	p.registerInfix(QUESTION, p.parseTernaryExpression)
-	p.registerInfix(QUESTION_QUESTION, p.parseInfixExpression)
+	p.registerInfix(QUESTION_QUESTION, p.parseCoalesceExpression)
 	p.registerInfix(QUESTION_QUESTION_ASSIGN, p.parseAssignmentExpression)
+	p.registerInfix(POW, p.parseInfixExpression)
+	p.registerInfix(POW_ASSIGN, p.parseAssignmentExpression)
+	p.registerInfix(PLUS_ASSIGN, p.parseAssignmentExpression)
+	p.registerInfix(MINUS_ASSIGN, p.parseAssignmentExpression)
+	p.registerInfix(MULTIPLY_ASSIGN, p.parseAssignmentExpression)
+	p.registerInfix(DIVIDE_ASSIGN, p.parseAssignmentExpression)
+	p.registerInfix(CONCAT_ASSIGN, p.parseAssignmentExpression)
+	p.registerInfix(MODULO_ASSIGN, p.parseAssignmentExpression)
+	p.registerInfix(BIT_AND_ASSIGN, p.parseAssignmentExpression)
+	p.registerInfix(BIT_OR_ASSIGN, p.parseAssignmentExpression)
+	p.registerInfix(BIT_XOR_ASSIGN, p.parseAssignmentExpression)
+	p.registerInfix(SHIFT_LEFT_ASSIGN, p.parseAssignmentExpression)
+	p.registerInfix(SHIFT_RIGHT_ASSIGN, p.parseAssignmentExpression)
 	p.registerInfix(QUESTION_ARROW, p.parseObjectAccessExpression)
 	p.registerInfix(ASSIGN, p.parseAssignmentExpression)
 	p.registerInfix(LPAREN, p.parseCallExpression)
@@ -127,6 +230,7 @@ func NewParser(l *Lexer) *Parser {
 	p.registerInfix(DECREMENT, p.parsePostfixExpression)
 	p.registerInfix(OBJECT_ACCESS, p.parseObjectAccessExpression)
 	p.registerInfix(STATIC_ACCESS, p.parseStaticAccessExpression)
+	p.registerInfix(INSTANCEOF, p.parseInstanceofExpression)
 
 	p.nextToken()
 	p.nextToken()
@@ -135,15 +239,112 @@ func NewParser(l *Lexer) *Parser {
 }
 
 func (p *Parser) nextToken() {
+	previous := p.curToken
 	p.curToken = p.peekToken
-	p.peekToken = p.l.NextToken()
+	p.peekToken = p.readToken()
+	p.heldComments = nil
+
+	// Comments between statements become Comment nodes; anywhere else, such
+	// as between the calls of a method chain, they are skipped
+	for (p.peekTokenIs(COMMENT) || p.peekTokenIs(DOCBLOCK)) && !p.atStatementBoundary() {
+		if p.curTokenIs(LBRACE) || p.curTokenIs(COLON) {
+			p.heldComments = append(p.heldComments, p.peekToken)
+		}
+		p.peekToken = p.readToken()
+	}
+
+	if p.AttachComments {
+		p.trackComments(previous)
+	}
+}
+
+// trackComments keeps leadingComments up to date as the current token
+// advances. A comment trailing code on the same line belongs to that code
+// and is not collected.
+func (p *Parser) trackComments(previous Token) {
+	switch p.curToken.Type {
+	case COMMENT, DOCBLOCK:
+		if previous.Line != p.curToken.Line || previous.Type == COMMENT || previous.Type == DOCBLOCK {
+			p.pendingComments = append(p.pendingComments, p.curToken)
+		}
+	default:
+		// Modifiers sit between a declaration and its comments
+		if len(p.pendingComments) > 0 || !isModifierToken(previous.Type) {
+			p.leadingComments, p.pendingComments = p.pendingComments, nil
+		}
+	}
+}
+
+func isModifierToken(tokenType TokenType) bool {
+	switch tokenType {
+	case PUBLIC, PROTECTED, PRIVATE, STATIC, ABSTRACT, FINAL, READONLY, VAR:
+		return true
+	}
+	return false
+}
+
+// leadingCommentTexts returns the text of the comments directly before the
+// current declaration, along with the last docblock among them
+func (p *Parser) leadingCommentTexts() (comments []string, docBlock string) {
+	for _, comment := range p.leadingComments {
+		comments = append(comments, comment.Literal)
+		if comment.Type == DOCBLOCK {
+			docBlock = comment.Literal
+		}
+	}
+	return comments, docBlock
+}
+
+func (p *Parser) readToken() Token {
+	if len(p.queuedTokens) > 0 {
+		tok := p.queuedTokens[0]
+		p.queuedTokens = p.queuedTokens[1:]
+		return tok
+	}
+	return p.l.NextToken()
+}
+
+// keepComments is called on the '{' or ':' that opens a statement list or
+// a class body, and brings back the comments skipped after it so that they
+// become Comment nodes or attach to the first declaration.
+func (p *Parser) keepComments() {
+	if len(p.heldComments) == 0 {
+		return
+	}
+	queued := append(p.heldComments[1:len(p.heldComments):len(p.heldComments)], p.peekToken)
+	p.queuedTokens = append(queued, p.queuedTokens...)
+	p.peekToken = p.heldComments[0]
+	p.heldComments = nil
+}
+
+// atStatementBoundary reports whether a statement may start after the
+// current token. Comments after '{' and ':' are held rather than kept,
+// since those also appear inside expressions and declarations.
+func (p *Parser) atStatementBoundary() bool {
+	switch p.curToken.Type {
+	case SEMICOLON, RBRACE, PHP_OPEN, PHP_CLOSE, COMMENT, DOCBLOCK:
+		return true
+	case ILLEGAL:
+		// The zero token before the first call to nextToken
+		return p.curToken.Literal == ""
+	}
+	return false
 }
 
 func (p *Parser) ParseProgram() *Program {
-	program := &Program{}
-	program.Statements = []Statement{}
+	program := &Program{source: p.l.input}
+	program.Statements, program.spans = p.parseTopLevelStatements(len(p.l.input))
 
-	for !p.curTokenIs(EOF) {
+	return program
+}
+
+// parseTopLevelStatements parses statements until the current token starts
+// at or after the end offset, returning each statement with its span.
+func (p *Parser) parseTopLevelStatements(end int) ([]Statement, []statementSpan) {
+	statements := []Statement{}
+	spans := []statementSpan{}
+
+	for !p.curTokenIs(EOF) && p.curToken.Position < end {
 		if p.curTokenIs(PHP_OPEN) {
 			p.nextToken()
 			continue
@@ -153,26 +354,50 @@ func (p *Parser) ParseProgram() *Program {
 			continue
 		}
 
+		start := p.curToken.Position
+		errorCount := len(p.errors)
 		stmt := p.parseStatement()
+		if p.recovering {
+			p.synchronize()
+		}
+		if p.bestEffort && len(p.errors) > errorCount {
+			stmt = nil
+		}
 		if stmt != nil {
-			program.Statements = append(program.Statements, stmt)
+			statements = append(statements, stmt)
+			spans = append(spans, statementSpan{start: start, end: p.peekToken.Position})
 		}
 		p.nextToken()
 	}
 
-	return program
+	return statements, spans
 }
 
 func (p *Parser) parseStatement() Statement {
+	stmt := p.parseStatementByToken()
+	p.closeNode(stmt)
+	return stmt
+}
+
+func (p *Parser) parseStatementByToken() Statement {
 	switch p.curToken.Type {
 	case FUNCTION:
 		return p.parseFunctionDeclaration()
 	case CLASS:
-		return p.parseClassDeclaration()
+		stmt := p.parseClassDeclaration()
+		if stmt == nil {
+			return nil
+		}
+		p.checkAbstractMethods(stmt)
+		return stmt
+	case ABSTRACT, FINAL:
+		return p.parseModifiedClassDeclaration()
 	case INTERFACE:
 		return p.parseInterfaceDeclaration()
 	case TRAIT:
 		return p.parseTraitDeclaration()
+	case ENUM:
+		return p.parseEnumDeclaration()
 	case CONST:
 		return p.parseConstantDeclaration()
 	case NAMESPACE:
@@ -185,6 +410,8 @@ func (p *Parser) parseStatement() Statement {
 		return p.parseComment()
 	case DOCBLOCK:
 		return p.parseComment()
+	case SEMICOLON:
+		return &EmptyStatement{Token: p.curToken}
 	case TRY:
 		return p.parseTryStatement()
 	case THROW:
@@ -195,10 +422,21 @@ func (p *Parser) parseStatement() Statement {
 		return p.parseIfStatement()
 	case ECHO:
 		return p.parseEchoStatement()
+	case UNSET:
+		return p.parseUnsetStatement()
+	case GLOBAL:
+		return p.parseGlobalStatement()
+	case STATIC:
+		if p.peekTokenIs(VARIABLE) {
+			return p.parseStaticVariableStatement()
+		}
+		return p.parseExpressionStatement()
 	case FOR:
 		return p.parseForStatement()
 	case WHILE:
 		return p.parseWhileStatement()
+	case SWITCH:
+		return p.parseSwitchStatement()
 	case FOREACH:
 		return p.parseForeachStatement()
 	case BREAK:
@@ -220,6 +458,13 @@ func (p *Parser) parseStatement() Statement {
 
 func (p *Parser) parseFunctionDeclaration() *FunctionDeclaration {
 	stmt := &FunctionDeclaration{Token: p.curToken}
+	stmt.LeadingComments, stmt.DocBlock = p.leadingCommentTexts()
+
+	// Check for return by reference: function &name()
+	if p.peekTokenIs(BIT_AND) {
+		p.nextToken()
+		stmt.ReturnsRef = true
+	}
 
 	if !p.expectPeek(IDENT) {
 		return nil
@@ -232,67 +477,233 @@ func (p *Parser) parseFunctionDeclaration() *FunctionDeclaration {
 	}
 
 	stmt.Parameters = p.parseFunctionParameters()
+	p.checkPromotedParameters(stmt.Parameters, false)
 
-	// Check for return type hint
-	if p.peekTokenIs(COLON) {
-		p.nextToken() // consume ':'
-		p.nextToken() // move to return type
-		stmt.ReturnType = p.parseExpression(LOWEST)
-	}
+	stmt.ReturnType = p.parseReturnType()
 
 	if !p.expectPeek(LBRACE) {
 		return nil
 	}
 
-	stmt.Body = p.parseBlockStatement()
+	stmt.Body, stmt.IsGenerator = p.parseFunctionBody()
 
 	return stmt
 }
 
-func (p *Parser) parseFunctionParameters() []*Variable {
-	identifiers := []*Variable{}
+// parseFunctionBody parses a function or method body and reports whether
+// it contains a yield, which makes the function a generator.
+func (p *Parser) parseFunctionBody() (*BlockStatement, bool) {
+	outer := p.sawYield
+	p.sawYield = false
+
+	body := p.parseBlockStatement()
+	isGenerator := p.sawYield
+
+	p.sawYield = outer
+	return body, isGenerator
+}
+
+func (p *Parser) parseFunctionParameters() []*Parameter {
+	parameters := []*Parameter{}
 
 	if p.peekTokenIs(RPAREN) {
 		p.nextToken()
-		return identifiers
+		return parameters
 	}
 
 	p.nextToken()
 
-	if p.curToken.Type == VARIABLE {
-		ident := &Variable{Token: p.curToken, Name: p.curToken.Literal[1:]}
-		identifiers = append(identifiers, ident)
+	param := p.parseParameter()
+	if param == nil {
+		return nil
 	}
+	parameters = append(parameters, param)
 
 	for p.peekTokenIs(COMMA) {
 		p.nextToken()
+
+		// PHP 8.0 allows a trailing comma before the closing parenthesis
+		if p.peekTokenIs(RPAREN) {
+			break
+		}
 		p.nextToken()
-		if p.curToken.Type == VARIABLE {
-			ident := &Variable{Token: p.curToken, Name: p.curToken.Literal[1:]}
-			identifiers = append(identifiers, ident)
+
+		// Only the last parameter may collect the remaining arguments
+		if last := parameters[len(parameters)-1]; last.Variadic {
+			p.addError(last.Token, "variadic parameter $%s must be the last parameter", last.Name)
+			return nil
+		}
+
+		param := p.parseParameter()
+		if param == nil {
+			return nil
 		}
+		parameters = append(parameters, param)
 	}
 
 	if !p.expectPeek(RPAREN) {
 		return nil
 	}
 
-	return identifiers
+	return parameters
+}
+
+// parseParameter parses a parameter in the order PHP requires: attributes,
+// promotion modifiers, type, &, ... and the variable with an optional
+// default, as in #[Required] private readonly ?int $x = null. It leaves
+// the current token on the last token of the parameter.
+func (p *Parser) parseParameter() *Parameter {
+	param := &Parameter{}
+
+	for p.curTokenIs(ATTRIBUTE_START) {
+		attributes := p.parseAttributeGroup()
+		if attributes == nil {
+			return nil
+		}
+		param.Attributes = append(param.Attributes, attributes...)
+		p.nextToken()
+	}
+
+	for p.curTokenIs(PUBLIC) || p.curTokenIs(PROTECTED) || p.curTokenIs(PRIVATE) || p.curTokenIs(READONLY) {
+		if p.curTokenIs(READONLY) {
+			if param.Readonly {
+				p.addError(p.curToken, "duplicate readonly modifier")
+				return nil
+			}
+			param.Readonly = true
+		} else {
+			if param.Visibility != "" {
+				p.addError(p.curToken, "multiple visibility modifiers on a parameter")
+				return nil
+			}
+			param.Visibility = strings.ToLower(p.curToken.Literal)
+		}
+		p.nextToken()
+	}
+
+	if p.isTypeHintStart() {
+		param.TypeHint = p.parseTypeHint()
+		if param.TypeHint == nil {
+			return nil
+		}
+		p.nextToken()
+	}
+
+	if p.curTokenIs(BIT_AND) {
+		param.ByRef = true
+		p.nextToken()
+	}
+	if p.curTokenIs(ELLIPSIS) {
+		param.Variadic = true
+		p.nextToken()
+	}
+
+	if !p.curTokenIs(VARIABLE) {
+		p.bareSyntaxError(p.curToken, "expected parameter variable, got %s instead", p.curToken.Type)
+		return nil
+	}
+	param.Token = p.curToken
+	param.Name = p.curToken.Literal[1:]
+
+	if param.Variadic && param.Promoted() {
+		p.addError(param.Token, "cannot declare variadic promoted property $%s", param.Name)
+		return nil
+	}
+
+	if p.peekTokenIs(ASSIGN) {
+		if param.Variadic {
+			p.addError(param.Token, "variadic parameter $%s cannot have a default value", param.Name)
+			return nil
+		}
+		p.nextToken()
+		p.nextToken()
+		param.Default = p.parseExpression(LOWEST)
+	}
+
+	return param
+}
+
+// parseAttributeGroup parses one #[...] group of comma-separated
+// attributes, leaving the current token on the closing bracket
+func (p *Parser) parseAttributeGroup() []*Attribute {
+	attributes := []*Attribute{}
+
+	for {
+		p.nextToken()
+		if !p.curTokenIs(IDENT) && !p.curTokenIs(NAMESPACE_SEPARATOR) {
+			p.syntaxError(p.curToken, "expected attribute name, got %s instead", p.curToken.Type)
+			return nil
+		}
+
+		attribute := &Attribute{Token: p.curToken}
+		attribute.Name = p.parseQualifiedName()
+		if attribute.Name == nil {
+			return nil
+		}
+		if p.peekTokenIs(LPAREN) {
+			p.nextToken()
+			attribute.Arguments = p.parseExpressionList(RPAREN)
+			if attribute.Arguments == nil {
+				return nil
+			}
+		}
+		attributes = append(attributes, attribute)
+
+		if !p.peekTokenIs(COMMA) {
+			break
+		}
+		p.nextToken()
+
+		// A trailing comma may precede the closing bracket
+		if p.peekTokenIs(RBRACKET) {
+			break
+		}
+	}
+
+	if !p.expectPeek(RBRACKET) {
+		return nil
+	}
+
+	return attributes
+}
+
+// checkPromotedParameters reports promoted parameters in a function that is
+// not a constructor, where they cannot declare properties
+func (p *Parser) checkPromotedParameters(parameters []*Parameter, isConstructor bool) {
+	if isConstructor {
+		return
+	}
+	for _, param := range parameters {
+		if param.Promoted() {
+			p.addError(param.Token, "cannot declare promoted property $%s outside a constructor", param.Name)
+		}
+	}
 }
 
 func (p *Parser) parseBlockStatement() *BlockStatement {
 	block := &BlockStatement{Token: p.curToken}
 	block.Statements = []Statement{}
 
+	p.keepComments()
 	p.nextToken()
 
 	for !p.curTokenIs(RBRACE) && !p.curTokenIs(EOF) {
+		// A close tag ends the statement before it like ';', and the open
+		// tag that resumes the block starts nothing itself
+		if p.curTokenIs(PHP_CLOSE) || p.curTokenIs(PHP_OPEN) {
+			p.nextToken()
+			continue
+		}
 		stmt := p.parseStatement()
+		if p.recovering {
+			p.synchronize()
+		}
 		if stmt != nil {
 			block.Statements = append(block.Statements, stmt)
 		}
 		p.nextToken()
 	}
+	p.closeNode(block)
 
 	return block
 }
@@ -300,6 +711,11 @@ func (p *Parser) parseBlockStatement() *BlockStatement {
 func (p *Parser) parseReturnStatement() *ReturnStatement {
 	stmt := &ReturnStatement{Token: p.curToken}
 
+	// A close tag or the end of the file also ends the statement
+	if p.peekTokenIs(PHP_CLOSE) || p.peekTokenIs(EOF) {
+		return stmt
+	}
+
 	p.nextToken()
 
 	if !p.curTokenIs(SEMICOLON) {
@@ -333,14 +749,42 @@ func (p *Parser) parseIfStatement() *IfStatement {
 
 	stmt.Consequence = p.parseBlockStatement()
 
-	if p.peekTokenIs(ELSE) {
+	for p.peekTokenIs(ELSEIF) || p.peekTokenIs(ELSE) {
 		p.nextToken()
 
+		// "else if" is the two-token spelling of "elseif"
+		if p.curTokenIs(ELSEIF) || p.peekTokenIs(IF) {
+			elseIf := &ElseIf{Token: p.curToken}
+			if p.curTokenIs(ELSE) {
+				p.nextToken()
+			}
+
+			if !p.expectPeek(LPAREN) {
+				return nil
+			}
+
+			p.nextToken()
+			elseIf.Condition = p.parseExpression(LOWEST)
+
+			if !p.expectPeek(RPAREN) {
+				return nil
+			}
+
+			if !p.expectPeek(LBRACE) {
+				return nil
+			}
+
+			elseIf.Consequence = p.parseBlockStatement()
+			stmt.ElseIfs = append(stmt.ElseIfs, elseIf)
+			continue
+		}
+
 		if !p.expectPeek(LBRACE) {
 			return nil
 		}
 
 		stmt.Alternative = p.parseBlockStatement()
+		break
 	}
 
 	return stmt
@@ -366,14 +810,144 @@ func (p *Parser) parseEchoStatement() *EchoStatement {
 	return stmt
 }
 
+func (p *Parser) parseUnsetStatement() Statement {
+	stmt := &UnsetStatement{Token: p.curToken}
+
+	stmt.Arguments = p.parseConstructArguments()
+	if stmt.Arguments == nil {
+		return nil
+	}
+
+	if p.peekTokenIs(SEMICOLON) {
+		p.nextToken()
+	}
+
+	return stmt
+}
+
+func (p *Parser) parseGlobalStatement() Statement {
+	stmt := &GlobalStatement{Token: p.curToken}
+
+	for {
+		if !p.expectPeek(VARIABLE) {
+			return nil
+		}
+		stmt.Variables = append(stmt.Variables, &Variable{Token: p.curToken, Name: p.curToken.Literal[1:]})
+
+		if !p.peekTokenIs(COMMA) {
+			break
+		}
+		p.nextToken()
+	}
+
+	if p.peekTokenIs(SEMICOLON) {
+		p.nextToken()
+	}
+
+	return stmt
+}
+
+func (p *Parser) parseStaticVariableStatement() Statement {
+	stmt := &StaticVariableStatement{Token: p.curToken}
+
+	for {
+		if !p.expectPeek(VARIABLE) {
+			return nil
+		}
+		variable := &StaticVariable{
+			Variable: &Variable{Token: p.curToken, Name: p.curToken.Literal[1:]},
+		}
+
+		if p.peekTokenIs(ASSIGN) {
+			p.nextToken()
+			p.nextToken()
+			variable.Default = p.parseExpression(LOWEST)
+			if variable.Default == nil {
+				return nil
+			}
+		}
+		stmt.Variables = append(stmt.Variables, variable)
+
+		if !p.peekTokenIs(COMMA) {
+			break
+		}
+		p.nextToken()
+	}
+
+	if p.peekTokenIs(SEMICOLON) {
+		p.nextToken()
+	}
+
+	return stmt
+}
+
+func (p *Parser) parseIssetExpression() Expression {
+	expr := &IssetExpression{Token: p.curToken}
+
+	expr.Arguments = p.parseConstructArguments()
+	if expr.Arguments == nil {
+		return nil
+	}
+
+	return expr
+}
+
+func (p *Parser) parseEmptyExpression() Expression {
+	expr := &EmptyExpression{Token: p.curToken}
+
+	if !p.expectPeek(LPAREN) {
+		return nil
+	}
+
+	expr.Arguments = p.parseExpressionList(RPAREN)
+	if expr.Arguments == nil {
+		return nil
+	}
+	if len(expr.Arguments) != 1 {
+		p.addError(expr.Token, "empty() takes exactly one argument, got %d", len(expr.Arguments))
+		return nil
+	}
+
+	return expr
+}
+
+// parseConstructArguments parses the parenthesized arguments of isset or
+// unset, which take at least one argument. It returns nil on error.
+func (p *Parser) parseConstructArguments() []Expression {
+	construct := p.curToken
+
+	if !p.expectPeek(LPAREN) {
+		return nil
+	}
+
+	args := p.parseExpressionList(RPAREN)
+	if args != nil && len(args) == 0 {
+		p.addError(construct, "%s() requires at least one argument", construct.Literal)
+		return nil
+	}
+
+	return args
+}
+
 func (p *Parser) parseExpressionStatement() *ExpressionStatement {
 	stmt := &ExpressionStatement{Token: p.curToken}
+	stmt.Expression = p.parseExpression(LOWEST)
 
-	// Check for assignment patterns: $var = value
-	if p.curToken.Type == VARIABLE && p.peekToken.Type == ASSIGN {
-		stmt.Expression = p.parseAssignmentExpressionFromVariable()
-	} else {
-		stmt.Expression = p.parseExpression(LOWEST)
+	// A list pattern only makes sense as the target of an assignment
+	if _, ok := stmt.Expression.(*ListExpression); ok {
+		p.syntaxError(p.peekToken, "expected '=' after list pattern, got '%s'", p.peekToken.Literal)
+		return stmt
+	}
+
+	// PHP has no comma operator; only for loop clauses take a comma list.
+	// Skip the comma so the next expression is read as its own statement
+	// instead of reporting a second error for it.
+	if p.peekTokenIs(COMMA) {
+		p.nextToken()
+		p.recordError(p.curToken, "unexpected ',' after expression; expressions can only be separated by commas in for loop clauses",
+			fmt.Sprintf("unexpected ',' after expression at line %d, column %d: expressions can only be separated by commas in for loop clauses",
+				p.curToken.Line, p.curToken.Column))
+		return stmt
 	}
 
 	if p.peekTokenIs(SEMICOLON) {
@@ -395,21 +969,40 @@ func (p *Parser) parseAssignmentExpressionFromVariable() Expression {
 		Name:  variable,
 	}
 
+	// As in parseAssignmentExpression, the value stops at and, or and xor
 	p.nextToken()
-	assignment.Value = p.parseExpression(LOWEST)
+	assignment.Value = p.parseExpression(WORD_AND)
 
 	return assignment
 }
 
 func (p *Parser) parseExpression(precedence int) Expression {
+	p.depth++
+	defer func() { p.depth-- }()
+	if p.MaxDepth > 0 && p.depth > p.MaxDepth {
+		p.addError(p.curToken, "expression nesting exceeds maximum depth of %d", p.MaxDepth)
+		return nil
+	}
+
 	prefix := p.prefixParseFns[p.curToken.Type]
 	if prefix == nil {
-		p.noPrefixParseFnError(p.curToken.Type)
+		p.noPrefixParseFnError(p.curToken)
 		return nil
 	}
 
+	// A grouped expression records its own parentheses
+	grouped := p.curToken.Type == LPAREN
 	leftExp := prefix()
+	if !grouped {
+		p.closeNode(leftExp)
+	}
+
+	return p.parseInfixExpressions(leftExp, precedence)
+}
 
+// parseInfixExpressions continues an expression whose left operand is
+// already parsed with the operators that bind tighter than precedence
+func (p *Parser) parseInfixExpressions(leftExp Expression, precedence int) Expression {
 	for !p.peekTokenIs(SEMICOLON) && precedence < p.peekPrecedence() {
 		infix := p.infixParseFns[p.peekToken.Type]
 		if infix == nil {
@@ -418,12 +1011,17 @@ func (p *Parser) parseExpression(precedence int) Expression {
 
 		p.nextToken()
 		leftExp = infix(leftExp)
+		p.closeNode(leftExp)
 	}
 
 	return leftExp
 }
 
 func (p *Parser) parseIdentifier() Expression {
+	// A qualified name such as App\Config is read as one identifier
+	if p.peekTokenIs(NAMESPACE_SEPARATOR) {
+		return p.parseQualifiedName()
+	}
 	return &Identifier{Token: p.curToken, Value: p.curToken.Literal}
 }
 
@@ -434,10 +1032,10 @@ func (p *Parser) parseVariable() Expression {
 func (p *Parser) parseIntegerLiteral() Expression {
 	lit := &IntegerLiteral{Token: p.curToken}
 
-	value, err := strconv.ParseInt(p.curToken.Literal, 0, 64)
-	if err != nil {
-		msg := fmt.Sprintf("could not parse %q as integer", p.curToken.Literal)
-		p.errors = append(p.errors, msg)
+	digits, ok := stripNumericSeparators(p.curToken.Literal)
+	value, err := strconv.ParseInt(digits, 0, 64)
+	if !ok || err != nil {
+		p.addBareError(p.curToken, "could not parse %q as integer", p.curToken.Literal)
 		return nil
 	}
 
@@ -448,10 +1046,10 @@ func (p *Parser) parseIntegerLiteral() Expression {
 func (p *Parser) parseFloatLiteral() Expression {
 	lit := &FloatLiteral{Token: p.curToken}
 
-	value, err := strconv.ParseFloat(p.curToken.Literal, 64)
-	if err != nil {
-		msg := fmt.Sprintf("could not parse %q as float", p.curToken.Literal)
-		p.errors = append(p.errors, msg)
+	digits, ok := stripNumericSeparators(p.curToken.Literal)
+	value, err := strconv.ParseFloat(digits, 64)
+	if !ok || err != nil {
+		p.addBareError(p.curToken, "could not parse %q as float", p.curToken.Literal)
 		return nil
 	}
 
@@ -459,60 +1057,61 @@ func (p *Parser) parseFloatLiteral() Expression {
 	return lit
 }
 
-func (p *Parser) parseStringLiteral() Expression {
-	literal := p.curToken.Literal
-
-	// Check if string contains variables (simple detection for $var)
-	if strings.Contains(literal, "$") {
-		return p.parseInterpolatedString()
+// stripNumericSeparators removes the underscores from a numeric literal
+// such as 1_000_000. It reports false if an underscore is not between two
+// digits, as in 1_, 1__0, 1_.5, 1e_3 or 0x_FF.
+func stripNumericSeparators(literal string) (string, bool) {
+	if !strings.Contains(literal, "_") {
+		return literal, true
 	}
 
-	return &StringLiteral{Token: p.curToken, Value: literal}
+	digit := isDigit
+	if len(literal) > 1 && (literal[1] == 'x' || literal[1] == 'X') {
+		digit = isHexDigit
+	}
+	for i := 0; i < len(literal); i++ {
+		if literal[i] == '_' && (i == 0 || i == len(literal)-1 || !digit(literal[i-1]) || !digit(literal[i+1])) {
+			return "", false
+		}
+	}
+	return strings.ReplaceAll(literal, "_", ""), true
 }
 
-func (p *Parser) parseInterpolatedString() Expression {
+func (p *Parser) parseStringLiteral() Expression {
 	literal := p.curToken.Literal
-	interpolated := &InterpolatedString{Token: p.curToken}
-
-	// Simple parsing: split on $ and create string parts and variable parts
-	parts := strings.Split(literal, "$")
+	quote := p.stringQuote(p.curToken)
 
-	// First part is always a string (may be empty)
-	if parts[0] != "" {
-		stringToken := Token{Type: STRING, Literal: parts[0], Line: p.curToken.Line, Column: p.curToken.Column}
-		interpolated.Parts = append(interpolated.Parts, &StringLiteral{Token: stringToken, Value: parts[0]})
+	// Only double-quoted strings interpolate; the scanner decides what is embedded
+	if quote == '"' && strings.Contains(literal, "$") {
+		return p.parseInterpolatedString()
 	}
 
-	// Process variable parts
-	for i := 1; i < len(parts); i++ {
-		part := parts[i]
-
-		// Extract variable name (up to first non-identifier character)
-		varName := ""
-		j := 0
-		for j < len(part) && (isLetter(part[j]) || (j > 0 && isDigit(part[j]))) {
-			j++
-		}
+	if quote == 0 {
+		return &StringLiteral{Token: p.curToken, Value: literal}
+	}
+	return &StringLiteral{Token: p.curToken, Value: unquoteString(literal, quote)}
+}
 
-		if j > 0 {
-			varName = part[:j]
-			varToken := Token{Type: VARIABLE, Literal: "$" + varName, Line: p.curToken.Line, Column: p.curToken.Column}
-			interpolated.Parts = append(interpolated.Parts, &Variable{Token: varToken, Name: varName})
+// stringQuote returns the quote character that opened a STRING token. The
+// token literal holds only the contents, so the quote is read from the input.
+// Heredocs are reported as '"' since they interpolate and decode the same
+// escapes, and nowdocs as 0 since their contents are taken verbatim.
+func (p *Parser) stringQuote(tok Token) byte {
+	if tok.Position >= len(p.l.input) {
+		return '"'
+	}
 
-			// Add remaining string part if any
-			if j < len(part) {
-				remaining := part[j:]
-				stringToken := Token{Type: STRING, Literal: remaining, Line: p.curToken.Line, Column: p.curToken.Column}
-				interpolated.Parts = append(interpolated.Parts, &StringLiteral{Token: stringToken, Value: remaining})
-			}
-		} else {
-			// Not a valid variable, treat as string
-			stringToken := Token{Type: STRING, Literal: "$" + part, Line: p.curToken.Line, Column: p.curToken.Column}
-			interpolated.Parts = append(interpolated.Parts, &StringLiteral{Token: stringToken, Value: "$" + part})
+	rest := p.l.input[tok.Position:]
+	if strings.HasPrefix(rest, "<<<") {
+		if strings.HasPrefix(strings.TrimLeft(rest[3:], " \t"), "'") {
+			return 0
 		}
+		return '"'
 	}
-
-	return interpolated
+	if rest[0] == '\'' {
+		return '\''
+	}
+	return '"'
 }
 
 func (p *Parser) parseBooleanLiteral() Expression {
@@ -529,7 +1128,7 @@ func (p *Parser) parseMagicConstant() Expression {
 func (p *Parser) parseUnexpectedToken() Expression {
 	// Handle tokens that appear in unexpected prefix positions
 	// This is often due to string interpolation or parsing context issues
-	p.noPrefixParseFnError(p.curToken.Type)
+	p.noPrefixParseFnError(p.curToken)
 	return nil
 }
 
@@ -563,6 +1162,10 @@ func (p *Parser) parseInfixExpression(left Expression) Expression {
 	}
 
 	precedence := p.curPrecedence()
+	// ** is right-associative, so 2 ** 3 ** 2 is 2 ** (3 ** 2)
+	if p.curTokenIs(POW) {
+		precedence--
+	}
 	p.nextToken()
 	expression.Right = p.parseExpression(precedence)
 
@@ -570,24 +1173,30 @@ func (p *Parser) parseInfixExpression(left Expression) Expression {
 }
 
 func (p *Parser) parseAssignmentExpression(left Expression) Expression {
-	variable, ok := left.(*Variable)
-	if !ok {
-		p.errors = append(p.errors, "left side of assignment must be a variable")
+	expression := &AssignmentExpression{Token: p.curToken}
+
+	switch target := left.(type) {
+	case *Variable:
+		expression.Name = target
+	case *ObjectAccessExpression, *StaticAccessExpression, *IndexExpression:
+		expression.Target = target
+	case *ListExpression, *ArrayLiteral, *AssociativeArrayLiteral:
+		return p.parseDestructuringAssignment(target)
+	default:
+		p.addBareError(p.curToken, "left side of assignment must be a variable")
 		return nil
 	}
 
-	expression := &AssignmentExpression{
-		Token: p.curToken,
-		Name:  variable,
-	}
-
+	// The value stops at and, or and xor, which bind more loosely than
+	// assignment: $a = true and false is ($a = true) and false
 	p.nextToken()
-	expression.Value = p.parseExpression(LOWEST)
+	expression.Value = p.parseExpression(WORD_AND)
 
 	return expression
 }
 
 func (p *Parser) parseGroupedExpression() Expression {
+	start := p.curToken.Position
 	p.nextToken()
 
 	exp := p.parseExpression(LOWEST)
@@ -595,6 +1204,7 @@ func (p *Parser) parseGroupedExpression() Expression {
 	if !p.expectPeek(RPAREN) {
 		return nil
 	}
+	p.groupNode(exp, start)
 
 	return exp
 }
@@ -630,13 +1240,34 @@ func (p *Parser) parseExpressionList(end TokenType) []Expression {
 }
 
 func (p *Parser) parseArrayLiteral() Expression {
+	return p.parseArrayElements(p.curToken, RBRACKET)
+}
+
+// parseArrayFunction parses the long-form array(...) syntax into the same
+// nodes as [...]
+func (p *Parser) parseArrayFunction() Expression {
 	tok := p.curToken
 
-	if p.peekTokenIs(RBRACKET) {
-		p.nextToken() // consume RBRACKET
+	if !p.expectPeek(LPAREN) {
+		return nil
+	}
+
+	return p.parseArrayElements(tok, RPAREN)
+}
+
+// parseArrayElements parses array elements or key => value pairs up to the
+// end token, with the current token on the opening bracket or parenthesis
+func (p *Parser) parseArrayElements(tok Token, end TokenType) Expression {
+	if p.peekTokenIs(end) {
+		p.nextToken() // consume end token
 		return &ArrayLiteral{Token: tok, Elements: []Expression{}}
 	}
 
+	// An empty element, as in [, $b] = $pair, can only be a short list
+	if end == RBRACKET && p.peekTokenIs(COMMA) {
+		return p.parseShortListRest(tok, nil)
+	}
+
 	p.nextToken() // move to first element
 
 	// Check if this is an associative array by looking for =>
@@ -669,7 +1300,7 @@ func (p *Parser) parseArrayLiteral() Expression {
 			assocArray.Pairs = append(assocArray.Pairs, ArrayPair{Key: key, Value: value})
 		}
 
-		if !p.expectPeek(RBRACKET) {
+		if !p.expectPeek(end) {
 			return nil
 		}
 
@@ -682,11 +1313,14 @@ func (p *Parser) parseArrayLiteral() Expression {
 		// Parse remaining elements
 		for p.peekTokenIs(COMMA) {
 			p.nextToken() // consume comma
+			if end == RBRACKET && p.peekTokenIs(COMMA) {
+				return p.parseShortListRest(tok, array.Elements)
+			}
 			p.nextToken() // move to next element
 			array.Elements = append(array.Elements, p.parseExpression(LOWEST))
 		}
 
-		if !p.expectPeek(RBRACKET) {
+		if !p.expectPeek(end) {
 			return nil
 		}
 
@@ -694,6 +1328,23 @@ func (p *Parser) parseArrayLiteral() Expression {
 	}
 }
 
+// parseShortListRest finishes a short [...] list after an empty element,
+// as in [$a, , $b] = $triple, keeping the elements read before it. The
+// current token is the '[' or the comma before the empty element.
+func (p *Parser) parseShortListRest(tok Token, elements []Expression) Expression {
+	list := &ListExpression{Token: tok}
+	for _, element := range elements {
+		list.Items = append(list.Items, &ListItem{Value: element})
+	}
+
+	items := p.parseListItems(RBRACKET)
+	if items == nil {
+		return nil
+	}
+	list.Items = append(list.Items, items...)
+	return list
+}
+
 func (p *Parser) parseForStatement() *ForStatement {
 	stmt := &ForStatement{Token: p.curToken}
 
@@ -702,40 +1353,21 @@ func (p *Parser) parseForStatement() *ForStatement {
 	}
 
 	p.nextToken()
-	// Handle assignment in init part of for loop
-	if p.curToken.Type == VARIABLE && p.peekToken.Type == ASSIGN {
-		stmt.Init = p.parseAssignmentExpressionFromVariable()
-	} else {
-		stmt.Init = p.parseExpression(LOWEST)
-	}
+	stmt.Init = p.parseForExpressions()
 
 	if !p.expectPeek(SEMICOLON) {
 		return nil
 	}
 
 	p.nextToken()
-	stmt.Condition = p.parseExpression(LOWEST)
+	stmt.Condition = p.parseForExpressions()
 
 	if !p.expectPeek(SEMICOLON) {
 		return nil
 	}
 
 	p.nextToken()
-	// Handle assignment or increment in update part of for loop
-	if p.curToken.Type == VARIABLE && p.peekToken.Type == ASSIGN {
-		stmt.Update = p.parseAssignmentExpressionFromVariable()
-	} else if p.curToken.Type == VARIABLE && p.peekToken.Type == INCREMENT {
-		// Parse variable first, then parse as postfix
-		variable := &Variable{Token: p.curToken, Name: p.curToken.Literal[1:]}
-		p.nextToken() // move to INCREMENT
-		stmt.Update = &PostfixExpression{
-			Token:    p.curToken,
-			Left:     variable,
-			Operator: p.curToken.Literal,
-		}
-	} else {
-		stmt.Update = p.parseExpression(LOWEST)
-	}
+	stmt.Update = p.parseForExpressions()
 
 	if !p.expectPeek(RPAREN) {
 		return nil
@@ -750,9 +1382,56 @@ func (p *Parser) parseForStatement() *ForStatement {
 	return stmt
 }
 
+// parseForExpressions parses one clause of a for loop header. Each clause
+// may hold several expressions separated by commas, which are returned as
+// a CommaExpression; a single expression is returned as is.
+func (p *Parser) parseForExpressions() Expression {
+	first := p.parseForExpression()
+	if !p.peekTokenIs(COMMA) {
+		return first
+	}
+
+	list := &CommaExpression{Token: p.peekToken, Expressions: []Expression{first}}
+	for p.peekTokenIs(COMMA) {
+		p.nextToken()
+		p.nextToken()
+		list.Expressions = append(list.Expressions, p.parseForExpression())
+	}
+	return list
+}
+
+func (p *Parser) parseForExpression() Expression {
+	// Handle assignment in for loop clauses
+	if p.curToken.Type == VARIABLE && p.peekToken.Type == ASSIGN {
+		assignment := p.parseAssignmentExpressionFromVariable()
+		if assignment == nil {
+			return nil
+		}
+		return p.parseInfixExpressions(assignment, LOWEST)
+	}
+	// Handle $i++ and $i--: parse the variable first, then as postfix
+	if p.curToken.Type == VARIABLE && (p.peekToken.Type == INCREMENT || p.peekToken.Type == DECREMENT) {
+		variable := &Variable{Token: p.curToken, Name: p.curToken.Literal[1:]}
+		p.nextToken() // move to the operator
+		p.checkStrayOperand()
+		return &PostfixExpression{
+			Token:    p.curToken,
+			Left:     variable,
+			Operator: p.curToken.Literal,
+		}
+	}
+	return p.parseExpression(LOWEST)
+}
+
 func (p *Parser) parseIndexExpression(left Expression) Expression {
 	exp := &IndexExpression{Token: p.curToken, Left: left}
 
+	// $arr[] appends and has no index
+	if p.peekTokenIs(RBRACKET) {
+		p.nextToken()
+		return exp
+	}
+
 	p.nextToken()
 	exp.Index = p.parseExpression(LOWEST)
 
@@ -764,6 +1443,7 @@ func (p *Parser) parseIndexExpression(left Expression) Expression {
 }
 
 func (p *Parser) parsePostfixExpression(left Expression) Expression {
+	p.checkStrayOperand()
 	return &PostfixExpression{
 		Token:    p.curToken,
 		Left:     left,
@@ -771,6 +1451,15 @@ func (p *Parser) parsePostfixExpression(left Expression) Expression {
 	}
 }
 
+// checkStrayOperand reports an operand right after a postfix ++ or --. The
+// lexer reads $a--1 as $a-- followed by 1, not as $a - -1, just as PHP does.
+func (p *Parser) checkStrayOperand() {
+	switch p.peekToken.Type {
+	case VARIABLE, INT, FLOAT, STRING:
+		p.syntaxError(p.peekToken, "unexpected '%s' after '%s'", p.peekToken.Literal, p.curToken.Literal)
+	}
+}
+
 func (p *Parser) curTokenIs(t TokenType) bool {
 	return p.curToken.Type == t
 }
@@ -779,6 +1468,13 @@ func (p *Parser) peekTokenIs(t TokenType) bool {
 	return p.peekToken.Type == t
 }
 
+// peekSecondToken returns the token after peekToken without consuming
+// anything, by lexing ahead on a copy of the lexer.
+func (p *Parser) peekSecondToken() Token {
+	l := *p.l
+	return l.NextToken()
+}
+
 func (p *Parser) expectPeek(t TokenType) bool {
 	if p.peekTokenIs(t) {
 		p.nextToken()
@@ -789,14 +1485,128 @@ func (p *Parser) expectPeek(t TokenType) bool {
 	}
 }
 
+// Errors returns the parse error messages. Most end in their position,
+// such as "unexpected ')' at line 4, column 6"; the few that never did,
+// such as peekError's, are left as they were. StructuredErrors has the
+// position of every error.
 func (p *Parser) Errors() []string {
+	messages := make([]string, len(p.errors))
+	for i, err := range p.errors {
+		messages[i] = err.text
+	}
+	return messages
+}
+
+// StructuredErrors returns the parse errors with their message and
+// position kept apart
+func (p *Parser) StructuredErrors() []*ParseError {
 	return p.errors
 }
 
+// addError records an error about tok, formatting the message like
+// fmt.Sprintf. Nothing is recorded while recovering from a syntax error.
+func (p *Parser) addError(tok Token, format string, args ...any) {
+	message := fmt.Sprintf(format, args...)
+	p.recordError(tok, message, fmt.Sprintf("%s at line %d, column %d", message, tok.Line, tok.Column))
+}
+
+// addBareError records an error like addError, except that Errors reports
+// it without its position
+func (p *Parser) addBareError(tok Token, format string, args ...any) {
+	message := fmt.Sprintf(format, args...)
+	p.recordError(tok, message, message)
+}
+
+// recordError records an error about tok that Errors reports as text
+func (p *Parser) recordError(tok Token, message, text string) {
+	if p.recovering {
+		return
+	}
+	p.errors = append(p.errors, &ParseError{
+		Message: message,
+		Line:    tok.Line,
+		Column:  tok.Column,
+		text:    text,
+	})
+}
+
+// syntaxError records an error like addError and starts recovery: the
+// statement loop skips what is left of the statement with synchronize
+func (p *Parser) syntaxError(tok Token, format string, args ...any) {
+	p.addError(tok, format, args...)
+	p.recovering = true
+}
+
+// bareSyntaxError is syntaxError for errors reported like addBareError
+func (p *Parser) bareSyntaxError(tok Token, format string, args ...any) {
+	p.addBareError(tok, format, args...)
+	p.recovering = true
+}
+
+// statementKeywords are the tokens synchronize stops before, since each
+// starts a new statement. use, static and default are left out because
+// they also appear inside expressions: in closures, static:: and match.
+var statementKeywords = map[TokenType]bool{
+	FUNCTION: true, CLASS: true, ABSTRACT: true, FINAL: true, INTERFACE: true,
+	TRAIT: true, ENUM: true, NAMESPACE: true, DECLARE: true, TRY: true,
+	RETURN: true, IF: true, ECHO: true, UNSET: true, GLOBAL: true, FOR: true,
+	WHILE: true, SWITCH: true, FOREACH: true, BREAK: true, CONTINUE: true,
+	CASE: true,
+}
+
+// memberKeywords are the tokens synchronize stops before inside a class,
+// interface, trait or enum body, since each starts a new member.
+var memberKeywords = map[TokenType]bool{
+	PUBLIC: true, PRIVATE: true, PROTECTED: true, STATIC: true, ABSTRACT: true,
+	FINAL: true, READONLY: true, VAR: true, CONST: true, FUNCTION: true,
+	USE: true, CASE: true,
+}
+
+// synchronize skips the rest of a statement after a syntax error, leaving
+// the current token at its last token so the statement loop's nextToken
+// moves on to the next statement. It stops at a ';' or after a skipped
+// block, or before a '}' or a statement keyword, without looking inside
+// braces opened while skipping.
+func (p *Parser) synchronize() {
+	p.synchronizeAt(statementKeywords)
+}
+
+// synchronizeMember is synchronize for the member loops of class-like
+// bodies, stopping before the next member instead of the next statement.
+func (p *Parser) synchronizeMember() {
+	p.synchronizeAt(memberKeywords)
+}
+
+func (p *Parser) synchronizeAt(keywords map[TokenType]bool) {
+	p.recovering = false
+
+	depth := 0
+	for !p.curTokenIs(EOF) {
+		switch p.curToken.Type {
+		case LBRACE:
+			depth++
+		case RBRACE:
+			if depth == 0 {
+				return
+			}
+			depth--
+			if depth == 0 {
+				return
+			}
+		case SEMICOLON:
+			if depth == 0 {
+				return
+			}
+		}
+		if depth == 0 && (p.peekTokenIs(RBRACE) || p.peekTokenIs(EOF) || p.peekTokenIs(PHP_CLOSE) || keywords[p.peekToken.Type]) {
+			return
+		}
+		p.nextToken()
+	}
+}
+
 func (p *Parser) peekError(t TokenType) {
-	msg := fmt.Sprintf("expected next token to be %s, got %s instead",
-		t, p.peekToken.Type)
-	p.errors = append(p.errors, msg)
+	p.bareSyntaxError(p.peekToken, "expected next token to be %s, got %s instead", t, p.peekToken.Type)
 }
 
 func (p *Parser) registerPrefix(tokenType TokenType, fn prefixParseFn) {
@@ -807,9 +1617,10 @@ func (p *Parser) registerInfix(tokenType TokenType, fn infixParseFn) {
 	p.infixParseFns[tokenType] = fn
 }
 
-func (p *Parser) noPrefixParseFnError(t TokenType) {
-	msg := fmt.Sprintf("no prefix parse function for %s found", t)
-	p.errors = append(p.errors, msg)
+// noPrefixParseFnError reports a token that cannot start an expression,
+// naming the token as written and where it appears.
+func (p *Parser) noPrefixParseFnError(tok Token) {
+	p.syntaxError(tok, "unexpected '%s'", tok.Literal)
 }
 
 func (p *Parser) peekPrecedence() int {
@@ -834,9 +1645,149 @@ func (p *Parser) parseWhileStatement() *WhileStatement {
 	if !p.expectPeek(LPAREN) {
 		return nil
 	}
-
-	p.nextToken()
-	stmt.Condition = p.parseExpression(LOWEST)
+
+	p.nextToken()
+	stmt.Condition = p.parseExpression(LOWEST)
+
+	if !p.expectPeek(RPAREN) {
+		return nil
+	}
+
+	if !p.expectPeek(LBRACE) {
+		return nil
+	}
+
+	stmt.Body = p.parseBlockStatement()
+
+	return stmt
+}
+
+func (p *Parser) parseSwitchStatement() *SwitchStatement {
+	stmt := &SwitchStatement{Token: p.curToken}
+
+	if !p.expectPeek(LPAREN) {
+		return nil
+	}
+
+	p.nextToken()
+	stmt.Subject = p.parseExpression(LOWEST)
+
+	if !p.expectPeek(RPAREN) {
+		return nil
+	}
+
+	if !p.expectPeek(LBRACE) {
+		return nil
+	}
+
+	p.nextToken()
+	for !p.curTokenIs(RBRACE) && !p.curTokenIs(EOF) {
+		switchCase := p.parseSwitchCase()
+		if switchCase == nil {
+			return nil
+		}
+		stmt.Cases = append(stmt.Cases, switchCase)
+	}
+
+	if !p.curTokenIs(RBRACE) {
+		p.bareSyntaxError(p.curToken, "expected next token to be %s, got %s instead", RBRACE, p.curToken.Type)
+		return nil
+	}
+
+	return stmt
+}
+
+// parseSwitchCase parses a case or default label and the statements up to
+// the next label. Cases without a break fall through, so the body is simply
+// whatever precedes the next label. It leaves curToken on that label or on
+// the closing brace.
+func (p *Parser) parseSwitchCase() *SwitchCase {
+	switchCase := &SwitchCase{Token: p.curToken}
+
+	switch p.curToken.Type {
+	case CASE:
+		p.nextToken()
+		switchCase.Condition = p.parseExpression(LOWEST)
+	case DEFAULT:
+	default:
+		p.bareSyntaxError(p.curToken, "expected case or default, got %s instead", p.curToken.Type)
+		return nil
+	}
+
+	// PHP accepts either ':' or ';' after a case label
+	if p.peekTokenIs(SEMICOLON) {
+		p.nextToken()
+	} else if !p.expectPeek(COLON) {
+		return nil
+	}
+
+	switchCase.Body = []Statement{}
+	p.keepComments()
+	p.nextToken()
+	for !p.curTokenIs(CASE) && !p.curTokenIs(DEFAULT) && !p.curTokenIs(RBRACE) && !p.curTokenIs(EOF) {
+		// A close tag ends the statement before it like ';', and the open
+		// tag that resumes the block starts nothing itself
+		if p.curTokenIs(PHP_CLOSE) || p.curTokenIs(PHP_OPEN) {
+			p.nextToken()
+			continue
+		}
+		stmt := p.parseStatement()
+		if p.recovering {
+			p.synchronize()
+		}
+		if stmt != nil {
+			switchCase.Body = append(switchCase.Body, stmt)
+		}
+		p.nextToken()
+	}
+
+	return switchCase
+}
+
+func (p *Parser) parseForeachStatement() *ForeachStatement {
+	stmt := &ForeachStatement{Token: p.curToken}
+
+	if !p.expectPeek(LPAREN) {
+		return nil
+	}
+
+	p.nextToken()
+	stmt.Array = p.parseExpression(LOWEST)
+
+	if !p.expectPeek(AS) {
+		return nil
+	}
+
+	p.nextToken()
+
+	// Check if we have key => value syntax
+	if p.peekTokenIs(DOUBLE_ARROW) {
+		// Parse key
+		if p.curToken.Type != VARIABLE {
+			p.addBareError(p.curToken, "foreach key must be a variable")
+			return nil
+		}
+		stmt.Key = &Variable{Token: p.curToken, Name: p.curToken.Literal[1:]}
+
+		p.nextToken() // consume =>
+		p.nextToken() // move to value
+	}
+
+	// Parse value, which may destructure each element: [$a, $b] or list($a, $b)
+	switch p.curToken.Type {
+	case VARIABLE:
+		stmt.Value = &Variable{Token: p.curToken, Name: p.curToken.Literal[1:]}
+	case LBRACKET:
+		stmt.ValuePattern = p.parseArrayLiteral()
+	case LIST:
+		stmt.ValuePattern = p.parseListPattern()
+	default:
+		p.addBareError(p.curToken, "foreach value must be a variable")
+		return nil
+	}
+	if stmt.Value == nil && stmt.ValuePattern == nil {
+		return nil
+	}
 
 	if !p.expectPeek(RPAREN) {
 		return nil
@@ -851,53 +1802,142 @@ func (p *Parser) parseWhileStatement() *WhileStatement {
 	return stmt
 }
 
-func (p *Parser) parseForeachStatement() *ForeachStatement {
-	stmt := &ForeachStatement{Token: p.curToken}
+// parseListPattern parses list(...) as a foreach value, where it reads the
+// same as the short [...] form
+func (p *Parser) parseListPattern() Expression {
+	list := &ArrayLiteral{Token: p.curToken}
 
 	if !p.expectPeek(LPAREN) {
 		return nil
 	}
 
-	p.nextToken()
-	stmt.Array = p.parseExpression(LOWEST)
+	list.Elements = p.parseExpressionList(RPAREN)
+	return list
+}
 
-	if !p.expectPeek(AS) {
+func (p *Parser) parseListExpression() Expression {
+	list := &ListExpression{Token: p.curToken}
+
+	if !p.expectPeek(LPAREN) {
 		return nil
 	}
 
-	p.nextToken()
+	list.Items = p.parseListItems(RPAREN)
+	if list.Items == nil {
+		return nil
+	}
+	return list
+}
 
-	// Check if we have key => value syntax
-	if p.peekTokenIs(DOUBLE_ARROW) {
-		// Parse key
-		if p.curToken.Type != VARIABLE {
-			p.errors = append(p.errors, "foreach key must be a variable")
+// parseListItems parses the elements of a list up to end. An empty
+// element, such as the first one in list(, $b), becomes a nil item.
+func (p *Parser) parseListItems(end TokenType) []*ListItem {
+	items := []*ListItem{}
+
+	for !p.peekTokenIs(end) {
+		if p.peekTokenIs(COMMA) {
+			p.nextToken()
+			items = append(items, nil)
+			continue
+		}
+
+		p.nextToken()
+		item := &ListItem{Value: p.parseExpression(LOWEST)}
+		if p.peekTokenIs(DOUBLE_ARROW) {
+			p.nextToken() // consume =>
+			p.nextToken() // move to value
+			item.Key = item.Value
+			item.Value = p.parseExpression(LOWEST)
+		}
+		if item.Value == nil {
 			return nil
 		}
-		stmt.Key = &Variable{Token: p.curToken, Name: p.curToken.Literal[1:]}
+		items = append(items, item)
 
-		p.nextToken() // consume =>
-		p.nextToken() // move to value
+		if !p.peekTokenIs(COMMA) {
+			break
+		}
+		p.nextToken()
 	}
 
-	// Parse value
-	if p.curToken.Type != VARIABLE {
-		p.errors = append(p.errors, "foreach value must be a variable")
+	if !p.expectPeek(end) {
 		return nil
 	}
-	stmt.Value = &Variable{Token: p.curToken, Name: p.curToken.Literal[1:]}
 
-	if !p.expectPeek(RPAREN) {
+	return items
+}
+
+// parseDestructuringAssignment parses an assignment whose left side is a
+// list() or a short [...] list
+func (p *Parser) parseDestructuringAssignment(left Expression) Expression {
+	assignment := &DestructuringAssignment{Token: p.curToken}
+
+	if !p.curTokenIs(ASSIGN) {
+		p.addError(p.curToken, "cannot use %s with a destructuring assignment", p.curToken.Literal)
 		return nil
 	}
 
-	if !p.expectPeek(LBRACE) {
+	assignment.Targets = p.toListExpression(left)
+	if assignment.Targets == nil {
 		return nil
 	}
 
-	stmt.Body = p.parseBlockStatement()
+	p.nextToken()
+	assignment.Source = p.parseExpression(WORD_AND)
+	if assignment.Source == nil {
+		return nil
+	}
 
-	return stmt
+	return assignment
+}
+
+// toListExpression converts the left side of a destructuring assignment
+// into a list, turning nested array literals into lists too. Every target
+// must be assignable.
+func (p *Parser) toListExpression(expr Expression) *ListExpression {
+	var list *ListExpression
+	switch e := expr.(type) {
+	case *ListExpression:
+		list = &ListExpression{Token: e.Token, Items: e.Items}
+	case *ArrayLiteral:
+		if e.Token.Type == LBRACKET {
+			list = &ListExpression{Token: e.Token}
+			for _, element := range e.Elements {
+				list.Items = append(list.Items, &ListItem{Value: element})
+			}
+		}
+	case *AssociativeArrayLiteral:
+		if e.Token.Type == LBRACKET {
+			list = &ListExpression{Token: e.Token}
+			for _, pair := range e.Pairs {
+				list.Items = append(list.Items, &ListItem{Key: pair.Key, Value: pair.Value})
+			}
+		}
+	}
+	if list == nil {
+		p.addError(p.curToken, "cannot assign to %s in a destructuring assignment", expr.String())
+		return nil
+	}
+
+	items := make([]*ListItem, len(list.Items))
+	for i, item := range list.Items {
+		if item == nil {
+			continue
+		}
+		items[i] = &ListItem{Key: item.Key, Value: item.Value}
+		switch item.Value.(type) {
+		case *Variable, *ObjectAccessExpression, *StaticAccessExpression, *IndexExpression:
+		default:
+			nested := p.toListExpression(item.Value)
+			if nested == nil {
+				return nil
+			}
+			items[i].Value = nested
+		}
+	}
+	list.Items = items
+
+	return list
 }
 
 func (p *Parser) parseBreakStatement() *BreakStatement {
@@ -934,6 +1974,7 @@ func (p *Parser) parseContinueStatement() *ContinueStatement {
 
 func (p *Parser) parseClassDeclaration() *ClassDeclaration {
 	stmt := &ClassDeclaration{Token: p.curToken}
+	stmt.LeadingComments, stmt.DocBlock = p.leadingCommentTexts()
 
 	if !p.expectPeek(IDENT) {
 		return nil
@@ -978,6 +2019,7 @@ func (p *Parser) parseClassDeclaration() *ClassDeclaration {
 	}
 
 	// Parse class body
+	p.keepComments()
 	p.nextToken()
 	for !p.curTokenIs(RBRACE) && !p.curTokenIs(EOF) {
 		// Handle trait uses
@@ -986,17 +2028,29 @@ func (p *Parser) parseClassDeclaration() *ClassDeclaration {
 				stmt.TraitUses = append(stmt.TraitUses, traitUse)
 			}
 		} else {
-			// Check for visibility modifiers or static
+			// Check for visibility, static, readonly, abstract and final
+			// modifiers in any order
 			visibility := "public" // default visibility
 			static := false
-
-			if p.curTokenIs(PUBLIC) || p.curTokenIs(PRIVATE) || p.curTokenIs(PROTECTED) {
-				visibility = p.curToken.Literal
-				p.nextToken()
-			}
-
-			if p.curTokenIs(STATIC) {
-				static = true
+			readonly := false
+			abstract := false
+			final := false
+			modifierToken := p.curToken
+
+			for {
+				if p.curTokenIs(PUBLIC) || p.curTokenIs(PRIVATE) || p.curTokenIs(PROTECTED) {
+					visibility = p.curToken.Literal
+				} else if p.curTokenIs(STATIC) {
+					static = true
+				} else if p.curTokenIs(READONLY) {
+					readonly = true
+				} else if p.curTokenIs(ABSTRACT) {
+					abstract = true
+				} else if p.curTokenIs(FINAL) {
+					final = true
+				} else if !p.curTokenIs(VAR) {
+					break
+				}
 				p.nextToken()
 			}
 
@@ -1011,40 +2065,135 @@ func (p *Parser) parseClassDeclaration() *ClassDeclaration {
 				// Parse method
 				method := p.parseMethodDeclaration(visibility, static)
 				if method != nil {
+					if method.Body == nil && !abstract {
+						p.addError(method.Token, "method %s::%s() has no body but is not abstract",
+							stmt.Name.Value, method.Name.Value)
+					}
+					if method.Body != nil && abstract {
+						p.addError(method.Token, "abstract method %s::%s() cannot have a body",
+							stmt.Name.Value, method.Name.Value)
+					}
+					method.Abstract = abstract
+					method.Final = final
+					if abstract && final {
+						p.addError(modifierToken, "method %s::%s() cannot be both abstract and final",
+							stmt.Name.Value, method.Name.Value)
+					}
 					stmt.Methods = append(stmt.Methods, method)
 				}
-			} else if p.curTokenIs(VARIABLE) {
-				// Parse property
-				property := p.parsePropertyDeclaration(visibility, static)
-				if property != nil {
+			} else if p.curTokenIs(VARIABLE) || p.isTypeHintStart() {
+				// Parse property, which may be typed
+				comments, docBlock := p.leadingCommentTexts()
+				var propertyType *TypeHint
+				if !p.curTokenIs(VARIABLE) {
+					propertyType = p.parseTypeHint()
+					if propertyType == nil || !p.expectPeek(VARIABLE) {
+						p.nextToken()
+						continue
+					}
+				}
+
+				for _, property := range p.parsePropertyDeclarations(visibility, static) {
+					property.Readonly = readonly
+					property.PropertyType = propertyType
+					property.LeadingComments, property.DocBlock = comments, docBlock
 					stmt.Properties = append(stmt.Properties, property)
 				}
 			}
 		}
 
+		if p.recovering {
+			p.synchronizeMember()
+		}
 		p.nextToken()
 	}
 
 	return stmt
 }
 
-func (p *Parser) parsePropertyDeclaration(visibility string, static bool) *PropertyDeclaration {
-	if !p.curTokenIs(VARIABLE) {
+// parseModifiedClassDeclaration parses a class declaration preceded by
+// abstract or final
+func (p *Parser) parseModifiedClassDeclaration() Statement {
+	modifierToken := p.curToken
+	abstract := false
+	final := false
+
+	for p.curTokenIs(ABSTRACT) || p.curTokenIs(FINAL) {
+		if p.curTokenIs(ABSTRACT) {
+			abstract = true
+		} else {
+			final = true
+		}
+		p.nextToken()
+	}
+
+	if !p.curTokenIs(CLASS) {
+		p.recordError(modifierToken, fmt.Sprintf("expected class after %s, got %s", modifierToken.Literal, p.curToken.Type),
+			fmt.Sprintf("expected class after %s at line %d, column %d, got %s",
+				modifierToken.Literal, modifierToken.Line, modifierToken.Column, p.curToken.Type))
+		p.recovering = true
 		return nil
 	}
 
-	prop := &PropertyDeclaration{
-		Token:      p.curToken,
-		Visibility: visibility,
-		Static:     static,
-		Name:       &Variable{Token: p.curToken, Name: p.curToken.Literal[1:]},
+	stmt := p.parseClassDeclaration()
+	if stmt == nil {
+		return nil
+	}
+	stmt.Abstract = abstract
+	stmt.Final = final
+	if abstract && final {
+		p.addError(modifierToken, "class %s cannot be both abstract and final", stmt.Name.Value)
 	}
+	p.checkAbstractMethods(stmt)
 
-	// Check for default value
-	if p.peekTokenIs(ASSIGN) {
-		p.nextToken() // consume =
-		p.nextToken() // move to value
-		prop.Value = p.parseExpression(LOWEST)
+	return stmt
+}
+
+// checkAbstractMethods reports abstract methods declared in a class that is
+// not itself abstract
+func (p *Parser) checkAbstractMethods(stmt *ClassDeclaration) {
+	if stmt.Abstract {
+		return
+	}
+	for _, method := range stmt.Methods {
+		if method.Abstract {
+			p.addError(method.Token, "class %s is not abstract but declares abstract method %s()",
+				stmt.Name.Value, method.Name.Value)
+		}
+	}
+}
+
+// parsePropertyDeclarations parses one or more comma-separated properties
+// sharing the same modifiers, as in public $a, $b = 1;
+func (p *Parser) parsePropertyDeclarations(visibility string, static bool) []*PropertyDeclaration {
+	if !p.curTokenIs(VARIABLE) {
+		return nil
+	}
+
+	var props []*PropertyDeclaration
+	for {
+		prop := &PropertyDeclaration{
+			Token:      p.curToken,
+			Visibility: visibility,
+			Static:     static,
+			Name:       &Variable{Token: p.curToken, Name: p.curToken.Literal[1:]},
+		}
+
+		// Check for default value
+		if p.peekTokenIs(ASSIGN) {
+			p.nextToken() // consume =
+			p.nextToken() // move to value
+			prop.Value = p.parseExpression(LOWEST)
+		}
+		props = append(props, prop)
+
+		if !p.peekTokenIs(COMMA) {
+			break
+		}
+		p.nextToken() // consume ','
+		if !p.expectPeek(VARIABLE) {
+			return props
+		}
 	}
 
 	// Expect semicolon
@@ -1052,7 +2201,7 @@ func (p *Parser) parsePropertyDeclaration(visibility string, static bool) *Prope
 		p.nextToken()
 	}
 
-	return prop
+	return props
 }
 
 func (p *Parser) parseMethodDeclaration(visibility string, static bool) *MethodDeclaration {
@@ -1065,8 +2214,15 @@ func (p *Parser) parseMethodDeclaration(visibility string, static bool) *MethodD
 		Visibility: visibility,
 		Static:     static,
 	}
+	method.LeadingComments, method.DocBlock = p.leadingCommentTexts()
 
-	if !p.expectPeek(IDENT) {
+	// Check for return by reference: function &name()
+	if p.peekTokenIs(BIT_AND) {
+		p.nextToken()
+		method.ReturnsRef = true
+	}
+
+	if !p.expectPeekMemberName() {
 		return nil
 	}
 
@@ -1077,12 +2233,21 @@ func (p *Parser) parseMethodDeclaration(visibility string, static bool) *MethodD
 	}
 
 	method.Parameters = p.parseFunctionParameters()
+	p.checkPromotedParameters(method.Parameters, strings.EqualFold(method.Name.Value, "__construct"))
+	method.ReturnType = p.parseReturnType()
+
+	// An abstract method ends after its signature
+	if p.peekTokenIs(SEMICOLON) {
+		p.nextToken()
+		method.Abstract = true
+		return method
+	}
 
 	if !p.expectPeek(LBRACE) {
 		return nil
 	}
 
-	method.Body = p.parseBlockStatement()
+	method.Body, method.IsGenerator = p.parseFunctionBody()
 
 	return method
 }
@@ -1090,8 +2255,9 @@ func (p *Parser) parseMethodDeclaration(visibility string, static bool) *MethodD
 func (p *Parser) parseNewExpression() Expression {
 	expr := &NewExpression{Token: p.curToken}
 
-	// Handle both regular identifiers and namespaced identifiers
-	if p.peekTokenIs(IDENT) {
+	// Handle both regular identifiers and namespaced identifiers; static is
+	// a keyword but names a class here
+	if p.peekTokenIs(IDENT) || p.peekTokenIs(STATIC) {
 		p.nextToken()
 		expr.ClassName = &Identifier{Token: p.curToken, Value: p.curToken.Literal}
 	} else if p.peekTokenIs(NAMESPACE_SEPARATOR) {
@@ -1126,11 +2292,14 @@ func (p *Parser) parseNewExpression() Expression {
 
 func (p *Parser) parseObjectAccessExpression(left Expression) Expression {
 	expr := &ObjectAccessExpression{
-		Token:  p.curToken,
-		Object: left,
+		Token:    p.curToken,
+		Object:   left,
+		Nullsafe: p.curTokenIs(QUESTION_ARROW),
 	}
 
-	p.nextToken()
+	if !p.expectMemberName() {
+		return nil
+	}
 	expr.Property = p.parseExpression(CALL)
 
 	return expr
@@ -1142,12 +2311,50 @@ func (p *Parser) parseStaticAccessExpression(left Expression) Expression {
 		Class: left,
 	}
 
-	p.nextToken()
+	if !p.expectMemberName() {
+		return nil
+	}
 	expr.Property = p.parseExpression(CALL)
 
 	return expr
 }
 
+// expectMemberName advances past '->', '?->' or '::' when the next token
+// can name a member: an identifier or keyword, a variable, or a {...}
+// dynamic name. Anything else, such as $obj->123, is reported and skipped.
+func (p *Parser) expectMemberName() bool {
+	operator := p.curToken.Literal
+	p.nextToken()
+
+	if p.curTokenIs(VARIABLE) || p.curTokenIs(LBRACE) {
+		return true
+	}
+	if isNameToken(p.curToken) {
+		// Keywords such as or and instanceof are plain names after -> and ::
+		p.curToken.Type = IDENT
+		return true
+	}
+
+	p.bareSyntaxError(p.curToken, "expected property name after '%s', got %s instead", operator, p.curToken.Type)
+	return false
+}
+
+// isMemberNameKeyword reports whether a keyword token can still name a
+// method or class constant, as in public function default(). Match arms
+// accept the same keywords as plain names.
+func isMemberNameKeyword(tok Token) bool {
+	return tok.Type == DEFAULT || tok.Type == SWITCH || tok.Type == CASE
+}
+
+// expectPeekMemberName advances onto a method or class constant name,
+// retyping the keywords isMemberNameKeyword allows to IDENT.
+func (p *Parser) expectPeekMemberName() bool {
+	if isMemberNameKeyword(p.peekToken) {
+		p.peekToken.Type = IDENT
+	}
+	return p.expectPeek(IDENT)
+}
+
 func (p *Parser) parseNamespaceDeclaration() *NamespaceDeclaration {
 	stmt := &NamespaceDeclaration{Token: p.curToken}
 
@@ -1240,105 +2447,397 @@ func (p *Parser) parseTryStatement() *TryStatement {
 	return stmt
 }
 
-func (p *Parser) parseCatchClause() *CatchClause {
-	clause := &CatchClause{Token: p.curToken}
-
-	if !p.expectPeek(LPAREN) {
-		return nil
-	}
+func (p *Parser) parseCatchClause() *CatchClause {
+	clause := &CatchClause{Token: p.curToken}
+
+	if !p.expectPeek(LPAREN) {
+		return nil
+	}
+
+	p.nextToken()
+
+	// Parse the caught exception types; PHP 8 allows several separated by |
+	for p.curTokenIs(IDENT) || p.curTokenIs(NAMESPACE_SEPARATOR) {
+		clause.ExceptionTypes = append(clause.ExceptionTypes, p.parseQualifiedName())
+		p.nextToken()
+
+		if !p.curTokenIs(BIT_OR) {
+			break
+		}
+		p.nextToken() // consume '|'
+	}
+
+	if len(clause.ExceptionTypes) > 0 {
+		clause.ExceptionType = clause.ExceptionTypes[0]
+	}
+
+	// Parse variable; PHP 8 allows leaving it out
+	if p.curTokenIs(VARIABLE) {
+		clause.Variable = &Variable{Token: p.curToken, Name: p.curToken.Literal[1:]}
+
+		if !p.expectPeek(RPAREN) {
+			return nil
+		}
+	} else if !p.curTokenIs(RPAREN) || len(clause.ExceptionTypes) == 0 {
+		p.bareSyntaxError(p.curToken, "expected variable in catch clause")
+		return nil
+	}
+
+	if !p.expectPeek(LBRACE) {
+		return nil
+	}
+
+	clause.Body = p.parseBlockStatement()
+
+	return clause
+}
+
+// parseQualifiedName parses a possibly namespaced name such as Foo, Foo\Bar
+// or \Foo\Bar into a single Identifier. It leaves the current token on the
+// last segment of the name.
+func (p *Parser) parseQualifiedName() *Identifier {
+	ident := &Identifier{Token: p.curToken}
+
+	if p.curTokenIs(NAMESPACE_SEPARATOR) {
+		if !p.expectPeek(IDENT) {
+			return nil
+		}
+		ident.Value = "\\"
+	}
+	ident.Value += p.curToken.Literal
+
+	for p.peekTokenIs(NAMESPACE_SEPARATOR) {
+		p.nextToken() // consume \
+		if !p.expectPeek(IDENT) {
+			break
+		}
+		ident.Value += "\\" + p.curToken.Literal
+	}
+
+	return ident
+}
+
+// isTypeHintStart reports whether the current token can begin a type hint.
+func (p *Parser) isTypeHintStart() bool {
+	switch p.curToken.Type {
+	case IDENT, ARRAY, NULL, FALSE, TRUE, QUESTION, NAMESPACE_SEPARATOR:
+		return true
+	}
+	return false
+}
+
+// parseTypeHint parses a declared type such as int, ?Foo, \Foo\Bar or
+// int|string, leaving the current token on the last token of the type.
+func (p *Parser) parseTypeHint() *TypeHint {
+	hint := &TypeHint{Token: p.curToken}
+
+	if p.curTokenIs(QUESTION) {
+		hint.Nullable = true
+		p.nextToken()
+	}
+
+	hint.Name = p.parseTypeName()
+	if hint.Name == "" {
+		return nil
+	}
+
+	// In a type, & joins an intersection only when another type name
+	// follows; before a variable or ... it marks a by-reference parameter
+	if !hint.Nullable && p.peekTokenIs(BIT_AND) && p.isTypeNameStart(p.peekSecondToken()) {
+		intersection := &TypeHint{Token: hint.Token, Intersection: []*TypeHint{hint}}
+		for p.peekTokenIs(BIT_AND) && p.isTypeNameStart(p.peekSecondToken()) {
+			p.nextToken()
+			p.nextToken()
+
+			member := &TypeHint{Token: p.curToken, Name: p.parseTypeName()}
+			if member.Name == "" {
+				return nil
+			}
+			intersection.Intersection = append(intersection.Intersection, member)
+		}
+		return intersection
+	}
+
+	// A nullable type cannot also be a union
+	if hint.Nullable || !p.peekTokenIs(BIT_OR) {
+		return hint
+	}
+
+	union := &TypeHint{Token: hint.Token, Union: []*TypeHint{hint}}
+	for p.peekTokenIs(BIT_OR) {
+		p.nextToken()
+		p.nextToken()
+
+		member := &TypeHint{Token: p.curToken, Name: p.parseTypeName()}
+		if member.Name == "" {
+			return nil
+		}
+		union.Union = append(union.Union, member)
+	}
+
+	return union
+}
+
+// parseReturnType parses an optional ": type" after a parameter list.
+func (p *Parser) parseReturnType() Expression {
+	if !p.peekTokenIs(COLON) {
+		return nil
+	}
+
+	p.nextToken() // consume ':'
+	p.nextToken() // move to return type
+
+	hint := p.parseTypeHint()
+	if hint == nil {
+		return nil
+	}
+	return hint
+}
+
+// isTypeNameStart reports whether tok can begin a type name.
+func (p *Parser) isTypeNameStart(tok Token) bool {
+	return tok.Type == NAMESPACE_SEPARATOR || isNameToken(tok)
+}
+
+// parseTypeName parses a possibly qualified type name.
+func (p *Parser) parseTypeName() string {
+	name := ""
+	if p.curTokenIs(NAMESPACE_SEPARATOR) {
+		name = "\\"
+		p.nextToken()
+	}
+
+	if !isNameToken(p.curToken) {
+		p.bareSyntaxError(p.curToken, "expected type name, got %s instead", p.curToken.Type)
+		return ""
+	}
+	name += p.curToken.Literal
+
+	for p.peekTokenIs(NAMESPACE_SEPARATOR) {
+		p.nextToken()
+		p.nextToken()
+		if !isNameToken(p.curToken) {
+			p.bareSyntaxError(p.curToken, "expected type name, got %s instead", p.curToken.Type)
+			return ""
+		}
+		name += "\\" + p.curToken.Literal
+	}
+
+	return name
+}
+
+func (p *Parser) parseThrowStatement() *ThrowStatement {
+	stmt := &ThrowStatement{Token: p.curToken}
+
+	p.nextToken()
+	stmt.Expression = p.parseExpression(LOWEST)
+
+	if p.peekTokenIs(SEMICOLON) {
+		p.nextToken()
+	}
+
+	return stmt
+}
+
+// parseThrowExpression parses throw used as an expression (PHP 8), e.g. in
+// a match arm or after ??.
+func (p *Parser) parseThrowExpression() Expression {
+	expr := &ThrowExpression{Token: p.curToken}
 
 	p.nextToken()
+	expr.Expression = p.parseExpression(LOWEST)
+
+	return expr
+}
+
+func (p *Parser) parseAnonymousFunction() Expression {
+	fn := &AnonymousFunction{Token: p.curToken}
 
-	// Check if there's an exception type
-	if p.curToken.Type == IDENT {
-		clause.ExceptionType = &Identifier{Token: p.curToken, Value: p.curToken.Literal}
+	// Check for return by reference: function &() {}
+	if p.peekTokenIs(BIT_AND) {
 		p.nextToken()
+		fn.ReturnsRef = true
 	}
 
-	// Parse variable
-	if p.curToken.Type != VARIABLE {
-		p.errors = append(p.errors, "expected variable in catch clause")
+	if !p.expectPeek(LPAREN) {
 		return nil
 	}
 
-	clause.Variable = &Variable{Token: p.curToken, Name: p.curToken.Literal[1:]}
+	fn.Parameters = p.parseFunctionParameters()
+	p.checkPromotedParameters(fn.Parameters, false)
 
-	if !p.expectPeek(RPAREN) {
-		return nil
+	fn.ReturnType = p.parseReturnType()
+
+	// Check for use clause
+	if p.peekTokenIs(USE) {
+		p.nextToken() // consume 'use'
+		useToken := p.curToken
+		if !p.expectPeek(LPAREN) {
+			return nil
+		}
+
+		// Each entry is a variable, optionally captured by reference with &.
+		// Types and defaults are not allowed here.
+		for !p.peekTokenIs(RPAREN) {
+			p.nextToken()
+			byRef := false
+			if p.curTokenIs(BIT_AND) {
+				byRef = true
+				p.nextToken()
+			}
+
+			if !p.curTokenIs(VARIABLE) {
+				p.bareSyntaxError(p.curToken, "expected variable in closure use clause, got %s instead", p.curToken.Type)
+				return nil
+			}
+			variable := &ClosureUse{Token: p.curToken, Name: p.curToken.Literal[1:], ByRef: byRef}
+			fn.UseClause = append(fn.UseClause, variable)
+
+			if p.peekTokenIs(ASSIGN) {
+				p.addError(p.peekToken, "closure use variable $%s cannot have a default value", variable.Name)
+				return nil
+			}
+			if !p.peekTokenIs(COMMA) {
+				break
+			}
+			p.nextToken()
+		}
+
+		if !p.expectPeek(RPAREN) {
+			return nil
+		}
+		if len(fn.UseClause) == 0 {
+			p.addError(useToken, "closure use clause cannot be empty")
+			return nil
+		}
 	}
 
 	if !p.expectPeek(LBRACE) {
 		return nil
 	}
 
-	clause.Body = p.parseBlockStatement()
+	fn.Body, fn.IsGenerator = p.parseFunctionBody()
 
-	return clause
+	return fn
 }
 
-func (p *Parser) parseThrowStatement() *ThrowStatement {
-	stmt := &ThrowStatement{Token: p.curToken}
-
-	p.nextToken()
-	stmt.Expression = p.parseExpression(LOWEST)
+func (p *Parser) parseArrowFunction() Expression {
+	fn := &ArrowFunction{Token: p.curToken}
 
-	if p.peekTokenIs(SEMICOLON) {
+	// Check for return by reference: fn&() => ...
+	if p.peekTokenIs(BIT_AND) {
 		p.nextToken()
+		fn.ReturnsRef = true
 	}
 
-	return stmt
-}
-
-func (p *Parser) parseAnonymousFunction() Expression {
-	fn := &AnonymousFunction{Token: p.curToken}
-
 	if !p.expectPeek(LPAREN) {
 		return nil
 	}
 
 	fn.Parameters = p.parseFunctionParameters()
+	p.checkPromotedParameters(fn.Parameters, false)
 
-	// Check for return type hint
-	if p.peekTokenIs(COLON) {
-		p.nextToken() // consume ':'
-		p.nextToken() // move to return type
-		fn.ReturnType = p.parseExpression(LOWEST)
+	fn.ReturnType = p.parseReturnType()
+
+	if !p.expectPeek(DOUBLE_ARROW) {
+		return nil
 	}
 
-	// Check for use clause
-	if p.peekTokenIs(USE) {
-		p.nextToken() // consume 'use'
+	// A yield in the arrow body belongs to the arrow function, not to the
+	// function around it
+	outer := p.sawYield
+	p.nextToken()
+	fn.Body = p.parseExpression(LOWEST)
+	p.sawYield = outer
+
+	return fn
+}
+
+func (p *Parser) parseMatchExpression() Expression {
+	expr := &MatchExpression{Token: p.curToken}
+
+	// A missing or empty subject is reported, but the arms are still parsed
+	// so the error does not cascade through them
+	if p.peekTokenIs(LBRACE) {
+		p.addError(expr.Token, "match expression requires a subject in parentheses")
+	} else {
 		if !p.expectPeek(LPAREN) {
 			return nil
 		}
 
-		p.nextToken()
-		for !p.curTokenIs(RPAREN) && !p.curTokenIs(EOF) {
-			if p.curToken.Type == VARIABLE {
-				fn.UseClause = append(fn.UseClause, &Variable{
-					Token: p.curToken,
-					Name:  p.curToken.Literal[1:],
-				})
+		if p.peekTokenIs(RPAREN) {
+			p.nextToken()
+			p.addError(expr.Token, "match expression subject cannot be empty")
+		} else {
+			p.nextToken()
+			expr.Subject = p.parseExpression(LOWEST)
+
+			if !p.expectPeek(RPAREN) {
+				return nil
 			}
+		}
+	}
 
-			if p.peekTokenIs(COMMA) {
-				p.nextToken()
+	if !p.expectPeek(LBRACE) {
+		return nil
+	}
+
+	for !p.peekTokenIs(RBRACE) && !p.peekTokenIs(EOF) {
+		p.nextToken()
+
+		arm := p.parseMatchArm()
+		if arm == nil {
+			return nil
+		}
+		expr.Arms = append(expr.Arms, arm)
+
+		// Arms are comma separated; the last one may have a trailing comma
+		if p.peekTokenIs(COMMA) {
+			p.nextToken()
+		} else {
+			break
+		}
+	}
+
+	if !p.expectPeek(RBRACE) || expr.Subject == nil {
+		return nil
+	}
+
+	return expr
+}
+
+func (p *Parser) parseMatchArm() *MatchArm {
+	arm := &MatchArm{}
+
+	isDefault := (p.curTokenIs(DEFAULT) || p.curTokenIs(IDENT)) && strings.EqualFold(p.curToken.Literal, "default")
+	if !(isDefault && p.peekTokenIs(DOUBLE_ARROW)) {
+		arm.Conditions = append(arm.Conditions, p.parseExpression(LOWEST))
+
+		for p.peekTokenIs(COMMA) {
+			p.nextToken()
+			if p.peekTokenIs(DOUBLE_ARROW) {
+				break
 			}
 			p.nextToken()
+			arm.Conditions = append(arm.Conditions, p.parseExpression(LOWEST))
 		}
 	}
 
-	if !p.expectPeek(LBRACE) {
+	if !p.expectPeek(DOUBLE_ARROW) {
 		return nil
 	}
 
-	fn.Body = p.parseBlockStatement()
+	// Arm bodies are parsed at the lowest precedence so they can be
+	// throw expressions, closures or arrow functions
+	p.nextToken()
+	arm.Body = p.parseExpression(LOWEST)
 
-	return fn
+	return arm
 }
 
 func (p *Parser) parseYieldExpression() Expression {
 	expr := &YieldExpression{Token: p.curToken}
+	p.sawYield = true
 
 	if !p.peekTokenIs(SEMICOLON) && !p.peekTokenIs(RBRACE) && !p.peekTokenIs(EOF) {
 		p.nextToken()
@@ -1372,11 +2871,15 @@ func (p *Parser) parseInterfaceDeclaration() *InterfaceDeclaration {
 		return nil
 	}
 
+	p.keepComments()
 	p.nextToken()
 	for !p.curTokenIs(RBRACE) && !p.curTokenIs(EOF) {
 		if method := p.parseInterfaceMethod(); method != nil {
 			stmt.Methods = append(stmt.Methods, method)
 		}
+		if p.recovering {
+			p.synchronizeMember()
+		}
 		p.nextToken()
 	}
 
@@ -1399,6 +2902,9 @@ func (p *Parser) parseInterfaceMethod() *InterfaceMethod {
 	}
 	p.nextToken()
 
+	if isMemberNameKeyword(p.curToken) {
+		p.curToken.Type = IDENT
+	}
 	if !p.curTokenIs(IDENT) {
 		return nil
 	}
@@ -1410,6 +2916,7 @@ func (p *Parser) parseInterfaceMethod() *InterfaceMethod {
 	}
 
 	method.Parameters = p.parseFunctionParameters()
+	p.checkPromotedParameters(method.Parameters, false)
 
 	if p.peekTokenIs(SEMICOLON) {
 		p.nextToken()
@@ -1431,6 +2938,7 @@ func (p *Parser) parseTraitDeclaration() *TraitDeclaration {
 		return nil
 	}
 
+	p.keepComments()
 	p.nextToken()
 	for !p.curTokenIs(RBRACE) && !p.curTokenIs(EOF) {
 		visibility := "public"
@@ -1450,8 +2958,15 @@ func (p *Parser) parseTraitDeclaration() *TraitDeclaration {
 			p.nextToken()
 		}
 
-		if p.curTokenIs(VARIABLE) {
-			if property := p.parsePropertyDeclaration(visibility, static); property != nil {
+		if p.curTokenIs(CONST) {
+			if constant := p.parseConstantDeclaration(); constant != nil {
+				constant.Visibility = visibility
+				stmt.Constants = append(stmt.Constants, constant)
+			}
+		} else if p.curTokenIs(VARIABLE) {
+			comments, docBlock := p.leadingCommentTexts()
+			for _, property := range p.parsePropertyDeclarations(visibility, static) {
+				property.LeadingComments, property.DocBlock = comments, docBlock
 				stmt.Properties = append(stmt.Properties, property)
 			}
 		} else if p.curTokenIs(FUNCTION) {
@@ -1459,12 +2974,125 @@ func (p *Parser) parseTraitDeclaration() *TraitDeclaration {
 				stmt.Methods = append(stmt.Methods, method)
 			}
 		}
+		if p.recovering {
+			p.synchronizeMember()
+		}
+		p.nextToken()
+	}
+
+	return stmt
+}
+
+func (p *Parser) parseEnumDeclaration() *EnumDeclaration {
+	stmt := &EnumDeclaration{Token: p.curToken}
+
+	if !p.expectPeek(IDENT) {
+		return nil
+	}
+
+	stmt.Name = &Identifier{Token: p.curToken, Value: p.curToken.Literal}
+
+	// Check for a backed enum: enum Suit: string
+	if p.peekTokenIs(COLON) {
+		p.nextToken() // consume ':'
+		p.nextToken() // move to backing type
+		stmt.BackingType = p.parseTypeHint()
+	}
+
+	if p.peekTokenIs(IMPLEMENTS) {
+		p.nextToken() // consume 'implements'
+		for {
+			p.nextToken()
+			iface := p.parseQualifiedName()
+			if iface == nil {
+				return nil
+			}
+			stmt.Interfaces = append(stmt.Interfaces, iface)
+
+			if !p.peekTokenIs(COMMA) {
+				break
+			}
+			p.nextToken()
+		}
+	}
+
+	if !p.expectPeek(LBRACE) {
+		return nil
+	}
+
+	p.keepComments()
+	p.nextToken()
+	for !p.curTokenIs(RBRACE) && !p.curTokenIs(EOF) {
+		if p.curTokenIs(USE) {
+			if traitUse := p.parseTraitUse(); traitUse != nil {
+				stmt.TraitUses = append(stmt.TraitUses, traitUse)
+			}
+		} else if p.curTokenIs(CASE) {
+			if enumCase := p.parseEnumCase(); enumCase != nil {
+				stmt.Cases = append(stmt.Cases, enumCase)
+			}
+		} else {
+			visibility := "public"
+			static := false
+
+			for {
+				if p.curTokenIs(PUBLIC) || p.curTokenIs(PRIVATE) || p.curTokenIs(PROTECTED) {
+					visibility = p.curToken.Literal
+				} else if p.curTokenIs(STATIC) {
+					static = true
+				} else {
+					break
+				}
+				p.nextToken()
+			}
+
+			if p.curTokenIs(CONST) {
+				if constant := p.parseConstantDeclaration(); constant != nil {
+					constant.Visibility = visibility
+					stmt.Constants = append(stmt.Constants, constant)
+				}
+			} else if p.curTokenIs(FUNCTION) {
+				if method := p.parseMethodDeclaration(visibility, static); method != nil {
+					stmt.Methods = append(stmt.Methods, method)
+				}
+			} else if !p.curTokenIs(RBRACE) {
+				p.syntaxError(p.curToken, "unexpected '%s' in enum %s", p.curToken.Literal, stmt.Name.Value)
+			}
+		}
+
+		if p.recovering {
+			p.synchronizeMember()
+		}
 		p.nextToken()
 	}
 
 	return stmt
 }
 
+func (p *Parser) parseEnumCase() *EnumCase {
+	enumCase := &EnumCase{Token: p.curToken}
+
+	p.nextToken()
+	if !isNameToken(p.curToken) {
+		p.bareSyntaxError(p.curToken, "expected enum case name, got %s instead", p.curToken.Type)
+		return nil
+	}
+	enumCase.Name = &Identifier{Token: p.curToken, Value: p.curToken.Literal}
+
+	// Backed enum cases have a value
+	if p.peekTokenIs(ASSIGN) {
+		p.nextToken() // consume '='
+		p.nextToken() // move to value
+		enumCase.Value = p.parseExpression(LOWEST)
+	}
+
+	if p.peekTokenIs(SEMICOLON) {
+		p.nextToken()
+	}
+
+	return enumCase
+}
+
 func (p *Parser) parseConstantDeclaration() *ConstantDeclaration {
 	stmt := &ConstantDeclaration{Token: p.curToken}
 
@@ -1478,7 +3106,7 @@ func (p *Parser) parseConstantDeclaration() *ConstantDeclaration {
 		stmt.Visibility = "public" // default
 	}
 
-	if !p.expectPeek(IDENT) {
+	if !p.expectPeekMemberName() {
 		return nil
 	}
 
@@ -1525,11 +3153,16 @@ func (p *Parser) parseTernaryExpression(condition Expression) Expression {
 		Condition: condition,
 	}
 
-	p.nextToken() // consume '?'
-	expr.TrueValue = p.parseExpression(LOWEST)
+	// $a ?: $b uses the condition itself as the true value
+	if p.peekTokenIs(COLON) {
+		p.nextToken()
+	} else {
+		p.nextToken() // consume '?'
+		expr.TrueValue = p.parseExpression(LOWEST)
 
-	if !p.expectPeek(COLON) {
-		return nil
+		if !p.expectPeek(COLON) {
+			return nil
+		}
 	}
 
 	p.nextToken() // consume ':'
@@ -1538,6 +3171,49 @@ func (p *Parser) parseTernaryExpression(condition Expression) Expression {
 	return expr
 }
 
+func (p *Parser) parseCloneExpression() Expression {
+	expr := &CloneExpression{Token: p.curToken}
+
+	p.nextToken()
+	expr.Operand = p.parseExpression(PREFIX)
+
+	return expr
+}
+
+// parseInstanceofExpression parses the class operand of instanceof, which is
+// a possibly qualified class name, self or static, or an expression such as
+// a variable holding the class name
+func (p *Parser) parseInstanceofExpression(left Expression) Expression {
+	expr := &InstanceofExpression{Token: p.curToken, Left: left}
+
+	p.nextToken()
+	switch p.curToken.Type {
+	case IDENT, NAMESPACE_SEPARATOR:
+		class := p.parseQualifiedName()
+		if class == nil {
+			return nil
+		}
+		expr.Class = class
+	case STATIC:
+		expr.Class = &Identifier{Token: p.curToken, Value: p.curToken.Literal}
+	default:
+		expr.Class = p.parseExpression(TYPE_CHECK)
+	}
+
+	return expr
+}
+
+// parseCoalesceExpression parses the right side of ?? one level below its
+// own precedence, so $a ?? $b ?? $c groups as $a ?? ($b ?? $c)
+func (p *Parser) parseCoalesceExpression(left Expression) Expression {
+	expr := &CoalesceExpression{Token: p.curToken, Left: left}
+
+	p.nextToken()
+	expr.Right = p.parseExpression(COALESCE - 1)
+
+	return expr
+}
+
 func (p *Parser) parseDeclareStatement() Statement {
 	stmt := &DeclareStatement{
 		Token:      p.curToken,
@@ -1587,20 +3263,13 @@ func (p *Parser) parseDeclareStatement() Statement {
 }
 
 func (p *Parser) parseNamespacedIdentifier() Expression {
-	// Handle leading namespace separator like \Exception or \define()
-	expr := &Identifier{Token: p.curToken, Value: p.curToken.Literal}
-	
-	// If next token is an identifier, this is a global reference like \Exception or \define
-	if p.peekTokenIs(IDENT) {
-		p.nextToken()
-		expr.Value = "\\" + p.curToken.Literal
-		expr.Token = p.curToken
-		
-		// If this is followed by parentheses, it might be a function call
-		// The call expression parser will handle the parentheses
+	// Handle a fully qualified name like \Exception, \define() or
+	// \App\Config::KEY; calls and static access are parsed as infixes
+	ident := p.parseQualifiedName()
+	if ident == nil {
+		return nil
 	}
-	
-	return expr
+	return ident
 }
 
 func (p *Parser) parseTernaryOrNullable() Expression {
@@ -1726,21 +3395,51 @@ func (p *Parser) parseRequireExpression() Expression {
 	return expr
 }
 
+// parsePrintExpression parses print, which unlike echo is an expression
+// that always evaluates to 1. Like include, it takes everything after it
+// as its operand.
+func (p *Parser) parsePrintExpression() Expression {
+	expr := &PrintExpression{Token: p.curToken}
+
+	p.nextToken()
+	expr.Value = p.parseExpression(LOWEST)
+
+	return expr
+}
+
 func (p *Parser) parseStaticFunction() Expression {
 	staticToken := p.curToken
-	
+
+	// static::member refers to the late-bound class
+	if p.peekTokenIs(STATIC_ACCESS) {
+		return &Identifier{Token: staticToken, Value: staticToken.Literal}
+	}
+
+	// static fn() => ... is a static arrow function
+	if p.peekTokenIs(ARROW_FUNCTION) {
+		p.nextToken()
+		fn, ok := p.parseArrowFunction().(*ArrowFunction)
+		if !ok {
+			return nil
+		}
+		fn.Static = true
+		fn.Token = staticToken
+		return fn
+	}
+
 	// Expect 'function' after 'static'
 	if !p.expectPeek(FUNCTION) {
 		return nil
 	}
-	
+
 	// Parse as anonymous function but mark as static
-	fn := p.parseAnonymousFunction().(*AnonymousFunction)
-	if fn != nil {
-		fn.Static = true
-		fn.Token = staticToken // Use static token as the main token
+	fn, ok := p.parseAnonymousFunction().(*AnonymousFunction)
+	if !ok {
+		return nil
 	}
-	
+	fn.Static = true
+	fn.Token = staticToken // Use static token as the main token
+
 	return fn
 }
 
@@ -1758,18 +3457,41 @@ func (p *Parser) parseNullLiteral() Expression {
 //     }
 //     // Use program.Statements to access the parsed AST
 func Parse(input string) (*Program, error) {
+	return ParseWithOptions(input, ParserOptions{})
+}
+
+// ParserOptions configures ParseWithOptions
+type ParserOptions struct {
+	// BestEffort returns the statements that parsed cleanly alongside the
+	// parse errors, instead of a nil program. Top-level statements with an
+	// error anywhere inside them are left out.
+	BestEffort bool
+
+	// AttachComments sets LeadingComments and DocBlock on function, class,
+	// method and property declarations, as Parser.AttachComments does
+	AttachComments bool
+}
+
+// ParseWithOptions parses PHP source code like Parse, configured by options
+func ParseWithOptions(input string, options ParserOptions) (*Program, error) {
 	// Create a lexer with the input string
 	lexer := New(input)
 
 	// Create a parser with the lexer
 	parser := NewParser(lexer)
+	parser.bestEffort = options.BestEffort
+	parser.AttachComments = options.AttachComments
 
 	// Parse the input to create a program (AST)
 	program := parser.ParseProgram()
 
 	// Check for any parsing errors
 	if len(parser.Errors()) > 0 {
-		return nil, fmt.Errorf("parser errors: %s", strings.Join(parser.Errors(), "; "))
+		err := fmt.Errorf("parser errors:\n%s", strings.Join(parser.Errors(), "\n"))
+		if options.BestEffort {
+			return program, err
+		}
+		return nil, err
 	}
 
 	// Return the parsed program and nil for the error
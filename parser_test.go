@@ -1,6 +1,9 @@
 package gophpparser
 
 import (
+	"fmt"
+	"strconv"
+	"strings"
 	"testing"
 )
 
@@ -241,6 +244,158 @@ foreach ($arr as $key => $value) {
 	}
 }
 
+func TestParseForCommaClauses(t *testing.T) {
+	input := `<?php
+for ($i = 0, $j = 10; $i < $j, $j > 0; $i++, $j--) {
+    echo $i;
+}
+?>`
+
+	l := New(input)
+	p := NewParser(l)
+	program := p.ParseProgram()
+
+	if len(p.Errors()) != 0 {
+		t.Errorf("parser has %d errors", len(p.Errors()))
+		for _, err := range p.Errors() {
+			t.Errorf("parser error: %q", err)
+		}
+		return
+	}
+
+	stmt, ok := program.Statements[0].(*ForStatement)
+	if !ok {
+		t.Fatalf("program.Statements[0] is not *ForStatement. got=%T", program.Statements[0])
+	}
+
+	clauses := []struct {
+		name     string
+		clause   Expression
+		expected []string
+	}{
+		{"Init", stmt.Init, []string{"$i = 0", "$j = 10"}},
+		{"Condition", stmt.Condition, []string{"($i < $j)", "($j > 0)"}},
+		{"Update", stmt.Update, []string{"($i++)", "($j--)"}},
+	}
+
+	for _, tt := range clauses {
+		list, ok := tt.clause.(*CommaExpression)
+		if !ok {
+			t.Errorf("stmt.%s is not *CommaExpression. got=%T", tt.name, tt.clause)
+			continue
+		}
+		if len(list.Expressions) != len(tt.expected) {
+			t.Errorf("stmt.%s has %d expressions. got=%d", tt.name, len(tt.expected), len(list.Expressions))
+			continue
+		}
+		for i, expected := range tt.expected {
+			if got := list.Expressions[i].String(); got != expected {
+				t.Errorf("stmt.%s.Expressions[%d] wrong. expected=%q, got=%q", tt.name, i, expected, got)
+			}
+		}
+	}
+}
+
+func TestParseCommaOutsideForIsError(t *testing.T) {
+	tests := []struct {
+		input      string
+		statements int
+	}{
+		{`<?php $a = 1, $b = 2;`, 2},
+		{`<?php $items[0] = 1, $this->count = 2;`, 2},
+		{`<?php function f() { $a = 1, $b = 2; }`, 1},
+	}
+
+	for _, tt := range tests {
+		l := New(tt.input)
+		p := NewParser(l)
+		program := p.ParseProgram()
+
+		if len(p.Errors()) != 1 {
+			t.Errorf("input %s: expected 1 error. got=%q", tt.input, p.Errors())
+			continue
+		}
+		if !strings.Contains(p.Errors()[0], "expressions can only be separated by commas in for loop clauses") {
+			t.Errorf("input %s: wrong error. got=%q", tt.input, p.Errors()[0])
+		}
+		if len(program.Statements) != tt.statements {
+			t.Errorf("input %s: expected %d statements. got=%d", tt.input, tt.statements, len(program.Statements))
+		}
+	}
+}
+
+func TestParseOperandAfterPostfixIsError(t *testing.T) {
+	tests := []string{
+		`<?php $a--1;`,
+		`<?php $a++ $b;`,
+		`<?php for ($i = 0; $i < 3; $i--2) {}`,
+	}
+
+	for _, input := range tests {
+		l := New(input)
+		p := NewParser(l)
+		p.ParseProgram()
+
+		if len(p.Errors()) == 0 {
+			t.Errorf("input %s: expected an error", input)
+			continue
+		}
+		if !strings.Contains(p.Errors()[0], "after '") {
+			t.Errorf("input %s: wrong error. got=%q", input, p.Errors()[0])
+		}
+	}
+
+	l := New(`<?php $a - -1; $a--; $b++;`)
+	p := NewParser(l)
+	p.ParseProgram()
+	if len(p.Errors()) != 0 {
+		t.Errorf("expected no errors. got=%q", p.Errors())
+	}
+}
+
+func TestParseEmptyStatements(t *testing.T) {
+	input := `<?php
+;;
+$a = 1;;
+if ($a) { ; }
+?>`
+
+	l := New(input)
+	p := NewParser(l)
+	program := p.ParseProgram()
+
+	if len(p.Errors()) != 0 {
+		t.Errorf("parser has %d errors", len(p.Errors()))
+		for _, err := range p.Errors() {
+			t.Errorf("parser error: %q", err)
+		}
+		return
+	}
+
+	expected := []string{"EmptyStatement", "EmptyStatement", "ExpressionStatement", "EmptyStatement", "IfStatement"}
+	if len(program.Statements) != len(expected) {
+		t.Fatalf("program.Statements does not contain %d statements. got=%d", len(expected), len(program.Statements))
+	}
+	for i, typ := range expected {
+		if got := program.Statements[i].Type(); got != typ {
+			t.Errorf("program.Statements[%d] is not %s. got=%s", i, typ, got)
+		}
+	}
+
+	empty := program.Statements[1].(*EmptyStatement)
+	if empty.Token.Line != 2 || empty.Token.Column != 2 {
+		t.Errorf("second empty statement has wrong position. got=%d:%d", empty.Token.Line, empty.Token.Column)
+	}
+
+	ifStmt := program.Statements[4].(*IfStatement)
+	if len(ifStmt.Consequence.Statements) != 1 {
+		t.Fatalf("if body does not contain 1 statement. got=%d", len(ifStmt.Consequence.Statements))
+	}
+	if _, ok := ifStmt.Consequence.Statements[0].(*EmptyStatement); !ok {
+		t.Errorf("if body statement is not *EmptyStatement. got=%T", ifStmt.Consequence.Statements[0])
+	}
+}
+
 func TestParseBreakContinueStatement(t *testing.T) {
 	tests := []struct {
 		input    string
@@ -711,6 +866,85 @@ try {
 	}
 }
 
+func TestParseCatchUnionTypesAndNoVariable(t *testing.T) {
+	input := `<?php
+try {
+    risky_operation();
+} catch (TypeError | \App\ValueError $e) {
+    echo $e->getMessage();
+} catch (Exception) {
+    echo "failed";
+}
+?>`
+
+	l := New(input)
+	p := NewParser(l)
+	program := p.ParseProgram()
+
+	if len(p.Errors()) != 0 {
+		t.Errorf("parser has %d errors", len(p.Errors()))
+		for _, err := range p.Errors() {
+			t.Errorf("parser error: %q", err)
+		}
+		return
+	}
+
+	stmt, ok := program.Statements[0].(*TryStatement)
+	if !ok {
+		t.Fatalf("program.Statements[0] is not *TryStatement. got=%T", program.Statements[0])
+	}
+	if len(stmt.Catches) != 2 {
+		t.Fatalf("expected 2 catch clauses. got=%d", len(stmt.Catches))
+	}
+
+	union := stmt.Catches[0]
+	if len(union.ExceptionTypes) != 2 {
+		t.Fatalf("expected 2 exception types. got=%d", len(union.ExceptionTypes))
+	}
+	for i, expected := range []string{"TypeError", "\\App\\ValueError"} {
+		if union.ExceptionTypes[i].Value != expected {
+			t.Errorf("exception type %d not %q. got=%q", i, expected, union.ExceptionTypes[i].Value)
+		}
+	}
+	if union.Variable == nil || union.Variable.Name != "e" {
+		t.Errorf("exception variable not 'e'. got=%v", union.Variable)
+	}
+
+	bare := stmt.Catches[1]
+	if len(bare.ExceptionTypes) != 1 || bare.ExceptionTypes[0].Value != "Exception" {
+		t.Errorf("exception types wrong. got=%v", bare.ExceptionTypes)
+	}
+	if bare.Variable != nil {
+		t.Errorf("expected no exception variable. got=%v", bare.Variable)
+	}
+	if !strings.HasPrefix(bare.String(), " catch (Exception) {") {
+		t.Errorf("bare.String() wrong. got=%q", bare.String())
+	}
+
+	data, err := ToJSON(bare)
+	if err != nil {
+		t.Fatalf("failed to marshal catch clause: %v", err)
+	}
+	if strings.Contains(string(data), `"variable"`) {
+		t.Errorf("expected no variable in JSON. got=%s", data)
+	}
+	if strings.Contains(string(data), `"exception_type"`) {
+		t.Errorf("expected only exception_types in JSON. got=%s", data)
+	}
+
+	data, err = ToJSON(program)
+	if err != nil {
+		t.Fatalf("failed to marshal program: %v", err)
+	}
+	decoded, err := FromJSON(data)
+	if err != nil {
+		t.Fatalf("FromJSON failed: %v", err)
+	}
+	decodedCatch := decoded.Statements[0].(*TryStatement).Catches[0]
+	if decodedCatch.ExceptionType == nil || decodedCatch.ExceptionType.Value != "TypeError" {
+		t.Errorf("ExceptionType not restored from exception_types. got=%v", decodedCatch.ExceptionType)
+	}
+}
 func TestParseThrowStatement(t *testing.T) {
 	input := `<?php
 throw new Exception("Error message");
@@ -923,3 +1157,3846 @@ func testIntegerLiteral(t *testing.T, il Expression, value int64) bool {
 
 	return true
 }
+
+func TestParseFunctionReturnsByReference(t *testing.T) {
+	input := `<?php
+function &getRef() {
+    return $this->value;
+}
+?>`
+
+	l := New(input)
+	p := NewParser(l)
+	program := p.ParseProgram()
+
+	if len(p.Errors()) != 0 {
+		t.Errorf("parser has %d errors", len(p.Errors()))
+		for _, err := range p.Errors() {
+			t.Errorf("parser error: %q", err)
+		}
+		return
+	}
+
+	if len(program.Statements) != 1 {
+		t.Fatalf("program.Statements does not contain 1 statements. got=%d",
+			len(program.Statements))
+	}
+
+	stmt, ok := program.Statements[0].(*FunctionDeclaration)
+	if !ok {
+		t.Fatalf("program.Statements[0] is not *FunctionDeclaration. got=%T",
+			program.Statements[0])
+	}
+
+	if stmt.Name.Value != "getRef" {
+		t.Errorf("stmt.Name.Value not 'getRef'. got=%s", stmt.Name.Value)
+	}
+
+	if !stmt.ReturnsRef {
+		t.Errorf("stmt.ReturnsRef is false, want true")
+	}
+
+	jsonData, err := ToJSON(stmt)
+	if err != nil {
+		t.Fatalf("ToJSON failed: %v", err)
+	}
+	if !strings.Contains(string(jsonData), `"returns_ref": true`) {
+		t.Errorf("JSON does not contain returns_ref flag. got=%s", jsonData)
+	}
+}
+
+func TestParseMethodReturnsByReference(t *testing.T) {
+	input := `<?php
+class Collection {
+    public function &items() {
+        return $this->items;
+    }
+
+    public function count() {
+        return 0;
+    }
+}
+?>`
+
+	l := New(input)
+	p := NewParser(l)
+	program := p.ParseProgram()
+
+	if len(p.Errors()) != 0 {
+		t.Errorf("parser has %d errors", len(p.Errors()))
+		for _, err := range p.Errors() {
+			t.Errorf("parser error: %q", err)
+		}
+		return
+	}
+
+	class, ok := program.Statements[0].(*ClassDeclaration)
+	if !ok {
+		t.Fatalf("program.Statements[0] is not *ClassDeclaration. got=%T",
+			program.Statements[0])
+	}
+
+	if len(class.Methods) != 2 {
+		t.Fatalf("class.Methods does not contain 2 methods. got=%d", len(class.Methods))
+	}
+
+	if class.Methods[0].Name.Value != "items" || !class.Methods[0].ReturnsRef {
+		t.Errorf("method 'items' should return by reference. got name=%s returnsRef=%t",
+			class.Methods[0].Name.Value, class.Methods[0].ReturnsRef)
+	}
+
+	if class.Methods[1].ReturnsRef {
+		t.Errorf("method 'count' should not return by reference")
+	}
+}
+
+func TestParseReferenceReturningGenerators(t *testing.T) {
+	input := `<?php
+class Stream {
+    public function &gen() {
+        yield $x;
+    }
+
+    public function plain() {
+        return $x;
+    }
+
+    public function lazy() {
+        return fn() => yield 1;
+    }
+}
+
+$closure = function &() {
+    yield 1;
+};
+?>`
+
+	l := New(input)
+	p := NewParser(l)
+	program := p.ParseProgram()
+
+	if len(p.Errors()) != 0 {
+		t.Errorf("parser has %d errors", len(p.Errors()))
+		for _, err := range p.Errors() {
+			t.Errorf("parser error: %q", err)
+		}
+		return
+	}
+
+	if len(program.Statements) != 2 {
+		t.Fatalf("program.Statements does not contain 2 statements. got=%d",
+			len(program.Statements))
+	}
+
+	class, ok := program.Statements[0].(*ClassDeclaration)
+	if !ok {
+		t.Fatalf("program.Statements[0] is not *ClassDeclaration. got=%T",
+			program.Statements[0])
+	}
+
+	gen := class.Methods[0]
+	if !gen.ReturnsRef {
+		t.Errorf("method 'gen' should return by reference")
+	}
+	if !gen.IsGenerator {
+		t.Errorf("method 'gen' should be detected as a generator")
+	}
+
+	plain := class.Methods[1]
+	if plain.ReturnsRef || plain.IsGenerator {
+		t.Errorf("method 'plain' should have no flags. got returnsRef=%t isGenerator=%t",
+			plain.ReturnsRef, plain.IsGenerator)
+	}
+
+	if lazy := class.Methods[2]; lazy.IsGenerator {
+		t.Errorf("method 'lazy' should not be a generator; the yield belongs to its arrow function")
+	}
+
+	stmt := program.Statements[1].(*ExpressionStatement)
+	assign, ok := stmt.Expression.(*AssignmentExpression)
+	if !ok {
+		t.Fatalf("stmt.Expression is not *AssignmentExpression. got=%T", stmt.Expression)
+	}
+
+	closure, ok := assign.Value.(*AnonymousFunction)
+	if !ok {
+		t.Fatalf("assign.Value is not *AnonymousFunction. got=%T", assign.Value)
+	}
+
+	if !closure.ReturnsRef || !closure.IsGenerator {
+		t.Errorf("closure flags wrong. got returnsRef=%t isGenerator=%t",
+			closure.ReturnsRef, closure.IsGenerator)
+	}
+}
+
+func TestParseMatchArmsWithThrowAndClosure(t *testing.T) {
+	input := `<?php
+$result = match($x) {
+    1 => throw new E(),
+    2, 3 => function() { return 1; },
+    default => fn() => 0,
+};
+?>`
+
+	l := New(input)
+	p := NewParser(l)
+	program := p.ParseProgram()
+
+	if len(p.Errors()) != 0 {
+		t.Errorf("parser has %d errors", len(p.Errors()))
+		for _, err := range p.Errors() {
+			t.Errorf("parser error: %q", err)
+		}
+		return
+	}
+
+	if len(program.Statements) != 1 {
+		t.Fatalf("program.Statements does not contain 1 statement. got=%d",
+			len(program.Statements))
+	}
+
+	stmt := program.Statements[0].(*ExpressionStatement)
+	assign, ok := stmt.Expression.(*AssignmentExpression)
+	if !ok {
+		t.Fatalf("stmt.Expression is not *AssignmentExpression. got=%T", stmt.Expression)
+	}
+
+	match, ok := assign.Value.(*MatchExpression)
+	if !ok {
+		t.Fatalf("assign.Value is not *MatchExpression. got=%T", assign.Value)
+	}
+
+	if match.Subject.String() != "$x" {
+		t.Errorf("match subject wrong. got=%q", match.Subject.String())
+	}
+
+	if len(match.Arms) != 3 {
+		t.Fatalf("match does not have 3 arms. got=%d", len(match.Arms))
+	}
+
+	throwArm := match.Arms[0]
+	if len(throwArm.Conditions) != 1 {
+		t.Errorf("first arm should have 1 condition. got=%d", len(throwArm.Conditions))
+	}
+	throwExpr, ok := throwArm.Body.(*ThrowExpression)
+	if !ok {
+		t.Fatalf("first arm body is not *ThrowExpression. got=%T", throwArm.Body)
+	}
+	if _, ok := throwExpr.Expression.(*NewExpression); !ok {
+		t.Errorf("thrown value is not *NewExpression. got=%T", throwExpr.Expression)
+	}
+
+	closureArm := match.Arms[1]
+	if len(closureArm.Conditions) != 2 {
+		t.Errorf("second arm should have 2 conditions. got=%d", len(closureArm.Conditions))
+	}
+	if _, ok := closureArm.Body.(*AnonymousFunction); !ok {
+		t.Errorf("second arm body is not *AnonymousFunction. got=%T", closureArm.Body)
+	}
+
+	defaultArm := match.Arms[2]
+	if !defaultArm.IsDefault() {
+		t.Errorf("third arm should be the default arm")
+	}
+	arrow, ok := defaultArm.Body.(*ArrowFunction)
+	if !ok {
+		t.Fatalf("default arm body is not *ArrowFunction. got=%T", defaultArm.Body)
+	}
+	if arrow.Body.String() != "0" {
+		t.Errorf("arrow function body wrong. got=%q", arrow.Body.String())
+	}
+}
+
+func TestParseMethodCallChains(t *testing.T) {
+	input := `<?php
+$a->b()->c();
+$user?->getAddress($type);
+?>`
+
+	l := New(input)
+	p := NewParser(l)
+	program := p.ParseProgram()
+
+	if len(p.Errors()) != 0 {
+		t.Errorf("parser has %d errors", len(p.Errors()))
+		for _, err := range p.Errors() {
+			t.Errorf("parser error: %q", err)
+		}
+		return
+	}
+
+	if len(program.Statements) != 2 {
+		t.Fatalf("program.Statements does not contain 2 statements. got=%d",
+			len(program.Statements))
+	}
+
+	// $a->b()->c() is Call(Access(Call(Access($a, b)), c))
+	stmt := program.Statements[0].(*ExpressionStatement)
+	outerCall, ok := stmt.Expression.(*CallExpression)
+	if !ok {
+		t.Fatalf("stmt.Expression is not *CallExpression. got=%T", stmt.Expression)
+	}
+
+	outerAccess, ok := outerCall.Function.(*ObjectAccessExpression)
+	if !ok {
+		t.Fatalf("outerCall.Function is not *ObjectAccessExpression. got=%T", outerCall.Function)
+	}
+	if outerAccess.Property.String() != "c" {
+		t.Errorf("outer method is not 'c'. got=%s", outerAccess.Property.String())
+	}
+
+	innerCall, ok := outerAccess.Object.(*CallExpression)
+	if !ok {
+		t.Fatalf("outerAccess.Object is not *CallExpression. got=%T", outerAccess.Object)
+	}
+
+	innerAccess, ok := innerCall.Function.(*ObjectAccessExpression)
+	if !ok {
+		t.Fatalf("innerCall.Function is not *ObjectAccessExpression. got=%T", innerCall.Function)
+	}
+	if innerAccess.Property.String() != "b" || innerAccess.Object.String() != "$a" {
+		t.Errorf("inner access wrong. got=%s", innerAccess.String())
+	}
+
+	// $user?->getAddress($type) is a call on a nullsafe access
+	stmt = program.Statements[1].(*ExpressionStatement)
+	call, ok := stmt.Expression.(*CallExpression)
+	if !ok {
+		t.Fatalf("stmt.Expression is not *CallExpression. got=%T", stmt.Expression)
+	}
+
+	access, ok := call.Function.(*ObjectAccessExpression)
+	if !ok {
+		t.Fatalf("call.Function is not *ObjectAccessExpression. got=%T", call.Function)
+	}
+	if !access.Nullsafe {
+		t.Errorf("access should be nullsafe")
+	}
+	if outerAccess.Nullsafe || innerAccess.Nullsafe {
+		t.Errorf("-> accesses should not be nullsafe")
+	}
+	if len(call.Arguments) != 1 {
+		t.Errorf("call should have 1 argument. got=%d", len(call.Arguments))
+	}
+}
+
+func TestParseBitwiseConstantExpressions(t *testing.T) {
+	tests := []struct {
+		input    string
+		name     string
+		expected string
+	}{
+		{"const FLAG_ALL = FLAG_A | FLAG_B | FLAG_C;", "FLAG_ALL", "((FLAG_A | FLAG_B) | FLAG_C)"},
+		{"const MASK = 1 << 4;", "MASK", "(1 << 4)"},
+		{"const LOW = MASK - 1 & 255 ^ MASK >> 2;", "LOW", "(((MASK - 1) & 255) ^ (MASK >> 2))"},
+	}
+
+	for _, tt := range tests {
+		l := New("<?php " + tt.input)
+		p := NewParser(l)
+		program := p.ParseProgram()
+
+		if len(p.Errors()) != 0 {
+			t.Errorf("parser has %d errors for %q", len(p.Errors()), tt.input)
+			for _, err := range p.Errors() {
+				t.Errorf("parser error: %q", err)
+			}
+			continue
+		}
+
+		constant, ok := program.Statements[0].(*ConstantDeclaration)
+		if !ok {
+			t.Fatalf("program.Statements[0] is not *ConstantDeclaration. got=%T",
+				program.Statements[0])
+		}
+
+		if constant.Name.Value != tt.name {
+			t.Errorf("constant name wrong. want=%s, got=%s", tt.name, constant.Name.Value)
+		}
+
+		if constant.Value.String() != tt.expected {
+			t.Errorf("constant value wrong. want=%q, got=%q", tt.expected, constant.Value.String())
+		}
+	}
+}
+
+func TestParsePrintAndIncludeAsExpressions(t *testing.T) {
+	input := `<?php
+$ok = include 'f.php';
+$x = print $y;
+$config = require_once $base . '/config.php';
+print 'a' . 'b';
+?>`
+
+	l := New(input)
+	p := NewParser(l)
+	program := p.ParseProgram()
+
+	if len(p.Errors()) != 0 {
+		t.Errorf("parser has %d errors", len(p.Errors()))
+		for _, err := range p.Errors() {
+			t.Errorf("parser error: %q", err)
+		}
+		return
+	}
+
+	if len(program.Statements) != 4 {
+		t.Fatalf("program.Statements does not contain 4 statements. got=%d",
+			len(program.Statements))
+	}
+
+	assignValue := func(i int) Expression {
+		stmt := program.Statements[i].(*ExpressionStatement)
+		assign, ok := stmt.Expression.(*AssignmentExpression)
+		if !ok {
+			t.Fatalf("statement %d is not an assignment. got=%T", i, stmt.Expression)
+		}
+		return assign.Value
+	}
+
+	include, ok := assignValue(0).(*IncludeExpression)
+	if !ok {
+		t.Fatalf("$ok value is not *IncludeExpression. got=%T", assignValue(0))
+	}
+	if include.Path.String() != "f.php" {
+		t.Errorf("include path wrong. got=%q", include.Path.String())
+	}
+
+	printExpr, ok := assignValue(1).(*PrintExpression)
+	if !ok {
+		t.Fatalf("$x value is not *PrintExpression. got=%T", assignValue(1))
+	}
+	if printExpr.Value.String() != "$y" {
+		t.Errorf("print value wrong. got=%q", printExpr.Value.String())
+	}
+
+	// include binds loosely, so the concatenation is the path
+	require, ok := assignValue(2).(*RequireExpression)
+	if !ok {
+		t.Fatalf("$config value is not *RequireExpression. got=%T", assignValue(2))
+	}
+	if !require.Once {
+		t.Errorf("require_once should set Once")
+	}
+	if _, ok := require.Path.(*InfixExpression); !ok {
+		t.Errorf("require path is not *InfixExpression. got=%T", require.Path)
+	}
+
+	stmt := program.Statements[3].(*ExpressionStatement)
+	printExpr, ok = stmt.Expression.(*PrintExpression)
+	if !ok {
+		t.Fatalf("statement 3 is not *PrintExpression. got=%T", stmt.Expression)
+	}
+	if printExpr.Value.String() != "(a . b)" {
+		t.Errorf("print operand wrong. got=%q", printExpr.Value.String())
+	}
+}
+
+func TestParsePropertyAssignmentAndReadonlyProperty(t *testing.T) {
+	input := `<?php
+class Point {
+    public readonly int $x;
+    protected ?\App\Point $next = null;
+
+    public function __construct($x) {
+        $this->x = $x;
+    }
+}
+?>`
+
+	l := New(input)
+	p := NewParser(l)
+	program := p.ParseProgram()
+
+	if len(p.Errors()) != 0 {
+		t.Errorf("parser has %d errors", len(p.Errors()))
+		for _, err := range p.Errors() {
+			t.Errorf("parser error: %q", err)
+		}
+		return
+	}
+
+	class, ok := program.Statements[0].(*ClassDeclaration)
+	if !ok {
+		t.Fatalf("program.Statements[0] is not *ClassDeclaration. got=%T",
+			program.Statements[0])
+	}
+
+	if len(class.Properties) != 2 {
+		t.Fatalf("class does not have 2 properties. got=%d", len(class.Properties))
+	}
+
+	x := class.Properties[0]
+	if !x.Readonly || x.Visibility != "public" || x.PropertyType.String() != "int" {
+		t.Errorf("property x wrong. got=%q", x.String())
+	}
+
+	next := class.Properties[1]
+	if next.Readonly || !next.PropertyType.Nullable || next.PropertyType.Name != "\\App\\Point" {
+		t.Errorf("property next wrong. got=%q", next.String())
+	}
+
+	body := class.Methods[0].Body
+	stmt := body.Statements[0].(*ExpressionStatement)
+	assign, ok := stmt.Expression.(*AssignmentExpression)
+	if !ok {
+		t.Fatalf("stmt.Expression is not *AssignmentExpression. got=%T", stmt.Expression)
+	}
+
+	if assign.Name != nil {
+		t.Errorf("property assignment should not set Name")
+	}
+	if _, ok := assign.Target.(*ObjectAccessExpression); !ok {
+		t.Errorf("assign.Target is not *ObjectAccessExpression. got=%T", assign.Target)
+	}
+	if assign.String() != "$this->x = $x" {
+		t.Errorf("assign.String() wrong. got=%q", assign.String())
+	}
+}
+
+func TestParseMultiplePropertyDeclaration(t *testing.T) {
+	input := `<?php
+class Point {
+    private $x, $y = 0;
+    public static int $count = 0, $limit;
+}
+?>`
+
+	l := New(input)
+	p := NewParser(l)
+	program := p.ParseProgram()
+
+	if len(p.Errors()) != 0 {
+		t.Errorf("parser has %d errors", len(p.Errors()))
+		for _, err := range p.Errors() {
+			t.Errorf("parser error: %q", err)
+		}
+		return
+	}
+
+	class, ok := program.Statements[0].(*ClassDeclaration)
+	if !ok {
+		t.Fatalf("program.Statements[0] is not *ClassDeclaration. got=%T", program.Statements[0])
+	}
+
+	tests := []struct {
+		name       string
+		visibility string
+		static     bool
+		value      string
+	}{
+		{"x", "private", false, ""},
+		{"y", "private", false, "0"},
+		{"count", "public", true, "0"},
+		{"limit", "public", true, ""},
+	}
+
+	if len(class.Properties) != len(tests) {
+		t.Fatalf("class.Properties does not contain %d properties. got=%d", len(tests), len(class.Properties))
+	}
+
+	for i, tt := range tests {
+		property := class.Properties[i]
+		if property.Name.Name != tt.name {
+			t.Errorf("property[%d].Name.Name not %q. got=%q", i, tt.name, property.Name.Name)
+		}
+		if property.Visibility != tt.visibility || property.Static != tt.static {
+			t.Errorf("property[%d] modifiers wrong. got visibility=%q static=%t", i, property.Visibility, property.Static)
+		}
+		value := ""
+		if property.Value != nil {
+			value = property.Value.String()
+		}
+		if value != tt.value {
+			t.Errorf("property[%d].Value not %q. got=%q", i, tt.value, value)
+		}
+	}
+
+	for _, property := range class.Properties[2:] {
+		if property.PropertyType == nil || property.PropertyType.String() != "int" {
+			t.Errorf("property $%s type not int. got=%v", property.Name.Name, property.PropertyType)
+		}
+	}
+}
+
+func TestParseEnumWithTraitUse(t *testing.T) {
+	input := `<?php
+enum Suit: string implements HasColor {
+    use HasLabel;
+
+    case Hearts = 'H';
+    case Spades = 'S';
+
+    public function color() {
+        return "Red";
+    }
+}
+?>`
+
+	l := New(input)
+	p := NewParser(l)
+	program := p.ParseProgram()
+
+	if len(p.Errors()) != 0 {
+		t.Errorf("parser has %d errors", len(p.Errors()))
+		for _, err := range p.Errors() {
+			t.Errorf("parser error: %q", err)
+		}
+		return
+	}
+
+	if len(program.Statements) != 1 {
+		t.Fatalf("program.Statements does not contain 1 statement. got=%d", len(program.Statements))
+	}
+
+	enum, ok := program.Statements[0].(*EnumDeclaration)
+	if !ok {
+		t.Fatalf("program.Statements[0] is not *EnumDeclaration. got=%T", program.Statements[0])
+	}
+
+	if enum.Name.Value != "Suit" {
+		t.Errorf("enum.Name.Value not 'Suit'. got=%q", enum.Name.Value)
+	}
+	if enum.BackingType == nil || enum.BackingType.Name != "string" {
+		t.Errorf("enum.BackingType wrong. got=%v", enum.BackingType)
+	}
+	if len(enum.Interfaces) != 1 || enum.Interfaces[0].Value != "HasColor" {
+		t.Errorf("enum.Interfaces wrong. got=%v", enum.Interfaces)
+	}
+
+	if len(enum.TraitUses) != 1 || len(enum.TraitUses[0].Traits) != 1 {
+		t.Fatalf("enum.TraitUses wrong. got=%v", enum.TraitUses)
+	}
+	if enum.TraitUses[0].Traits[0].Value != "HasLabel" {
+		t.Errorf("trait name not 'HasLabel'. got=%q", enum.TraitUses[0].Traits[0].Value)
+	}
+
+	if len(enum.Cases) != 2 {
+		t.Fatalf("enum.Cases does not contain 2 cases. got=%d", len(enum.Cases))
+	}
+	if enum.Cases[0].String() != "case Hearts = H;" {
+		t.Errorf("enum.Cases[0].String() wrong. got=%q", enum.Cases[0].String())
+	}
+
+	if len(enum.Methods) != 1 || enum.Methods[0].Name.Value != "color" {
+		t.Errorf("enum.Methods wrong. got=%v", enum.Methods)
+	}
+}
+
+func TestParseEnumUnexpectedMember(t *testing.T) {
+	input := `<?php
+enum Suit {
+    case Hearts;
+    public $label;
+    case Spades;
+}
+?>`
+
+	l := New(input)
+	p := NewParser(l)
+	program := p.ParseProgram()
+
+	if len(p.Errors()) != 1 || !strings.HasPrefix(p.Errors()[0], "unexpected '$label' in enum Suit at line 4") {
+		t.Fatalf("expected one error for the property. got=%q", p.Errors())
+	}
+
+	enum, ok := program.Statements[0].(*EnumDeclaration)
+	if !ok {
+		t.Fatalf("program.Statements[0] is not *EnumDeclaration. got=%T", program.Statements[0])
+	}
+	if len(enum.Cases) != 2 || enum.Cases[1].Name.Value != "Spades" {
+		t.Errorf("enum should keep case Spades. got=%s", enum.String())
+	}
+}
+
+func TestParseTraitConstants(t *testing.T) {
+	input := `<?php
+trait HasVersion {
+    const VERSION = "1.0";
+    protected const PREFIX = "v";
+
+    public $label;
+
+    public function version() {
+        return self::PREFIX . self::VERSION;
+    }
+}
+?>`
+
+	l := New(input)
+	p := NewParser(l)
+	program := p.ParseProgram()
+
+	if len(p.Errors()) != 0 {
+		t.Errorf("parser has %d errors", len(p.Errors()))
+		for _, err := range p.Errors() {
+			t.Errorf("parser error: %q", err)
+		}
+		return
+	}
+
+	if len(program.Statements) != 1 {
+		t.Fatalf("program.Statements does not contain 1 statement. got=%d", len(program.Statements))
+	}
+
+	trait, ok := program.Statements[0].(*TraitDeclaration)
+	if !ok {
+		t.Fatalf("program.Statements[0] is not *TraitDeclaration. got=%T", program.Statements[0])
+	}
+
+	if len(trait.Constants) != 2 {
+		t.Fatalf("trait.Constants does not contain 2 constants. got=%d", len(trait.Constants))
+	}
+
+	tests := []struct {
+		name       string
+		visibility string
+	}{
+		{"VERSION", "public"},
+		{"PREFIX", "protected"},
+	}
+	for i, tt := range tests {
+		constant := trait.Constants[i]
+		if constant.Name.Value != tt.name {
+			t.Errorf("constant[%d].Name.Value not %q. got=%q", i, tt.name, constant.Name.Value)
+		}
+		if constant.Visibility != tt.visibility {
+			t.Errorf("constant[%d].Visibility not %q. got=%q", i, tt.visibility, constant.Visibility)
+		}
+	}
+
+	if len(trait.Properties) != 1 || len(trait.Methods) != 1 {
+		t.Errorf("trait members wrong. got %d properties and %d methods", len(trait.Properties), len(trait.Methods))
+	}
+
+	semanticProgram, err := ParseWithSemantics(input, "version.php")
+	if err != nil {
+		t.Fatalf("Failed to parse with semantics: %v", err)
+	}
+	members := semanticProgram.SymbolTable.GetMembers("HasVersion")
+	for _, expected := range []string{"VERSION", "PREFIX"} {
+		found := false
+		for _, member := range members {
+			if member == expected {
+				found = true
+			}
+		}
+		if !found {
+			t.Errorf("trait constant %s not registered as a member. got=%v", expected, members)
+		}
+	}
+}
+
+func TestParseReportsEachErrorOnItsOwnLine(t *testing.T) {
+	program, err := Parse("<?php\n$a = ;\n$b = ;\n?>")
+	if err == nil {
+		t.Fatalf("expected an error, got program %q", program.String())
+	}
+	if program != nil {
+		t.Errorf("program should be nil when parsing fails")
+	}
+
+	lines := strings.Split(err.Error(), "\n")
+	if lines[0] != "parser errors:" {
+		t.Errorf("first line wrong. got=%q", lines[0])
+	}
+	if len(lines) < 3 {
+		t.Fatalf("expected one line per parser error, got %q", err.Error())
+	}
+	for _, line := range lines[1:] {
+		if line == "" || strings.Contains(line, "; ") {
+			t.Errorf("each error should be on its own line. got=%q", line)
+		}
+	}
+
+	if _, err := Parse("<?php echo 'ok'; ?>"); err != nil {
+		t.Errorf("valid input should parse without error. got=%v", err)
+	}
+}
+
+func TestParseBestEffort(t *testing.T) {
+	input := "<?php\n$a = 1;\n$b = ;\necho $a;\n?>"
+
+	program, err := ParseWithOptions(input, ParserOptions{BestEffort: true})
+	if err == nil {
+		t.Fatalf("expected an error for the broken statement")
+	}
+	if !strings.Contains(err.Error(), "line 3") {
+		t.Errorf("error should point at the broken statement. got=%q", err.Error())
+	}
+	if program == nil {
+		t.Fatalf("program should not be nil with BestEffort")
+	}
+
+	if len(program.Statements) != 2 {
+		t.Fatalf("expected the 2 good statements. got=%d", len(program.Statements))
+	}
+	if program.Statements[0].String() != "$a = 1" {
+		t.Errorf("first statement wrong. got=%q", program.Statements[0].String())
+	}
+	if _, ok := program.Statements[1].(*EchoStatement); !ok {
+		t.Errorf("second statement is not *EchoStatement. got=%T", program.Statements[1])
+	}
+
+	if program, _ := ParseWithOptions(input, ParserOptions{}); program != nil {
+		t.Errorf("program should be nil without BestEffort")
+	}
+}
+
+func TestParseArrowFunctions(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected string
+		static   bool
+		params   int
+	}{
+		{`<?php $f = fn($x) => $x * 2; ?>`, "fn($x) => ($x * 2)", false, 1},
+		{`<?php $f = static fn($a, $b) => $a + $b; ?>`, "static fn($a, $b) => ($a + $b)", true, 2},
+		{`<?php $f = fn() => 42; ?>`, "fn() => 42", false, 0},
+	}
+
+	for _, tt := range tests {
+		l := New(tt.input)
+		p := NewParser(l)
+		program := p.ParseProgram()
+
+		if len(p.Errors()) != 0 {
+			t.Errorf("parser has %d errors for %q", len(p.Errors()), tt.input)
+			for _, err := range p.Errors() {
+				t.Errorf("parser error: %q", err)
+			}
+			continue
+		}
+
+		stmt, ok := program.Statements[0].(*ExpressionStatement)
+		if !ok {
+			t.Fatalf("program.Statements[0] is not *ExpressionStatement. got=%T", program.Statements[0])
+		}
+		assign, ok := stmt.Expression.(*AssignmentExpression)
+		if !ok {
+			t.Fatalf("stmt.Expression is not *AssignmentExpression. got=%T", stmt.Expression)
+		}
+		arrow, ok := assign.Value.(*ArrowFunction)
+		if !ok {
+			t.Fatalf("assign.Value is not *ArrowFunction. got=%T", assign.Value)
+		}
+
+		if arrow.Static != tt.static {
+			t.Errorf("arrow.Static wrong for %q. got=%t", tt.input, arrow.Static)
+		}
+		if len(arrow.Parameters) != tt.params {
+			t.Errorf("arrow.Parameters wrong for %q. expected=%d, got=%d", tt.input, tt.params, len(arrow.Parameters))
+		}
+		if arrow.String() != tt.expected {
+			t.Errorf("arrow.String() wrong. expected=%q, got=%q", tt.expected, arrow.String())
+		}
+
+		data, err := ToJSON(arrow)
+		if err != nil {
+			t.Fatalf("ToJSON failed: %v", err)
+		}
+		if !strings.Contains(string(data), `"type": "ArrowFunction"`) {
+			t.Errorf("ToJSON output missing ArrowFunction type: %s", data)
+		}
+	}
+}
+
+func longConcatenation(terms int) string {
+	var b strings.Builder
+	b.WriteString("<?php $s = $v0")
+	for i := 1; i < terms; i++ {
+		b.WriteString(" . $v")
+		b.WriteString(strconv.Itoa(i))
+	}
+	b.WriteString("; ?>")
+	return b.String()
+}
+
+func TestParseLongConcatenationChain(t *testing.T) {
+	const terms = 1000
+
+	l := New(longConcatenation(terms))
+	p := NewParser(l)
+	p.MaxDepth = 10
+	program := p.ParseProgram()
+
+	if len(p.Errors()) != 0 {
+		t.Errorf("parser has %d errors", len(p.Errors()))
+		for _, err := range p.Errors() {
+			t.Errorf("parser error: %q", err)
+		}
+		return
+	}
+
+	stmt := program.Statements[0].(*ExpressionStatement)
+	assign, ok := stmt.Expression.(*AssignmentExpression)
+	if !ok {
+		t.Fatalf("stmt.Expression is not *AssignmentExpression. got=%T", stmt.Expression)
+	}
+
+	// The chain is left-associative, so the last term is the outermost
+	// right operand and the first term sits at the bottom of the Left spine
+	expr := assign.Value
+	for i := terms - 1; i > 0; i-- {
+		infix, ok := expr.(*InfixExpression)
+		if !ok {
+			t.Fatalf("term %d: expr is not *InfixExpression. got=%T", i, expr)
+		}
+		if infix.Operator != "." {
+			t.Fatalf("term %d: operator is not '.'. got=%q", i, infix.Operator)
+		}
+		right, ok := infix.Right.(*Variable)
+		if !ok || right.Name != "v"+strconv.Itoa(i) {
+			t.Fatalf("term %d: right operand wrong. got=%v", i, infix.Right)
+		}
+		expr = infix.Left
+	}
+
+	if first, ok := expr.(*Variable); !ok || first.Name != "v0" {
+		t.Errorf("first term wrong. got=%v", expr)
+	}
+}
+
+func TestParseMaxDepth(t *testing.T) {
+	nested := "<?php $x = " + strings.Repeat("(", 20) + "1" + strings.Repeat(")", 20) + "; ?>"
+
+	p := NewParser(New(nested))
+	p.MaxDepth = 10
+	p.ParseProgram()
+
+	if len(p.Errors()) == 0 {
+		t.Fatalf("expected a depth error for 20 nested parentheses")
+	}
+	if !strings.Contains(p.Errors()[0], "exceeds maximum depth of 10") {
+		t.Errorf("unexpected first error: %q", p.Errors()[0])
+	}
+
+	p = NewParser(New(nested))
+	p.ParseProgram()
+	if len(p.Errors()) != 0 {
+		t.Errorf("nesting should be unlimited by default. got=%v", p.Errors())
+	}
+}
+
+func BenchmarkParseLongConcatenation(b *testing.B) {
+	input := longConcatenation(1000)
+
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		p := NewParser(New(input))
+		p.ParseProgram()
+		if len(p.Errors()) != 0 {
+			b.Fatalf("parser errors: %v", p.Errors())
+		}
+	}
+}
+
+func TestParseMatchExpression(t *testing.T) {
+	input := `<?php $r = match($x) { 1, 2 => "a", default => "b" }; ?>`
+
+	l := New(input)
+	p := NewParser(l)
+	program := p.ParseProgram()
+
+	if len(p.Errors()) != 0 {
+		t.Errorf("parser has %d errors", len(p.Errors()))
+		for _, err := range p.Errors() {
+			t.Errorf("parser error: %q", err)
+		}
+		return
+	}
+
+	stmt := program.Statements[0].(*ExpressionStatement)
+	assign, ok := stmt.Expression.(*AssignmentExpression)
+	if !ok {
+		t.Fatalf("stmt.Expression is not *AssignmentExpression. got=%T", stmt.Expression)
+	}
+	match, ok := assign.Value.(*MatchExpression)
+	if !ok {
+		t.Fatalf("assign.Value is not *MatchExpression. got=%T", assign.Value)
+	}
+
+	if match.Subject.String() != "$x" {
+		t.Errorf("match.Subject wrong. got=%q", match.Subject.String())
+	}
+	if len(match.Arms) != 2 {
+		t.Fatalf("match.Arms does not contain 2 arms. got=%d", len(match.Arms))
+	}
+
+	first := match.Arms[0]
+	if first.IsDefault() || len(first.Conditions) != 2 {
+		t.Fatalf("first arm should have 2 conditions. got=%q", first.String())
+	}
+	testIntegerLiteral(t, first.Conditions[0], 1)
+	testIntegerLiteral(t, first.Conditions[1], 2)
+	if first.Body.String() != "a" {
+		t.Errorf("first arm body wrong. got=%q", first.Body.String())
+	}
+
+	if !match.Arms[1].IsDefault() {
+		t.Errorf("second arm should be the default arm. got=%q", match.Arms[1].String())
+	}
+
+	if match.String() != "match ($x) { 1, 2 => a, default => b }" {
+		t.Errorf("match.String() wrong. got=%q", match.String())
+	}
+
+	data, err := ToJSON(match)
+	if err != nil {
+		t.Fatalf("ToJSON failed: %v", err)
+	}
+	for _, want := range []string{`"type": "MatchExpression"`, `"subject"`, `"arms"`, `"conditions"`} {
+		if !strings.Contains(string(data), want) {
+			t.Errorf("ToJSON output missing %s: %s", want, data)
+		}
+	}
+}
+
+func TestParseMatchWithoutSubject(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected string
+	}{
+		{"<?php\n$r = match {\n    1 => 'one',\n};", "match expression requires a subject in parentheses at line 2, column 6"},
+		{"<?php\n$r = match() {\n    1 => 'one',\n};", "match expression subject cannot be empty at line 2, column 6"},
+	}
+
+	for _, tt := range tests {
+		p := NewParser(New(tt.input))
+		program := p.ParseProgram()
+
+		if len(p.Errors()) != 1 {
+			t.Errorf("input %q: expected exactly 1 error. got=%q", tt.input, p.Errors())
+			continue
+		}
+		if p.Errors()[0] != tt.expected {
+			t.Errorf("input %q: wrong error message. got=%q", tt.input, p.Errors()[0])
+		}
+
+		// The malformed match is dropped rather than kept without a subject
+		Walk(program, func(node Node) bool {
+			if _, ok := node.(*MatchExpression); ok {
+				t.Errorf("input %q: malformed match kept in the AST", tt.input)
+			}
+			return true
+		})
+	}
+}
+
+func TestParseErrorSuppressionWrapsPostfixChain(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected string
+		operand  string
+	}{
+		{`<?php $v = @$a['k']; ?>`, "$v = (@($a[k]))", "*gophpparser.IndexExpression"},
+		{`<?php $v = @$o->m(); ?>`, "$v = (@$o->m())", "*gophpparser.CallExpression"},
+		{`<?php $v = @$o->items[0] + 1; ?>`, "$v = ((@($o->items[0])) + 1)", "*gophpparser.IndexExpression"},
+	}
+
+	for _, tt := range tests {
+		l := New(tt.input)
+		p := NewParser(l)
+		program := p.ParseProgram()
+
+		if len(p.Errors()) != 0 {
+			t.Errorf("parser has %d errors for %q", len(p.Errors()), tt.input)
+			for _, err := range p.Errors() {
+				t.Errorf("parser error: %q", err)
+			}
+			continue
+		}
+
+		if len(program.Statements) != 1 {
+			t.Fatalf("program.Statements does not contain 1 statement for %q. got=%d", tt.input, len(program.Statements))
+		}
+		if program.String() != tt.expected {
+			t.Errorf("program.String() wrong. expected=%q, got=%q", tt.expected, program.String())
+		}
+
+		stmt := program.Statements[0].(*ExpressionStatement)
+		assign := stmt.Expression.(*AssignmentExpression)
+
+		var suppress *PrefixExpression
+		switch v := assign.Value.(type) {
+		case *PrefixExpression:
+			suppress = v
+		case *InfixExpression:
+			suppress, _ = v.Left.(*PrefixExpression)
+		}
+		if suppress == nil || suppress.Operator != "@" {
+			t.Fatalf("expected an @ prefix expression for %q. got=%T", tt.input, assign.Value)
+		}
+		if got := fmt.Sprintf("%T", suppress.Right); got != tt.operand {
+			t.Errorf("@ should wrap the whole chain in %q. expected=%s, got=%s", tt.input, tt.operand, got)
+		}
+	}
+}
+
+func TestParseSwitchStatement(t *testing.T) {
+	input := `<?php
+switch ($x) {
+    case 1:
+    case 2:
+        echo "low";
+        break;
+    case 3;
+        echo "three";
+    default:
+        echo "other";
+}
+?>`
+
+	l := New(input)
+	p := NewParser(l)
+	program := p.ParseProgram()
+
+	if len(p.Errors()) != 0 {
+		t.Errorf("parser has %d errors", len(p.Errors()))
+		for _, err := range p.Errors() {
+			t.Errorf("parser error: %q", err)
+		}
+		return
+	}
+
+	if len(program.Statements) != 1 {
+		t.Fatalf("program.Statements does not contain 1 statement. got=%d", len(program.Statements))
+	}
+
+	stmt, ok := program.Statements[0].(*SwitchStatement)
+	if !ok {
+		t.Fatalf("program.Statements[0] is not *SwitchStatement. got=%T", program.Statements[0])
+	}
+
+	if stmt.Subject.String() != "$x" {
+		t.Errorf("stmt.Subject wrong. got=%q", stmt.Subject.String())
+	}
+	if len(stmt.Cases) != 4 {
+		t.Fatalf("stmt.Cases does not contain 4 cases. got=%d", len(stmt.Cases))
+	}
+
+	tests := []struct {
+		condition string
+		bodyLen   int
+	}{
+		{"1", 0},
+		{"2", 2},
+		{"3", 1},
+		{"", 1},
+	}
+
+	for i, tt := range tests {
+		switchCase := stmt.Cases[i]
+		if tt.condition == "" {
+			if !switchCase.IsDefault() {
+				t.Errorf("case %d should be the default label. got=%q", i, switchCase.String())
+			}
+		} else if switchCase.IsDefault() || switchCase.Condition.String() != tt.condition {
+			t.Errorf("case %d condition wrong. expected=%q, got=%q", i, tt.condition, switchCase.String())
+		}
+		if len(switchCase.Body) != tt.bodyLen {
+			t.Errorf("case %d body length wrong. expected=%d, got=%d", i, tt.bodyLen, len(switchCase.Body))
+		}
+	}
+
+	if _, ok := stmt.Cases[1].Body[1].(*BreakStatement); !ok {
+		t.Errorf("case 2 should end with *BreakStatement. got=%T", stmt.Cases[1].Body[1])
+	}
+
+	data, err := ToJSON(stmt)
+	if err != nil {
+		t.Fatalf("ToJSON failed: %v", err)
+	}
+	for _, want := range []string{`"type": "SwitchStatement"`, `"subject"`, `"cases"`} {
+		if !strings.Contains(string(data), want) {
+			t.Errorf("ToJSON output missing %s: %s", want, data)
+		}
+	}
+}
+
+func TestParseSwitchKeywordsAsMemberNames(t *testing.T) {
+	input := `<?php
+class A {
+    const CASE = 1;
+    public function default() {}
+    public static function switch() {}
+    public function case() { return $this->default(); }
+}
+interface B {
+    public function default();
+}
+$a->default();
+A::switch();
+?>`
+
+	l := New(input)
+	p := NewParser(l)
+	program := p.ParseProgram()
+
+	if len(p.Errors()) != 0 {
+		t.Errorf("parser has %d errors", len(p.Errors()))
+		for _, err := range p.Errors() {
+			t.Errorf("parser error: %q", err)
+		}
+		return
+	}
+
+	class, ok := program.Statements[0].(*ClassDeclaration)
+	if !ok {
+		t.Fatalf("program.Statements[0] is not *ClassDeclaration. got=%T", program.Statements[0])
+	}
+	if len(class.Constants) != 1 || class.Constants[0].Name.Value != "CASE" {
+		t.Errorf("class constant not parsed. got=%v", class.Constants)
+	}
+
+	expected := []string{"default", "switch", "case"}
+	if len(class.Methods) != len(expected) {
+		t.Fatalf("class.Methods does not contain %d methods. got=%d", len(expected), len(class.Methods))
+	}
+	for i, name := range expected {
+		if class.Methods[i].Name.Value != name {
+			t.Errorf("method %d name wrong. expected=%q, got=%q", i, name, class.Methods[i].Name.Value)
+		}
+	}
+
+	iface, ok := program.Statements[1].(*InterfaceDeclaration)
+	if !ok {
+		t.Fatalf("program.Statements[1] is not *InterfaceDeclaration. got=%T", program.Statements[1])
+	}
+	if len(iface.Methods) != 1 || iface.Methods[0].Name.Value != "default" {
+		t.Errorf("interface method not parsed. got=%v", iface.Methods)
+	}
+}
+
+func TestParseInvalidMemberNames(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected string
+	}{
+		{`<?php $obj->123; ?>`, "expected property name after '->', got INT instead"},
+		{`<?php $obj?->"x"; ?>`, "expected property name after '?->', got STRING instead"},
+		{`<?php Foo::+; ?>`, "expected property name after '::', got PLUS instead"},
+	}
+
+	for _, tt := range tests {
+		p := NewParser(New(tt.input))
+		p.ParseProgram()
+
+		if len(p.Errors()) == 0 {
+			t.Errorf("expected an error for %q", tt.input)
+			continue
+		}
+		if p.Errors()[0] != tt.expected {
+			t.Errorf("wrong error for %q. expected=%q, got=%q", tt.input, tt.expected, p.Errors()[0])
+		}
+	}
+
+	valid := `<?php $a = $obj->name; $b = $obj->$dynamic; $c = Foo::$count; $d = Foo::BAR; ?>`
+	p := NewParser(New(valid))
+	p.ParseProgram()
+	if len(p.Errors()) != 0 {
+		t.Errorf("valid member names should parse. got=%v", p.Errors())
+	}
+}
+
+func TestParseForeachWithDestructuredValue(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected string
+	}{
+		{`<?php foreach ($m as $k => [$a, $b]) { echo $k; } ?>`, "foreach ($m as $k => [$a, $b]) {echo $k;}"},
+		{`<?php foreach ($m as list($a, $b)) { echo $a; } ?>`, "foreach ($m as [$a, $b]) {echo $a;}"},
+	}
+
+	for _, tt := range tests {
+		l := New(tt.input)
+		p := NewParser(l)
+		program := p.ParseProgram()
+
+		if len(p.Errors()) != 0 {
+			t.Errorf("parser has %d errors for %q", len(p.Errors()), tt.input)
+			for _, err := range p.Errors() {
+				t.Errorf("parser error: %q", err)
+			}
+			continue
+		}
+
+		stmt, ok := program.Statements[0].(*ForeachStatement)
+		if !ok {
+			t.Fatalf("program.Statements[0] is not *ForeachStatement. got=%T", program.Statements[0])
+		}
+		if stmt.Value != nil {
+			t.Errorf("stmt.Value should be nil for a destructured value. got=%q", stmt.Value.String())
+		}
+		pattern, ok := stmt.ValuePattern.(*ArrayLiteral)
+		if !ok {
+			t.Fatalf("stmt.ValuePattern is not *ArrayLiteral. got=%T", stmt.ValuePattern)
+		}
+		if len(pattern.Elements) != 2 {
+			t.Errorf("pattern does not contain 2 elements. got=%d", len(pattern.Elements))
+		}
+		if stmt.String() != tt.expected {
+			t.Errorf("stmt.String() wrong. expected=%q, got=%q", tt.expected, stmt.String())
+		}
+	}
+}
+
+func TestParseIfWithElseIfBranches(t *testing.T) {
+	input := `<?php
+if ($x < 0) {
+    echo "negative";
+} elseif ($x == 0) {
+    echo "zero";
+} else if ($x < 10) {
+    echo "small";
+} else {
+    echo "large";
+}
+?>`
+
+	l := New(input)
+	p := NewParser(l)
+	program := p.ParseProgram()
+
+	if len(p.Errors()) != 0 {
+		t.Errorf("parser has %d errors", len(p.Errors()))
+		for _, err := range p.Errors() {
+			t.Errorf("parser error: %q", err)
+		}
+		return
+	}
+
+	if len(program.Statements) != 1 {
+		t.Fatalf("program.Statements does not contain 1 statement. got=%d", len(program.Statements))
+	}
+
+	stmt, ok := program.Statements[0].(*IfStatement)
+	if !ok {
+		t.Fatalf("program.Statements[0] is not *IfStatement. got=%T", program.Statements[0])
+	}
+
+	if len(stmt.ElseIfs) != 2 {
+		t.Fatalf("stmt.ElseIfs does not contain 2 branches. got=%d", len(stmt.ElseIfs))
+	}
+
+	expected := []string{"($x == 0)", "($x < 10)"}
+	for i, elseIf := range stmt.ElseIfs {
+		if elseIf.Condition.String() != expected[i] {
+			t.Errorf("elseif %d condition wrong. expected=%q, got=%q", i, expected[i], elseIf.Condition.String())
+		}
+		if len(elseIf.Consequence.Statements) != 1 {
+			t.Errorf("elseif %d consequence does not contain 1 statement. got=%d", i, len(elseIf.Consequence.Statements))
+		}
+	}
+
+	if stmt.Alternative == nil || len(stmt.Alternative.Statements) != 1 {
+		t.Fatalf("stmt.Alternative should contain the trailing else branch")
+	}
+
+	expectedString := "if($x < 0) {echo negative;}elseif($x == 0) {echo zero;}elseif($x < 10) {echo small;}else {echo large;}"
+	if stmt.String() != expectedString {
+		t.Errorf("stmt.String() wrong. expected=%q, got=%q", expectedString, stmt.String())
+	}
+
+	data, err := ToJSON(stmt)
+	if err != nil {
+		t.Fatalf("ToJSON failed: %v", err)
+	}
+	if !strings.Contains(string(data), `"elseifs"`) {
+		t.Errorf("ToJSON output missing elseifs: %s", data)
+	}
+}
+
+func TestParseTypedParameters(t *testing.T) {
+	input := `<?php
+function f(string $a, $b, int $c, \App\User $user, array $items) {
+    return $a;
+}
+?>`
+
+	l := New(input)
+	p := NewParser(l)
+	program := p.ParseProgram()
+
+	if len(p.Errors()) != 0 {
+		t.Errorf("parser has %d errors", len(p.Errors()))
+		for _, err := range p.Errors() {
+			t.Errorf("parser error: %q", err)
+		}
+		return
+	}
+
+	fn, ok := program.Statements[0].(*FunctionDeclaration)
+	if !ok {
+		t.Fatalf("program.Statements[0] is not *FunctionDeclaration. got=%T", program.Statements[0])
+	}
+
+	tests := []struct {
+		name     string
+		typeName string
+	}{
+		{"a", "string"},
+		{"b", ""},
+		{"c", "int"},
+		{"user", "\\App\\User"},
+		{"items", "array"},
+	}
+
+	if len(fn.Parameters) != len(tests) {
+		t.Fatalf("function parameters wrong. want %d, got=%d", len(tests), len(fn.Parameters))
+	}
+
+	for i, tt := range tests {
+		param := fn.Parameters[i]
+		if param.Name != tt.name {
+			t.Errorf("param %d name wrong. expected=%q, got=%q", i, tt.name, param.Name)
+		}
+		if tt.typeName == "" {
+			if param.TypeHint != nil {
+				t.Errorf("param %d should be untyped. got=%q", i, param.TypeHint.String())
+			}
+			continue
+		}
+		if param.TypeHint == nil || param.TypeHint.Name != tt.typeName {
+			t.Errorf("param %d type wrong. expected=%q, got=%v", i, tt.typeName, param.TypeHint)
+		}
+	}
+
+	expected := "function f(string $a, $b, int $c, \\App\\User $user, array $items) "
+	if !strings.HasPrefix(fn.String(), expected) {
+		t.Errorf("fn.String() wrong. expected prefix %q, got=%q", expected, fn.String())
+	}
+
+	data, err := ToJSON(fn)
+	if err != nil {
+		t.Fatalf("ToJSON failed: %v", err)
+	}
+	if !strings.Contains(string(data), `"type_hint"`) || !strings.Contains(string(data), `"name": "string"`) {
+		t.Errorf("ToJSON output missing parameter types: %s", data)
+	}
+}
+
+func TestUnexpectedTokenErrorNamesToken(t *testing.T) {
+	input := "<?php\n$a = 1;\n\n$b = );\n?>"
+
+	p := NewParser(New(input))
+	p.ParseProgram()
+
+	if len(p.Errors()) == 0 {
+		t.Fatalf("expected an error for the stray ')'")
+	}
+	if p.Errors()[0] != "unexpected ')' at line 4, column 6" {
+		t.Errorf("wrong error message. got=%q", p.Errors()[0])
+	}
+
+	structured := p.StructuredErrors()
+	if len(structured) == 0 {
+		t.Fatalf("expected a structured error")
+	}
+	if structured[0].Message != "unexpected ')'" || structured[0].Line != 4 || structured[0].Column != 6 {
+		t.Errorf("structured error wrong. got=%+v", structured[0])
+	}
+
+	debug := DebugParsePHP(input)
+	if len(debug.MissingPrefixFuncs) != 1 || debug.MissingPrefixFuncs[0] != "RPAREN" {
+		t.Errorf("debug report should name RPAREN as the missing prefix. got=%v", debug.MissingPrefixFuncs)
+	}
+}
+
+func TestMissingParenErrorPosition(t *testing.T) {
+	input := "<?php\n$x = 1;\nif ($x > 1 {\n    echo $x;\n}\n"
+
+	p := NewParser(New(input))
+	p.ParseProgram()
+
+	structured := p.StructuredErrors()
+	if len(structured) == 0 {
+		t.Fatalf("expected an error for the missing ')'")
+	}
+	first := structured[0]
+	if first.Message != "expected next token to be RPAREN, got LBRACE instead" {
+		t.Errorf("wrong message. got=%q", first.Message)
+	}
+	if first.Line != 3 || first.Column != 12 {
+		t.Errorf("wrong position. expected=3:12, got=%d:%d", first.Line, first.Column)
+	}
+
+	if len(p.Errors()) != len(structured) {
+		t.Fatalf("Errors() and StructuredErrors() disagree. got %d and %d", len(p.Errors()), len(structured))
+	}
+	// Errors() keeps reporting this message without its position
+	if p.Errors()[0] != "expected next token to be RPAREN, got LBRACE instead" {
+		t.Errorf("wrong formatted error. got=%q", p.Errors()[0])
+	}
+}
+
+func TestParseRecoversAfterSyntaxError(t *testing.T) {
+	input := `<?php
+$before = 1;
+if ($before + ) {
+    echo "skipped";
+}
+$after = 2;
+function f() {
+    $x = (1 + ;
+    return $x;
+}
+echo $after;
+?>`
+
+	l := New(input)
+	p := NewParser(l)
+	program := p.ParseProgram()
+
+	if len(p.Errors()) != 2 {
+		t.Fatalf("expected one error per broken statement. got=%q", p.Errors())
+	}
+	if p.Errors()[0] != "unexpected ')' at line 3, column 15" || p.Errors()[1] != "unexpected ';' at line 8, column 15" {
+		t.Errorf("wrong errors. got=%q", p.Errors())
+	}
+
+	expected := []string{"ExpressionStatement", "IfStatement", "ExpressionStatement", "FunctionDeclaration", "EchoStatement"}
+	if len(program.Statements) != len(expected) {
+		t.Fatalf("program.Statements does not contain %d statements. got=%d", len(expected), len(program.Statements))
+	}
+	for i, typ := range expected {
+		if got := program.Statements[i].Type(); got != typ {
+			t.Errorf("program.Statements[%d] is not %s. got=%s", i, typ, got)
+		}
+	}
+
+	if got := program.Statements[0].String(); got != "$before = 1" {
+		t.Errorf("statement before the error wrong. got=%q", got)
+	}
+	if got := program.Statements[2].String(); got != "$after = 2" {
+		t.Errorf("statement after the error wrong. got=%q", got)
+	}
+
+	fn := program.Statements[3].(*FunctionDeclaration)
+	if len(fn.Body.Statements) != 2 {
+		t.Fatalf("function body does not contain 2 statements. got=%d", len(fn.Body.Statements))
+	}
+	if got := fn.Body.Statements[1].String(); got != "return $x;" {
+		t.Errorf("statement after the error in the body wrong. got=%q", got)
+	}
+
+	program, err := ParseWithOptions(input, ParserOptions{BestEffort: true})
+	if err == nil {
+		t.Fatalf("expected an error from ParseWithOptions")
+	}
+	if len(program.Statements) != 3 {
+		t.Errorf("best effort should keep the 3 statements without errors. got=%d", len(program.Statements))
+	}
+}
+
+func TestParseRecoversInsideClassBodies(t *testing.T) {
+	input := `<?php
+class A {
+    public function f( { }
+    public $b = );
+    public function g() { return 1; }
+}
+interface I {
+    public function f(;
+    public function g();
+}
+trait T {
+    public $a = );
+    public function g() { return 1; }
+}
+enum E {
+    case A = ;
+    case B;
+    public function g() { return 1; }
+}
+?>`
+
+	l := New(input)
+	p := NewParser(l)
+	program := p.ParseProgram()
+
+	expectedErrors := []string{
+		"expected parameter variable, got LBRACE instead",
+		"unexpected ')' at line 4, column 17",
+		"expected parameter variable, got SEMICOLON instead",
+		"unexpected ')' at line 12, column 17",
+		"unexpected ';' at line 16, column 14",
+	}
+	if len(p.Errors()) != len(expectedErrors) {
+		t.Fatalf("expected one error per broken member. got=%q", p.Errors())
+	}
+	for i, expected := range expectedErrors {
+		if p.Errors()[i] != expected {
+			t.Errorf("error %d wrong. expected=%q, got=%q", i, expected, p.Errors()[i])
+		}
+	}
+
+	if len(program.Statements) != 4 {
+		t.Fatalf("program.Statements does not contain 4 statements. got=%d", len(program.Statements))
+	}
+
+	class, ok := program.Statements[0].(*ClassDeclaration)
+	if !ok {
+		t.Fatalf("program.Statements[0] is not *ClassDeclaration. got=%T", program.Statements[0])
+	}
+	if len(class.Methods) != 1 || class.Methods[0].Name.Value != "g" {
+		t.Errorf("class should keep method g. got=%s", class.String())
+	}
+
+	iface := program.Statements[1].(*InterfaceDeclaration)
+	if len(iface.Methods) != 2 || iface.Methods[1].Name.Value != "g" {
+		t.Errorf("interface should keep method g. got=%s", iface.String())
+	}
+
+	trait := program.Statements[2].(*TraitDeclaration)
+	if len(trait.Methods) != 1 || trait.Methods[0].Name.Value != "g" {
+		t.Errorf("trait should keep method g. got=%s", trait.String())
+	}
+
+	enum := program.Statements[3].(*EnumDeclaration)
+	if len(enum.Cases) != 2 || len(enum.Methods) != 1 {
+		t.Errorf("enum should keep case B and method g. got=%s", enum.String())
+	}
+}
+
+func TestParseNullableTypeHints(t *testing.T) {
+	input := `<?php
+function f(?int $x, ?\App\User $user): ?string {
+    return $x ? "set" : null;
+}
+?>`
+
+	l := New(input)
+	p := NewParser(l)
+	program := p.ParseProgram()
+
+	if len(p.Errors()) != 0 {
+		t.Errorf("parser has %d errors", len(p.Errors()))
+		for _, err := range p.Errors() {
+			t.Errorf("parser error: %q", err)
+		}
+		return
+	}
+
+	fn, ok := program.Statements[0].(*FunctionDeclaration)
+	if !ok {
+		t.Fatalf("program.Statements[0] is not *FunctionDeclaration. got=%T", program.Statements[0])
+	}
+
+	expected := []string{"int", "\\App\\User"}
+	for i, name := range expected {
+		hint := fn.Parameters[i].TypeHint
+		if hint == nil || !hint.Nullable || hint.Name != name {
+			t.Errorf("param %d should be nullable %s. got=%v", i, name, hint)
+		}
+	}
+
+	returnType, ok := fn.ReturnType.(*TypeHint)
+	if !ok {
+		t.Fatalf("fn.ReturnType is not *TypeHint. got=%T", fn.ReturnType)
+	}
+	if !returnType.Nullable || returnType.Name != "string" {
+		t.Errorf("return type should be ?string. got=%q", returnType.String())
+	}
+
+	// The ? in the body is still a ternary
+	ret := fn.Body.Statements[0].(*ReturnStatement)
+	if _, ok := ret.ReturnValue.(*TernaryExpression); !ok {
+		t.Errorf("return value is not *TernaryExpression. got=%T", ret.ReturnValue)
+	}
+
+	data, err := ToJSON(fn)
+	if err != nil {
+		t.Fatalf("ToJSON failed: %v", err)
+	}
+	if strings.Count(string(data), `"nullable": true`) != 3 {
+		t.Errorf("ToJSON output should flag 3 nullable types: %s", data)
+	}
+}
+
+func TestParseTrailingCommaInParameterLists(t *testing.T) {
+	input := `<?php
+function f($a, int $b,) {}
+class Foo {
+    public function bar($x,) {}
+}
+$fn = function ($y,) use ($a, $b,) {};
+?>`
+
+	l := New(input)
+	p := NewParser(l)
+	program := p.ParseProgram()
+
+	if len(p.Errors()) != 0 {
+		t.Errorf("parser has %d errors", len(p.Errors()))
+		for _, err := range p.Errors() {
+			t.Errorf("parser error: %q", err)
+		}
+		return
+	}
+
+	if len(program.Statements) != 3 {
+		t.Fatalf("program.Statements does not contain 3 statements. got=%d", len(program.Statements))
+	}
+
+	fn, ok := program.Statements[0].(*FunctionDeclaration)
+	if !ok {
+		t.Fatalf("program.Statements[0] is not *FunctionDeclaration. got=%T", program.Statements[0])
+	}
+	if len(fn.Parameters) != 2 {
+		t.Errorf("function should have 2 parameters. got=%d", len(fn.Parameters))
+	}
+
+	class, ok := program.Statements[1].(*ClassDeclaration)
+	if !ok {
+		t.Fatalf("program.Statements[1] is not *ClassDeclaration. got=%T", program.Statements[1])
+	}
+	if len(class.Methods) != 1 {
+		t.Fatalf("class should have 1 method. got=%d", len(class.Methods))
+	}
+	method := class.Methods[0]
+	if len(method.Parameters) != 1 {
+		t.Errorf("method should have 1 parameter. got=%d", len(method.Parameters))
+	}
+
+	stmt := program.Statements[2].(*ExpressionStatement)
+	assign, ok := stmt.Expression.(*AssignmentExpression)
+	if !ok {
+		t.Fatalf("stmt.Expression is not *AssignmentExpression. got=%T", stmt.Expression)
+	}
+	closure, ok := assign.Value.(*AnonymousFunction)
+	if !ok {
+		t.Fatalf("assign.Value is not *AnonymousFunction. got=%T", assign.Value)
+	}
+	if len(closure.Parameters) != 1 {
+		t.Errorf("closure should have 1 parameter. got=%d", len(closure.Parameters))
+	}
+	if len(closure.UseClause) != 2 {
+		t.Errorf("closure should capture 2 variables. got=%d", len(closure.UseClause))
+	}
+}
+
+func TestParseReturnTypes(t *testing.T) {
+	tests := []struct {
+		input          string
+		expectedString string
+		expectedJSON   string
+	}{
+		{"<?php function f(): int {} ?>", "function f(): int {}", `"name": "int"`},
+		{"<?php function f(): ?Foo {} ?>", "function f(): ?Foo {}", `"nullable": true`},
+		{"<?php function f(): \\Foo\\Bar {} ?>", "function f(): \\Foo\\Bar {}", `"name": "\\Foo\\Bar"`},
+		{"<?php class C { public function m(): ?string {} } ?>", "public function m(): ?string {}", `"nullable": true`},
+	}
+
+	for _, tt := range tests {
+		l := New(tt.input)
+		p := NewParser(l)
+		program := p.ParseProgram()
+
+		if len(p.Errors()) != 0 {
+			t.Errorf("parser has %d errors for %q", len(p.Errors()), tt.input)
+			for _, err := range p.Errors() {
+				t.Errorf("parser error: %q", err)
+			}
+			continue
+		}
+
+		var node Node = program.Statements[0]
+		if class, ok := node.(*ClassDeclaration); ok {
+			node = class.Methods[0]
+		}
+
+		if !strings.Contains(node.String(), tt.expectedString) {
+			t.Errorf("String() wrong. expected to contain %q, got=%q", tt.expectedString, node.String())
+		}
+
+		data, err := ToJSON(node)
+		if err != nil {
+			t.Fatalf("ToJSON failed: %v", err)
+		}
+		if !strings.Contains(string(data), `"return_type"`) || !strings.Contains(string(data), tt.expectedJSON) {
+			t.Errorf("ToJSON output missing return type %s: %s", tt.expectedJSON, data)
+		}
+	}
+}
+
+func TestParseDefaultParameterValues(t *testing.T) {
+	input := `<?php
+function f($a = 10, string $b = "x", array $c = [1, 2], $d = PHP_EOL, $e) {}
+?>`
+
+	l := New(input)
+	p := NewParser(l)
+	program := p.ParseProgram()
+
+	if len(p.Errors()) != 0 {
+		t.Errorf("parser has %d errors", len(p.Errors()))
+		for _, err := range p.Errors() {
+			t.Errorf("parser error: %q", err)
+		}
+		return
+	}
+
+	fn, ok := program.Statements[0].(*FunctionDeclaration)
+	if !ok {
+		t.Fatalf("program.Statements[0] is not *FunctionDeclaration. got=%T", program.Statements[0])
+	}
+	if len(fn.Parameters) != 5 {
+		t.Fatalf("function should have 5 parameters. got=%d", len(fn.Parameters))
+	}
+
+	if !testIntegerLiteral(t, fn.Parameters[0].Default, 10) {
+		return
+	}
+	if str, ok := fn.Parameters[1].Default.(*StringLiteral); !ok || str.Value != "x" {
+		t.Errorf("param 1 default is not \"x\". got=%T (%+v)", fn.Parameters[1].Default, fn.Parameters[1].Default)
+	}
+	if array, ok := fn.Parameters[2].Default.(*ArrayLiteral); !ok || len(array.Elements) != 2 {
+		t.Errorf("param 2 default is not a 2-element *ArrayLiteral. got=%T", fn.Parameters[2].Default)
+	}
+	if _, ok := fn.Parameters[3].Default.(*Identifier); !ok {
+		t.Errorf("param 3 default is not *Identifier. got=%T", fn.Parameters[3].Default)
+	}
+	if fn.Parameters[4].Default != nil {
+		t.Errorf("param 4 should have no default. got=%T", fn.Parameters[4].Default)
+	}
+
+	if fn.Parameters[0].String() != "$a = 10" {
+		t.Errorf("param 0 String() wrong. got=%q", fn.Parameters[0].String())
+	}
+	if fn.Parameters[2].String() != "array $c = [1, 2]" {
+		t.Errorf("param 2 String() wrong. got=%q", fn.Parameters[2].String())
+	}
+
+	data, err := ToJSON(fn)
+	if err != nil {
+		t.Fatalf("ToJSON failed: %v", err)
+	}
+	if strings.Count(string(data), `"default"`) != 4 {
+		t.Errorf("ToJSON output should include 4 defaults: %s", data)
+	}
+}
+
+func TestParseClosuresAsArrayValues(t *testing.T) {
+	input := `<?php
+$routes = ['GET' => function() { return 1; }, 'POST' => fn() => 1, 'PUT' => static fn($x) => $x];
+?>`
+
+	l := New(input)
+	p := NewParser(l)
+	program := p.ParseProgram()
+
+	if len(p.Errors()) != 0 {
+		t.Errorf("parser has %d errors", len(p.Errors()))
+		for _, err := range p.Errors() {
+			t.Errorf("parser error: %q", err)
+		}
+		return
+	}
+
+	stmt := program.Statements[0].(*ExpressionStatement)
+	assign, ok := stmt.Expression.(*AssignmentExpression)
+	if !ok {
+		t.Fatalf("stmt.Expression is not *AssignmentExpression. got=%T", stmt.Expression)
+	}
+	array, ok := assign.Value.(*AssociativeArrayLiteral)
+	if !ok {
+		t.Fatalf("assign.Value is not *AssociativeArrayLiteral. got=%T", assign.Value)
+	}
+	if len(array.Pairs) != 3 {
+		t.Fatalf("array should have 3 pairs. got=%d", len(array.Pairs))
+	}
+
+	if _, ok := array.Pairs[0].Value.(*AnonymousFunction); !ok {
+		t.Errorf("GET handler is not *AnonymousFunction. got=%T", array.Pairs[0].Value)
+	}
+	if _, ok := array.Pairs[1].Value.(*ArrowFunction); !ok {
+		t.Errorf("POST handler is not *ArrowFunction. got=%T", array.Pairs[1].Value)
+	}
+	arrow, ok := array.Pairs[2].Value.(*ArrowFunction)
+	if !ok {
+		t.Fatalf("PUT handler is not *ArrowFunction. got=%T", array.Pairs[2].Value)
+	}
+	if !arrow.Static {
+		t.Errorf("PUT handler should be static")
+	}
+}
+
+func TestParseCoalesceExpression(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected string
+	}{
+		{"<?php $a ?? $b ?? $c; ?>", "($a ?? ($b ?? $c))"},
+		{"<?php $a ?? $b . $c; ?>", "($a ?? ($b . $c))"},
+		{"<?php $a ?? $b ? 1 : 2; ?>", "(($a ?? $b) ? 1 : 2)"},
+	}
+
+	for _, tt := range tests {
+		l := New(tt.input)
+		p := NewParser(l)
+		program := p.ParseProgram()
+
+		if len(p.Errors()) != 0 {
+			t.Errorf("parser has %d errors for %q", len(p.Errors()), tt.input)
+			for _, err := range p.Errors() {
+				t.Errorf("parser error: %q", err)
+			}
+			continue
+		}
+
+		stmt := program.Statements[0].(*ExpressionStatement)
+		if stmt.Expression.String() != tt.expected {
+			t.Errorf("expected=%q, got=%q", tt.expected, stmt.Expression.String())
+		}
+	}
+
+	l := New("<?php $a ?? $b ?? $c; ?>")
+	p := NewParser(l)
+	program := p.ParseProgram()
+
+	stmt := program.Statements[0].(*ExpressionStatement)
+	coalesce, ok := stmt.Expression.(*CoalesceExpression)
+	if !ok {
+		t.Fatalf("stmt.Expression is not *CoalesceExpression. got=%T", stmt.Expression)
+	}
+	if _, ok := coalesce.Right.(*CoalesceExpression); !ok {
+		t.Errorf("coalesce.Right is not *CoalesceExpression. got=%T", coalesce.Right)
+	}
+
+	data, err := ToJSON(coalesce)
+	if err != nil {
+		t.Fatalf("ToJSON failed: %v", err)
+	}
+	if !strings.Contains(string(data), `"type": "CoalesceExpression"`) {
+		t.Errorf("ToJSON output missing CoalesceExpression type: %s", data)
+	}
+}
+
+func TestParseNullsafeAccessIsDistinguishable(t *testing.T) {
+	tests := []struct {
+		input            string
+		expectedNullsafe bool
+		expectedString   string
+	}{
+		{"<?php $a->b; ?>", false, "$a->b"},
+		{"<?php $a?->b; ?>", true, "$a?->b"},
+	}
+
+	for _, tt := range tests {
+		l := New(tt.input)
+		p := NewParser(l)
+		program := p.ParseProgram()
+
+		if len(p.Errors()) != 0 {
+			t.Errorf("parser has %d errors for %q", len(p.Errors()), tt.input)
+			for _, err := range p.Errors() {
+				t.Errorf("parser error: %q", err)
+			}
+			continue
+		}
+
+		stmt := program.Statements[0].(*ExpressionStatement)
+		access, ok := stmt.Expression.(*ObjectAccessExpression)
+		if !ok {
+			t.Fatalf("stmt.Expression is not *ObjectAccessExpression. got=%T", stmt.Expression)
+		}
+		if access.Nullsafe != tt.expectedNullsafe {
+			t.Errorf("access.Nullsafe wrong for %q. got=%t", tt.input, access.Nullsafe)
+		}
+		if access.String() != tt.expectedString {
+			t.Errorf("access.String() wrong. expected=%q, got=%q", tt.expectedString, access.String())
+		}
+
+		data, err := ToJSON(access)
+		if err != nil {
+			t.Fatalf("ToJSON failed: %v", err)
+		}
+		if strings.Contains(string(data), `"nullsafe": true`) != tt.expectedNullsafe {
+			t.Errorf("ToJSON nullsafe flag wrong for %q: %s", tt.input, data)
+		}
+	}
+}
+
+func TestParseSpaceshipExpression(t *testing.T) {
+	l := New("<?php $a <=> $b; ?>")
+	p := NewParser(l)
+	program := p.ParseProgram()
+
+	if len(p.Errors()) != 0 {
+		t.Errorf("parser has %d errors", len(p.Errors()))
+		for _, err := range p.Errors() {
+			t.Errorf("parser error: %q", err)
+		}
+		return
+	}
+
+	stmt := program.Statements[0].(*ExpressionStatement)
+	infix, ok := stmt.Expression.(*InfixExpression)
+	if !ok {
+		t.Fatalf("stmt.Expression is not *InfixExpression. got=%T", stmt.Expression)
+	}
+	if infix.Operator != "<=>" {
+		t.Errorf("infix.Operator is not '<=>'. got=%q", infix.Operator)
+	}
+}
+
+func TestParseSpaceshipInSortCallback(t *testing.T) {
+	l := New("<?php usort($a, fn($x, $y) => $x <=> $y); ?>")
+	p := NewParser(l)
+	program := p.ParseProgram()
+
+	if len(p.Errors()) != 0 {
+		t.Errorf("parser has %d errors", len(p.Errors()))
+		for _, err := range p.Errors() {
+			t.Errorf("parser error: %q", err)
+		}
+		return
+	}
+
+	stmt := program.Statements[0].(*ExpressionStatement)
+	call, ok := stmt.Expression.(*CallExpression)
+	if !ok {
+		t.Fatalf("stmt.Expression is not *CallExpression. got=%T", stmt.Expression)
+	}
+	if call.Function.String() != "usort" {
+		t.Errorf("call.Function is not usort. got=%s", call.Function.String())
+	}
+	if len(call.Arguments) != 2 {
+		t.Fatalf("expected 2 arguments. got=%d", len(call.Arguments))
+	}
+
+	arrow, ok := call.Arguments[1].(*ArrowFunction)
+	if !ok {
+		t.Fatalf("second argument is not *ArrowFunction. got=%T", call.Arguments[1])
+	}
+	if len(arrow.Parameters) != 2 {
+		t.Fatalf("expected 2 parameters. got=%d", len(arrow.Parameters))
+	}
+
+	infix, ok := arrow.Body.(*InfixExpression)
+	if !ok {
+		t.Fatalf("arrow.Body is not *InfixExpression. got=%T", arrow.Body)
+	}
+	if infix.Operator != "<=>" {
+		t.Errorf("infix.Operator is not '<=>'. got=%q", infix.Operator)
+	}
+	if infix.Left.String() != "$x" || infix.Right.String() != "$y" {
+		t.Errorf("spaceship operands wrong. got=%s", infix.String())
+	}
+}
+
+func TestParseIntersectionTypes(t *testing.T) {
+	input := `<?php
+class Repo {
+    private Countable&Traversable $items;
+
+    public function first(Countable&\App\Sized $items): Countable&Traversable {
+        return $this->items;
+    }
+}
+?>`
+
+	l := New(input)
+	p := NewParser(l)
+	program := p.ParseProgram()
+
+	if len(p.Errors()) != 0 {
+		t.Errorf("parser has %d errors", len(p.Errors()))
+		for _, err := range p.Errors() {
+			t.Errorf("parser error: %q", err)
+		}
+		return
+	}
+
+	class, ok := program.Statements[0].(*ClassDeclaration)
+	if !ok {
+		t.Fatalf("program.Statements[0] is not *ClassDeclaration. got=%T", program.Statements[0])
+	}
+
+	propertyType := class.Properties[0].PropertyType
+	if propertyType == nil || len(propertyType.Intersection) != 2 {
+		t.Fatalf("property type is not a 2-member intersection. got=%v", propertyType)
+	}
+	if propertyType.String() != "Countable&Traversable" {
+		t.Errorf("property type String() wrong. got=%q", propertyType.String())
+	}
+
+	method := class.Methods[0]
+	if method.Parameters[0].TypeHint.String() != "Countable&\\App\\Sized" {
+		t.Errorf("parameter type String() wrong. got=%q", method.Parameters[0].TypeHint.String())
+	}
+
+	returnType, ok := method.ReturnType.(*TypeHint)
+	if !ok {
+		t.Fatalf("method.ReturnType is not *TypeHint. got=%T", method.ReturnType)
+	}
+	if len(returnType.Intersection) != 2 || returnType.Intersection[1].Name != "Traversable" {
+		t.Errorf("return type is not Countable&Traversable. got=%q", returnType.String())
+	}
+
+	data, err := ToJSON(method)
+	if err != nil {
+		t.Fatalf("ToJSON failed: %v", err)
+	}
+	if strings.Count(string(data), `"intersection"`) != 2 {
+		t.Errorf("ToJSON output should include 2 intersections: %s", data)
+	}
+}
+
+func TestParsePowerOperator(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected string
+	}{
+		{"<?php 2 ** 3 ** 2; ?>", "(2 ** (3 ** 2))"},
+		{"<?php 2 * 3 ** 2; ?>", "(2 * (3 ** 2))"},
+		{"<?php -2 ** 2; ?>", "(-(2 ** 2))"},
+		{"<?php $x **= 2; ?>", "$x **= 2"},
+	}
+
+	for _, tt := range tests {
+		l := New(tt.input)
+		p := NewParser(l)
+		program := p.ParseProgram()
+
+		if len(p.Errors()) != 0 {
+			t.Errorf("parser has %d errors for %q", len(p.Errors()), tt.input)
+			for _, err := range p.Errors() {
+				t.Errorf("parser error: %q", err)
+			}
+			continue
+		}
+
+		stmt := program.Statements[0].(*ExpressionStatement)
+		if stmt.Expression.String() != tt.expected {
+			t.Errorf("expected=%q, got=%q", tt.expected, stmt.Expression.String())
+		}
+	}
+
+	l := New("<?php $x **= 2; ?>")
+	p := NewParser(l)
+	program := p.ParseProgram()
+
+	stmt := program.Statements[0].(*ExpressionStatement)
+	assign, ok := stmt.Expression.(*AssignmentExpression)
+	if !ok {
+		t.Fatalf("stmt.Expression is not *AssignmentExpression. got=%T", stmt.Expression)
+	}
+	if assign.Token.Type != POW_ASSIGN {
+		t.Errorf("assign.Token.Type is not POW_ASSIGN. got=%s", assign.Token.Type)
+	}
+}
+
+func TestParseBitwiseOperatorsOnVariables(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected string
+	}{
+		{"<?php $a & $b; ?>", "($a & $b)"},
+		{"<?php $a | $b ^ $c; ?>", "($a | ($b ^ $c))"},
+		{"<?php $a << 2; ?>", "($a << 2)"},
+		{"<?php $a >> 2 + 1; ?>", "($a >> (2 + 1))"},
+		{"<?php ~$x; ?>", "(~$x)"},
+		{"<?php ~$x & $mask; ?>", "((~$x) & $mask)"},
+	}
+
+	for _, tt := range tests {
+		l := New(tt.input)
+		p := NewParser(l)
+		program := p.ParseProgram()
+
+		if len(p.Errors()) != 0 {
+			t.Errorf("parser has %d errors for %q", len(p.Errors()), tt.input)
+			for _, err := range p.Errors() {
+				t.Errorf("parser error: %q", err)
+			}
+			continue
+		}
+
+		stmt := program.Statements[0].(*ExpressionStatement)
+		if stmt.Expression.String() != tt.expected {
+			t.Errorf("expected=%q, got=%q", tt.expected, stmt.Expression.String())
+		}
+	}
+}
+
+func TestParseStringEscapes(t *testing.T) {
+	input := `<?php
+echo "a\nb";
+echo 'a\nb';
+echo 'cost: $5';
+?>`
+
+	l := New(input)
+	p := NewParser(l)
+	program := p.ParseProgram()
+
+	if len(p.Errors()) != 0 {
+		t.Errorf("parser has %d errors", len(p.Errors()))
+		for _, err := range p.Errors() {
+			t.Errorf("parser error: %q", err)
+		}
+		return
+	}
+
+	expected := []struct {
+		value   string
+		literal string
+	}{
+		{"a\nb", `a\nb`},
+		{`a\nb`, `a\nb`},
+		{"cost: $5", "cost: $5"},
+	}
+
+	for i, want := range expected {
+		echo := program.Statements[i].(*EchoStatement)
+		str, ok := echo.Values[0].(*StringLiteral)
+		if !ok {
+			t.Fatalf("statement %d value is not *StringLiteral. got=%T", i, echo.Values[0])
+		}
+		if str.Value != want.value {
+			t.Errorf("statement %d Value wrong. expected=%q, got=%q", i, want.value, str.Value)
+		}
+		if str.Token.Literal != want.literal {
+			t.Errorf("statement %d Token.Literal wrong. expected=%q, got=%q", i, want.literal, str.Token.Literal)
+		}
+	}
+}
+
+func TestParseClosureUseClause(t *testing.T) {
+	l := New(`<?php $fn = function () use ($a, &$b) { return $a + $b; }; ?>`)
+	p := NewParser(l)
+	program := p.ParseProgram()
+
+	if len(p.Errors()) != 0 {
+		t.Errorf("parser has %d errors", len(p.Errors()))
+		for _, err := range p.Errors() {
+			t.Errorf("parser error: %q", err)
+		}
+		return
+	}
+
+	assign := program.Statements[0].(*ExpressionStatement).Expression.(*AssignmentExpression)
+	closure, ok := assign.Value.(*AnonymousFunction)
+	if !ok {
+		t.Fatalf("assign.Value is not *AnonymousFunction. got=%T", assign.Value)
+	}
+	if len(closure.UseClause) != 2 || closure.UseClause[1].Name != "b" {
+		t.Errorf("closure should capture $a and $b. got=%v", closure.UseClause)
+	}
+	if closure.UseClause[0].ByRef || !closure.UseClause[1].ByRef {
+		t.Errorf("only $b should be captured by reference. got=%v", closure.UseClause)
+	}
+	if got := closure.String(); got != "function() use ($a, &$b) {return ($a + $b);}" {
+		t.Errorf("closure.String() wrong. got=%q", got)
+	}
+	if got := PrettyPrint(program); !strings.Contains(got, "use ($a, &$b)") {
+		t.Errorf("PrettyPrint should keep the reference capture. got=%q", got)
+	}
+	data, err := ToJSON(closure)
+	if err != nil {
+		t.Fatalf("ToJSON failed: %v", err)
+	}
+	if !strings.Contains(string(data), `"by_ref": true`) {
+		t.Errorf("ToJSON output missing by_ref: %s", data)
+	}
+
+	tests := []struct {
+		input    string
+		expected string
+	}{
+		{`<?php $fn = function () use ($x = 1) {}; ?>`, "closure use variable $x cannot have a default value at line 1, column 33"},
+		{`<?php $fn = function () use (int $x) {}; ?>`, "expected variable in closure use clause, got IDENT instead"},
+		{`<?php $fn = function () use () {}; ?>`, "closure use clause cannot be empty at line 1, column 25"},
+	}
+
+	for _, tt := range tests {
+		l := New(tt.input)
+		p := NewParser(l)
+		p.ParseProgram()
+
+		if len(p.Errors()) == 0 || p.Errors()[0] != tt.expected {
+			t.Errorf("expected first error %q for %q, got=%q", tt.expected, tt.input, p.Errors())
+		}
+	}
+}
+
+func TestParseInterpolatedStrings(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected string
+	}{
+		{`"Hello {$user->name}!"`, `"Hello {$user->name}!"`},
+		{`"Hello $name!"`, `"Hello $name!"`},
+		{`"First: $arr[0]"`, `"First: {($arr[0])}"`},
+		{`"Key: $arr[key]"`, `"Key: {($arr[key])}"`},
+		{`"Owner: $obj->owner."`, `"Owner: {$obj->owner}."`},
+		{`"Sum: {$a + $b}"`, `"Sum: {($a + $b)}"`},
+		{`"Name: ${name}"`, `"Name: $name"`},
+		{`"Item: ${a['b']}"`, `"Item: {($a[b])}"`},
+		{`"Dynamic: ${$a . 'b'}"`, `"Dynamic: {($($a . b))}"`},
+	}
+
+	for _, tt := range tests {
+		l := New("<?php echo " + tt.input + "; ?>")
+		p := NewParser(l)
+		program := p.ParseProgram()
+
+		if len(p.Errors()) != 0 {
+			t.Errorf("input %s: parser has %d errors", tt.input, len(p.Errors()))
+			for _, err := range p.Errors() {
+				t.Errorf("parser error: %q", err)
+			}
+			continue
+		}
+
+		echo := program.Statements[0].(*EchoStatement)
+		str, ok := echo.Values[0].(*InterpolatedString)
+		if !ok {
+			t.Errorf("input %s: value is not *InterpolatedString. got=%T", tt.input, echo.Values[0])
+			continue
+		}
+		if str.String() != tt.expected {
+			t.Errorf("input %s: expected=%s, got=%s", tt.input, tt.expected, str.String())
+		}
+	}
+
+	l := New(`<?php echo "Hello {$user->name}!"; ?>`)
+	p := NewParser(l)
+	program := p.ParseProgram()
+
+	str := program.Statements[0].(*EchoStatement).Values[0].(*InterpolatedString)
+	if len(str.Parts) != 3 {
+		t.Fatalf("expected 3 parts. got=%d", len(str.Parts))
+	}
+	if lit, ok := str.Parts[0].(*StringLiteral); !ok || lit.Value != "Hello " {
+		t.Errorf("part 0 is not StringLiteral \"Hello \". got=%#v", str.Parts[0])
+	}
+	access, ok := str.Parts[1].(*ObjectAccessExpression)
+	if !ok {
+		t.Fatalf("part 1 is not *ObjectAccessExpression. got=%T", str.Parts[1])
+	}
+	if access.Token.Line != 1 || access.Object.(*Variable).Token.Column != 25 {
+		t.Errorf("embedded expression position wrong. got line %d, column %d",
+			access.Token.Line, access.Object.(*Variable).Token.Column)
+	}
+	if lit, ok := str.Parts[2].(*StringLiteral); !ok || lit.Value != "!" {
+		t.Errorf("part 2 is not StringLiteral \"!\". got=%#v", str.Parts[2])
+	}
+
+	// ${a['b']} is an element of $a, not a variable variable
+	l = New(`<?php echo "${a['b']}"; ?>`)
+	p = NewParser(l)
+	program = p.ParseProgram()
+
+	str = program.Statements[0].(*EchoStatement).Values[0].(*InterpolatedString)
+	index, ok := str.Parts[0].(*IndexExpression)
+	if !ok {
+		t.Fatalf("part 0 is not *IndexExpression. got=%T", str.Parts[0])
+	}
+	if variable, ok := index.Left.(*Variable); !ok || variable.Name != "a" {
+		t.Errorf("index.Left is not the variable $a. got=%#v", index.Left)
+	}
+	if key, ok := index.Index.(*StringLiteral); !ok || key.Value != "b" {
+		t.Errorf("index.Index is not the string 'b'. got=%#v", index.Index)
+	}
+}
+
+func TestParseEscapedDollarIsNotInterpolated(t *testing.T) {
+	l := New(`<?php echo "Price: \$5 or \$total"; ?>`)
+	p := NewParser(l)
+	program := p.ParseProgram()
+
+	if len(p.Errors()) != 0 {
+		t.Fatalf("parser has errors: %v", p.Errors())
+	}
+
+	echo := program.Statements[0].(*EchoStatement)
+	str, ok := echo.Values[0].(*StringLiteral)
+	if !ok {
+		t.Fatalf("value is not *StringLiteral. got=%T", echo.Values[0])
+	}
+	if str.Value != "Price: $5 or $total" {
+		t.Errorf("Value wrong. got=%q", str.Value)
+	}
+}
+
+func TestParseHeredocAndNowdoc(t *testing.T) {
+	input := `<?php
+$greeting = <<<EOT
+Hello {$user->name},
+you owe \$5.
+EOT;
+$template = <<<'EOT'
+Hello $name\n
+EOT;
+?>`
+
+	l := New(input)
+	p := NewParser(l)
+	program := p.ParseProgram()
+
+	if len(p.Errors()) != 0 {
+		t.Errorf("parser has %d errors", len(p.Errors()))
+		for _, err := range p.Errors() {
+			t.Errorf("parser error: %q", err)
+		}
+		return
+	}
+
+	heredoc := program.Statements[0].(*ExpressionStatement).Expression.(*AssignmentExpression)
+	str, ok := heredoc.Value.(*InterpolatedString)
+	if !ok {
+		t.Fatalf("heredoc is not *InterpolatedString. got=%T", heredoc.Value)
+	}
+	if len(str.Parts) != 3 {
+		t.Fatalf("expected 3 heredoc parts. got=%d", len(str.Parts))
+	}
+	access, ok := str.Parts[1].(*ObjectAccessExpression)
+	if !ok {
+		t.Fatalf("heredoc part 1 is not *ObjectAccessExpression. got=%T", str.Parts[1])
+	}
+	if access.Token.Line != 3 {
+		t.Errorf("embedded expression line wrong. expected=3, got=%d", access.Token.Line)
+	}
+	if tail := str.Parts[2].(*StringLiteral); tail.Value != ",\nyou owe $5." {
+		t.Errorf("heredoc tail wrong. got=%q", tail.Value)
+	}
+
+	nowdoc := program.Statements[1].(*ExpressionStatement).Expression.(*AssignmentExpression)
+	lit, ok := nowdoc.Value.(*StringLiteral)
+	if !ok {
+		t.Fatalf("nowdoc is not *StringLiteral. got=%T", nowdoc.Value)
+	}
+	if lit.Value != `Hello $name\n` {
+		t.Errorf("nowdoc value wrong. got=%q", lit.Value)
+	}
+	if nowdoc.Token.Line != 6 {
+		t.Errorf("nowdoc assignment line wrong. expected=6, got=%d", nowdoc.Token.Line)
+	}
+}
+
+func TestParseEchoAndPrintHeredoc(t *testing.T) {
+	input := `<?php
+echo <<<HTML
+<p>Hello $name</p>
+HTML;
+print <<<HTML
+Total: {$order->total}
+HTML;
+?>`
+
+	l := New(input)
+	p := NewParser(l)
+	program := p.ParseProgram()
+
+	if len(p.Errors()) != 0 {
+		t.Errorf("parser has %d errors", len(p.Errors()))
+		for _, err := range p.Errors() {
+			t.Errorf("parser error: %q", err)
+		}
+		return
+	}
+
+	if len(program.Statements) != 2 {
+		t.Fatalf("program.Statements does not contain 2 statements. got=%d", len(program.Statements))
+	}
+
+	echo, ok := program.Statements[0].(*EchoStatement)
+	if !ok {
+		t.Fatalf("program.Statements[0] is not *EchoStatement. got=%T", program.Statements[0])
+	}
+	if len(echo.Values) != 1 {
+		t.Fatalf("echo does not have 1 value. got=%d", len(echo.Values))
+	}
+	str, ok := echo.Values[0].(*InterpolatedString)
+	if !ok {
+		t.Fatalf("echo value is not *InterpolatedString. got=%T", echo.Values[0])
+	}
+	if len(str.Parts) != 3 {
+		t.Fatalf("expected 3 heredoc parts. got=%d", len(str.Parts))
+	}
+	variable, ok := str.Parts[1].(*Variable)
+	if !ok {
+		t.Fatalf("heredoc part 1 is not *Variable. got=%T", str.Parts[1])
+	}
+	if variable.Name != "name" {
+		t.Errorf("embedded variable name wrong. expected=name, got=%s", variable.Name)
+	}
+	if variable.Token.Line != 3 {
+		t.Errorf("embedded variable line wrong. expected=3, got=%d", variable.Token.Line)
+	}
+
+	stmt, ok := program.Statements[1].(*ExpressionStatement)
+	if !ok {
+		t.Fatalf("program.Statements[1] is not *ExpressionStatement. got=%T", program.Statements[1])
+	}
+	printExpr, ok := stmt.Expression.(*PrintExpression)
+	if !ok {
+		t.Fatalf("stmt.Expression is not *PrintExpression. got=%T", stmt.Expression)
+	}
+	str, ok = printExpr.Value.(*InterpolatedString)
+	if !ok {
+		t.Fatalf("print value is not *InterpolatedString. got=%T", printExpr.Value)
+	}
+	if len(str.Parts) != 2 {
+		t.Fatalf("expected 2 heredoc parts. got=%d", len(str.Parts))
+	}
+	if _, ok := str.Parts[1].(*ObjectAccessExpression); !ok {
+		t.Errorf("heredoc part 1 is not *ObjectAccessExpression. got=%T", str.Parts[1])
+	}
+}
+
+func TestParseNewStaticAndSelf(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected string
+	}{
+		{"<?php new static(); ?>", "static"},
+		{"<?php new self(); ?>", "self"},
+	}
+
+	for _, tt := range tests {
+		l := New(tt.input)
+		p := NewParser(l)
+		program := p.ParseProgram()
+
+		if len(p.Errors()) != 0 {
+			t.Errorf("input %q: parser has errors: %v", tt.input, p.Errors())
+			continue
+		}
+
+		stmt := program.Statements[0].(*ExpressionStatement)
+		newExpr, ok := stmt.Expression.(*NewExpression)
+		if !ok {
+			t.Fatalf("stmt.Expression is not *NewExpression. got=%T", stmt.Expression)
+		}
+		if newExpr.ClassName.Value != tt.expected {
+			t.Errorf("ClassName wrong. expected=%q, got=%q", tt.expected, newExpr.ClassName.Value)
+		}
+	}
+}
+
+func TestParseNumericLiterals(t *testing.T) {
+	integers := []struct {
+		input    string
+		expected int64
+	}{
+		{"1_000", 1000},
+		{"1_000_000", 1000000},
+		{"42", 42},
+		{"0xFF", 255},
+		{"0x7F_FF", 32767},
+		{"0b101", 5},
+	}
+
+	for _, tt := range integers {
+		l := New("<?php " + tt.input + "; ?>")
+		p := NewParser(l)
+		program := p.ParseProgram()
+
+		if len(p.Errors()) != 0 {
+			t.Errorf("input %q: parser has errors: %v", tt.input, p.Errors())
+			continue
+		}
+
+		stmt := program.Statements[0].(*ExpressionStatement)
+		literal, ok := stmt.Expression.(*IntegerLiteral)
+		if !ok {
+			t.Errorf("input %q: exp is not *IntegerLiteral. got=%T", tt.input, stmt.Expression)
+			continue
+		}
+		if literal.Value != tt.expected {
+			t.Errorf("input %q: literal.Value not %d. got=%d", tt.input, tt.expected, literal.Value)
+		}
+	}
+
+	floats := []struct {
+		input    string
+		expected float64
+	}{
+		{"3.14e2", 314},
+		{"1_000.000_5", 1000.0005},
+		{"1.5e10", 1.5e10},
+		{"2E-3", 0.002},
+		{"1e3", 1000},
+	}
+
+	for _, tt := range floats {
+		l := New("<?php " + tt.input + "; ?>")
+		p := NewParser(l)
+		program := p.ParseProgram()
+
+		if len(p.Errors()) != 0 {
+			t.Errorf("input %q: parser has errors: %v", tt.input, p.Errors())
+			continue
+		}
+
+		stmt := program.Statements[0].(*ExpressionStatement)
+		literal, ok := stmt.Expression.(*FloatLiteral)
+		if !ok {
+			t.Errorf("input %q: exp is not *FloatLiteral. got=%T", tt.input, stmt.Expression)
+			continue
+		}
+		if literal.Value != tt.expected {
+			t.Errorf("input %q: literal.Value not %g. got=%g", tt.input, tt.expected, literal.Value)
+		}
+	}
+
+	for _, input := range []string{"1_", "1__000", "1_.5", "1.5_", "1e_3", "0x_FF"} {
+		l := New("<?php " + input + "; ?>")
+		p := NewParser(l)
+		p.ParseProgram()
+
+		if len(p.Errors()) == 0 {
+			t.Errorf("input %q: expected a parser error for a misplaced underscore", input)
+		}
+	}
+
+	for _, input := range []string{"1e", "2.5E"} {
+		l := New("<?php " + input + "; ?>")
+		p := NewParser(l)
+		p.ParseProgram()
+
+		expected := fmt.Sprintf("could not parse %q as float", input)
+		if len(p.Errors()) == 0 || !strings.HasPrefix(p.Errors()[0], expected) {
+			t.Errorf("input %q: expected error %q for an exponent without digits. got=%q", input, expected, p.Errors())
+		}
+	}
+}
+
+func TestParseMultilineMethodChainWithComments(t *testing.T) {
+	input := `<?php
+$users = $query
+    ->where('x', 1) // filter
+    ->orderBy('y') /* sort */
+    # then fetch
+    ->get();
+// done
+?>`
+
+	l := New(input)
+	p := NewParser(l)
+	program := p.ParseProgram()
+
+	if len(p.Errors()) != 0 {
+		t.Errorf("parser has %d errors", len(p.Errors()))
+		for _, err := range p.Errors() {
+			t.Errorf("parser error: %q", err)
+		}
+		return
+	}
+
+	if len(program.Statements) != 2 {
+		t.Fatalf("program.Statements does not contain 2 statements. got=%d", len(program.Statements))
+	}
+	if _, ok := program.Statements[1].(*Comment); !ok {
+		t.Errorf("program.Statements[1] is not *Comment. got=%T", program.Statements[1])
+	}
+
+	assign := program.Statements[0].(*ExpressionStatement).Expression.(*AssignmentExpression)
+
+	// The chain nests outwards: get() wraps orderBy() wraps where()
+	expr := assign.Value
+	for _, method := range []string{"get", "orderBy", "where"} {
+		call, ok := expr.(*CallExpression)
+		if !ok {
+			t.Fatalf("%s: expression is not *CallExpression. got=%T", method, expr)
+		}
+		access, ok := call.Function.(*ObjectAccessExpression)
+		if !ok {
+			t.Fatalf("%s: call.Function is not *ObjectAccessExpression. got=%T", method, call.Function)
+		}
+		if access.Property.String() != method {
+			t.Errorf("expected method %s. got=%s", method, access.Property.String())
+		}
+		expr = access.Object
+	}
+
+	if variable, ok := expr.(*Variable); !ok || variable.Name != "query" {
+		t.Errorf("chain does not start at $query. got=%s", expr.String())
+	}
+}
+
+func TestParseCommentsAfterBracesAndColons(t *testing.T) {
+	input := `<?php
+$x = $a ? $b : /* c */ $d;
+function f(): /* c */ int {
+    // first
+    return match ($a) { // c
+        1 => 2,
+    };
+}
+switch ($a) { // c
+    case 1: // c
+        break;
+}
+class A { // c
+    public function g() {}
+}
+?>`
+
+	l := New(input)
+	p := NewParser(l)
+	program := p.ParseProgram()
+
+	if len(p.Errors()) != 0 {
+		t.Errorf("parser has %d errors", len(p.Errors()))
+		for _, err := range p.Errors() {
+			t.Errorf("parser error: %q", err)
+		}
+		return
+	}
+
+	if len(program.Statements) != 4 {
+		t.Fatalf("program.Statements does not contain 4 statements. got=%d", len(program.Statements))
+	}
+
+	fn, ok := program.Statements[1].(*FunctionDeclaration)
+	if !ok {
+		t.Fatalf("program.Statements[1] is not *FunctionDeclaration. got=%T", program.Statements[1])
+	}
+	if len(fn.Body.Statements) != 2 {
+		t.Fatalf("function body does not contain 2 statements. got=%d", len(fn.Body.Statements))
+	}
+	if _, ok := fn.Body.Statements[0].(*Comment); !ok {
+		t.Errorf("fn.Body.Statements[0] is not *Comment. got=%T", fn.Body.Statements[0])
+	}
+
+	switchStmt, ok := program.Statements[2].(*SwitchStatement)
+	if !ok {
+		t.Fatalf("program.Statements[2] is not *SwitchStatement. got=%T", program.Statements[2])
+	}
+	if len(switchStmt.Cases) != 1 || len(switchStmt.Cases[0].Body) != 2 {
+		t.Fatalf("expected one case with a comment and a break. got=%v", switchStmt.Cases)
+	}
+	if _, ok := switchStmt.Cases[0].Body[0].(*Comment); !ok {
+		t.Errorf("case body starts with %T, not *Comment", switchStmt.Cases[0].Body[0])
+	}
+
+	class, ok := program.Statements[3].(*ClassDeclaration)
+	if !ok {
+		t.Fatalf("program.Statements[3] is not *ClassDeclaration. got=%T", program.Statements[3])
+	}
+	if len(class.Methods) != 1 {
+		t.Errorf("class does not contain 1 method. got=%d", len(class.Methods))
+	}
+}
+
+func TestParseLongFormArray(t *testing.T) {
+	tests := []struct {
+		longForm  string
+		shortForm string
+	}{
+		{"array(1, 2)", "[1, 2]"},
+		{"array()", "[]"},
+		{`array("k" => "v", "n" => 2)`, `["k" => "v", "n" => 2]`},
+		{"array(array(1), [2])", "[[1], [2]]"},
+	}
+
+	parse := func(input string) Expression {
+		l := New("<?php $a = " + input + "; ?>")
+		p := NewParser(l)
+		program := p.ParseProgram()
+
+		if len(p.Errors()) != 0 {
+			t.Fatalf("input %q: parser has errors: %v", input, p.Errors())
+		}
+		return program.Statements[0].(*ExpressionStatement).Expression.(*AssignmentExpression).Value
+	}
+
+	for _, tt := range tests {
+		long := parse(tt.longForm)
+		short := parse(tt.shortForm)
+
+		if fmt.Sprintf("%T", long) != fmt.Sprintf("%T", short) {
+			t.Errorf("%s is %T, but %s is %T", tt.longForm, long, tt.shortForm, short)
+		}
+		if long.String() != short.String() {
+			t.Errorf("%s and %s differ. got=%s and %s", tt.longForm, tt.shortForm, long.String(), short.String())
+		}
+	}
+}
+
+func TestParseFullyQualifiedStaticAccess(t *testing.T) {
+	l := New(`<?php
+$host = \App\Config::database['host'];
+$version = \Vendor\Pkg\Util::VERSION;
+$user = App\Models\User::find(1);
+?>`)
+	p := NewParser(l)
+	program := p.ParseProgram()
+
+	if len(p.Errors()) != 0 {
+		t.Errorf("parser has %d errors", len(p.Errors()))
+		for _, err := range p.Errors() {
+			t.Errorf("parser error: %q", err)
+		}
+		return
+	}
+
+	if len(program.Statements) != 3 {
+		t.Fatalf("program.Statements does not contain 3 statements. got=%d", len(program.Statements))
+	}
+
+	value := func(i int) Expression {
+		return program.Statements[i].(*ExpressionStatement).Expression.(*AssignmentExpression).Value
+	}
+
+	index, ok := value(0).(*IndexExpression)
+	if !ok {
+		t.Fatalf("first value is not *IndexExpression. got=%T", value(0))
+	}
+	access, ok := index.Left.(*StaticAccessExpression)
+	if !ok {
+		t.Fatalf("index.Left is not *StaticAccessExpression. got=%T", index.Left)
+	}
+	if access.Class.String() != `\App\Config` || access.Property.String() != "database" {
+		t.Errorf("static access wrong. got=%s", access.String())
+	}
+	if key, ok := index.Index.(*StringLiteral); !ok || key.Value != "host" {
+		t.Errorf("index is not \"host\". got=%s", index.Index.String())
+	}
+
+	access, ok = value(1).(*StaticAccessExpression)
+	if !ok {
+		t.Fatalf("second value is not *StaticAccessExpression. got=%T", value(1))
+	}
+	if access.Class.String() != `\Vendor\Pkg\Util` || access.Property.String() != "VERSION" {
+		t.Errorf("static access wrong. got=%s", access.String())
+	}
+
+	call, ok := value(2).(*CallExpression)
+	if !ok {
+		t.Fatalf("third value is not *CallExpression. got=%T", value(2))
+	}
+	access, ok = call.Function.(*StaticAccessExpression)
+	if !ok {
+		t.Fatalf("call.Function is not *StaticAccessExpression. got=%T", call.Function)
+	}
+	if access.Class.String() != `App\Models\User` {
+		t.Errorf("qualified class name wrong. got=%s", access.Class.String())
+	}
+}
+
+func TestParseShortTernary(t *testing.T) {
+	tests := []struct {
+		input          string
+		expectedShort  bool
+		expectedString string
+	}{
+		{`<?php $x ?: "default"; ?>`, true, "($x ?: default)"},
+		{`<?php $x ? $x : "default"; ?>`, false, "($x ? $x : default)"},
+		{`<?php $a ?: $b ?: $c; ?>`, true, "($a ?: ($b ?: $c))"},
+	}
+
+	for _, tt := range tests {
+		l := New(tt.input)
+		p := NewParser(l)
+		program := p.ParseProgram()
+
+		if len(p.Errors()) != 0 {
+			t.Errorf("parser has %d errors for %q", len(p.Errors()), tt.input)
+			for _, err := range p.Errors() {
+				t.Errorf("parser error: %q", err)
+			}
+			continue
+		}
+
+		stmt := program.Statements[0].(*ExpressionStatement)
+		ternary, ok := stmt.Expression.(*TernaryExpression)
+		if !ok {
+			t.Fatalf("stmt.Expression is not *TernaryExpression. got=%T", stmt.Expression)
+		}
+		if (ternary.TrueValue == nil) != tt.expectedShort {
+			t.Errorf("ternary.TrueValue wrong for %q. got=%v", tt.input, ternary.TrueValue)
+		}
+		if ternary.String() != tt.expectedString {
+			t.Errorf("ternary.String() wrong. expected=%q, got=%q", tt.expectedString, ternary.String())
+		}
+
+		data, err := ToJSON(ternary)
+		if err != nil {
+			t.Fatalf("ToJSON failed: %v", err)
+		}
+		if strings.Contains(string(data), `"short": true`) != tt.expectedShort {
+			t.Errorf("ToJSON short flag wrong for %q: %s", tt.input, data)
+		}
+	}
+}
+
+func TestParseInstanceofExpression(t *testing.T) {
+	tests := []struct {
+		input          string
+		expectedString string
+	}{
+		{`<?php $obj instanceof \App\User; ?>`, `($obj instanceof \App\User)`},
+		{`<?php $obj instanceof User; ?>`, `($obj instanceof User)`},
+		{`<?php !$obj instanceof User; ?>`, `(!($obj instanceof User))`},
+		{`<?php $obj instanceof $class; ?>`, `($obj instanceof $class)`},
+		{`<?php $a instanceof A === true; ?>`, `(($a instanceof A) === true)`},
+	}
+
+	for _, tt := range tests {
+		l := New(tt.input)
+		p := NewParser(l)
+		program := p.ParseProgram()
+
+		if len(p.Errors()) != 0 {
+			t.Errorf("parser has %d errors for %q", len(p.Errors()), tt.input)
+			for _, err := range p.Errors() {
+				t.Errorf("parser error: %q", err)
+			}
+			continue
+		}
+
+		stmt := program.Statements[0].(*ExpressionStatement)
+		if stmt.Expression.String() != tt.expectedString {
+			t.Errorf("expected=%q, got=%q", tt.expectedString, stmt.Expression.String())
+		}
+	}
+
+	l := New(`<?php $obj instanceof \App\User; ?>`)
+	p := NewParser(l)
+	program := p.ParseProgram()
+
+	stmt := program.Statements[0].(*ExpressionStatement)
+	expr, ok := stmt.Expression.(*InstanceofExpression)
+	if !ok {
+		t.Fatalf("stmt.Expression is not *InstanceofExpression. got=%T", stmt.Expression)
+	}
+	class, ok := expr.Class.(*Identifier)
+	if !ok {
+		t.Fatalf("expr.Class is not *Identifier. got=%T", expr.Class)
+	}
+	if class.Value != `\App\User` {
+		t.Errorf("class.Value not %q. got=%q", `\App\User`, class.Value)
+	}
+}
+
+func TestParseIssetEmptyAndUnset(t *testing.T) {
+	input := `<?php
+$set = isset($a, $b['k']);
+$blank = empty($x);
+unset($y);
+unset($z->prop, $w[0]);
+?>`
+
+	l := New(input)
+	p := NewParser(l)
+	program := p.ParseProgram()
+
+	if len(p.Errors()) != 0 {
+		t.Errorf("parser has %d errors", len(p.Errors()))
+		for _, err := range p.Errors() {
+			t.Errorf("parser error: %q", err)
+		}
+		return
+	}
+
+	if len(program.Statements) != 4 {
+		t.Fatalf("program.Statements does not contain 4 statements. got=%d", len(program.Statements))
+	}
+
+	value := func(i int) Expression {
+		return program.Statements[i].(*ExpressionStatement).Expression.(*AssignmentExpression).Value
+	}
+
+	isset, ok := value(0).(*IssetExpression)
+	if !ok {
+		t.Fatalf("value is not *IssetExpression. got=%T", value(0))
+	}
+	if len(isset.Arguments) != 2 || isset.String() != "isset($a, ($b[k]))" {
+		t.Errorf("isset wrong. got=%s", isset.String())
+	}
+
+	empty, ok := value(1).(*EmptyExpression)
+	if !ok {
+		t.Fatalf("value is not *EmptyExpression. got=%T", value(1))
+	}
+	if len(empty.Arguments) != 1 || empty.String() != "empty($x)" {
+		t.Errorf("empty wrong. got=%s", empty.String())
+	}
+
+	for i, expected := range []string{"unset($y);", "unset($z->prop, ($w[0]));"} {
+		unset, ok := program.Statements[i+2].(*UnsetStatement)
+		if !ok {
+			t.Fatalf("statement is not *UnsetStatement. got=%T", program.Statements[i+2])
+		}
+		if unset.String() != expected {
+			t.Errorf("unset wrong. expected=%q, got=%q", expected, unset.String())
+		}
+	}
+
+	data, err := ToJSON(isset)
+	if err != nil {
+		t.Fatalf("ToJSON failed: %v", err)
+	}
+	if !strings.Contains(string(data), `"type": "IssetExpression"`) || !strings.Contains(string(data), `"arguments"`) {
+		t.Errorf("ToJSON output wrong: %s", data)
+	}
+
+	l = New("<?php isset(); ?>")
+	p = NewParser(l)
+	p.ParseProgram()
+	if len(p.Errors()) == 0 || !strings.Contains(p.Errors()[0], "isset() requires at least one argument") {
+		t.Errorf("expected an error for isset() without arguments. got=%v", p.Errors())
+	}
+
+	l = New("<?php\nunset();\n$after = 1;\n?>")
+	p = NewParser(l)
+	program = p.ParseProgram()
+	if len(p.Errors()) != 1 || p.Errors()[0] != "unset() requires at least one argument at line 2, column 1" {
+		t.Errorf("expected one error for unset() without arguments. got=%q", p.Errors())
+	}
+
+	for _, input := range []string{"<?php empty(); ?>", "<?php empty($a, $b); ?>"} {
+		p = NewParser(New(input))
+		p.ParseProgram()
+		if len(p.Errors()) != 1 || !strings.Contains(p.Errors()[0], "empty() takes exactly one argument") {
+			t.Errorf("expected one error for %q. got=%q", input, p.Errors())
+		}
+	}
+}
+
+func TestParseParameterFeatures(t *testing.T) {
+	input := `<?php
+class Point {
+    public function __construct(
+        #[Required] private readonly ?int $x = null,
+        #[Range(0, 10), Deprecated] public int|float $y = 0,
+    ) {}
+}
+function sum(int ...$nums) {}
+function swap(array &$items, &...$rest) {}
+?>`
+
+	l := New(input)
+	p := NewParser(l)
+	program := p.ParseProgram()
+
+	if len(p.Errors()) != 0 {
+		t.Errorf("parser has %d errors", len(p.Errors()))
+		for _, err := range p.Errors() {
+			t.Errorf("parser error: %q", err)
+		}
+		return
+	}
+
+	class := program.Statements[0].(*ClassDeclaration)
+	params := class.Methods[0].Parameters
+	if len(params) != 2 {
+		t.Fatalf("expected 2 constructor parameters. got=%d", len(params))
+	}
+
+	x := params[0]
+	if len(x.Attributes) != 1 || x.Attributes[0].Name.Value != "Required" {
+		t.Errorf("x attributes wrong. got=%v", x.Attributes)
+	}
+	if x.Visibility != "private" || !x.Readonly || !x.Promoted() {
+		t.Errorf("x should be a private readonly promoted parameter. got=%q", x.String())
+	}
+	if x.TypeHint == nil || !x.TypeHint.Nullable || x.TypeHint.Name != "int" {
+		t.Errorf("x type wrong. got=%v", x.TypeHint)
+	}
+	if _, ok := x.Default.(*NullLiteral); !ok {
+		t.Errorf("x default is not *NullLiteral. got=%T", x.Default)
+	}
+	if x.String() != "#[Required] private readonly ?int $x = null" {
+		t.Errorf("x.String() wrong. got=%q", x.String())
+	}
+
+	y := params[1]
+	if len(y.Attributes) != 2 || y.Attributes[0].String() != "#[Range(0, 10)]" {
+		t.Errorf("y attributes wrong. got=%v", y.Attributes)
+	}
+	if y.Readonly || y.Visibility != "public" || len(y.TypeHint.Union) != 2 {
+		t.Errorf("y wrong. got=%q", y.String())
+	}
+
+	sum := program.Statements[1].(*FunctionDeclaration)
+	if nums := sum.Parameters[0]; !nums.Variadic || nums.TypeHint.Name != "int" || nums.String() != "int ...$nums" {
+		t.Errorf("nums wrong. got=%q", nums.String())
+	}
+
+	swap := program.Statements[2].(*FunctionDeclaration)
+	if items := swap.Parameters[0]; !items.ByRef || items.String() != "array &$items" {
+		t.Errorf("items wrong. got=%q", items.String())
+	}
+	if rest := swap.Parameters[1]; !rest.ByRef || !rest.Variadic || rest.String() != "&...$rest" {
+		t.Errorf("rest wrong. got=%q", rest.String())
+	}
+}
+
+func TestParseIllegalParameterCombinations(t *testing.T) {
+	tests := []struct {
+		input         string
+		expectedError string
+	}{
+		{"<?php function f(int ...$nums = []) {} ?>", "variadic parameter $nums cannot have a default value at line 1"},
+		{"<?php function f(...$a, $b) {} ?>", "variadic parameter $a must be the last parameter"},
+		{"<?php function f(private $x) {} ?>", "cannot declare promoted property $x outside a constructor"},
+		{"<?php class A { public function set(public $x) {} } ?>", "cannot declare promoted property $x outside a constructor"},
+		{"<?php class A { public function __construct(public ...$xs) {} } ?>", "cannot declare variadic promoted property $xs"},
+		{"<?php class A { public function __construct(public private $x) {} } ?>", "multiple visibility modifiers on a parameter"},
+	}
+
+	for _, tt := range tests {
+		l := New(tt.input)
+		p := NewParser(l)
+		p.ParseProgram()
+
+		found := false
+		for _, err := range p.Errors() {
+			if strings.Contains(err, tt.expectedError) {
+				found = true
+			}
+		}
+		if !found {
+			t.Errorf("input %q: expected error containing %q. got=%v", tt.input, tt.expectedError, p.Errors())
+		}
+	}
+}
+
+func TestParseCloneExpression(t *testing.T) {
+	l := New("<?php $copy = clone $original; $inner = clone $a->b; ?>")
+	p := NewParser(l)
+	program := p.ParseProgram()
+
+	if len(p.Errors()) != 0 {
+		t.Errorf("parser has %d errors", len(p.Errors()))
+		for _, err := range p.Errors() {
+			t.Errorf("parser error: %q", err)
+		}
+		return
+	}
+
+	assign := program.Statements[0].(*ExpressionStatement).Expression.(*AssignmentExpression)
+	clone, ok := assign.Value.(*CloneExpression)
+	if !ok {
+		t.Fatalf("assign.Value is not *CloneExpression. got=%T", assign.Value)
+	}
+	if variable, ok := clone.Operand.(*Variable); !ok || variable.Name != "original" {
+		t.Errorf("clone.Operand is not $original. got=%s", clone.Operand.String())
+	}
+
+	data, err := ToJSON(clone)
+	if err != nil {
+		t.Fatalf("ToJSON failed: %v", err)
+	}
+	if !strings.Contains(string(data), `"type": "CloneExpression"`) || !strings.Contains(string(data), `"operand"`) {
+		t.Errorf("ToJSON output wrong: %s", data)
+	}
+
+	// Member access binds tighter than clone
+	assign = program.Statements[1].(*ExpressionStatement).Expression.(*AssignmentExpression)
+	if assign.Value.String() != "(clone $a->b)" {
+		t.Errorf("expected (clone $a->b). got=%s", assign.Value.String())
+	}
+}
+
+func TestParseGlobalAndStaticVariables(t *testing.T) {
+	input := `<?php
+function counter() {
+    global $a, $b;
+    static $n = 1;
+    static $cache;
+    $n = $n + 1;
+    return $n;
+}
+?>`
+
+	l := New(input)
+	p := NewParser(l)
+	program := p.ParseProgram()
+
+	if len(p.Errors()) != 0 {
+		t.Errorf("parser has %d errors", len(p.Errors()))
+		for _, err := range p.Errors() {
+			t.Errorf("parser error: %q", err)
+		}
+		return
+	}
+
+	fn, ok := program.Statements[0].(*FunctionDeclaration)
+	if !ok {
+		t.Fatalf("statement is not *FunctionDeclaration. got=%T", program.Statements[0])
+	}
+	if len(fn.Body.Statements) != 5 {
+		t.Fatalf("function body does not contain 5 statements. got=%d", len(fn.Body.Statements))
+	}
+
+	global, ok := fn.Body.Statements[0].(*GlobalStatement)
+	if !ok {
+		t.Fatalf("statement is not *GlobalStatement. got=%T", fn.Body.Statements[0])
+	}
+	if len(global.Variables) != 2 || global.String() != "global $a, $b;" {
+		t.Errorf("global wrong. got=%s", global.String())
+	}
+
+	tests := []struct {
+		expected   string
+		hasDefault bool
+	}{
+		{"static $n = 1;", true},
+		{"static $cache;", false},
+	}
+
+	for i, tt := range tests {
+		static, ok := fn.Body.Statements[i+1].(*StaticVariableStatement)
+		if !ok {
+			t.Fatalf("statement is not *StaticVariableStatement. got=%T", fn.Body.Statements[i+1])
+		}
+		if static.String() != tt.expected {
+			t.Errorf("static wrong. expected=%q, got=%q", tt.expected, static.String())
+		}
+		if (static.Variables[0].Default != nil) != tt.hasDefault {
+			t.Errorf("static default wrong. expected hasDefault=%t, got=%v", tt.hasDefault, static.Variables[0].Default)
+		}
+	}
+
+	data, err := ToJSON(fn.Body.Statements[1])
+	if err != nil {
+		t.Fatalf("ToJSON failed: %v", err)
+	}
+	if !strings.Contains(string(data), `"type": "StaticVariableStatement"`) || !strings.Contains(string(data), `"default"`) {
+		t.Errorf("ToJSON output wrong: %s", data)
+	}
+
+	data, err = ToJSON(global)
+	if err != nil {
+		t.Fatalf("ToJSON failed: %v", err)
+	}
+	if !strings.Contains(string(data), `"type": "GlobalStatement"`) || !strings.Contains(string(data), `"variables"`) {
+		t.Errorf("ToJSON output wrong: %s", data)
+	}
+}
+
+func TestParseRequireOnceWithMagicDirectory(t *testing.T) {
+	input := `<?php
+require_once __DIR__ . "/bootstrap.php";
+$config = require __DIR__ . "/cfg.php";
+?>`
+
+	l := New(input)
+	p := NewParser(l)
+	program := p.ParseProgram()
+
+	if len(p.Errors()) != 0 {
+		t.Errorf("parser has %d errors", len(p.Errors()))
+		for _, err := range p.Errors() {
+			t.Errorf("parser error: %q", err)
+		}
+		return
+	}
+
+	if len(program.Statements) != 2 {
+		t.Fatalf("program.Statements does not contain 2 statements. got=%d", len(program.Statements))
+	}
+
+	stmt, ok := program.Statements[0].(*RequireStatement)
+	if !ok {
+		t.Fatalf("statement is not *RequireStatement. got=%T", program.Statements[0])
+	}
+	if !stmt.Once {
+		t.Errorf("require_once should set Once")
+	}
+	path, ok := stmt.Path.(*InfixExpression)
+	if !ok {
+		t.Fatalf("require path is not *InfixExpression. got=%T", stmt.Path)
+	}
+	if _, ok := path.Left.(*MagicConstant); !ok {
+		t.Errorf("path.Left is not *MagicConstant. got=%T", path.Left)
+	}
+
+	assign := program.Statements[1].(*ExpressionStatement).Expression.(*AssignmentExpression)
+	require, ok := assign.Value.(*RequireExpression)
+	if !ok {
+		t.Fatalf("$config value is not *RequireExpression. got=%T", assign.Value)
+	}
+	if require.Once {
+		t.Errorf("require should not set Once")
+	}
+
+	data, err := ToJSON(stmt)
+	if err != nil {
+		t.Fatalf("ToJSON failed: %v", err)
+	}
+	if !strings.Contains(string(data), `"type": "RequireStatement"`) || !strings.Contains(string(data), `"once": true`) {
+		t.Errorf("ToJSON output wrong: %s", data)
+	}
+}
+
+func TestParseArrowFunctionComplexBodies(t *testing.T) {
+	tests := []struct {
+		input    string
+		bodyType string
+		expected string
+	}{
+		{`<?php $f = fn($x) => match($x) { 1, 2 => 'low', default => 'high' }; ?>`, "*gophpparser.MatchExpression", "match ($x) { 1, 2 => low, default => high }"},
+		{`<?php $f = fn($x) => $x > 0 ? 'p' : 'n'; ?>`, "*gophpparser.TernaryExpression", "(($x > 0) ? p : n)"},
+		{`<?php $f = fn($x) => $x ?? 'd'; ?>`, "*gophpparser.CoalesceExpression", "($x ?? d)"},
+	}
+
+	for _, tt := range tests {
+		l := New(tt.input)
+		p := NewParser(l)
+		program := p.ParseProgram()
+
+		if len(p.Errors()) != 0 {
+			t.Errorf("parser has %d errors for %q", len(p.Errors()), tt.input)
+			for _, err := range p.Errors() {
+				t.Errorf("parser error: %q", err)
+			}
+			continue
+		}
+
+		assign := program.Statements[0].(*ExpressionStatement).Expression.(*AssignmentExpression)
+		fn, ok := assign.Value.(*ArrowFunction)
+		if !ok {
+			t.Fatalf("value is not *ArrowFunction. got=%T", assign.Value)
+		}
+		if got := fmt.Sprintf("%T", fn.Body); got != tt.bodyType {
+			t.Errorf("body type wrong. expected=%s, got=%s", tt.bodyType, got)
+		}
+		if fn.Body.String() != tt.expected {
+			t.Errorf("body wrong. expected=%q, got=%q", tt.expected, fn.Body.String())
+		}
+	}
+}
+
+func TestParseDestructuringAssignment(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected string
+		items    int
+	}{
+		{`<?php list($a, $b) = $arr; ?>`, "list($a, $b) = $arr", 2},
+		{`<?php [$a, $b] = $arr; ?>`, "[$a, $b] = $arr", 2},
+		{`<?php list(, $b) = $arr; ?>`, "list(, $b) = $arr", 2},
+		{`<?php list('x' => $x, 'y' => $y) = $point; ?>`, "list(x => $x, y => $y) = $point", 2},
+		{`<?php ['id' => $id, 'tags' => [$first]] = $row; ?>`, "[id => $id, tags => [$first]] = $row", 2},
+		{`<?php [$this->a, $b[0]] = pair(); ?>`, "[$this->a, ($b[0])] = pair()", 2},
+		{`<?php [, $b] = $arr; ?>`, "[, $b] = $arr", 2},
+		{`<?php [$a, , $b] = $arr; ?>`, "[$a, , $b] = $arr", 3},
+		{`<?php [[, $x], $y] = $arr; ?>`, "[[, $x], $y] = $arr", 2},
+	}
+
+	for _, tt := range tests {
+		l := New(tt.input)
+		p := NewParser(l)
+		program := p.ParseProgram()
+
+		if len(p.Errors()) != 0 {
+			t.Errorf("parser has %d errors for %q", len(p.Errors()), tt.input)
+			for _, err := range p.Errors() {
+				t.Errorf("parser error: %q", err)
+			}
+			continue
+		}
+
+		stmt := program.Statements[0].(*ExpressionStatement)
+		assign, ok := stmt.Expression.(*DestructuringAssignment)
+		if !ok {
+			t.Fatalf("expression is not *DestructuringAssignment. got=%T", stmt.Expression)
+		}
+		if len(assign.Targets.Items) != tt.items {
+			t.Errorf("targets do not contain %d items. got=%d", tt.items, len(assign.Targets.Items))
+		}
+		if assign.String() != tt.expected {
+			t.Errorf("assignment wrong. expected=%q, got=%q", tt.expected, assign.String())
+		}
+	}
+}
+
+func TestParseDestructuringSkipsAndKeys(t *testing.T) {
+	input := `<?php list(, $b, 'k' => list($c)) = $arr; ?>`
+
+	l := New(input)
+	p := NewParser(l)
+	program := p.ParseProgram()
+
+	if len(p.Errors()) != 0 {
+		t.Fatalf("parser errors: %v", p.Errors())
+	}
+
+	assign := program.Statements[0].(*ExpressionStatement).Expression.(*DestructuringAssignment)
+	items := assign.Targets.Items
+	if len(items) != 3 {
+		t.Fatalf("targets do not contain 3 items. got=%d", len(items))
+	}
+	if items[0] != nil {
+		t.Errorf("items[0] should be nil for a skipped element. got=%q", items[0].String())
+	}
+	if items[1].Key != nil || items[1].Value.String() != "$b" {
+		t.Errorf("items[1] wrong. got=%q", items[1].String())
+	}
+	if _, ok := items[2].Value.(*ListExpression); !ok {
+		t.Errorf("items[2].Value is not *ListExpression. got=%T", items[2].Value)
+	}
+
+	data, err := ToJSON(assign)
+	if err != nil {
+		t.Fatalf("ToJSON failed: %v", err)
+	}
+	if !strings.Contains(string(data), `"type": "DestructuringAssignment"`) || !strings.Contains(string(data), `"targets"`) {
+		t.Errorf("ToJSON output wrong: %s", data)
+	}
+
+	short := `<?php foreach ($rows as [, $second]) { echo $second; } ?>`
+	p = NewParser(New(short))
+	program = p.ParseProgram()
+	if len(p.Errors()) != 0 {
+		t.Fatalf("parser errors for %q: %v", short, p.Errors())
+	}
+	if got := PrettyPrint(program); !strings.Contains(got, "foreach ($rows as [, $second])") {
+		t.Errorf("skipped element not printed. got=%q", got)
+	}
+
+	for _, input := range []string{`<?php [1, $b] = $arr; ?>`, `<?php [$a] += $arr; ?>`} {
+		p := NewParser(New(input))
+		p.ParseProgram()
+		if len(p.Errors()) == 0 {
+			t.Errorf("expected an error for %q", input)
+		}
+	}
+
+	for _, input := range []string{`<?php list($a); ?>`, `<?php [$a, , $b]; ?>`} {
+		p := NewParser(New(input))
+		p.ParseProgram()
+		if len(p.Errors()) != 1 || !strings.HasPrefix(p.Errors()[0], "expected '=' after list pattern, got ';'") {
+			t.Errorf("expected one error for %q. got=%q", input, p.Errors())
+		}
+	}
+}
+
+func TestParseCompoundAssignmentToProperties(t *testing.T) {
+	tests := []struct {
+		input        string
+		expected     string
+		operator     TokenType
+		expectedType string
+	}{
+		{"<?php self::$count += 1; ?>", "self::$count += 1", PLUS_ASSIGN, "*gophpparser.StaticAccessExpression"},
+		{"<?php $this->total *= 2; ?>", "$this->total *= 2", MULTIPLY_ASSIGN, "*gophpparser.ObjectAccessExpression"},
+		{"<?php Foo::$map['k'] = $v; ?>", "(Foo::$map[k]) = $v", ASSIGN, "*gophpparser.IndexExpression"},
+		{"<?php $this->items['a'] .= 'x'; ?>", "($this->items[a]) .= x", CONCAT_ASSIGN, "*gophpparser.IndexExpression"},
+		{"<?php static::$flags |= 4; ?>", "static::$flags |= 4", BIT_OR_ASSIGN, "*gophpparser.StaticAccessExpression"},
+		{"<?php $n -= $step; ?>", "$n -= $step", MINUS_ASSIGN, ""},
+	}
+
+	for _, tt := range tests {
+		l := New(tt.input)
+		p := NewParser(l)
+		program := p.ParseProgram()
+
+		if len(p.Errors()) != 0 {
+			t.Errorf("parser has %d errors for %q", len(p.Errors()), tt.input)
+			for _, err := range p.Errors() {
+				t.Errorf("parser error: %q", err)
+			}
+			continue
+		}
+
+		stmt := program.Statements[0].(*ExpressionStatement)
+		assign, ok := stmt.Expression.(*AssignmentExpression)
+		if !ok {
+			t.Fatalf("stmt.Expression is not *AssignmentExpression. got=%T", stmt.Expression)
+		}
+		if assign.Token.Type != tt.operator {
+			t.Errorf("operator wrong for %q. expected=%s, got=%s", tt.input, tt.operator, assign.Token.Type)
+		}
+		if tt.expectedType == "" {
+			if assign.Name == nil {
+				t.Errorf("assign.Name should be set for %q", tt.input)
+			}
+		} else if got := fmt.Sprintf("%T", assign.Target); got != tt.expectedType {
+			t.Errorf("target type wrong for %q. expected=%s, got=%s", tt.input, tt.expectedType, got)
+		}
+		if assign.String() != tt.expected {
+			t.Errorf("expected=%q, got=%q", tt.expected, assign.String())
+		}
+	}
+}
+
+func TestParseAbstractAndFinalModifiers(t *testing.T) {
+	input := `<?php
+abstract class A {
+    final public function f() {}
+    public abstract static function g();
+}
+final class B {}
+?>`
+
+	l := New(input)
+	p := NewParser(l)
+	program := p.ParseProgram()
+
+	if len(p.Errors()) != 0 {
+		t.Errorf("parser has %d errors", len(p.Errors()))
+		for _, err := range p.Errors() {
+			t.Errorf("parser error: %q", err)
+		}
+		return
+	}
+
+	if len(program.Statements) != 2 {
+		t.Fatalf("program.Statements does not contain 2 statements. got=%d", len(program.Statements))
+	}
+
+	a, ok := program.Statements[0].(*ClassDeclaration)
+	if !ok {
+		t.Fatalf("statement is not *ClassDeclaration. got=%T", program.Statements[0])
+	}
+	if !a.Abstract || a.Final {
+		t.Errorf("class A modifiers wrong. abstract=%t, final=%t", a.Abstract, a.Final)
+	}
+	if len(a.Methods) != 2 {
+		t.Fatalf("class A does not contain 2 methods. got=%d", len(a.Methods))
+	}
+	if f := a.Methods[0]; !f.Final || f.Abstract || f.Visibility != "public" {
+		t.Errorf("method f modifiers wrong. final=%t, abstract=%t, visibility=%s", f.Final, f.Abstract, f.Visibility)
+	}
+	if g := a.Methods[1]; !g.Abstract || !g.Static || g.Final {
+		t.Errorf("method g modifiers wrong. abstract=%t, static=%t, final=%t", g.Abstract, g.Static, g.Final)
+	}
+
+	b, ok := program.Statements[1].(*ClassDeclaration)
+	if !ok {
+		t.Fatalf("statement is not *ClassDeclaration. got=%T", program.Statements[1])
+	}
+	if !b.Final || b.Abstract {
+		t.Errorf("class B modifiers wrong. abstract=%t, final=%t", b.Abstract, b.Final)
+	}
+
+	data, err := ToJSON(a)
+	if err != nil {
+		t.Fatalf("ToJSON failed: %v", err)
+	}
+	if !strings.Contains(string(data), `"abstract": true`) || !strings.Contains(string(data), `"final": true`) {
+		t.Errorf("ToJSON output wrong: %s", data)
+	}
+
+	for _, input := range []string{
+		`<?php abstract final class C {} ?>`,
+		`<?php class D { abstract final function h() {} } ?>`,
+		`<?php final function i() {} ?>`,
+		`<?php abstract class E { abstract function j() {} } ?>`,
+		`<?php class F { abstract function k(); } ?>`,
+		`<?php final class G { abstract function l(); } ?>`,
+	} {
+		p := NewParser(New(input))
+		p.ParseProgram()
+		if len(p.Errors()) == 0 {
+			t.Errorf("expected an error for %q", input)
+		}
+	}
+}
+
+func TestParseAbstractMethodWithoutBody(t *testing.T) {
+	input := `<?php
+abstract class A {
+    abstract public function f();
+    abstract protected function g(int $x): string;
+    public function h() { return 1; }
+}
+?>`
+
+	l := New(input)
+	p := NewParser(l)
+	program := p.ParseProgram()
+
+	if len(p.Errors()) != 0 {
+		t.Errorf("parser has %d errors", len(p.Errors()))
+		for _, err := range p.Errors() {
+			t.Errorf("parser error: %q", err)
+		}
+		return
+	}
+
+	class := program.Statements[0].(*ClassDeclaration)
+	if len(class.Methods) != 3 {
+		t.Fatalf("class does not contain 3 methods. got=%d", len(class.Methods))
+	}
+
+	tests := []struct {
+		expected string
+		abstract bool
+	}{
+		{"abstract public function f();", true},
+		{"abstract protected function g(int $x): string;", true},
+		{"public function h() {return 1;}", false},
+	}
+
+	for i, tt := range tests {
+		method := class.Methods[i]
+		if method.Abstract != tt.abstract {
+			t.Errorf("methods[%d].Abstract wrong. expected=%t, got=%t", i, tt.abstract, method.Abstract)
+		}
+		if (method.Body == nil) != tt.abstract {
+			t.Errorf("methods[%d].Body wrong. got=%v", i, method.Body)
+		}
+		if method.String() != tt.expected {
+			t.Errorf("methods[%d].String() wrong. expected=%q, got=%q", i, tt.expected, method.String())
+		}
+	}
+
+	data, err := ToJSON(class.Methods[0])
+	if err != nil {
+		t.Fatalf("ToJSON failed: %v", err)
+	}
+	if !strings.Contains(string(data), `"body": null`) || !strings.Contains(string(data), `"abstract": true`) {
+		t.Errorf("ToJSON output wrong: %s", data)
+	}
+
+	p = NewParser(New(`<?php class B { public function f(); } ?>`))
+	p.ParseProgram()
+	if len(p.Errors()) == 0 {
+		t.Errorf("expected an error for a body-less method that is not abstract")
+	}
+}
+
+func TestParseReturnStatementTerminators(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected string
+	}{
+		{"<?php return $x ?>", "return $x;"},
+		{"<?php return ?>", "return;"},
+		{"<?php return;", "return;"},
+		{"<?php return", "return;"},
+		{"<?php return $x ?>\n<?php echo $y;", "return $x;"},
+	}
+
+	for _, tt := range tests {
+		l := New(tt.input)
+		p := NewParser(l)
+		program := p.ParseProgram()
+
+		if len(p.Errors()) != 0 {
+			t.Errorf("input %q: parser has %d errors", tt.input, len(p.Errors()))
+			for _, err := range p.Errors() {
+				t.Errorf("parser error: %q", err)
+			}
+			continue
+		}
+
+		if len(program.Statements) == 0 {
+			t.Errorf("input %q: program has no statements", tt.input)
+			continue
+		}
+
+		stmt, ok := program.Statements[0].(*ReturnStatement)
+		if !ok {
+			t.Errorf("input %q: program.Statements[0] is not *ReturnStatement. got=%T", tt.input, program.Statements[0])
+			continue
+		}
+		if stmt.String() != tt.expected {
+			t.Errorf("input %q: stmt.String() wrong. expected=%q, got=%q", tt.input, tt.expected, stmt.String())
+		}
+	}
+}
+
+func TestParseCloseTagInsideBlocks(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected string
+	}{
+		{"<?php function f($x) { return $x ?> <?php }", "function f($x) {return $x;}"},
+		{"<?php function f($x) { if ($x) { echo $x ?>\n<?php } return 1; }", "function f($x) {if$x {echo $x;}return 1;}"},
+		{"<?php switch ($a) { case 1: return $x ?> <?php case 2: echo 1; }", "switch ($a) { case 1: return $x; case 2: echo 1; }"},
+	}
+
+	for _, tt := range tests {
+		l := New(tt.input)
+		p := NewParser(l)
+		program := p.ParseProgram()
+
+		if len(p.Errors()) != 0 {
+			t.Errorf("input %q: parser has %d errors", tt.input, len(p.Errors()))
+			for _, err := range p.Errors() {
+				t.Errorf("parser error: %q", err)
+			}
+			continue
+		}
+
+		if program.String() != tt.expected {
+			t.Errorf("input %q: program.String() wrong. expected=%q, got=%q", tt.input, tt.expected, program.String())
+		}
+	}
+}
+
+func TestParseWordLogicalOperators(t *testing.T) {
+	input := `<?php
+$a = true and false;
+$f = fopen("data.txt") or die("cannot open");
+$x or $y xor $z and $w;
+$query->or()->and();
+?>`
+
+	l := New(input)
+	p := NewParser(l)
+	program := p.ParseProgram()
+
+	if len(p.Errors()) != 0 {
+		t.Errorf("parser has %d errors", len(p.Errors()))
+		for _, err := range p.Errors() {
+			t.Errorf("parser error: %q", err)
+		}
+		return
+	}
+
+	if len(program.Statements) != 4 {
+		t.Fatalf("program.Statements does not contain 4 statements. got=%d", len(program.Statements))
+	}
+
+	// The assignment binds tighter than the word operator on both sides
+	for i, operator := range []string{"and", "or"} {
+		stmt := program.Statements[i].(*ExpressionStatement)
+		infix, ok := stmt.Expression.(*InfixExpression)
+		if !ok {
+			t.Fatalf("statement %d is not *InfixExpression. got=%T", i, stmt.Expression)
+		}
+		if infix.Operator != operator {
+			t.Errorf("statement %d operator not %q. got=%q", i, operator, infix.Operator)
+		}
+		if _, ok := infix.Left.(*AssignmentExpression); !ok {
+			t.Errorf("statement %d left side is not *AssignmentExpression. got=%T", i, infix.Left)
+		}
+	}
+
+	// or binds loosest, then xor, then and
+	chain := program.Statements[2].(*ExpressionStatement).Expression
+	if chain.String() != "($x or ($y xor ($z and $w)))" {
+		t.Errorf("word operator precedence wrong. got=%q", chain.String())
+	}
+
+	// The words remain usable as method names
+	if got := program.Statements[3].String(); got != "$query->or()->and()" {
+		t.Errorf("method names wrong. got=%q", got)
+	}
+}
+
+func TestParseWordOperatorsIgnoreCase(t *testing.T) {
+	input := `<?php
+$a = TRUE AND false;
+for ($i = 1 Or 0; $i < 1; $i++) {}
+?>`
+
+	l := New(input)
+	p := NewParser(l)
+	program := p.ParseProgram()
+
+	if len(p.Errors()) != 0 {
+		t.Errorf("parser has %d errors", len(p.Errors()))
+		for _, err := range p.Errors() {
+			t.Errorf("parser error: %q", err)
+		}
+		return
+	}
+
+	if len(program.Statements) != 2 {
+		t.Fatalf("program.Statements does not contain 2 statements. got=%d", len(program.Statements))
+	}
+
+	stmt := program.Statements[0].(*ExpressionStatement)
+	and, ok := stmt.Expression.(*InfixExpression)
+	if !ok {
+		t.Fatalf("stmt.Expression is not *InfixExpression. got=%T", stmt.Expression)
+	}
+	if _, ok := and.Left.(*AssignmentExpression); !ok || and.Operator != "AND" {
+		t.Errorf("statement binds wrong. got=%q", stmt.Expression.String())
+	}
+
+	loop, ok := program.Statements[1].(*ForStatement)
+	if !ok {
+		t.Fatalf("program.Statements[1] is not *ForStatement. got=%T", program.Statements[1])
+	}
+	infix, ok := loop.Init.(*InfixExpression)
+	if !ok {
+		t.Fatalf("loop.Init is not *InfixExpression. got=%T", loop.Init)
+	}
+	if _, ok := infix.Left.(*AssignmentExpression); !ok || infix.Operator != "Or" {
+		t.Errorf("for init binds wrong. got=%q", loop.Init.String())
+	}
+}
+
+func TestParseAttachesLeadingComments(t *testing.T) {
+	input := `<?php
+/** @param int $x */
+function double($x) {
+    return $x * 2;
+}
+
+$y = 1; // not about triple
+function triple($x) {}
+
+// Users of the system
+/** A user. */
+final class User {
+    /** @var int */
+    private ?int $id = null;
+
+    /** Returns the name. */
+    public static function name(): string {
+        return "";
+    }
+}
+?>`
+
+	p := NewParser(New(input))
+	p.AttachComments = true
+	program := p.ParseProgram()
+
+	if len(p.Errors()) != 0 {
+		t.Errorf("parser has %d errors", len(p.Errors()))
+		for _, err := range p.Errors() {
+			t.Errorf("parser error: %q", err)
+		}
+		return
+	}
+
+	var functions []*FunctionDeclaration
+	var class *ClassDeclaration
+	for _, stmt := range program.Statements {
+		switch s := stmt.(type) {
+		case *FunctionDeclaration:
+			functions = append(functions, s)
+		case *ClassDeclaration:
+			class = s
+		}
+	}
+	if len(functions) != 2 || class == nil {
+		t.Fatalf("expected 2 functions and a class. got=%d functions, class=%v", len(functions), class)
+	}
+
+	if functions[0].DocBlock != "/** @param int $x */" {
+		t.Errorf("double() docblock wrong. got=%q", functions[0].DocBlock)
+	}
+	if len(functions[1].LeadingComments) != 0 || functions[1].DocBlock != "" {
+		t.Errorf("a comment trailing other code should not attach to triple(). got=%q", functions[1].LeadingComments)
+	}
+
+	if len(class.LeadingComments) != 2 || class.LeadingComments[0] != "// Users of the system" {
+		t.Errorf("class leading comments wrong. got=%q", class.LeadingComments)
+	}
+	if class.DocBlock != "/** A user. */" {
+		t.Errorf("class docblock wrong. got=%q", class.DocBlock)
+	}
+	if len(class.Properties) != 1 || class.Properties[0].DocBlock != "/** @var int */" {
+		t.Errorf("property docblock wrong. got=%v", class.Properties)
+	}
+	if len(class.Methods) != 1 || class.Methods[0].DocBlock != "/** Returns the name. */" {
+		t.Errorf("method docblock wrong. got=%v", class.Methods)
+	}
+
+	// Attachment is off by default
+	p = NewParser(New(input))
+	program = p.ParseProgram()
+	for _, stmt := range program.Statements {
+		if fn, ok := stmt.(*FunctionDeclaration); ok && fn.DocBlock != "" {
+			t.Errorf("docblock attached without AttachComments. got=%q", fn.DocBlock)
+		}
+	}
+}
@@ -0,0 +1,165 @@
+package gophpparser
+
+import "reflect"
+
+// SourceRange returns the byte offsets of the source a node was parsed
+// from, as the half-open range [start, end), so that source[start:end] is
+// the node's code. The range spans the node's own token and those of every
+// node below it, along with the closing delimiters the AST does not keep,
+// such as the ) of a call or the } of a block, and the parentheses around
+// the nodes below it. A node without positioned tokens yields 0, 0.
+func SourceRange(node Node) (start, end int) {
+	start = -1
+	Walk(node, func(n Node) bool {
+		tok, ok := nodeToken(n)
+		if !ok {
+			return true
+		}
+		if tok.End > tok.Position {
+			if start < 0 || tok.Position < start {
+				start = tok.Position
+			}
+			end = max(end, tok.End)
+		}
+		end = max(end, tok.closeEnd)
+		// The parentheses around the node itself are not part of it
+		if n != node && tok.groupEnd > tok.groupStart {
+			if start < 0 || tok.groupStart < start {
+				start = tok.groupStart
+			}
+			end = max(end, tok.groupEnd)
+		}
+		return true
+	})
+
+	if start < 0 {
+		return 0, 0
+	}
+	return start, end
+}
+
+// nodeToken returns the Token field of a node, if it has one
+func nodeToken(node Node) (Token, bool) {
+	value := reflect.ValueOf(node)
+	if value.Kind() == reflect.Pointer {
+		value = value.Elem()
+	}
+	if value.Kind() != reflect.Struct {
+		return Token{}, false
+	}
+
+	field := value.FieldByName("Token")
+	if !field.IsValid() {
+		return Token{}, false
+	}
+	tok, ok := field.Interface().(Token)
+	return tok, ok
+}
+
+// closeNode records that node ends with the current token, when that is a
+// closing delimiter the AST does not keep
+func (p *Parser) closeNode(node Node) {
+	switch p.curToken.Type {
+	case RPAREN, RBRACKET, RBRACE:
+		end := p.curToken.End
+		updateToken(node, func(tok *Token) { tok.closeEnd = max(tok.closeEnd, end) })
+	}
+}
+
+// groupNode records the parentheses around node, from the ( at start to
+// the current token
+func (p *Parser) groupNode(node Node, start int) {
+	end := p.curToken.End
+	updateToken(node, func(tok *Token) { tok.groupStart, tok.groupEnd = start, end })
+}
+
+// updateToken changes the Token field of a node in place
+func updateToken(node Node, update func(*Token)) {
+	value := reflect.ValueOf(node)
+	if value.Kind() != reflect.Pointer || value.IsNil() || value.Elem().Kind() != reflect.Struct {
+		return
+	}
+	field := value.Elem().FieldByName("Token")
+	if !field.IsValid() || field.Type() != tokenType || !field.CanSet() {
+		return
+	}
+	tok := field.Interface().(Token)
+	update(&tok)
+	field.Set(reflect.ValueOf(tok))
+}
+
+// shiftedCopy returns a deep copy of node with delta added to the byte
+// offsets of every positioned token, for a node reused after an edit moved
+// it. node itself is left untouched.
+func shiftedCopy(node Node, delta int) Node {
+	copied := shiftValue(reflect.ValueOf(node), delta, make(map[uintptr]reflect.Value))
+	return copied.Interface().(Node)
+}
+
+func shiftValue(value reflect.Value, delta int, copies map[uintptr]reflect.Value) reflect.Value {
+	switch value.Kind() {
+	case reflect.Pointer:
+		if value.IsNil() {
+			return value
+		}
+		if copied, ok := copies[value.Pointer()]; ok {
+			return copied
+		}
+		copied := reflect.New(value.Type().Elem())
+		copies[value.Pointer()] = copied
+		copied.Elem().Set(shiftValue(value.Elem(), delta, copies))
+		return copied
+	case reflect.Interface:
+		if value.IsNil() {
+			return value
+		}
+		copied := reflect.New(value.Type()).Elem()
+		copied.Set(shiftValue(value.Elem(), delta, copies))
+		return copied
+	case reflect.Slice:
+		if value.IsNil() {
+			return value
+		}
+		copied := reflect.MakeSlice(value.Type(), value.Len(), value.Len())
+		for i := 0; i < value.Len(); i++ {
+			copied.Index(i).Set(shiftValue(value.Index(i), delta, copies))
+		}
+		return copied
+	case reflect.Map:
+		if value.IsNil() {
+			return value
+		}
+		copied := reflect.MakeMapWithSize(value.Type(), value.Len())
+		iter := value.MapRange()
+		for iter.Next() {
+			copied.SetMapIndex(iter.Key(), shiftValue(iter.Value(), delta, copies))
+		}
+		return copied
+	case reflect.Struct:
+		copied := reflect.New(value.Type()).Elem()
+		copied.Set(value)
+		if value.Type() == tokenType {
+			tok := value.Interface().(Token)
+			if tok.End > tok.Position {
+				tok.Position += delta
+				tok.End += delta
+			}
+			if tok.closeEnd > 0 {
+				tok.closeEnd += delta
+			}
+			if tok.groupEnd > tok.groupStart {
+				tok.groupStart += delta
+				tok.groupEnd += delta
+			}
+			copied.Set(reflect.ValueOf(tok))
+			return copied
+		}
+		for i := 0; i < value.NumField(); i++ {
+			if value.Type().Field(i).IsExported() {
+				copied.Field(i).Set(shiftValue(value.Field(i), delta, copies))
+			}
+		}
+		return copied
+	}
+	return value
+}
@@ -0,0 +1,57 @@
+package gophpparser
+
+import "testing"
+
+func TestSourceRange(t *testing.T) {
+	input := `<?php
+$total = $price * (1 + $rate);
+echo format($total, "EUR");
+if ($total > 100) {
+    $total = round($total); // rounded
+}
+`
+
+	l := New(input)
+	p := NewParser(l)
+	program := p.ParseProgram()
+
+	if len(p.Errors()) != 0 {
+		t.Errorf("parser has %d errors", len(p.Errors()))
+		for _, err := range p.Errors() {
+			t.Errorf("parser error: %q", err)
+		}
+		return
+	}
+
+	assignment := program.Statements[0].(*ExpressionStatement).Expression.(*AssignmentExpression)
+	infix := assignment.Value.(*InfixExpression)
+	echo := program.Statements[1].(*EchoStatement)
+	block := program.Statements[2].(*IfStatement).Consequence
+
+	tests := []struct {
+		name     string
+		node     Node
+		expected string
+	}{
+		{"assignment", assignment, "$total = $price * (1 + $rate)"},
+		{"product", infix, "$price * (1 + $rate)"},
+		{"grouped sum", infix.Right, "1 + $rate"},
+		{"variable", infix.Left, "$price"},
+		{"echo", echo, `echo format($total, "EUR")`},
+		{"call", echo.Values[0], `format($total, "EUR")`},
+		{"string argument", echo.Values[0].(*CallExpression).Arguments[1], `"EUR"`},
+		{"block", block, "{\n    $total = round($total); // rounded\n}"},
+		{"comment", block.Statements[1], "// rounded"},
+	}
+
+	for _, tt := range tests {
+		start, end := SourceRange(tt.node)
+		if got := input[start:end]; got != tt.expected {
+			t.Errorf("%s: source range wrong. expected=%q, got=%q", tt.name, tt.expected, got)
+		}
+	}
+
+	if start, end := SourceRange(&Identifier{Value: "synthetic"}); start != 0 || end != 0 {
+		t.Errorf("node without a positioned token should yield 0, 0. got=%d, %d", start, end)
+	}
+}
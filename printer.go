@@ -0,0 +1,901 @@
+package gophpparser
+
+import (
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// PrinterOptions configures PrettyPrintWithOptions
+type PrinterOptions struct {
+	// Indent is the text for one level of indentation. It defaults to four
+	// spaces.
+	Indent string
+}
+
+// PrettyPrint renders a node as readable PHP source. A Program is printed
+// as a complete file starting with <?php; any other node is printed on its
+// own. Parentheses are only added where operator precedence requires them,
+// so parsing the output gives back an equivalent AST.
+func PrettyPrint(node Node) string {
+	return PrettyPrintWithOptions(node, PrinterOptions{})
+}
+
+// PrettyPrintWithOptions renders a node as PrettyPrint does, configured by
+// options
+func PrettyPrintWithOptions(node Node, options PrinterOptions) string {
+	if options.Indent == "" {
+		options.Indent = "    "
+	}
+	pr := &printer{options: options, out: &strings.Builder{}}
+
+	switch n := node.(type) {
+	case *Program:
+		pr.out.WriteString("<?php\n")
+		if len(n.Statements) > 0 {
+			pr.out.WriteString("\n")
+		}
+		pr.statements(n.Statements)
+	case Statement:
+		pr.statement(n)
+	case Expression:
+		pr.out.WriteString(pr.expression(n, LOWEST, true))
+	}
+	return pr.out.String()
+}
+
+// primaryPrecedence binds tighter than any operator, for literals, names
+// and other expressions that never need parentheses
+const primaryPrecedence = CALL + 1
+
+type printer struct {
+	options PrinterOptions
+	out     *strings.Builder
+	depth   int
+}
+
+// line writes text as one line at the current indentation
+func (pr *printer) line(text string) {
+	pr.out.WriteString(pr.indent() + text + "\n")
+}
+
+func (pr *printer) indent() string {
+	return strings.Repeat(pr.options.Indent, pr.depth)
+}
+
+// statements writes a statement list, separating declarations from their
+// neighbours with a blank line
+func (pr *printer) statements(statements []Statement) {
+	for i, stmt := range statements {
+		if i > 0 && needsBlankLine(statements[i-1], commentedStatement(statements[i:])) {
+			pr.out.WriteString("\n")
+		}
+		pr.statement(stmt)
+	}
+}
+
+// commentedStatement returns the first statement that is not a comment, so
+// comments are spaced as part of the statement they lead into
+func commentedStatement(statements []Statement) Statement {
+	for _, stmt := range statements {
+		if _, ok := stmt.(*Comment); !ok {
+			return stmt
+		}
+	}
+	return statements[0]
+}
+
+func needsBlankLine(previous, next Statement) bool {
+	if _, ok := previous.(*Comment); ok {
+		return false
+	}
+	if isDeclaration(previous) || isDeclaration(next) {
+		return true
+	}
+	switch p := previous.(type) {
+	case *NamespaceDeclaration:
+		return true
+	case *DeclareStatement:
+		return p.Body == nil
+	case *UseStatement:
+		_, ok := next.(*UseStatement)
+		return !ok
+	}
+	return false
+}
+
+func isDeclaration(stmt Statement) bool {
+	switch stmt.(type) {
+	case *FunctionDeclaration, *ClassDeclaration, *InterfaceDeclaration, *TraitDeclaration, *EnumDeclaration:
+		return true
+	}
+	return false
+}
+
+// block renders a brace-delimited body, its statements indented one level
+// deeper than the current line
+func (pr *printer) block(block *BlockStatement) string {
+	if block == nil {
+		return "{\n" + pr.indent() + "}"
+	}
+	return pr.nested(func() { pr.statements(block.Statements) })
+}
+
+// nested renders whatever write produces one level deeper, wrapped in braces
+func (pr *printer) nested(write func()) string {
+	outer := pr.out
+	pr.out = &strings.Builder{}
+	pr.depth++
+	write()
+	pr.depth--
+	inner := pr.out.String()
+	pr.out = outer
+	return "{\n" + inner + pr.indent() + "}"
+}
+
+func (pr *printer) statement(stmt Statement) {
+	switch s := stmt.(type) {
+	case *ExpressionStatement:
+		if s.Expression != nil {
+			pr.line(pr.expression(s.Expression, LOWEST, true) + ";")
+		}
+	case *EchoStatement:
+		pr.line("echo " + pr.expressionList(s.Values) + ";")
+	case *ReturnStatement:
+		if s.ReturnValue == nil {
+			pr.line("return;")
+		} else {
+			pr.line("return " + pr.expression(s.ReturnValue, LOWEST, true) + ";")
+		}
+	case *BreakStatement:
+		pr.line(pr.withLevel("break", s.Level) + ";")
+	case *ContinueStatement:
+		pr.line(pr.withLevel("continue", s.Level) + ";")
+	case *ThrowStatement:
+		pr.line("throw " + pr.expression(s.Expression, LOWEST, true) + ";")
+	case *UnsetStatement:
+		pr.line("unset(" + pr.expressionList(s.Arguments) + ");")
+	case *GlobalStatement:
+		names := make([]string, len(s.Variables))
+		for i, variable := range s.Variables {
+			names[i] = variable.String()
+		}
+		pr.line("global " + strings.Join(names, ", ") + ";")
+	case *StaticVariableStatement:
+		variables := make([]string, len(s.Variables))
+		for i, variable := range s.Variables {
+			variables[i] = variable.Variable.String()
+			if variable.Default != nil {
+				variables[i] += " = " + pr.expression(variable.Default, LOWEST, true)
+			}
+		}
+		pr.line("static " + strings.Join(variables, ", ") + ";")
+	case *IncludeStatement:
+		pr.line(includeKeyword("include", s.Once) + " " + pr.expression(s.Path, LOWEST, true) + ";")
+	case *RequireStatement:
+		pr.line(includeKeyword("require", s.Once) + " " + pr.expression(s.Path, LOWEST, true) + ";")
+	case *BlockStatement:
+		pr.line(pr.block(s))
+	case *IfStatement:
+		out := "if (" + pr.expression(s.Condition, LOWEST, true) + ") " + pr.block(s.Consequence)
+		for _, elseIf := range s.ElseIfs {
+			out += " elseif (" + pr.expression(elseIf.Condition, LOWEST, true) + ") " + pr.block(elseIf.Consequence)
+		}
+		if s.Alternative != nil {
+			out += " else " + pr.block(s.Alternative)
+		}
+		pr.line(out)
+	case *WhileStatement:
+		pr.line("while (" + pr.expression(s.Condition, LOWEST, true) + ") " + pr.block(s.Body))
+	case *ForStatement:
+		clauses := []string{"", "", ""}
+		for i, clause := range []Expression{s.Init, s.Condition, s.Update} {
+			if clause != nil {
+				clauses[i] = pr.expression(clause, LOWEST, true)
+			}
+		}
+		pr.line("for (" + strings.Join(clauses, "; ") + ") " + pr.block(s.Body))
+	case *ForeachStatement:
+		out := "foreach (" + pr.expression(s.Array, LOWEST, true) + " as "
+		if s.Key != nil {
+			out += s.Key.String() + " => "
+		}
+		if s.ValuePattern != nil {
+			out += pr.expression(s.ValuePattern, LOWEST, true)
+		} else {
+			out += s.Value.String()
+		}
+		pr.line(out + ") " + pr.block(s.Body))
+	case *SwitchStatement:
+		pr.line("switch (" + pr.expression(s.Subject, LOWEST, true) + ") " + pr.nested(func() {
+			for _, switchCase := range s.Cases {
+				if switchCase.IsDefault() {
+					pr.line("default:")
+				} else {
+					pr.line("case " + pr.expression(switchCase.Condition, LOWEST, true) + ":")
+				}
+				pr.depth++
+				pr.statements(switchCase.Body)
+				pr.depth--
+			}
+		}))
+	case *TryStatement:
+		out := "try " + pr.block(s.Body)
+		for _, catch := range s.Catches {
+			types := make([]string, len(catch.ExceptionTypes))
+			for i, exceptionType := range catch.ExceptionTypes {
+				types[i] = exceptionType.String()
+			}
+			out += " catch (" + strings.Join(types, " | ")
+			if catch.Variable != nil {
+				out += " " + catch.Variable.String()
+			}
+			out += ") " + pr.block(catch.Body)
+		}
+		if s.Finally != nil {
+			out += " finally " + pr.block(s.Finally)
+		}
+		pr.line(out)
+	case *DeclareStatement:
+		keys := make([]string, 0, len(s.Directives))
+		for key := range s.Directives {
+			keys = append(keys, key)
+		}
+		sort.Strings(keys)
+		directives := make([]string, len(keys))
+		for i, key := range keys {
+			directives[i] = key + "=" + pr.expression(s.Directives[key], LOWEST, true)
+		}
+		out := "declare(" + strings.Join(directives, ", ") + ")"
+		if s.Body != nil {
+			pr.line(out + " " + pr.block(s.Body))
+		} else {
+			pr.line(out + ";")
+		}
+	case *NamespaceDeclaration:
+		pr.line("namespace " + s.Name.String() + ";")
+	case *UseStatement:
+		out := "use " + s.Namespace.String()
+		if s.Alias != nil {
+			out += " as " + s.Alias.String()
+		}
+		pr.line(out + ";")
+	case *Comment:
+		pr.comment(s.Text)
+	case *EmptyStatement:
+		pr.line(";")
+	case *FunctionDeclaration:
+		out := "function "
+		if s.ReturnsRef {
+			out += "&"
+		}
+		out += s.Name.String() + "(" + pr.parameters(s.Parameters) + ")" + pr.returnType(s.ReturnType)
+		pr.line(out)
+		pr.line(pr.block(s.Body))
+	case *ClassDeclaration:
+		out := "class " + s.Name.String()
+		if s.Final {
+			out = "final " + out
+		}
+		if s.Abstract {
+			out = "abstract " + out
+		}
+		if s.SuperClass != nil {
+			out += " extends " + s.SuperClass.String()
+		}
+		out += implementsClause(s.Interfaces)
+		pr.line(out)
+		pr.line(pr.nested(func() {
+			pr.members(s.TraitUses, nil, s.Constants, s.Properties, s.Methods)
+		}))
+	case *InterfaceDeclaration:
+		pr.line("interface " + s.Name.String())
+		pr.line(pr.nested(func() {
+			for _, method := range s.Methods {
+				pr.line(withModifier(method.Visibility, "function "+method.Name.String()+"("+pr.parameters(method.Parameters)+");"))
+			}
+		}))
+	case *TraitDeclaration:
+		pr.line("trait " + s.Name.String())
+		pr.line(pr.nested(func() {
+			pr.members(nil, nil, s.Constants, s.Properties, s.Methods)
+		}))
+	case *EnumDeclaration:
+		out := "enum " + s.Name.String()
+		if s.BackingType != nil {
+			out += ": " + pr.typeHint(s.BackingType)
+		}
+		out += implementsClause(s.Interfaces)
+		pr.line(out)
+		pr.line(pr.nested(func() {
+			pr.members(s.TraitUses, s.Cases, s.Constants, nil, s.Methods)
+		}))
+	case *ConstantDeclaration:
+		pr.line(pr.constant(s))
+	case *PropertyDeclaration:
+		pr.property(s)
+	case *MethodDeclaration:
+		pr.method(s)
+	case *TraitUse:
+		pr.line(traitUse(s))
+	case *EnumCase:
+		pr.line(pr.enumCase(s))
+	case *InterfaceMethod:
+		pr.line(withModifier(s.Visibility, "function "+s.Name.String()+"("+pr.parameters(s.Parameters)+");"))
+	}
+}
+
+// members writes the body of a class, trait or enum. Each kind of member
+// forms its own group, and groups and methods are separated by blank lines.
+func (pr *printer) members(traitUses []*TraitUse, cases []*EnumCase, constants []*ConstantDeclaration, properties []*PropertyDeclaration, methods []*MethodDeclaration) {
+	var groups []func()
+	if len(traitUses) > 0 {
+		groups = append(groups, func() {
+			for _, use := range traitUses {
+				pr.line(traitUse(use))
+			}
+		})
+	}
+	if len(cases) > 0 {
+		groups = append(groups, func() {
+			for _, enumCase := range cases {
+				pr.line(pr.enumCase(enumCase))
+			}
+		})
+	}
+	if len(constants) > 0 {
+		groups = append(groups, func() {
+			for _, constant := range constants {
+				pr.line(pr.constant(constant))
+			}
+		})
+	}
+	if len(properties) > 0 {
+		groups = append(groups, func() {
+			for _, property := range properties {
+				pr.property(property)
+			}
+		})
+	}
+	for _, method := range methods {
+		groups = append(groups, func() { pr.method(method) })
+	}
+
+	for i, group := range groups {
+		if i > 0 {
+			pr.out.WriteString("\n")
+		}
+		group()
+	}
+}
+
+func (pr *printer) property(property *PropertyDeclaration) {
+	for _, comment := range property.LeadingComments {
+		pr.comment(comment)
+	}
+	out := property.Visibility
+	if property.Static {
+		out = withModifier(out, "static")
+	}
+	if property.Readonly {
+		out = withModifier(out, "readonly")
+	}
+	if property.PropertyType != nil {
+		out = withModifier(out, pr.typeHint(property.PropertyType))
+	}
+	out = withModifier(out, property.Name.String())
+	if property.Value != nil {
+		out += " = " + pr.expression(property.Value, LOWEST, true)
+	}
+	pr.line(out + ";")
+}
+
+func (pr *printer) method(method *MethodDeclaration) {
+	for _, comment := range method.LeadingComments {
+		pr.comment(comment)
+	}
+	out := method.Visibility
+	if method.Final {
+		out = withModifier("final", out)
+	}
+	if method.Abstract {
+		out = withModifier("abstract", out)
+	}
+	if method.Static {
+		out = withModifier(out, "static")
+	}
+	signature := "function "
+	if method.ReturnsRef {
+		signature += "&"
+	}
+	signature += method.Name.String() + "(" + pr.parameters(method.Parameters) + ")" + pr.returnType(method.ReturnType)
+	out = withModifier(out, signature)
+
+	if method.Body == nil {
+		pr.line(out + ";")
+		return
+	}
+	pr.line(out)
+	pr.line(pr.block(method.Body))
+}
+
+func (pr *printer) constant(constant *ConstantDeclaration) string {
+	return withModifier(constant.Visibility, "const "+constant.Name.String()+" = "+pr.expression(constant.Value, LOWEST, true)+";")
+}
+
+func (pr *printer) enumCase(enumCase *EnumCase) string {
+	if enumCase.Value == nil {
+		return "case " + enumCase.Name.String() + ";"
+	}
+	return "case " + enumCase.Name.String() + " = " + pr.expression(enumCase.Value, LOWEST, true) + ";"
+}
+
+func traitUse(use *TraitUse) string {
+	traits := make([]string, len(use.Traits))
+	for i, trait := range use.Traits {
+		traits[i] = trait.String()
+	}
+	return "use " + strings.Join(traits, ", ") + ";"
+}
+
+func implementsClause(interfaces []*Identifier) string {
+	if len(interfaces) == 0 {
+		return ""
+	}
+	names := make([]string, len(interfaces))
+	for i, iface := range interfaces {
+		names[i] = iface.String()
+	}
+	return " implements " + strings.Join(names, ", ")
+}
+
+// withModifier joins a modifier and the rest of a declaration, skipping
+// the space when the modifier is absent
+func withModifier(modifier, rest string) string {
+	if modifier == "" {
+		return rest
+	}
+	return modifier + " " + rest
+}
+
+func includeKeyword(keyword string, once bool) string {
+	if once {
+		return keyword + "_once"
+	}
+	return keyword
+}
+
+func (pr *printer) withLevel(keyword string, level Expression) string {
+	if level == nil {
+		return keyword
+	}
+	return keyword + " " + pr.expression(level, LOWEST, true)
+}
+
+// comment writes a comment, re-indenting the continuation lines of a block
+// comment to the current depth
+func (pr *printer) comment(text string) {
+	lines := strings.Split(strings.TrimRight(text, "\n"), "\n")
+	pr.line(lines[0])
+	for _, line := range lines[1:] {
+		line = strings.TrimSpace(line)
+		if strings.HasPrefix(line, "*") {
+			line = " " + line
+		}
+		pr.line(line)
+	}
+}
+
+func (pr *printer) parameters(parameters []*Parameter) string {
+	out := make([]string, len(parameters))
+	for i, param := range parameters {
+		text := ""
+		for _, attribute := range param.Attributes {
+			text += pr.attribute(attribute) + " "
+		}
+		if param.Visibility != "" {
+			text += param.Visibility + " "
+		}
+		if param.Readonly {
+			text += "readonly "
+		}
+		if param.TypeHint != nil {
+			text += pr.typeHint(param.TypeHint) + " "
+		}
+		if param.ByRef {
+			text += "&"
+		}
+		if param.Variadic {
+			text += "..."
+		}
+		text += "$" + param.Name
+		if param.Default != nil {
+			text += " = " + pr.expression(param.Default, LOWEST, true)
+		}
+		out[i] = text
+	}
+	return strings.Join(out, ", ")
+}
+
+func (pr *printer) attribute(attribute *Attribute) string {
+	out := "#[" + attribute.Name.String()
+	if attribute.Arguments != nil {
+		out += "(" + pr.expressionList(attribute.Arguments) + ")"
+	}
+	return out + "]"
+}
+
+func (pr *printer) returnType(returnType Expression) string {
+	if returnType == nil {
+		return ""
+	}
+	return ": " + pr.expression(returnType, LOWEST, true)
+}
+
+// typeHint renders a declared type, grouping the intersections inside a
+// union as in (A&B)|null
+func (pr *printer) typeHint(th *TypeHint) string {
+	if len(th.Union) > 0 {
+		members := make([]string, len(th.Union))
+		for i, member := range th.Union {
+			members[i] = pr.typeHint(member)
+			if len(member.Intersection) > 0 {
+				members[i] = "(" + members[i] + ")"
+			}
+		}
+		return strings.Join(members, "|")
+	}
+	if len(th.Intersection) > 0 {
+		members := make([]string, len(th.Intersection))
+		for i, member := range th.Intersection {
+			members[i] = pr.typeHint(member)
+		}
+		return strings.Join(members, "&")
+	}
+	if th.Nullable {
+		return "?" + th.Name
+	}
+	return th.Name
+}
+
+func (pr *printer) expressionList(expressions []Expression) string {
+	out := make([]string, len(expressions))
+	for i, expr := range expressions {
+		out[i] = pr.expression(expr, LOWEST, true)
+	}
+	return strings.Join(out, ", ")
+}
+
+// expression renders expr where an operand must bind at least as tightly
+// as minPrecedence. open reports whether the expression runs to the end of
+// its context, such as a statement's semicolon or a closing bracket; where
+// it does not, expressions whose last operand would swallow the operator
+// that follows are wrapped in parentheses.
+func (pr *printer) expression(expr Expression, minPrecedence int, open bool) string {
+	if expr == nil {
+		return ""
+	}
+
+	wrap := expressionPrecedence(expr) < minPrecedence
+	switch e := expr.(type) {
+	case *ArrowFunction, *ThrowExpression, *YieldExpression, *PrintExpression, *IncludeExpression, *RequireExpression:
+		// These start with a keyword, so only what follows can go wrong
+		wrap = !open
+	case *TernaryExpression:
+		wrap = wrap || !open
+	case *AssignmentExpression:
+		// ??= binds more tightly than the value it parses
+		wrap = wrap || (!open && expressionPrecedence(e) > ASSIGNMENT)
+	}
+
+	if wrap {
+		return "(" + pr.bareExpression(expr, true) + ")"
+	}
+	return pr.bareExpression(expr, open)
+}
+
+// expressionPrecedence returns how tightly an expression binds as the
+// operand of another
+func expressionPrecedence(expr Expression) int {
+	switch e := expr.(type) {
+	case *InfixExpression:
+		return tokenPrecedence(e.Token.Type)
+	case *AssignmentExpression:
+		if e.Token.Type == ILLEGAL {
+			return ASSIGNMENT
+		}
+		return tokenPrecedence(e.Token.Type)
+	case *DestructuringAssignment:
+		return ASSIGNMENT
+	case *TernaryExpression:
+		return TERNARY
+	case *CoalesceExpression:
+		return COALESCE
+	case *PrefixExpression, *CloneExpression:
+		return PREFIX
+	case *InstanceofExpression:
+		return TYPE_CHECK
+	case *CallExpression, *IndexExpression, *ObjectAccessExpression, *StaticAccessExpression, *PostfixExpression:
+		return CALL
+	case *ArrowFunction, *ThrowExpression, *YieldExpression, *PrintExpression, *IncludeExpression, *RequireExpression:
+		return LOWEST
+	case *CommaExpression:
+		return LOWEST
+	}
+	return primaryPrecedence
+}
+
+func tokenPrecedence(tokenType TokenType) int {
+	if precedence, ok := precedences[tokenType]; ok {
+		return precedence
+	}
+	return LOWEST
+}
+
+// bareExpression renders expr without parentheses around itself
+func (pr *printer) bareExpression(expr Expression, open bool) string {
+	switch e := expr.(type) {
+	case *Identifier:
+		return e.Value
+	case *NamespacedIdentifier:
+		return e.String()
+	case *Variable:
+		return "$" + e.Name
+	case *IntegerLiteral:
+		if e.Token.Literal != "" {
+			return e.Token.Literal
+		}
+		return strconv.FormatInt(e.Value, 10)
+	case *FloatLiteral:
+		if e.Token.Literal != "" {
+			return e.Token.Literal
+		}
+		return strconv.FormatFloat(e.Value, 'g', -1, 64)
+	case *BooleanLiteral:
+		if e.Token.Literal != "" {
+			return e.Token.Literal
+		}
+		return strconv.FormatBool(e.Value)
+	case *NullLiteral:
+		if e.Token.Literal != "" {
+			return e.Token.Literal
+		}
+		return "null"
+	case *MagicConstant:
+		return e.Value
+	case *StringLiteral:
+		return quoteString(e.Value)
+	case *InterpolatedString:
+		return pr.interpolatedString(e)
+	case *ArrayLiteral:
+		return "[" + pr.expressionList(e.Elements) + "]"
+	case *AssociativeArrayLiteral:
+		pairs := make([]string, len(e.Pairs))
+		for i, pair := range e.Pairs {
+			pairs[i] = pr.expression(pair.Key, LOWEST, true) + " => " + pr.expression(pair.Value, LOWEST, true)
+		}
+		return "[" + strings.Join(pairs, ", ") + "]"
+	case *ListExpression:
+		items := make([]string, len(e.Items))
+		for i, item := range e.Items {
+			if item == nil {
+				continue
+			}
+			items[i] = pr.expression(item.Value, LOWEST, true)
+			if item.Key != nil {
+				items[i] = pr.expression(item.Key, LOWEST, true) + " => " + items[i]
+			}
+		}
+		if e.Token.Type == LBRACKET {
+			return "[" + strings.Join(items, ", ") + "]"
+		}
+		return "list(" + strings.Join(items, ", ") + ")"
+	case *InfixExpression:
+		precedence := tokenPrecedence(e.Token.Type)
+		left, right := precedence, precedence+1
+		if e.Token.Type == POW {
+			left, right = precedence+1, precedence
+		}
+		return pr.expression(e.Left, left, false) + " " + e.Operator + " " + pr.expression(e.Right, right, open)
+	case *PrefixExpression:
+		operand := pr.expression(e.Right, PREFIX, open)
+		// Keep - -$a from reading as --$a
+		if (e.Operator == "-" || e.Operator == "+") && strings.HasPrefix(operand, e.Operator) {
+			return e.Operator + " " + operand
+		}
+		return e.Operator + operand
+	case *PostfixExpression:
+		return pr.expression(e.Left, CALL, false) + e.Operator
+	case *CommaExpression:
+		return pr.expressionList(e.Expressions)
+	case *AssignmentExpression:
+		operator := "="
+		if e.Token.Type != ASSIGN && e.Token.Literal != "" {
+			operator = e.Token.Literal
+		}
+		var target Expression = e.Target
+		if e.Name != nil {
+			target = e.Name
+		}
+		return pr.expression(target, CALL, false) + " " + operator + " " + pr.expression(e.Value, ASSIGNMENT, open)
+	case *DestructuringAssignment:
+		return pr.expression(e.Targets, LOWEST, false) + " = " + pr.expression(e.Source, ASSIGNMENT, open)
+	case *TernaryExpression:
+		condition := pr.expression(e.Condition, TERNARY+1, false)
+		if e.TrueValue == nil {
+			return condition + " ?: " + pr.expression(e.FalseValue, LOWEST, open)
+		}
+		return condition + " ? " + pr.expression(e.TrueValue, LOWEST, true) + " : " + pr.expression(e.FalseValue, LOWEST, open)
+	case *CoalesceExpression:
+		return pr.expression(e.Left, COALESCE+1, false) + " ?? " + pr.expression(e.Right, COALESCE, open)
+	case *InstanceofExpression:
+		return pr.expression(e.Left, TYPE_CHECK, false) + " instanceof " + pr.expression(e.Class, TYPE_CHECK+1, open)
+	case *CloneExpression:
+		return "clone " + pr.expression(e.Operand, PREFIX, open)
+	case *CallExpression:
+		return pr.callee(e.Function) + "(" + pr.expressionList(e.Arguments) + ")"
+	case *IndexExpression:
+		return pr.callee(e.Left) + "[" + pr.expression(e.Index, LOWEST, true) + "]"
+	case *ObjectAccessExpression:
+		operator := "->"
+		if e.Nullsafe {
+			operator = "?->"
+		}
+		return pr.callee(e.Object) + operator + pr.expression(e.Property, primaryPrecedence, false)
+	case *StaticAccessExpression:
+		return pr.callee(e.Class) + "::" + pr.expression(e.Property, primaryPrecedence, false)
+	case *NewExpression:
+		return "new " + e.ClassName.String() + "(" + pr.expressionList(e.Arguments) + ")"
+	case *IssetExpression:
+		return "isset(" + pr.expressionList(e.Arguments) + ")"
+	case *EmptyExpression:
+		return "empty(" + pr.expressionList(e.Arguments) + ")"
+	case *IncludeExpression:
+		return includeKeyword("include", e.Once) + " " + pr.expression(e.Path, LOWEST, true)
+	case *RequireExpression:
+		return includeKeyword("require", e.Once) + " " + pr.expression(e.Path, LOWEST, true)
+	case *PrintExpression:
+		return "print " + pr.expression(e.Value, LOWEST, true)
+	case *ThrowExpression:
+		return "throw " + pr.expression(e.Expression, LOWEST, true)
+	case *YieldExpression:
+		switch {
+		case e.Key != nil && e.Value != nil:
+			return "yield " + pr.expression(e.Key, LOWEST, true) + " => " + pr.expression(e.Value, LOWEST, true)
+		case e.Value != nil:
+			return "yield " + pr.expression(e.Value, LOWEST, true)
+		}
+		return "yield"
+	case *AnonymousFunction:
+		out := "function "
+		if e.Static {
+			out = "static " + out
+		}
+		if e.ReturnsRef {
+			out += "&"
+		}
+		out += "(" + pr.parameters(e.Parameters) + ")"
+		if len(e.UseClause) > 0 {
+			uses := make([]string, len(e.UseClause))
+			for i, variable := range e.UseClause {
+				uses[i] = variable.String()
+			}
+			out += " use (" + strings.Join(uses, ", ") + ")"
+		}
+		return out + pr.returnType(e.ReturnType) + " " + pr.block(e.Body)
+	case *ArrowFunction:
+		out := "fn"
+		if e.Static {
+			out = "static " + out
+		}
+		if e.ReturnsRef {
+			out += "&"
+		}
+		out += "(" + pr.parameters(e.Parameters) + ")" + pr.returnType(e.ReturnType)
+		return out + " => " + pr.expression(e.Body, LOWEST, true)
+	case *MatchExpression:
+		return "match (" + pr.expression(e.Subject, LOWEST, true) + ") " + pr.nested(func() {
+			for _, arm := range e.Arms {
+				conditions := "default"
+				if !arm.IsDefault() {
+					conditions = pr.expressionList(arm.Conditions)
+				}
+				pr.line(conditions + " => " + pr.expression(arm.Body, LOWEST, true) + ",")
+			}
+		})
+	case *TypeHint:
+		return pr.typeHint(e)
+	case *NullableType:
+		return "?" + pr.expression(e.BaseType, primaryPrecedence, false)
+	}
+	return expr.String()
+}
+
+// callee renders the expression a call, index or member access applies
+// to. Closures and new expressions are wrapped so the access applies to
+// their result.
+func (pr *printer) callee(expr Expression) string {
+	switch expr.(type) {
+	case *AnonymousFunction, *NewExpression:
+		return "(" + pr.bareExpression(expr, true) + ")"
+	}
+	return pr.expression(expr, CALL, false)
+}
+
+// interpolatedString renders a double-quoted string, wrapping embedded
+// expressions other than plain variables in braces
+func (pr *printer) interpolatedString(is *InterpolatedString) string {
+	var out strings.Builder
+	out.WriteByte('"')
+	for i, part := range is.Parts {
+		switch p := part.(type) {
+		case *StringLiteral:
+			out.WriteString(escapeDoubleQuoted(p.Value))
+		case *Variable:
+			if i+1 < len(is.Parts) && continuesVariable(is.Parts[i+1]) {
+				out.WriteString("{$" + p.Name + "}")
+			} else {
+				out.WriteString("$" + p.Name)
+			}
+		default:
+			out.WriteString("{" + pr.expression(part, LOWEST, true) + "}")
+		}
+	}
+	out.WriteByte('"')
+	return out.String()
+}
+
+// continuesVariable reports whether text following a bare variable in a
+// string would be read as part of it
+func continuesVariable(next Expression) bool {
+	literal, ok := next.(*StringLiteral)
+	if !ok || literal.Value == "" {
+		return false
+	}
+	c := literal.Value[0]
+	return c == '_' || c == '[' || c >= 0x80 || strings.HasPrefix(literal.Value, "->") ||
+		('a' <= c && c <= 'z') || ('A' <= c && c <= 'Z') || ('0' <= c && c <= '9')
+}
+
+// quoteString renders a string value as a PHP literal, single-quoted
+// unless it holds control characters that read better escaped
+func quoteString(value string) string {
+	if strings.IndexFunc(value, func(r rune) bool { return r < 0x20 || r == 0x7f }) >= 0 {
+		return `"` + escapeDoubleQuoted(value) + `"`
+	}
+	value = strings.ReplaceAll(value, `\`, `\\`)
+	value = strings.ReplaceAll(value, `'`, `\'`)
+	return "'" + value + "'"
+}
+
+// escapeDoubleQuoted escapes a string value for use between double quotes
+func escapeDoubleQuoted(value string) string {
+	var out strings.Builder
+	for i := 0; i < len(value); i++ {
+		switch c := value[i]; c {
+		case '\\', '"', '$':
+			out.WriteByte('\\')
+			out.WriteByte(c)
+		case '\n':
+			out.WriteString(`\n`)
+		case '\t':
+			out.WriteString(`\t`)
+		case '\r':
+			out.WriteString(`\r`)
+		case '\v':
+			out.WriteString(`\v`)
+		case '\f':
+			out.WriteString(`\f`)
+		case 0x1b:
+			out.WriteString(`\e`)
+		default:
+			if c < 0x20 || c == 0x7f {
+				fmt.Fprintf(&out, `\x%02x`, c)
+			} else {
+				out.WriteByte(c)
+			}
+		}
+	}
+	return out.String()
+}
@@ -0,0 +1,158 @@
+package gophpparser
+
+import (
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestPrettyPrintGolden(t *testing.T) {
+	input, err := os.ReadFile("testfiles/pretty_print_class.php")
+	if err != nil {
+		t.Fatalf("failed to read input: %v", err)
+	}
+	expected, err := os.ReadFile("testfiles/pretty_print_class.golden")
+	if err != nil {
+		t.Fatalf("failed to read golden file: %v", err)
+	}
+
+	program, err := ParseWithOptions(string(input), ParserOptions{AttachComments: true})
+	if err != nil {
+		t.Fatalf("failed to parse input: %v", err)
+	}
+
+	output := PrettyPrint(program)
+	if output != string(expected) {
+		t.Errorf("output does not match golden file.\nexpected=\n%s\ngot=\n%s", expected, output)
+	}
+
+	reparsed, err := ParseWithOptions(output, ParserOptions{AttachComments: true})
+	if err != nil {
+		t.Fatalf("failed to reparse output: %v", err)
+	}
+	if again := PrettyPrint(reparsed); again != output {
+		t.Errorf("printing the reparsed output changed it.\nexpected=\n%s\ngot=\n%s", output, again)
+	}
+}
+
+func TestPrettyPrintReparses(t *testing.T) {
+	files, err := filepath.Glob("testfiles/*.php")
+	if err != nil {
+		t.Fatalf("glob failed: %v", err)
+	}
+
+	for _, file := range files {
+		program, err := Parsefile(file)
+		if err != nil {
+			continue
+		}
+		t.Run(filepath.Base(file), func(t *testing.T) {
+			assertPrintReparses(t, program)
+		})
+	}
+}
+
+func TestPrettyPrintParentheses(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected string
+	}{
+		{`$x = ($a + $b) * $c;`, `$x = ($a + $b) * $c;`},
+		{`$x = (($a * $b)) + $c;`, `$x = $a * $b + $c;`},
+		{`$x = $a - ($b - $c);`, `$x = $a - ($b - $c);`},
+		{`$x = ($a - $b) - $c;`, `$x = $a - $b - $c;`},
+		{`$x = 2 ** (3 ** 2);`, `$x = 2 ** 3 ** 2;`},
+		{`$x = (2 ** 3) ** 2;`, `$x = (2 ** 3) ** 2;`},
+		{`$x = (-2) ** 2;`, `$x = (-2) ** 2;`},
+		{`$x = -(-$a);`, `$x = - -$a;`},
+		{`$x = ($a ?? $b) ?? $c;`, `$x = ($a ?? $b) ?? $c;`},
+		{`$x = ($a ? 1 : 2) . 'px';`, `$x = ($a ? 1 : 2) . 'px';`},
+		{`$x = ($a = 1) + 2;`, `$x = ($a = 1) + 2;`},
+		{`$x = (!$a) instanceof B;`, `$x = (!$a) instanceof B;`},
+		{`$x = (fn($v) => $v)(3);`, `$x = (fn($v) => $v)(3);`},
+		{`$x = (new Foo())->bar();`, `$x = (new Foo())->bar();`},
+		{`$x = (clone $a)->b;`, `$x = (clone $a)->b;`},
+		{`$x = (yield $a) + 1;`, `$x = (yield $a) + 1;`},
+		{`$x = ($a and $b);`, `$x = ($a and $b);`},
+	}
+
+	for _, tt := range tests {
+		program, err := Parse("<?php " + tt.input)
+		if err != nil {
+			t.Errorf("input %s: failed to parse: %v", tt.input, err)
+			continue
+		}
+
+		output := strings.TrimPrefix(PrettyPrint(program), "<?php\n\n")
+		if output != tt.expected+"\n" {
+			t.Errorf("input %s: expected %q. got=%q", tt.input, tt.expected+"\n", output)
+		}
+		assertPrintReparses(t, program)
+	}
+}
+
+func TestPrettyPrintStrings(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected string
+	}{
+		{`echo "plain";`, `echo 'plain';`},
+		{`echo 'it\'s';`, `echo 'it\'s';`},
+		{`echo "tab\there";`, `echo "tab\there";`},
+		{`echo "{$user->name}s and $count";`, `echo "{$user->name}s and $count";`},
+		{`echo "{$a}b";`, `echo "{$a}b";`},
+	}
+
+	for _, tt := range tests {
+		program, err := Parse("<?php " + tt.input)
+		if err != nil {
+			t.Errorf("input %s: failed to parse: %v", tt.input, err)
+			continue
+		}
+
+		output := strings.TrimPrefix(PrettyPrint(program), "<?php\n\n")
+		if output != tt.expected+"\n" {
+			t.Errorf("input %s: expected %q. got=%q", tt.input, tt.expected+"\n", output)
+		}
+		assertPrintReparses(t, program)
+	}
+}
+
+func TestPrettyPrintWithOptions(t *testing.T) {
+	program, err := Parse("<?php function f() { if ($a) { return 1; } }")
+	if err != nil {
+		t.Fatalf("failed to parse: %v", err)
+	}
+
+	expected := "<?php\n\nfunction f()\n{\n\tif ($a) {\n\t\treturn 1;\n\t}\n}\n"
+	output := PrettyPrintWithOptions(program, PrinterOptions{Indent: "\t"})
+	if output != expected {
+		t.Errorf("expected %q. got=%q", expected, output)
+	}
+}
+
+// assertPrintReparses checks that the printed program parses back into the
+// same tree. Tokens are left out of the comparison, since positions and
+// quoting change when the code is reformatted.
+func assertPrintReparses(t *testing.T, program *Program) {
+	t.Helper()
+
+	output := PrettyPrint(program)
+	reparsed, err := Parse(output)
+	if err != nil {
+		t.Fatalf("failed to reparse printed output: %v\n%s", err, output)
+	}
+
+	expected, got := astShape(program), astShape(reparsed)
+	if expected != got {
+		t.Errorf("reparsed AST differs.\noutput=\n%s\nexpected=%s\ngot=%s", output, expected, got)
+	}
+}
+
+func astShape(node Node) string {
+	data, _ := json.Marshal(withoutTokens(encodeValue(reflect.ValueOf(node), JSONOptions{})))
+	return string(data)
+}
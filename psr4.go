@@ -0,0 +1,76 @@
+package gophpparser
+
+import (
+	"fmt"
+	"path"
+	"strings"
+)
+
+// ValidatePSR4 checks that a PHP file is placed according to PSR-4.
+// expectedNamespacePrefix is the namespace mapped to the base directory
+// (e.g. "App\\") and relativePath is the file's path relative to that
+// directory (e.g. "Models/User.php"). The file must declare the namespace
+// formed from the prefix and the directories in the path, and a class,
+// interface, trait or enum named after the file. One error is returned per
+// violation; a correctly placed file returns nil.
+func ValidatePSR4(source, expectedNamespacePrefix, relativePath string) []error {
+	relativePath = strings.ReplaceAll(relativePath, "\\", "/")
+	if path.Ext(relativePath) != ".php" {
+		return []error{fmt.Errorf("%s: PSR-4 class files must have a .php extension", relativePath)}
+	}
+
+	dir, file := path.Split(relativePath)
+	expectedNamespace := strings.Trim(expectedNamespacePrefix, "\\")
+	for _, segment := range strings.Split(dir, "/") {
+		if segment == "" {
+			continue
+		}
+		if expectedNamespace != "" {
+			expectedNamespace += "\\"
+		}
+		expectedNamespace += segment
+	}
+
+	expectedClass := strings.TrimSuffix(file, ".php")
+	if expectedNamespace != "" {
+		expectedClass = expectedNamespace + "\\" + expectedClass
+	}
+
+	decls, err := scanDeclarations(source)
+	if err != nil {
+		return []error{fmt.Errorf("%s: %w", relativePath, err)}
+	}
+
+	var errs []error
+
+	switch len(decls.namespaces) {
+	case 0:
+		if expectedNamespace != "" {
+			errs = append(errs, fmt.Errorf("%s: no namespace declared, expected %q", relativePath, expectedNamespace))
+		}
+	case 1:
+		if decls.namespaces[0] != expectedNamespace {
+			errs = append(errs, fmt.Errorf("%s: namespace %q does not match expected %q", relativePath, decls.namespaces[0], expectedNamespace))
+		}
+	default:
+		errs = append(errs, fmt.Errorf("%s: declares %d namespaces, PSR-4 files must declare one", relativePath, len(decls.namespaces)))
+	}
+
+	found := false
+	for _, class := range decls.classes {
+		if class == expectedClass {
+			found = true
+			break
+		}
+	}
+
+	if !found {
+		if len(decls.classes) == 0 {
+			errs = append(errs, fmt.Errorf("%s: no class, interface, trait or enum declared, expected %q", relativePath, expectedClass))
+		} else {
+			errs = append(errs, fmt.Errorf("%s: declares %s, expected %q", relativePath, strings.Join(decls.classes, ", "), expectedClass))
+		}
+	}
+
+	return errs
+}
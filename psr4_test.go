@@ -0,0 +1,60 @@
+package gophpparser
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestValidatePSR4CorrectlyPlacedClass(t *testing.T) {
+	source := `<?php
+namespace App\Models;
+
+use App\Contracts\Saveable;
+
+class User implements Saveable {
+    public function table() {
+        return User::class;
+    }
+}
+`
+
+	errs := ValidatePSR4(source, "App\\", "Models/User.php")
+	if len(errs) != 0 {
+		t.Errorf("expected no errors, got %d", len(errs))
+		for _, err := range errs {
+			t.Errorf("  %v", err)
+		}
+	}
+}
+
+func TestValidatePSR4MisnamedClass(t *testing.T) {
+	source := `<?php
+namespace App\Model;
+
+class Users {
+}
+`
+
+	errs := ValidatePSR4(source, "App\\", "Models/User.php")
+	if len(errs) != 2 {
+		t.Fatalf("expected 2 errors, got %d: %v", len(errs), errs)
+	}
+
+	if !strings.Contains(errs[0].Error(), `namespace "App\\Model" does not match expected "App\\Models"`) {
+		t.Errorf("unexpected namespace error: %v", errs[0])
+	}
+
+	if !strings.Contains(errs[1].Error(), `expected "App\\Models\\User"`) {
+		t.Errorf("unexpected class error: %v", errs[1])
+	}
+}
+
+func TestValidatePSR4Enum(t *testing.T) {
+	errs := ValidatePSR4("<?php namespace App; enum Status { case A; }", "App\\", "Status.php")
+	if len(errs) != 0 {
+		t.Errorf("expected no errors, got %d", len(errs))
+		for _, err := range errs {
+			t.Errorf("  %v", err)
+		}
+	}
+}
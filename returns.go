@@ -0,0 +1,138 @@
+package gophpparser
+
+import "strings"
+
+// returnTypeRequiresValue reports whether a declared return type forbids
+// falling off the end of the function body. Types that accept the implicit
+// null return, and void/never, do not.
+func returnTypeRequiresValue(returnType Expression) bool {
+	hint, ok := returnType.(*TypeHint)
+	if !ok || hint.Nullable {
+		return false
+	}
+
+	if len(hint.Union) > 0 {
+		for _, member := range hint.Union {
+			if !returnTypeRequiresValue(member) {
+				return false
+			}
+		}
+		return true
+	}
+
+	switch strings.ToLower(hint.Name) {
+	case "void", "never", "null", "mixed":
+		return false
+	}
+	return true
+}
+
+// blockAlwaysExits reports whether every path through the block ends in a
+// return, throw or exit. It errs on the side of true so the missing-return
+// check never reports a body that might be fine: only statements known to
+// fall through count against it.
+func blockAlwaysExits(block *BlockStatement) bool {
+	if block == nil {
+		return false
+	}
+
+	for _, stmt := range block.Statements {
+		if statementAlwaysExits(stmt) {
+			return true
+		}
+	}
+	return false
+}
+
+func statementAlwaysExits(stmt Statement) bool {
+	switch s := stmt.(type) {
+	case *ReturnStatement, *ThrowStatement:
+		return true
+	case *ExpressionStatement:
+		return expressionExits(s.Expression)
+	case *BlockStatement:
+		return blockAlwaysExits(s)
+	case *IfStatement:
+		if s.Alternative == nil || !blockAlwaysExits(s.Consequence) || !blockAlwaysExits(s.Alternative) {
+			return false
+		}
+		for _, elseIf := range s.ElseIfs {
+			if !blockAlwaysExits(elseIf.Consequence) {
+				return false
+			}
+		}
+		return true
+	case *WhileStatement:
+		// A loop that never ends on its own only leaves through a return,
+		// throw or break
+		return alwaysTruthy(s.Condition)
+	case *ForStatement:
+		return alwaysTruthy(s.Condition)
+	case *SwitchStatement:
+		// Every label must end in an exit or fall through to one, and a
+		// default label must exist so no value skips the switch
+		hasDefault := false
+		for i, switchCase := range s.Cases {
+			if switchCase.IsDefault() {
+				hasDefault = true
+			}
+			if len(switchCase.Body) == 0 && i < len(s.Cases)-1 {
+				continue
+			}
+			if !blockAlwaysExits(&BlockStatement{Statements: switchCase.Body}) {
+				return false
+			}
+		}
+		return hasDefault
+	case *TryStatement:
+		if s.Finally != nil && blockAlwaysExits(s.Finally) {
+			return true
+		}
+		if !blockAlwaysExits(s.Body) {
+			return false
+		}
+		for _, catch := range s.Catches {
+			if !blockAlwaysExits(catch.Body) {
+				return false
+			}
+		}
+		return true
+	case *ForeachStatement, *EchoStatement, *UnsetStatement, *GlobalStatement, *StaticVariableStatement,
+		*BreakStatement, *ContinueStatement, *IncludeStatement, *RequireStatement, *EmptyStatement, *Comment,
+		*FunctionDeclaration, *ClassDeclaration, *InterfaceDeclaration, *TraitDeclaration, *EnumDeclaration:
+		return false
+	}
+	// Anything not known to fall through is assumed to exit
+	return true
+}
+
+// expressionExits reports whether evaluating the expression ends the
+// function: a throw, or a call to exit or die
+func expressionExits(expr Expression) bool {
+	switch e := expr.(type) {
+	case *ThrowExpression:
+		return true
+	case *Identifier:
+		return isExitName(e.Value)
+	case *CallExpression:
+		name, ok := e.Function.(*Identifier)
+		return ok && isExitName(name.Value)
+	}
+	return false
+}
+
+func isExitName(name string) bool {
+	name = strings.ToLower(name)
+	return name == "exit" || name == "die"
+}
+
+// alwaysTruthy reports whether a loop condition is a constant that PHP
+// treats as true, such as true or 1. A missing condition, as in for (;;),
+// also loops forever.
+func alwaysTruthy(condition Expression) bool {
+	if condition == nil {
+		return true
+	}
+	value, ok := EvalConstExpr(condition, nil)
+	return ok && constToBool(value)
+}
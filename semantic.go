@@ -1,7 +1,10 @@
 package gophpparser
 
 import (
+	"encoding/json"
 	"fmt"
+	"sort"
+	"strconv"
 	"strings"
 )
 
@@ -15,6 +18,7 @@ const (
 	CONSTANT_SYMBOL
 	INTERFACE_SYMBOL
 	TRAIT_SYMBOL
+	ENUM_SYMBOL
 )
 
 func (st SymbolType) String() string {
@@ -31,6 +35,8 @@ func (st SymbolType) String() string {
 		return "interface"
 	case TRAIT_SYMBOL:
 		return "trait"
+	case ENUM_SYMBOL:
+		return "enum"
 	default:
 		return "unknown"
 	}
@@ -44,35 +50,77 @@ type Symbol struct {
 	Namespace    string     `json:"namespace"`      // Declaring namespace
 	File         string     `json:"file,omitempty"` // Source file
 	Line         int        `json:"line,omitempty"` // Line number
+	BuiltIn      bool       `json:"builtin,omitempty"` // Provided by PHP itself
 }
 
+// RefKind describes how a name is used at a reference site
+type RefKind string
+
+const (
+	INSTANTIATION_REF RefKind = "instantiation" // new Foo()
+	STATIC_ACCESS_REF RefKind = "static_access" // Foo::bar(), Foo::BAZ
+	INHERITANCE_REF   RefKind = "inheritance"   // extends, implements, use Trait
+	CATCH_REF         RefKind = "catch"         // catch (Foo $e)
+	TYPE_HINT_REF     RefKind = "type_hint"     // Foo $x, ): Foo
+	CALL_REF          RefKind = "call"          // foo()
+	CONSTANT_REF      RefKind = "constant"      // FOO
+	CAPTURE_REF       RefKind = "capture"       // function () use ($x)
+	INSTANCEOF_REF    RefKind = "instanceof"    // $x instanceof Foo
+)
+
 // SymbolReference represents a reference to a symbol with resolved information
 type SymbolReference struct {
-	Name           string  `json:"name"`             // Used name (e.g., "User")
-	ResolvedSymbol *Symbol `json:"resolved_symbol"`  // What it actually refers to
-	Line           int     `json:"line,omitempty"`   // Where it's used
-	Column         int     `json:"column,omitempty"` // Column position
+	Name           string       `json:"name"`                     // Used name (e.g., "User")
+	ResolvedSymbol *Symbol      `json:"resolved_symbol"`          // What it actually refers to
+	ExpectedTypes  []SymbolType `json:"expected_types,omitempty"` // Symbol kinds the name may refer to
+	RefKind        RefKind      `json:"ref_kind,omitempty"`       // How the name is used
+	LateBound      bool         `json:"late_bound,omitempty"`     // static, resolved to the enclosing class
+	Line           int          `json:"line,omitempty"`           // Where it's used
+	Column         int          `json:"column,omitempty"`         // Column position
 }
 
 // Scope represents a lexical scope (global, namespace, class, function)
 type Scope struct {
-	Type      string             `json:"type"`      // "global", "namespace", "class", "function"
-	Name      string             `json:"name"`      // Scope identifier
-	Parent    *Scope             `json:"-"`         // Parent scope
-	Symbols   map[string]*Symbol `json:"symbols"`   // Symbols declared in this scope
-	Children  []*Scope           `json:"children"`  // Child scopes
-	Namespace string             `json:"namespace"` // Current namespace
-	Imports   map[string]string  `json:"imports"`   // use statements (alias -> fully qualified)
+	Type      string             `json:"type"`                 // "global", "namespace", "class", "function"
+	Name      string             `json:"name"`                 // Scope identifier
+	Parent    *Scope             `json:"-"`                    // Parent scope
+	Symbols   map[string]*Symbol `json:"symbols"`              // Symbols declared in this scope
+	Children  []*Scope           `json:"children"`             // Child scopes
+	Namespace string             `json:"namespace"`            // Current namespace
+	Imports   map[string]string  `json:"imports"`              // use statements (alias -> fully qualified)
+	ThisClass string             `json:"this_class,omitempty"` // Class $this refers to, if any
 }
 
 // SymbolTable manages all symbols and scopes
 type SymbolTable struct {
-	GlobalScope   *Scope                        `json:"global_scope"`
-	CurrentScope  *Scope                        `json:"-"`
-	AllSymbols    map[string]*Symbol            `json:"all_symbols"`    // All symbols by fully qualified name
-	References    []*SymbolReference            `json:"references"`     // All symbol references
-	Namespaces    map[string][]*Symbol          `json:"namespaces"`     // Symbols grouped by namespace
-	ClassHierarchy map[string][]string          `json:"class_hierarchy"` // class -> [parent, interfaces...]
+	GlobalScope    *Scope               `json:"global_scope"`
+	CurrentScope   *Scope               `json:"-"`
+	AllSymbols     map[string]*Symbol   `json:"all_symbols"`                 // All symbols by fully qualified name
+	References     []*SymbolReference   `json:"references"`                  // All symbol references
+	Namespaces     map[string][]*Symbol `json:"namespaces"`                  // Symbols grouped by namespace
+	ClassHierarchy map[string][]string  `json:"class_hierarchy"`             // class -> [parent, interfaces...]
+	Members        map[string][]string  `json:"members,omitempty"`           // class, trait or enum -> declared member names
+	TraitUses      map[string][]string  `json:"trait_uses,omitempty"`        // class, trait or enum -> used traits
+	MemberRefs     []*MemberReference   `json:"member_references,omitempty"` // $this->member accesses
+
+	// ExternalResolver, if set, is consulted for names the table cannot resolve
+	ExternalResolver SymbolResolver `json:"-"`
+}
+
+// SymbolResolver resolves names that static analysis cannot see, such as
+// classes a framework provides through container bindings or facades.
+// Resolve returns nil when it does not know the name either.
+type SymbolResolver interface {
+	Resolve(name string, symbolType SymbolType, namespace string) *Symbol
+}
+
+// MemberReference represents an access to a class member through $this
+type MemberReference struct {
+	Class    string `json:"class"`            // Fully qualified class $this refers to
+	Member   string `json:"member"`           // Accessed property or method name
+	Resolved bool   `json:"resolved"`         // Whether the class or its traits declare it
+	Line     int    `json:"line,omitempty"`   // Where it's used
+	Column   int    `json:"column,omitempty"` // Column position
 }
 
 // NewSymbolTable creates a new symbol table
@@ -93,6 +141,8 @@ func NewSymbolTable() *SymbolTable {
 		References:     []*SymbolReference{},
 		Namespaces:     make(map[string][]*Symbol),
 		ClassHierarchy: make(map[string][]string),
+		Members:        make(map[string][]string),
+		TraitUses:      make(map[string][]string),
 	}
 }
 
@@ -106,6 +156,7 @@ func (st *SymbolTable) EnterScope(scopeType, name string) {
 		Children:  []*Scope{},
 		Namespace: st.CurrentScope.Namespace, // Inherit namespace
 		Imports:   make(map[string]string),   // Copy imports from parent
+		ThisClass: st.CurrentScope.ThisClass, // Closures capture $this
 	}
 
 	// Copy imports from parent
@@ -168,14 +219,28 @@ func (st *SymbolTable) DeclareSymbol(name string, symbolType SymbolType, file st
 	return symbol
 }
 
-// ResolveSymbol resolves a symbol reference
+// ResolveSymbol resolves a symbol reference, falling back to the external
+// resolver when the name is neither declared nor built in
 func (st *SymbolTable) ResolveSymbol(name string, symbolType SymbolType) *Symbol {
+	if symbol := st.resolveDeclaredSymbol(name, symbolType); symbol != nil {
+		return symbol
+	}
+
+	if st.ExternalResolver != nil {
+		return st.ExternalResolver.Resolve(name, symbolType, st.CurrentScope.Namespace)
+	}
+	return nil
+}
+
+// resolveDeclaredSymbol resolves a name against declared and built-in symbols
+func (st *SymbolTable) resolveDeclaredSymbol(name string, symbolType SymbolType) *Symbol {
 	// 1. Check if it's an absolute reference (starts with \)
 	if strings.HasPrefix(name, "\\") {
-		if symbol, exists := st.AllSymbols[name]; exists && symbol.Type == symbolType {
+		fqn := strings.TrimPrefix(name, "\\")
+		if symbol, exists := st.AllSymbols[fqn]; exists && symbol.Type == symbolType {
 			return symbol
 		}
-		return nil
+		return lookupBuiltinClass(fqn, symbolType)
 	}
 
 	// 2. Check imports/aliases first
@@ -183,6 +248,9 @@ func (st *SymbolTable) ResolveSymbol(name string, symbolType SymbolType) *Symbol
 		if symbol, exists := st.AllSymbols[fqn]; exists && symbol.Type == symbolType {
 			return symbol
 		}
+		if symbol := lookupBuiltinClass(fqn, symbolType); symbol != nil {
+			return symbol
+		}
 	}
 
 	// 3. Check current scope and parent scopes
@@ -208,16 +276,72 @@ func (st *SymbolTable) ResolveSymbol(name string, symbolType SymbolType) *Symbol
 		return symbol
 	}
 
+	// 6. Unqualified names outside a namespace may refer to PHP's built-in
+	// classes; inside a namespace they must be imported or fully qualified
+	if currentNamespace == "" {
+		return lookupBuiltinClass(name, symbolType)
+	}
+
 	return nil
 }
 
 // AddReference adds a symbol reference
 func (st *SymbolTable) AddReference(name string, symbolType SymbolType, line, column int) *SymbolReference {
-	resolvedSymbol := st.ResolveSymbol(name, symbolType)
+	return st.AddReferenceOfKind(name, symbolType, "", line, column)
+}
+
+// AddReferenceOfKind adds a symbol reference and records how the name is used
+func (st *SymbolTable) AddReferenceOfKind(name string, symbolType SymbolType, kind RefKind, line, column int) *SymbolReference {
+	return st.AddReferenceAny(name, []SymbolType{symbolType}, kind, line, column)
+}
+
+// AddReferenceAny adds a single reference to a name that may refer to any of
+// the given symbol types. The types are tried in order and the first declared
+// symbol wins; the external resolver is only asked when none of the types
+// is declared.
+func (st *SymbolTable) AddReferenceAny(name string, symbolTypes []SymbolType, kind RefKind, line, column int) *SymbolReference {
+	var resolvedSymbol *Symbol
+	for _, symbolType := range symbolTypes {
+		if resolvedSymbol = st.resolveDeclaredSymbol(name, symbolType); resolvedSymbol != nil {
+			break
+		}
+	}
+	if resolvedSymbol == nil && st.ExternalResolver != nil {
+		for _, symbolType := range symbolTypes {
+			resolvedSymbol = st.ExternalResolver.Resolve(name, symbolType, st.CurrentScope.Namespace)
+			if resolvedSymbol != nil {
+				break
+			}
+		}
+	}
 
 	ref := &SymbolReference{
 		Name:           name,
 		ResolvedSymbol: resolvedSymbol,
+		ExpectedTypes:  symbolTypes,
+		RefKind:        kind,
+		Line:           line,
+		Column:         column,
+	}
+
+	st.References = append(st.References, ref)
+	return ref
+}
+
+// AddCaptureReference adds a reference for a variable captured by a
+// closure's use clause. It only looks in the current scope, since a closure
+// captures from the scope it is created in and not from any around that.
+func (st *SymbolTable) AddCaptureReference(name string, line, column int) *SymbolReference {
+	var resolvedSymbol *Symbol
+	if symbol, exists := st.CurrentScope.Symbols[name]; exists && symbol.Type == VARIABLE_SYMBOL {
+		resolvedSymbol = symbol
+	}
+
+	ref := &SymbolReference{
+		Name:           name,
+		ResolvedSymbol: resolvedSymbol,
+		ExpectedTypes:  []SymbolType{VARIABLE_SYMBOL},
+		RefKind:        CAPTURE_REF,
 		Line:           line,
 		Column:         column,
 	}
@@ -236,6 +360,92 @@ func (st *SymbolTable) AddClassHierarchy(className string, extends string, imple
 	st.ClassHierarchy[className] = hierarchy
 }
 
+// AddMember records a method, property, constant or enum case declared
+// directly in a class, trait or enum
+func (st *SymbolTable) AddMember(typeName, member string) {
+	st.Members[typeName] = append(st.Members[typeName], member)
+}
+
+// AddTraitUse records that a class, trait or enum uses a trait
+func (st *SymbolTable) AddTraitUse(typeName, trait string) {
+	st.TraitUses[typeName] = append(st.TraitUses[typeName], trait)
+}
+
+// AddMemberReference records an access to a member of a class through $this.
+// A member the class cannot be shown to lack, because an ancestor is not
+// declared in the table, counts as resolved.
+func (st *SymbolTable) AddMemberReference(className, member string, line, column int) *MemberReference {
+	ref := &MemberReference{
+		Class:  className,
+		Member: member,
+		Line:   line,
+		Column: column,
+	}
+
+	members, complete := st.collectMembers(className)
+	ref.Resolved = !complete
+	for _, declared := range members {
+		if declared == member {
+			ref.Resolved = true
+			break
+		}
+	}
+
+	st.MemberRefs = append(st.MemberRefs, ref)
+	return ref
+}
+
+// GetMembers returns the members of a class, trait or enum, including the
+// members merged in from the traits it uses and those inherited from its
+// parent class and interfaces
+func (st *SymbolTable) GetMembers(typeName string) []string {
+	members, _ := st.collectMembers(typeName)
+	return members
+}
+
+// collectMembers gathers the members GetMembers returns. complete is false
+// when an ancestor is built in or not declared, so its members are unknown.
+func (st *SymbolTable) collectMembers(typeName string) ([]string, bool) {
+	members := []string{}
+	complete := true
+	seen := make(map[string]bool)
+	visited := make(map[string]bool)
+
+	var collect func(name string)
+	collect = func(name string) {
+		if visited[name] {
+			return
+		}
+		visited[name] = true
+
+		for _, member := range st.Members[name] {
+			if !seen[member] {
+				seen[member] = true
+				members = append(members, member)
+			}
+		}
+		for _, trait := range st.TraitUses[name] {
+			collect(trait)
+		}
+		// ClassHierarchy keeps parent names as written, so resolve them
+		// to the declared class or interface first
+		for _, parent := range st.ClassHierarchy[name] {
+			symbol := st.resolveDeclaredSymbol(parent, CLASS_SYMBOL)
+			if symbol == nil {
+				symbol = st.resolveDeclaredSymbol(parent, INTERFACE_SYMBOL)
+			}
+			if symbol == nil || symbol.BuiltIn {
+				complete = false
+				continue
+			}
+			collect(symbol.FullyQualified)
+		}
+	}
+	collect(typeName)
+
+	return members, complete
+}
+
 // makeFullyQualified creates a fully qualified name
 func (st *SymbolTable) makeFullyQualified(name string) string {
 	if strings.HasPrefix(name, "\\") {
@@ -270,11 +480,131 @@ func (st *SymbolTable) GetUnresolvedReferences() []*SymbolReference {
 	return unresolved
 }
 
+// symbolTableJSON is the serialized form of a symbol table, without the scope graph
+type symbolTableJSON struct {
+	Symbols        []symbolJSON        `json:"symbols"`
+	Namespaces     map[string][]string `json:"namespaces"`
+	ClassHierarchy map[string][]string `json:"class_hierarchy"`
+}
+
+type symbolJSON struct {
+	Name           string `json:"name"`
+	FullyQualified string `json:"fully_qualified"`
+	Type           string `json:"type"`
+	Namespace      string `json:"namespace"`
+	File           string `json:"file,omitempty"`
+	Line           int    `json:"line,omitempty"`
+}
+
+// ToJSON serializes the declared symbols, namespaces and class hierarchy.
+// Symbols are sorted by fully qualified name so the output is deterministic.
+func (st *SymbolTable) ToJSON() ([]byte, error) {
+	out := symbolTableJSON{
+		Symbols:        []symbolJSON{},
+		Namespaces:     make(map[string][]string),
+		ClassHierarchy: st.ClassHierarchy,
+	}
+
+	for _, symbol := range st.AllSymbols {
+		out.Symbols = append(out.Symbols, symbolJSON{
+			Name:           symbol.Name,
+			FullyQualified: symbol.FullyQualified,
+			Type:           symbol.Type.String(),
+			Namespace:      symbol.Namespace,
+			File:           symbol.File,
+			Line:           symbol.Line,
+		})
+	}
+	sort.Slice(out.Symbols, func(i, j int) bool {
+		if out.Symbols[i].FullyQualified != out.Symbols[j].FullyQualified {
+			return out.Symbols[i].FullyQualified < out.Symbols[j].FullyQualified
+		}
+		return out.Symbols[i].Type < out.Symbols[j].Type
+	})
+
+	for namespace, symbols := range st.Namespaces {
+		names := []string{}
+		seen := make(map[string]bool)
+		for _, symbol := range symbols {
+			if !seen[symbol.FullyQualified] {
+				seen[symbol.FullyQualified] = true
+				names = append(names, symbol.FullyQualified)
+			}
+		}
+		sort.Strings(names)
+		out.Namespaces[namespace] = names
+	}
+
+	return json.MarshalIndent(out, "", "  ")
+}
+
+// Include records a file pulled in by include, include_once, require or
+// require_once
+type Include struct {
+	Kind        string `json:"kind"`        // include, include_once, require or require_once
+	Path        string `json:"path"`        // The string literal's value, or the path expression as written
+	Literal     bool   `json:"literal"`     // Whether the path is a plain string literal
+	Conditional bool   `json:"conditional"` // Inside a branch, loop, switch, match or ternary
+	Line        int    `json:"line"`
+}
+
 // SemanticAnalyzer performs semantic analysis on AST
 type SemanticAnalyzer struct {
 	SymbolTable *SymbolTable
 	CurrentFile string
 	Errors      []string
+	Comments    []*Comment
+	Includes    []*Include
+
+	// Warnings holds what the Lint checks report. Unlike Errors, a warning
+	// does not mean the code is invalid.
+	Warnings []string
+
+	// LintLooseComparisons enables reporting == and != between a
+	// non-numeric string literal and a number
+	LintLooseComparisons bool
+
+	// LintPropertyShadowing enables reporting method locals that share a
+	// name with a property of the class, a hint that $this-> was meant
+	LintPropertyShadowing bool
+
+	// LintThrowsDocs enables comparing the @throws tags of functions and
+	// methods with the exceptions they throw
+	LintThrowsDocs bool
+
+	// LintEmptyStatements enables reporting stray semicolons, such as the
+	// second one in $a = 1;;
+	LintEmptyStatements bool
+
+	// Context for checks that depend on the enclosing class and method
+	currentClass       string
+	currentParent      string // The class the enclosing class extends
+	currentMethod      string
+	readonlyProperties map[string]bool
+	classProperties    map[string]bool
+
+	// How many branches, loops, switches, matches or ternaries enclose the
+	// current node
+	conditionalDepth int
+
+	// Variables last assigned a number literal, per scope
+	numericVariables map[*Scope]map[string]bool
+
+	// source is the code of the program being analyzed, if it is known
+	source string
+}
+
+// AnalyzerOptions configures a SemanticAnalyzer
+type AnalyzerOptions struct {
+	// ExternalResolver is consulted before a reference is left unresolved
+	ExternalResolver SymbolResolver
+
+	// The Lint options enable the SemanticAnalyzer lints of the same name,
+	// which report warnings
+	LintLooseComparisons  bool
+	LintPropertyShadowing bool
+	LintThrowsDocs        bool
+	LintEmptyStatements   bool
 }
 
 // NewSemanticAnalyzer creates a new semantic analyzer
@@ -282,13 +612,30 @@ func NewSemanticAnalyzer() *SemanticAnalyzer {
 	return &SemanticAnalyzer{
 		SymbolTable: NewSymbolTable(),
 		Errors:      []string{},
+		Warnings:    []string{},
 	}
 }
 
+// NewSemanticAnalyzerWithOptions creates a semantic analyzer configured by options
+func NewSemanticAnalyzerWithOptions(options AnalyzerOptions) *SemanticAnalyzer {
+	sa := NewSemanticAnalyzer()
+	sa.SymbolTable.ExternalResolver = options.ExternalResolver
+	sa.LintLooseComparisons = options.LintLooseComparisons
+	sa.LintPropertyShadowing = options.LintPropertyShadowing
+	sa.LintThrowsDocs = options.LintThrowsDocs
+	sa.LintEmptyStatements = options.LintEmptyStatements
+	return sa
+}
+
 // AnalyzeProgram performs semantic analysis on a program
 func (sa *SemanticAnalyzer) AnalyzeProgram(program *Program, filename string) {
 	sa.CurrentFile = filename
+	sa.source = program.source
 	sa.visitProgram(program)
+
+	if sa.LintThrowsDocs {
+		sa.checkThrowsDocs(program)
+	}
 }
 
 // visitProgram visits program node
@@ -296,6 +643,10 @@ func (sa *SemanticAnalyzer) visitProgram(program *Program) {
 	for _, stmt := range program.Statements {
 		sa.visitStatement(stmt)
 	}
+
+	if sa.LintEmptyStatements {
+		sa.checkEmptyStatements(program.Statements)
+	}
 }
 
 // visitStatement visits statement nodes
@@ -311,8 +662,12 @@ func (sa *SemanticAnalyzer) visitStatement(stmt Statement) {
 		sa.visitInterfaceDeclaration(s)
 	case *TraitDeclaration:
 		sa.visitTraitDeclaration(s)
+	case *EnumDeclaration:
+		sa.visitEnumDeclaration(s)
 	case *FunctionDeclaration:
 		sa.visitFunctionDeclaration(s)
+	case *ConstantDeclaration:
+		sa.visitConstantDeclaration(s)
 	case *ExpressionStatement:
 		sa.visitExpression(s.Expression)
 	case *BlockStatement:
@@ -323,16 +678,47 @@ func (sa *SemanticAnalyzer) visitStatement(stmt Statement) {
 		sa.visitForStatement(s)
 	case *WhileStatement:
 		sa.visitWhileStatement(s)
+	case *SwitchStatement:
+		sa.visitSwitchStatement(s)
 	case *ForeachStatement:
 		sa.visitForeachStatement(s)
 	case *ReturnStatement:
 		sa.visitReturnStatement(s)
 	case *EchoStatement:
 		sa.visitEchoStatement(s)
+	case *UnsetStatement:
+		for _, arg := range s.Arguments {
+			sa.visitExpression(arg)
+		}
+	case *GlobalStatement:
+		if !sa.inFunction() {
+			sa.AddError(fmt.Sprintf("global has no effect outside a function at line %d, column %d",
+				s.Token.Line, s.Token.Column))
+		}
+		for _, variable := range s.Variables {
+			sa.SymbolTable.DeclareSymbol(variable.Name, VARIABLE_SYMBOL, sa.CurrentFile, variable.Token.Line)
+		}
+	case *StaticVariableStatement:
+		if !sa.inFunction() {
+			sa.AddError(fmt.Sprintf("static variables are only meaningful inside a function at line %d, column %d",
+				s.Token.Line, s.Token.Column))
+		}
+		for _, variable := range s.Variables {
+			if variable.Default != nil {
+				sa.visitExpression(variable.Default)
+			}
+			sa.SymbolTable.DeclareSymbol(variable.Variable.Name, VARIABLE_SYMBOL, sa.CurrentFile, variable.Variable.Token.Line)
+		}
 	case *TryStatement:
 		sa.visitTryStatement(s)
 	case *ThrowStatement:
 		sa.visitThrowStatement(s)
+	case *IncludeStatement:
+		sa.recordInclude("include", s.Once, s.Path, s.Token.Line)
+	case *RequireStatement:
+		sa.recordInclude("require", s.Once, s.Path, s.Token.Line)
+	case *Comment:
+		sa.Comments = append(sa.Comments, s)
 	}
 }
 
@@ -367,6 +753,44 @@ func (sa *SemanticAnalyzer) visitExpression(expr Expression) {
 		sa.visitYieldExpression(e)
 	case *TernaryExpression:
 		sa.visitTernaryExpression(e)
+	case *CoalesceExpression:
+		sa.visitExpression(e.Left)
+		sa.visitExpression(e.Right)
+	case *InstanceofExpression:
+		sa.visitInstanceofExpression(e)
+	case *CloneExpression:
+		sa.visitExpression(e.Operand)
+	case *CommaExpression:
+		for _, expr := range e.Expressions {
+			sa.visitExpression(expr)
+		}
+	case *IssetExpression:
+		for _, arg := range e.Arguments {
+			sa.visitExpression(arg)
+		}
+	case *EmptyExpression:
+		for _, arg := range e.Arguments {
+			sa.visitExpression(arg)
+		}
+	case *ThrowExpression:
+		sa.visitExpression(e.Expression)
+	case *InterpolatedString:
+		for _, part := range e.Parts {
+			sa.visitExpression(part)
+		}
+	case *PrintExpression:
+		sa.visitExpression(e.Value)
+	case *DestructuringAssignment:
+		sa.visitExpression(e.Source)
+		sa.declarePatternVariables(e.Targets, e.Token.Line)
+	case *IncludeExpression:
+		sa.recordInclude("include", e.Once, e.Path, e.Token.Line)
+	case *RequireExpression:
+		sa.recordInclude("require", e.Once, e.Path, e.Token.Line)
+	case *ArrowFunction:
+		sa.visitArrowFunction(e)
+	case *MatchExpression:
+		sa.visitMatchExpression(e)
 	case *Identifier:
 		// This might be a function call or constant reference
 		sa.addIdentifierReference(e)
@@ -394,19 +818,63 @@ func (sa *SemanticAnalyzer) visitClassDeclaration(stmt *ClassDeclaration) {
 	extends := ""
 	if stmt.SuperClass != nil {
 		extends = stmt.SuperClass.Value
+		sa.SymbolTable.AddReferenceOfKind(extends, CLASS_SYMBOL, INHERITANCE_REF, stmt.SuperClass.Token.Line, 0)
 	}
 	
 	implements := []string{}
 	for _, iface := range stmt.Interfaces {
 		implements = append(implements, iface.Value)
+		sa.SymbolTable.AddReferenceOfKind(iface.Value, INTERFACE_SYMBOL, INHERITANCE_REF, iface.Token.Line, 0)
 	}
 	
 	sa.SymbolTable.AddClassHierarchy(symbol.FullyQualified, extends, implements)
 
+	// Record members up front so methods can refer to ones declared later
+	for _, constant := range stmt.Constants {
+		sa.SymbolTable.AddMember(symbol.FullyQualified, constant.Name.Value)
+	}
+	for _, property := range stmt.Properties {
+		sa.SymbolTable.AddMember(symbol.FullyQualified, property.Name.Name)
+	}
+	for _, method := range stmt.Methods {
+		sa.SymbolTable.AddMember(symbol.FullyQualified, method.Name.Value)
+	}
+
 	// Enter class scope
 	sa.SymbolTable.EnterScope("class", stmt.Name.Value)
+	sa.SymbolTable.CurrentScope.ThisClass = symbol.FullyQualified
+
+	outerClass, outerReadonly, outerProperties := sa.currentClass, sa.readonlyProperties, sa.classProperties
+	outerParent := sa.currentParent
+	sa.currentClass, sa.currentParent = stmt.Name.Value, extends
+	sa.readonlyProperties = map[string]bool{}
+	sa.classProperties = map[string]bool{}
+	for _, property := range stmt.Properties {
+		sa.classProperties[property.Name.Name] = true
+		if property.Readonly {
+			sa.readonlyProperties[property.Name.Name] = true
+		}
+	}
+
+	// Promoted constructor parameters declare properties too
+	for _, method := range stmt.Methods {
+		if !strings.EqualFold(method.Name.Value, "__construct") {
+			continue
+		}
+		for _, param := range method.Parameters {
+			if !param.Promoted() {
+				continue
+			}
+			sa.SymbolTable.AddMember(symbol.FullyQualified, param.Name)
+			sa.classProperties[param.Name] = true
+			if param.Readonly {
+				sa.readonlyProperties[param.Name] = true
+			}
+		}
+	}
 
 	// Visit class members
+	sa.visitTraitUses(symbol.FullyQualified, stmt.TraitUses)
 	for _, constant := range stmt.Constants {
 		sa.visitConstantDeclaration(constant)
 	}
@@ -417,6 +885,9 @@ func (sa *SemanticAnalyzer) visitClassDeclaration(stmt *ClassDeclaration) {
 		sa.visitMethodDeclaration(method)
 	}
 
+	sa.currentClass, sa.readonlyProperties, sa.classProperties = outerClass, outerReadonly, outerProperties
+	sa.currentParent = outerParent
+
 	// Exit class scope
 	sa.SymbolTable.ExitScope()
 }
@@ -432,9 +903,23 @@ func (sa *SemanticAnalyzer) visitInterfaceDeclaration(stmt *InterfaceDeclaration
 }
 
 func (sa *SemanticAnalyzer) visitTraitDeclaration(stmt *TraitDeclaration) {
-	sa.SymbolTable.DeclareSymbol(stmt.Name.Value, TRAIT_SYMBOL, sa.CurrentFile, stmt.Token.Line)
+	symbol := sa.SymbolTable.DeclareSymbol(stmt.Name.Value, TRAIT_SYMBOL, sa.CurrentFile, stmt.Token.Line)
+
+	for _, constant := range stmt.Constants {
+		sa.SymbolTable.AddMember(symbol.FullyQualified, constant.Name.Value)
+	}
+	for _, property := range stmt.Properties {
+		sa.SymbolTable.AddMember(symbol.FullyQualified, property.Name.Name)
+	}
+	for _, method := range stmt.Methods {
+		sa.SymbolTable.AddMember(symbol.FullyQualified, method.Name.Value)
+	}
 
 	sa.SymbolTable.EnterScope("trait", stmt.Name.Value)
+	sa.SymbolTable.CurrentScope.ThisClass = symbol.FullyQualified
+	for _, constant := range stmt.Constants {
+		sa.visitConstantDeclaration(constant)
+	}
 	for _, property := range stmt.Properties {
 		sa.visitPropertyDeclaration(property)
 	}
@@ -444,31 +929,126 @@ func (sa *SemanticAnalyzer) visitTraitDeclaration(stmt *TraitDeclaration) {
 	sa.SymbolTable.ExitScope()
 }
 
+func (sa *SemanticAnalyzer) visitEnumDeclaration(stmt *EnumDeclaration) {
+	symbol := sa.SymbolTable.DeclareSymbol(stmt.Name.Value, ENUM_SYMBOL, sa.CurrentFile, stmt.Token.Line)
+
+	implements := []string{}
+	for _, iface := range stmt.Interfaces {
+		implements = append(implements, iface.Value)
+		sa.SymbolTable.AddReferenceOfKind(iface.Value, INTERFACE_SYMBOL, INHERITANCE_REF, iface.Token.Line, 0)
+	}
+	sa.SymbolTable.AddClassHierarchy(symbol.FullyQualified, "", implements)
+
+	for _, enumCase := range stmt.Cases {
+		sa.SymbolTable.AddMember(symbol.FullyQualified, enumCase.Name.Value)
+	}
+	for _, constant := range stmt.Constants {
+		sa.SymbolTable.AddMember(symbol.FullyQualified, constant.Name.Value)
+	}
+	for _, method := range stmt.Methods {
+		sa.SymbolTable.AddMember(symbol.FullyQualified, method.Name.Value)
+	}
+
+	sa.SymbolTable.EnterScope("enum", stmt.Name.Value)
+	sa.SymbolTable.CurrentScope.ThisClass = symbol.FullyQualified
+
+	outerClass, outerParent := sa.currentClass, sa.currentParent
+	sa.currentClass, sa.currentParent = stmt.Name.Value, ""
+
+	sa.visitTraitUses(symbol.FullyQualified, stmt.TraitUses)
+	for _, enumCase := range stmt.Cases {
+		if enumCase.Value != nil {
+			sa.visitExpression(enumCase.Value)
+		}
+	}
+	for _, constant := range stmt.Constants {
+		sa.visitConstantDeclaration(constant)
+	}
+	for _, method := range stmt.Methods {
+		sa.visitMethodDeclaration(method)
+	}
+
+	sa.currentClass, sa.currentParent = outerClass, outerParent
+
+	sa.SymbolTable.ExitScope()
+}
+
+// visitTraitUses references each used trait and records it on the using
+// type so the trait's members are merged into it
+func (sa *SemanticAnalyzer) visitTraitUses(typeName string, uses []*TraitUse) {
+	for _, use := range uses {
+		for _, trait := range use.Traits {
+			ref := sa.SymbolTable.AddReferenceOfKind(trait.Value, TRAIT_SYMBOL, INHERITANCE_REF, trait.Token.Line, 0)
+			if ref.ResolvedSymbol != nil {
+				sa.SymbolTable.AddTraitUse(typeName, ref.ResolvedSymbol.FullyQualified)
+			} else {
+				sa.SymbolTable.AddTraitUse(typeName, sa.SymbolTable.makeFullyQualified(trait.Value))
+			}
+		}
+	}
+}
+
 func (sa *SemanticAnalyzer) visitFunctionDeclaration(stmt *FunctionDeclaration) {
 	sa.SymbolTable.DeclareSymbol(stmt.Name.Value, FUNCTION_SYMBOL, sa.CurrentFile, stmt.Token.Line)
 
 	sa.SymbolTable.EnterScope("function", stmt.Name.Value)
-	for _, param := range stmt.Parameters {
-		sa.SymbolTable.DeclareSymbol(param.Name, VARIABLE_SYMBOL, sa.CurrentFile, param.Token.Line)
-	}
+	sa.SymbolTable.CurrentScope.ThisClass = ""
+	sa.declareParameters(stmt.Parameters)
+	sa.addTypeHintReferences(stmt.ReturnType)
 	sa.visitBlockStatement(stmt.Body)
 	sa.SymbolTable.ExitScope()
 }
 
 func (sa *SemanticAnalyzer) visitNewExpression(expr *NewExpression) {
 	// Add reference to the class being instantiated
-	_ = sa.SymbolTable.AddReference(expr.ClassName.Value, CLASS_SYMBOL, expr.Token.Line, 0)
-	
+	if _, ok := sa.addRelativeClassReference(expr.ClassName.Value, INSTANTIATION_REF, expr.Token.Line); !ok {
+		sa.SymbolTable.AddReferenceOfKind(expr.ClassName.Value, CLASS_SYMBOL, INSTANTIATION_REF, expr.Token.Line, 0)
+	}
+
 	// Visit constructor arguments
 	for _, arg := range expr.Arguments {
 		sa.visitExpression(arg)
 	}
 }
 
+// addRelativeClassReference records a use of self or static, which both
+// resolve to the enclosing class, or of parent, which resolves to the class
+// it extends. static is bound to the called class at runtime; statically
+// the enclosing class is the closest answer, so the reference is marked
+// late-bound. Outside a class, or for parent in a class without extends,
+// the reference stays unresolved. It returns false for any other name.
+func (sa *SemanticAnalyzer) addRelativeClassReference(name string, kind RefKind, line int) (*SymbolReference, bool) {
+	lower := strings.ToLower(name)
+	if lower != "self" && lower != "static" && lower != "parent" {
+		return nil, false
+	}
+
+	var resolved *Symbol
+	switch {
+	case lower == "parent":
+		if sa.currentParent != "" {
+			resolved = sa.SymbolTable.ResolveSymbol(sa.currentParent, CLASS_SYMBOL)
+		}
+	case sa.currentClass != "":
+		resolved = sa.SymbolTable.AllSymbols[sa.SymbolTable.makeFullyQualified(sa.currentClass)]
+	}
+
+	ref := &SymbolReference{
+		Name:           name,
+		ResolvedSymbol: resolved,
+		ExpectedTypes:  []SymbolType{CLASS_SYMBOL},
+		RefKind:        kind,
+		LateBound:      lower == "static",
+		Line:           line,
+	}
+	sa.SymbolTable.References = append(sa.SymbolTable.References, ref)
+	return ref, true
+}
+
 func (sa *SemanticAnalyzer) visitCallExpression(expr *CallExpression) {
 	// If it's a simple function call (Identifier), add reference
 	if identifier, ok := expr.Function.(*Identifier); ok {
-		sa.SymbolTable.AddReference(identifier.Value, FUNCTION_SYMBOL, expr.Token.Line, 0)
+		sa.SymbolTable.AddReferenceOfKind(identifier.Value, FUNCTION_SYMBOL, CALL_REF, expr.Token.Line, 0)
 	} else {
 		// Visit the function expression (could be method call, etc.)
 		sa.visitExpression(expr.Function)
@@ -482,50 +1062,240 @@ func (sa *SemanticAnalyzer) visitCallExpression(expr *CallExpression) {
 
 func (sa *SemanticAnalyzer) visitObjectAccessExpression(expr *ObjectAccessExpression) {
 	sa.visitExpression(expr.Object)
-	sa.visitExpression(expr.Property)
+
+	// A plain member name is not a constant; resolve it against the class
+	// $this refers to when the object is $this
+	property, ok := expr.Property.(*Identifier)
+	if !ok {
+		sa.visitExpression(expr.Property)
+		return
+	}
+	if object, ok := expr.Object.(*Variable); ok && object.Name == "this" && sa.SymbolTable.CurrentScope.ThisClass != "" {
+		sa.SymbolTable.AddMemberReference(sa.SymbolTable.CurrentScope.ThisClass, property.Value,
+			property.Token.Line, property.Token.Column)
+	}
 }
 
 func (sa *SemanticAnalyzer) visitStaticAccessExpression(expr *StaticAccessExpression) {
 	// Add reference to the class
+	var classSymbol *Symbol
 	if identifier, ok := expr.Class.(*Identifier); ok {
-		sa.SymbolTable.AddReference(identifier.Value, CLASS_SYMBOL, expr.Token.Line, 0)
+		ref, relative := sa.addRelativeClassReference(identifier.Value, STATIC_ACCESS_REF, expr.Token.Line)
+		if !relative {
+			ref = sa.SymbolTable.AddReferenceAny(identifier.Value, []SymbolType{CLASS_SYMBOL, ENUM_SYMBOL}, STATIC_ACCESS_REF, expr.Token.Line, 0)
+		}
+		classSymbol = ref.ResolvedSymbol
 	} else {
 		sa.visitExpression(expr.Class)
 	}
-	sa.visitExpression(expr.Property)
+
+	// A plain member name such as an enum case or class constant is not a
+	// global constant; resolve it against the class when it is declared here
+	property, ok := expr.Property.(*Identifier)
+	if !ok {
+		sa.visitExpression(expr.Property)
+		return
+	}
+	if classSymbol != nil && !classSymbol.BuiltIn && !strings.EqualFold(property.Value, "class") {
+		sa.SymbolTable.AddMemberReference(classSymbol.FullyQualified, property.Value,
+			property.Token.Line, property.Token.Column)
+	}
 }
 
 func (sa *SemanticAnalyzer) visitAssignmentExpression(expr *AssignmentExpression) {
-	// Declare variable if it's new
-	sa.SymbolTable.DeclareSymbol(expr.Name.Name, VARIABLE_SYMBOL, sa.CurrentFile, expr.Token.Line)
+	if expr.Name != nil {
+		if expr.Name.Name == "this" {
+			sa.AddError(fmt.Sprintf("Cannot re-assign $this at line %d, column %d",
+				expr.Name.Token.Line, expr.Name.Token.Column))
+		}
+
+		if sa.LintPropertyShadowing {
+			sa.checkPropertyShadowing(expr.Name)
+		}
+
+		// Declare variable if it's new
+		sa.SymbolTable.DeclareSymbol(expr.Name.Name, VARIABLE_SYMBOL, sa.CurrentFile, expr.Token.Line)
+		sa.recordNumericAssignment(expr)
+	} else {
+		sa.checkReadonlyWrite(expr)
+		sa.visitExpression(expr.Target)
+	}
 	sa.visitExpression(expr.Value)
 }
 
+// checkReadonlyWrite reports writes to a readonly property of the current
+// class through $this anywhere but the constructor.
+func (sa *SemanticAnalyzer) checkReadonlyWrite(expr *AssignmentExpression) {
+	access, ok := expr.Target.(*ObjectAccessExpression)
+	if !ok {
+		return
+	}
+
+	object, ok := access.Object.(*Variable)
+	if !ok || object.Name != "this" {
+		return
+	}
+
+	property, ok := access.Property.(*Identifier)
+	if !ok || !sa.readonlyProperties[property.Value] {
+		return
+	}
+
+	if strings.EqualFold(sa.currentMethod, "__construct") {
+		return
+	}
+
+	sa.AddError(fmt.Sprintf("Cannot modify readonly property %s::$%s outside the constructor at line %d, column %d",
+		sa.currentClass, property.Value, property.Token.Line, property.Token.Column))
+}
+
+// checkPropertyShadowing reports the first assignment to a method local
+// named like a property of the enclosing class. Parameters are skipped
+// since constructors conventionally pass values in under property names.
+func (sa *SemanticAnalyzer) checkPropertyShadowing(variable *Variable) {
+	scope := sa.SymbolTable.CurrentScope
+	if scope.Type != "method" || !sa.classProperties[variable.Name] {
+		return
+	}
+	if _, declared := scope.Symbols[variable.Name]; declared {
+		return
+	}
+
+	sa.AddWarning(fmt.Sprintf("Local variable $%s in %s::%s() shadows property $%s at line %d, column %d; did you mean $this->%s?",
+		variable.Name, sa.currentClass, sa.currentMethod, variable.Name, variable.Token.Line, variable.Token.Column, variable.Name))
+}
+
+// recordNumericAssignment remembers whether a variable was last assigned a
+// number literal in the current scope. Branches are not tracked, so the
+// last assignment in source order wins.
+func (sa *SemanticAnalyzer) recordNumericAssignment(expr *AssignmentExpression) {
+	if sa.numericVariables == nil {
+		sa.numericVariables = map[*Scope]map[string]bool{}
+	}
+	scope := sa.SymbolTable.CurrentScope
+	if sa.numericVariables[scope] == nil {
+		sa.numericVariables[scope] = map[string]bool{}
+	}
+	sa.numericVariables[scope][expr.Name.Name] = expr.Token.Type == ASSIGN && sa.isNumeric(expr.Value)
+}
+
+// isNumeric reports whether an expression is known to be an int or float
+func (sa *SemanticAnalyzer) isNumeric(expr Expression) bool {
+	switch e := expr.(type) {
+	case *IntegerLiteral, *FloatLiteral:
+		return true
+	case *PrefixExpression:
+		return (e.Operator == "-" || e.Operator == "+") && sa.isNumeric(e.Right)
+	case *Variable:
+		return sa.numericVariables[sa.SymbolTable.CurrentScope][e.Name]
+	}
+	return false
+}
+
+// checkLooseComparison reports == and != between a non-numeric string
+// literal and a number, which PHP compares as strings since 8.0 and as
+// numbers before it.
+func (sa *SemanticAnalyzer) checkLooseComparison(expr *InfixExpression) {
+	str, ok := expr.Left.(*StringLiteral)
+	other := expr.Right
+	if !ok {
+		str, ok = expr.Right.(*StringLiteral)
+		other = expr.Left
+	}
+	if !ok || !sa.isNumeric(other) {
+		return
+	}
+	if _, err := strconv.ParseFloat(strings.TrimSpace(str.Value), 64); err == nil {
+		return
+	}
+
+	strict := "==="
+	if expr.Operator == "!=" {
+		strict = "!=="
+	}
+	sa.AddWarning(fmt.Sprintf("Loose comparison %s between string \"%s\" and a number at line %d, column %d; use %s instead",
+		expr.Operator, str.Value, expr.Token.Line, expr.Token.Column, strict))
+}
+
+// checkEmptyStatements reports each run of empty statements once, at its
+// first semicolon. The semicolon ending a statement is part of it, so the
+// run in $a = 1;; is the second semicolon alone.
+func (sa *SemanticAnalyzer) checkEmptyStatements(statements []Statement) {
+	for i, stmt := range statements {
+		empty, ok := stmt.(*EmptyStatement)
+		if !ok {
+			continue
+		}
+		if i > 0 {
+			if _, run := statements[i-1].(*EmptyStatement); run {
+				continue
+			}
+		}
+		sa.AddWarning(fmt.Sprintf("Empty statement at line %d, column %d; remove the stray ';'",
+			empty.Token.Line, empty.Token.Column))
+	}
+}
+
+// inFunction reports whether the analyzer is inside a function, method,
+// closure or arrow function body
+func (sa *SemanticAnalyzer) inFunction() bool {
+	scopeType := sa.SymbolTable.CurrentScope.Type
+	return scopeType == "function" || scopeType == "method"
+}
+
 // Helper methods
 func (sa *SemanticAnalyzer) visitBlockStatement(stmt *BlockStatement) {
 	for _, s := range stmt.Statements {
 		sa.visitStatement(s)
 	}
+
+	if sa.LintEmptyStatements {
+		sa.checkEmptyStatements(stmt.Statements)
+	}
 }
 
 func (sa *SemanticAnalyzer) visitIfStatement(stmt *IfStatement) {
 	sa.visitExpression(stmt.Condition)
+	sa.conditionalDepth++
 	sa.visitBlockStatement(stmt.Consequence)
+	for _, elseIf := range stmt.ElseIfs {
+		sa.visitExpression(elseIf.Condition)
+		sa.visitBlockStatement(elseIf.Consequence)
+	}
 	if stmt.Alternative != nil {
 		sa.visitBlockStatement(stmt.Alternative)
 	}
+	sa.conditionalDepth--
 }
 
 func (sa *SemanticAnalyzer) visitForStatement(stmt *ForStatement) {
 	sa.visitExpression(stmt.Init)
+	sa.conditionalDepth++
 	sa.visitExpression(stmt.Condition)
 	sa.visitExpression(stmt.Update)
 	sa.visitBlockStatement(stmt.Body)
+	sa.conditionalDepth--
 }
 
 func (sa *SemanticAnalyzer) visitWhileStatement(stmt *WhileStatement) {
 	sa.visitExpression(stmt.Condition)
+	sa.conditionalDepth++
 	sa.visitBlockStatement(stmt.Body)
+	sa.conditionalDepth--
+}
+
+func (sa *SemanticAnalyzer) visitSwitchStatement(stmt *SwitchStatement) {
+	sa.visitExpression(stmt.Subject)
+	sa.conditionalDepth++
+	for _, switchCase := range stmt.Cases {
+		if switchCase.Condition != nil {
+			sa.visitExpression(switchCase.Condition)
+		}
+		for _, s := range switchCase.Body {
+			sa.visitStatement(s)
+		}
+	}
+	sa.conditionalDepth--
 }
 
 func (sa *SemanticAnalyzer) visitForeachStatement(stmt *ForeachStatement) {
@@ -533,8 +1303,45 @@ func (sa *SemanticAnalyzer) visitForeachStatement(stmt *ForeachStatement) {
 	if stmt.Key != nil {
 		sa.SymbolTable.DeclareSymbol(stmt.Key.Name, VARIABLE_SYMBOL, sa.CurrentFile, stmt.Token.Line)
 	}
-	sa.SymbolTable.DeclareSymbol(stmt.Value.Name, VARIABLE_SYMBOL, sa.CurrentFile, stmt.Token.Line)
+	if stmt.ValuePattern != nil {
+		sa.declarePatternVariables(stmt.ValuePattern, stmt.Token.Line)
+	} else {
+		sa.SymbolTable.DeclareSymbol(stmt.Value.Name, VARIABLE_SYMBOL, sa.CurrentFile, stmt.Token.Line)
+	}
+	sa.conditionalDepth++
 	sa.visitBlockStatement(stmt.Body)
+	sa.conditionalDepth--
+}
+
+// declarePatternVariables declares every variable bound by a destructuring
+// pattern such as [$a, [$b, $c]] or ['id' => $id]
+func (sa *SemanticAnalyzer) declarePatternVariables(pattern Expression, line int) {
+	switch p := pattern.(type) {
+	case *Variable:
+		sa.SymbolTable.DeclareSymbol(p.Name, VARIABLE_SYMBOL, sa.CurrentFile, line)
+	case *ArrayLiteral:
+		for _, element := range p.Elements {
+			sa.declarePatternVariables(element, line)
+		}
+	case *AssociativeArrayLiteral:
+		for _, pair := range p.Pairs {
+			sa.visitExpression(pair.Key)
+			sa.declarePatternVariables(pair.Value, line)
+		}
+	case *ListExpression:
+		for _, item := range p.Items {
+			if item == nil {
+				continue
+			}
+			if item.Key != nil {
+				sa.visitExpression(item.Key)
+			}
+			sa.declarePatternVariables(item.Value, line)
+		}
+	default:
+		// Targets such as $this->prop are ordinary expressions
+		sa.visitExpression(pattern)
+	}
 }
 
 func (sa *SemanticAnalyzer) visitReturnStatement(stmt *ReturnStatement) {
@@ -564,16 +1371,23 @@ func (sa *SemanticAnalyzer) visitThrowStatement(stmt *ThrowStatement) {
 }
 
 func (sa *SemanticAnalyzer) visitCatchClause(clause *CatchClause) {
-	if clause.ExceptionType != nil {
-		sa.SymbolTable.AddReference(clause.ExceptionType.Value, CLASS_SYMBOL, clause.Token.Line, 0)
+	// Record one reference per caught type; each may be a class or an interface
+	for _, exceptionType := range clause.ExceptionTypes {
+		sa.SymbolTable.AddReferenceAny(exceptionType.Value, []SymbolType{CLASS_SYMBOL, INTERFACE_SYMBOL}, CATCH_REF, exceptionType.Token.Line, 0)
+	}
+	if clause.Variable != nil {
+		sa.SymbolTable.DeclareSymbol(clause.Variable.Name, VARIABLE_SYMBOL, sa.CurrentFile, clause.Token.Line)
 	}
-	sa.SymbolTable.DeclareSymbol(clause.Variable.Name, VARIABLE_SYMBOL, sa.CurrentFile, clause.Token.Line)
 	sa.visitBlockStatement(clause.Body)
 }
 
 func (sa *SemanticAnalyzer) visitInfixExpression(expr *InfixExpression) {
 	sa.visitExpression(expr.Left)
 	sa.visitExpression(expr.Right)
+
+	if sa.LintLooseComparisons && (expr.Operator == "==" || expr.Operator == "!=") {
+		sa.checkLooseComparison(expr)
+	}
 }
 
 func (sa *SemanticAnalyzer) visitPrefixExpression(expr *PrefixExpression) {
@@ -603,17 +1417,48 @@ func (sa *SemanticAnalyzer) visitIndexExpression(expr *IndexExpression) {
 }
 
 func (sa *SemanticAnalyzer) visitAnonymousFunction(expr *AnonymousFunction) {
+	// Captured variables come from the enclosing scope and become locals of
+	// the closure, where closures nested inside it can capture them in turn
+	for _, useVar := range expr.UseClause {
+		sa.SymbolTable.AddCaptureReference(useVar.Name, useVar.Token.Line, 0)
+	}
+
 	sa.SymbolTable.EnterScope("function", "anonymous")
-	for _, param := range expr.Parameters {
-		sa.SymbolTable.DeclareSymbol(param.Name, VARIABLE_SYMBOL, sa.CurrentFile, param.Token.Line)
+	if expr.Static {
+		sa.SymbolTable.CurrentScope.ThisClass = ""
 	}
+	sa.declareParameters(expr.Parameters)
+	sa.addTypeHintReferences(expr.ReturnType)
 	for _, useVar := range expr.UseClause {
-		sa.SymbolTable.AddReference(useVar.Name, VARIABLE_SYMBOL, useVar.Token.Line, 0)
+		sa.SymbolTable.DeclareSymbol(useVar.Name, VARIABLE_SYMBOL, sa.CurrentFile, useVar.Token.Line)
 	}
 	sa.visitBlockStatement(expr.Body)
 	sa.SymbolTable.ExitScope()
 }
 
+func (sa *SemanticAnalyzer) visitArrowFunction(expr *ArrowFunction) {
+	sa.SymbolTable.EnterScope("function", "arrow")
+	if expr.Static {
+		sa.SymbolTable.CurrentScope.ThisClass = ""
+	}
+	sa.declareParameters(expr.Parameters)
+	sa.addTypeHintReferences(expr.ReturnType)
+	sa.visitExpression(expr.Body)
+	sa.SymbolTable.ExitScope()
+}
+
+func (sa *SemanticAnalyzer) visitMatchExpression(expr *MatchExpression) {
+	sa.visitExpression(expr.Subject)
+	sa.conditionalDepth++
+	for _, arm := range expr.Arms {
+		for _, condition := range arm.Conditions {
+			sa.visitExpression(condition)
+		}
+		sa.visitExpression(arm.Body)
+	}
+	sa.conditionalDepth--
+}
+
 func (sa *SemanticAnalyzer) visitYieldExpression(expr *YieldExpression) {
 	if expr.Key != nil {
 		sa.visitExpression(expr.Key)
@@ -623,10 +1468,56 @@ func (sa *SemanticAnalyzer) visitYieldExpression(expr *YieldExpression) {
 	}
 }
 
+func (sa *SemanticAnalyzer) visitInstanceofExpression(expr *InstanceofExpression) {
+	sa.visitExpression(expr.Left)
+
+	class, ok := expr.Class.(*Identifier)
+	if !ok {
+		sa.visitExpression(expr.Class)
+		return
+	}
+	if _, relative := sa.addRelativeClassReference(class.Value, INSTANCEOF_REF, expr.Token.Line); !relative {
+		sa.SymbolTable.AddReferenceAny(class.Value, []SymbolType{CLASS_SYMBOL, INTERFACE_SYMBOL, ENUM_SYMBOL}, INSTANCEOF_REF, expr.Token.Line, 0)
+	}
+}
+
 func (sa *SemanticAnalyzer) visitTernaryExpression(expr *TernaryExpression) {
 	sa.visitExpression(expr.Condition)
+	sa.conditionalDepth++
 	sa.visitExpression(expr.TrueValue)
 	sa.visitExpression(expr.FalseValue)
+	sa.conditionalDepth--
+}
+
+// recordInclude notes the file an include or require pulls in, then visits
+// the path expression for the references it makes
+func (sa *SemanticAnalyzer) recordInclude(kind string, once bool, path Expression, line int) {
+	if once {
+		kind += "_once"
+	}
+	include := &Include{
+		Kind:        kind,
+		Conditional: sa.conditionalDepth > 0,
+		Line:        line,
+	}
+	if literal, ok := path.(*StringLiteral); ok {
+		include.Path = literal.Value
+		include.Literal = true
+	} else if path != nil {
+		include.Path = sa.sourceText(path)
+	}
+	sa.Includes = append(sa.Includes, include)
+
+	sa.visitExpression(path)
+}
+
+// sourceText returns the code a node was parsed from, or the node printed
+// as PHP when the source is not known
+func (sa *SemanticAnalyzer) sourceText(node Node) string {
+	if start, end := SourceRange(node); end > start && end <= len(sa.source) {
+		return sa.source[start:end]
+	}
+	return PrettyPrint(node)
 }
 
 func (sa *SemanticAnalyzer) visitConstantDeclaration(stmt *ConstantDeclaration) {
@@ -644,25 +1535,71 @@ func (sa *SemanticAnalyzer) visitPropertyDeclaration(stmt *PropertyDeclaration)
 func (sa *SemanticAnalyzer) visitMethodDeclaration(stmt *MethodDeclaration) {
 	sa.SymbolTable.DeclareSymbol(stmt.Name.Value, FUNCTION_SYMBOL, sa.CurrentFile, stmt.Token.Line)
 
+	outerMethod := sa.currentMethod
+	sa.currentMethod = stmt.Name.Value
+
 	sa.SymbolTable.EnterScope("method", stmt.Name.Value)
-	for _, param := range stmt.Parameters {
-		sa.SymbolTable.DeclareSymbol(param.Name, VARIABLE_SYMBOL, sa.CurrentFile, param.Token.Line)
+	if stmt.Static {
+		sa.SymbolTable.CurrentScope.ThisClass = ""
+	}
+	sa.declareParameters(stmt.Parameters)
+	sa.addTypeHintReferences(stmt.ReturnType)
+	if stmt.Body != nil {
+		sa.visitBlockStatement(stmt.Body)
 	}
-	sa.visitBlockStatement(stmt.Body)
 	sa.SymbolTable.ExitScope()
+
+	// Abstract methods have no body to check
+	if stmt.Body != nil && !stmt.IsGenerator && returnTypeRequiresValue(stmt.ReturnType) && !blockAlwaysExits(stmt.Body) {
+		sa.AddError(fmt.Sprintf("Method %s::%s() with return type %s does not return on all paths at line %d, column %d",
+			sa.currentClass, stmt.Name.Value, stmt.ReturnType.String(), stmt.Token.Line, stmt.Token.Column))
+	}
+
+	sa.currentMethod = outerMethod
 }
 
 func (sa *SemanticAnalyzer) visitInterfaceMethod(stmt *InterfaceMethod) {
 	sa.SymbolTable.DeclareSymbol(stmt.Name.Value, FUNCTION_SYMBOL, sa.CurrentFile, stmt.Token.Line)
 }
 
-func (sa *SemanticAnalyzer) addIdentifierReference(identifier *Identifier) {
-	// This could be a function call or constant reference
-	// Try to resolve as function first, then as constant
-	ref := sa.SymbolTable.AddReference(identifier.Value, FUNCTION_SYMBOL, identifier.Token.Line, 0)
-	if ref.ResolvedSymbol == nil {
-		sa.SymbolTable.AddReference(identifier.Value, CONSTANT_SYMBOL, identifier.Token.Line, 0)
+// declareParameters declares each parameter in the current scope and
+// references the classes named in its type and the names in its default
+func (sa *SemanticAnalyzer) declareParameters(parameters []*Parameter) {
+	for _, param := range parameters {
+		sa.SymbolTable.DeclareSymbol(param.Name, VARIABLE_SYMBOL, sa.CurrentFile, param.Token.Line)
+		if param.TypeHint != nil {
+			sa.addTypeHintReferences(param.TypeHint)
+		}
+		if param.Default != nil {
+			sa.visitExpression(param.Default)
+		}
+	}
+}
+
+// addTypeHintReferences references the class-like names in a type,
+// skipping built-in types and self/static/parent
+func (sa *SemanticAnalyzer) addTypeHintReferences(typeHint Expression) {
+	hint, ok := typeHint.(*TypeHint)
+	if !ok {
+		return
+	}
+	for _, member := range hint.Union {
+		sa.addTypeHintReferences(member)
+	}
+	for _, member := range hint.Intersection {
+		sa.addTypeHintReferences(member)
 	}
+	if hint.Name == "" || isBuiltinTypeName(hint.Name) {
+		return
+	}
+	sa.SymbolTable.AddReferenceAny(hint.Name, []SymbolType{CLASS_SYMBOL, INTERFACE_SYMBOL, ENUM_SYMBOL},
+		TYPE_HINT_REF, hint.Token.Line, 0)
+}
+
+func (sa *SemanticAnalyzer) addIdentifierReference(identifier *Identifier) {
+	// A bare identifier is usually a constant but may also name a function,
+	// so record a single reference that resolves against either kind
+	sa.SymbolTable.AddReferenceAny(identifier.Value, []SymbolType{CONSTANT_SYMBOL, FUNCTION_SYMBOL}, CONSTANT_REF, identifier.Token.Line, 0)
 }
 
 // AddError adds a semantic error
@@ -675,6 +1612,16 @@ func (sa *SemanticAnalyzer) GetErrors() []string {
 	return sa.Errors
 }
 
+// AddWarning adds a lint warning
+func (sa *SemanticAnalyzer) AddWarning(message string) {
+	sa.Warnings = append(sa.Warnings, message)
+}
+
+// GetWarnings returns all lint warnings
+func (sa *SemanticAnalyzer) GetWarnings() []string {
+	return sa.Warnings
+}
+
 // ValidateReferences validates all symbol references and reports errors
 func (sa *SemanticAnalyzer) ValidateReferences() {
 	for _, ref := range sa.SymbolTable.References {
@@ -685,8 +1632,14 @@ func (sa *SemanticAnalyzer) ValidateReferences() {
 	}
 }
 
-func getSymbolTypeString(_ *SymbolReference) string {
-	// This is a simplified approach - in reality you'd track the expected type
-	// The ref parameter is not used in this simple implementation
-	return "symbol"
+func getSymbolTypeString(ref *SymbolReference) string {
+	if len(ref.ExpectedTypes) == 0 {
+		return "symbol"
+	}
+
+	names := make([]string, len(ref.ExpectedTypes))
+	for i, symbolType := range ref.ExpectedTypes {
+		names[i] = symbolType.String()
+	}
+	return strings.Join(names, " or ")
 }
\ No newline at end of file
@@ -20,28 +20,32 @@ type SemanticNewExpression struct {
 	SemanticInfo *SemanticInfo `json:"semantic_info,omitempty"`
 }
 
-func (sne *SemanticNewExpression) Type() string { return "SemanticNewExpression" }
+func (sne *SemanticNewExpression) Type() string   { return "SemanticNewExpression" }
+func (sne *SemanticNewExpression) Kind() NodeKind { return SEMANTIC_NEW_EXPRESSION_NODE }
 
 type SemanticCallExpression struct {
 	*CallExpression
 	SemanticInfo *SemanticInfo `json:"semantic_info,omitempty"`
 }
 
-func (sce *SemanticCallExpression) Type() string { return "SemanticCallExpression" }
+func (sce *SemanticCallExpression) Type() string   { return "SemanticCallExpression" }
+func (sce *SemanticCallExpression) Kind() NodeKind { return SEMANTIC_CALL_EXPRESSION_NODE }
 
 type SemanticIdentifier struct {
 	*Identifier
 	SemanticInfo *SemanticInfo `json:"semantic_info,omitempty"`
 }
 
-func (si *SemanticIdentifier) Type() string { return "SemanticIdentifier" }
+func (si *SemanticIdentifier) Type() string   { return "SemanticIdentifier" }
+func (si *SemanticIdentifier) Kind() NodeKind { return SEMANTIC_IDENTIFIER_NODE }
 
 type SemanticStaticAccess struct {
 	*StaticAccessExpression
 	SemanticInfo *SemanticInfo `json:"semantic_info,omitempty"`
 }
 
-func (ssa *SemanticStaticAccess) Type() string { return "SemanticStaticAccess" }
+func (ssa *SemanticStaticAccess) Type() string   { return "SemanticStaticAccess" }
+func (ssa *SemanticStaticAccess) Kind() NodeKind { return SEMANTIC_STATIC_ACCESS_NODE }
 
 // SemanticProgram contains the original AST plus semantic analysis results
 type SemanticProgram struct {
@@ -51,10 +55,20 @@ type SemanticProgram struct {
 	UnresolvedRefs   []*SymbolReference  `json:"unresolved_references"`
 	ClassHierarchy   map[string][]string `json:"class_hierarchy"`
 	NamespaceSymbols map[string][]*Symbol `json:"namespace_symbols"`
+	Errors           []string             `json:"errors,omitempty"`
+	Warnings         []string             `json:"warnings,omitempty"`
+	Comments         []*Comment           `json:"comments,omitempty"`
+	Includes         []*Include           `json:"includes,omitempty"`
 }
 
 // ParseWithSemantics parses PHP code and performs semantic analysis
 func ParseWithSemantics(input string, filename string) (*SemanticProgram, error) {
+	return ParseWithSemanticsOptions(input, filename, AnalyzerOptions{})
+}
+
+// ParseWithSemanticsOptions is ParseWithSemantics with the analyzer
+// configured by options, e.g. to enable lints
+func ParseWithSemanticsOptions(input string, filename string, options AnalyzerOptions) (*SemanticProgram, error) {
 	// 1. Parse syntax
 	lexer := New(input)
 	parser := NewParser(lexer)
@@ -65,7 +79,7 @@ func ParseWithSemantics(input string, filename string) (*SemanticProgram, error)
 	}
 
 	// 2. Perform semantic analysis
-	analyzer := NewSemanticAnalyzer()
+	analyzer := NewSemanticAnalyzerWithOptions(options)
 	analyzer.AnalyzeProgram(program, filename)
 	analyzer.ValidateReferences()
 
@@ -77,6 +91,10 @@ func ParseWithSemantics(input string, filename string) (*SemanticProgram, error)
 		UnresolvedRefs:   analyzer.SymbolTable.GetUnresolvedReferences(),
 		ClassHierarchy:   analyzer.SymbolTable.ClassHierarchy,
 		NamespaceSymbols: analyzer.SymbolTable.Namespaces,
+		Errors:           analyzer.GetErrors(),
+		Warnings:         analyzer.GetWarnings(),
+		Comments:         analyzer.Comments,
+		Includes:         analyzer.Includes,
 	}
 
 	return semanticProgram, nil
@@ -138,11 +156,10 @@ func (sp *SemanticProgram) GetClassHierarchy(className string) []string {
 func (sp *SemanticProgram) FindClassInstantiations(className string) []*SymbolReference {
 	var instantiations []*SymbolReference
 	for _, ref := range sp.AllReferences {
-		if ref.ResolvedSymbol != nil && 
+		if ref.RefKind == INSTANTIATION_REF &&
+		   ref.ResolvedSymbol != nil && 
 		   ref.ResolvedSymbol.Type == CLASS_SYMBOL && 
 		   (ref.ResolvedSymbol.Name == className || ref.ResolvedSymbol.FullyQualified == className) {
-			// Note: In a more sophisticated implementation, you'd distinguish between
-			// different types of references (instantiation vs static access vs inheritance)
 			instantiations = append(instantiations, ref)
 		}
 	}
@@ -0,0 +1,1335 @@
+package gophpparser
+
+import (
+	"bytes"
+	"encoding/json"
+	"slices"
+	"strings"
+	"testing"
+)
+
+func TestSymbolTableToJSON(t *testing.T) {
+	phpCode := `<?php
+namespace App;
+
+class User {
+    public function getName() {
+        return "name";
+    }
+}
+
+class Admin extends User {
+}
+
+function helper() {
+    return 1;
+}
+?>`
+
+	first, err := ParseWithSemantics(phpCode, "app.php")
+	if err != nil {
+		t.Fatalf("Failed to parse with semantics: %v", err)
+	}
+	second, err := ParseWithSemantics(phpCode, "app.php")
+	if err != nil {
+		t.Fatalf("Failed to parse with semantics: %v", err)
+	}
+
+	firstJSON, err := first.SymbolTable.ToJSON()
+	if err != nil {
+		t.Fatalf("SymbolTable.ToJSON failed: %v", err)
+	}
+	secondJSON, err := second.SymbolTable.ToJSON()
+	if err != nil {
+		t.Fatalf("SymbolTable.ToJSON failed: %v", err)
+	}
+
+	if !json.Valid(firstJSON) {
+		t.Fatalf("symbol table JSON is not valid: %s", firstJSON)
+	}
+
+	if !bytes.Equal(firstJSON, secondJSON) {
+		t.Errorf("symbol table JSON is not deterministic:\n%s\n---\n%s", firstJSON, secondJSON)
+	}
+
+	var decoded struct {
+		Symbols []struct {
+			FullyQualified string `json:"fully_qualified"`
+			Type           string `json:"type"`
+		} `json:"symbols"`
+		Namespaces     map[string][]string `json:"namespaces"`
+		ClassHierarchy map[string][]string `json:"class_hierarchy"`
+	}
+	if err := json.Unmarshal(firstJSON, &decoded); err != nil {
+		t.Fatalf("failed to decode symbol table JSON: %v", err)
+	}
+
+	for i := 1; i < len(decoded.Symbols); i++ {
+		if decoded.Symbols[i-1].FullyQualified > decoded.Symbols[i].FullyQualified {
+			t.Errorf("symbols not sorted: %q before %q",
+				decoded.Symbols[i-1].FullyQualified, decoded.Symbols[i].FullyQualified)
+		}
+	}
+
+	found := false
+	for _, symbol := range decoded.Symbols {
+		if symbol.FullyQualified == "App\\User" && symbol.Type == "class" {
+			found = true
+		}
+	}
+	if !found {
+		t.Errorf("App\\User class not found in symbol table JSON: %s", firstJSON)
+	}
+
+	if parents := decoded.ClassHierarchy["App\\Admin"]; len(parents) != 1 || parents[0] != "User" {
+		t.Errorf("class hierarchy for App\\Admin wrong. got=%v", parents)
+	}
+
+	if len(decoded.Namespaces["App"]) == 0 {
+		t.Errorf("namespace App has no symbols")
+	}
+}
+
+func TestBareConstantReference(t *testing.T) {
+	phpCode := `<?php
+const MY_CONSTANT = 42;
+
+echo MY_CONSTANT;
+?>`
+
+	semanticProgram, err := ParseWithSemantics(phpCode, "const.php")
+	if err != nil {
+		t.Fatalf("Failed to parse with semantics: %v", err)
+	}
+
+	var refs []*SymbolReference
+	for _, ref := range semanticProgram.AllReferences {
+		if ref.Name == "MY_CONSTANT" {
+			refs = append(refs, ref)
+		}
+	}
+
+	if len(refs) != 1 {
+		t.Fatalf("expected exactly 1 reference to MY_CONSTANT, got=%d", len(refs))
+	}
+
+	if refs[0].ResolvedSymbol == nil {
+		t.Fatalf("MY_CONSTANT reference is unresolved")
+	}
+
+	if refs[0].ResolvedSymbol.Type != CONSTANT_SYMBOL {
+		t.Errorf("MY_CONSTANT resolved to a %s, want constant", refs[0].ResolvedSymbol.Type)
+	}
+
+	if len(semanticProgram.UnresolvedRefs) != 0 {
+		t.Errorf("expected no unresolved references, got %d", len(semanticProgram.UnresolvedRefs))
+		for _, ref := range semanticProgram.UnresolvedRefs {
+			t.Logf("  unresolved: %s (line %d)", ref.Name, ref.Line)
+		}
+	}
+}
+
+func TestUnionCatchResolvesBuiltinClasses(t *testing.T) {
+	phpCode := `<?php
+try {
+    risky();
+} catch (TypeError | ValueError $e) {
+    echo "failed";
+}
+?>`
+
+	semanticProgram, err := ParseWithSemantics(phpCode, "catch.php")
+	if err != nil {
+		t.Fatalf("Failed to parse with semantics: %v", err)
+	}
+
+	tryStmt, ok := semanticProgram.Program.Statements[0].(*TryStatement)
+	if !ok {
+		t.Fatalf("statement is not *TryStatement. got=%T", semanticProgram.Program.Statements[0])
+	}
+
+	clause := tryStmt.Catches[0]
+	if len(clause.ExceptionTypes) != 2 {
+		t.Fatalf("expected 2 exception types, got=%d", len(clause.ExceptionTypes))
+	}
+
+	for i, want := range []string{"TypeError", "ValueError"} {
+		if clause.ExceptionTypes[i].Value != want {
+			t.Errorf("exception type %d is %q, want %q", i, clause.ExceptionTypes[i].Value, want)
+		}
+	}
+
+	resolved := map[string]bool{}
+	for _, ref := range semanticProgram.AllReferences {
+		if ref.ResolvedSymbol != nil && ref.ResolvedSymbol.BuiltIn {
+			resolved[ref.Name] = true
+		}
+	}
+
+	for _, name := range []string{"TypeError", "ValueError"} {
+		if !resolved[name] {
+			t.Errorf("expected a resolved reference to built-in class %s", name)
+		}
+	}
+}
+
+func TestBitwiseConstantOperandsResolve(t *testing.T) {
+	phpCode := `<?php
+const FLAG_A = 1;
+const FLAG_B = 1 << 1;
+const FLAG_ALL = FLAG_A | FLAG_B;
+?>`
+
+	semanticProgram, err := ParseWithSemantics(phpCode, "flags.php")
+	if err != nil {
+		t.Fatalf("Failed to parse with semantics: %v", err)
+	}
+
+	resolved := map[string]bool{}
+	for _, ref := range semanticProgram.AllReferences {
+		if ref.ResolvedSymbol != nil && ref.ResolvedSymbol.Type == CONSTANT_SYMBOL {
+			resolved[ref.Name] = true
+		}
+	}
+
+	for _, name := range []string{"FLAG_A", "FLAG_B"} {
+		if !resolved[name] {
+			t.Errorf("expected %s in FLAG_ALL to resolve to a constant", name)
+		}
+	}
+
+	if len(semanticProgram.UnresolvedRefs) != 0 {
+		t.Errorf("expected no unresolved references, got %d", len(semanticProgram.UnresolvedRefs))
+	}
+}
+
+func TestThisAssignmentIsReported(t *testing.T) {
+	phpCode := `<?php
+class User {
+    public function reset() {
+        $this = null;
+    }
+}
+?>`
+
+	semanticProgram, err := ParseWithSemantics(phpCode, "this.php")
+	if err != nil {
+		t.Fatalf("Failed to parse with semantics: %v", err)
+	}
+
+	if !containsError(semanticProgram.Errors, "Cannot re-assign $this at line 4") {
+		t.Errorf("expected a $this re-assignment error, got %v", semanticProgram.Errors)
+	}
+}
+
+func TestReadonlyPropertyWriteOutsideConstructor(t *testing.T) {
+	phpCode := `<?php
+class Account {
+    public readonly int $id;
+    private string $name;
+
+    public function __construct($id) {
+        $this->id = $id;
+        $this->name = "new";
+    }
+
+    public function rename() {
+        $this->name = "renamed";
+        $this->id = 2;
+    }
+}
+?>`
+
+	semanticProgram, err := ParseWithSemantics(phpCode, "account.php")
+	if err != nil {
+		t.Fatalf("Failed to parse with semantics: %v", err)
+	}
+
+	var readonlyErrors []string
+	for _, message := range semanticProgram.Errors {
+		if strings.Contains(message, "readonly") {
+			readonlyErrors = append(readonlyErrors, message)
+		}
+	}
+
+	if len(readonlyErrors) != 1 {
+		t.Fatalf("expected exactly 1 readonly error, got %d: %v", len(readonlyErrors), readonlyErrors)
+	}
+
+	if !strings.Contains(readonlyErrors[0], "Account::$id outside the constructor at line 13") {
+		t.Errorf("unexpected readonly error: %s", readonlyErrors[0])
+	}
+}
+
+func containsError(errors []string, substr string) bool {
+	for _, message := range errors {
+		if strings.Contains(message, substr) {
+			return true
+		}
+	}
+	return false
+}
+
+func TestEnumMergesTraitMembers(t *testing.T) {
+	phpCode := `<?php
+trait HasLabel {
+    public function label() {
+        return "label";
+    }
+}
+
+enum Suit: string {
+    use HasLabel;
+
+    case Hearts = 'H';
+
+    public function color() {
+        return "Red";
+    }
+}
+?>`
+
+	semanticProgram, err := ParseWithSemantics(phpCode, "suit.php")
+	if err != nil {
+		t.Fatalf("Failed to parse with semantics: %v", err)
+	}
+
+	symbol, exists := semanticProgram.SymbolTable.AllSymbols["Suit"]
+	if !exists {
+		t.Fatalf("Enum Suit not found in symbol table")
+	}
+	if symbol.Type != ENUM_SYMBOL {
+		t.Errorf("Suit should be an enum, got %s", symbol.Type)
+	}
+
+	members := semanticProgram.SymbolTable.GetMembers("Suit")
+	for _, expected := range []string{"Hearts", "color", "label"} {
+		found := false
+		for _, member := range members {
+			if member == expected {
+				found = true
+				break
+			}
+		}
+		if !found {
+			t.Errorf("Suit should have member %s, got %v", expected, members)
+		}
+	}
+
+	for _, ref := range semanticProgram.UnresolvedRefs {
+		if ref.Name == "HasLabel" {
+			t.Errorf("trait HasLabel should resolve")
+		}
+	}
+}
+
+func TestMissingReturnPathIsReported(t *testing.T) {
+	phpCode := `<?php
+class Calculator {
+    public function sign($n): int {
+        if ($n > 0) {
+            return 1;
+        }
+    }
+
+    public function covered($n): int {
+        if ($n > 0) {
+            return 1;
+        } else {
+            return -1;
+        }
+    }
+
+    public function guarded($n): string {
+        try {
+            return "ok";
+        } catch (Exception $e) {
+            throw $e;
+        }
+    }
+
+    public function forever(): int {
+        while (true) {
+            $n = 1;
+        }
+    }
+
+    public function maybe($n): ?int {
+        if ($n > 0) {
+            return 1;
+        }
+    }
+
+    public function log($n): void {
+        echo $n;
+    }
+
+    public function label($n): string {
+        switch ($n) {
+            case 1:
+            case 2:
+                return "low";
+            default:
+                return "high";
+        }
+    }
+
+    public function partial($n): string {
+        switch ($n) {
+            case 1:
+                return "one";
+        }
+    }
+
+    public function halt(): int {
+        exit(1);
+    }
+
+    public function fail(): string {
+        die('x');
+    }
+
+    public function spin(): int {
+        while (1) {
+            return 1;
+        }
+    }
+}
+?>`
+
+	semanticProgram, err := ParseWithSemantics(phpCode, "calculator.php")
+	if err != nil {
+		t.Fatalf("Failed to parse with semantics: %v", err)
+	}
+
+	var returnErrors []string
+	for _, message := range semanticProgram.Errors {
+		if strings.Contains(message, "does not return on all paths") {
+			returnErrors = append(returnErrors, message)
+		}
+	}
+
+	if len(returnErrors) != 2 {
+		t.Fatalf("expected exactly 2 missing-return errors, got %d: %v", len(returnErrors), returnErrors)
+	}
+
+	if !strings.Contains(returnErrors[0], "Calculator::sign() with return type int") {
+		t.Errorf("unexpected missing-return error: %s", returnErrors[0])
+	}
+	if !strings.Contains(returnErrors[1], "Calculator::partial() with return type string") {
+		t.Errorf("unexpected missing-return error: %s", returnErrors[1])
+	}
+}
+
+func TestClosuresCaptureThis(t *testing.T) {
+	phpCode := `<?php
+class User {
+    private $name;
+
+    public function getter() {
+        return fn() => $this->name;
+    }
+
+    public function missing() {
+        return function() {
+            return $this->email;
+        };
+    }
+
+    public function detached() {
+        return static function() {
+            return $this->name;
+        };
+    }
+}
+?>`
+
+	semanticProgram, err := ParseWithSemantics(phpCode, "user.php")
+	if err != nil {
+		t.Fatalf("Failed to parse with semantics: %v", err)
+	}
+
+	refs := semanticProgram.SymbolTable.MemberRefs
+	if len(refs) != 2 {
+		t.Fatalf("expected 2 member references, got %d", len(refs))
+	}
+
+	if refs[0].Class != "User" || refs[0].Member != "name" || !refs[0].Resolved {
+		t.Errorf("fn() => $this->name should resolve against User, got %+v", refs[0])
+	}
+	if refs[0].Line != 6 {
+		t.Errorf("member reference line wrong. got=%d", refs[0].Line)
+	}
+
+	if refs[1].Class != "User" || refs[1].Member != "email" || refs[1].Resolved {
+		t.Errorf("$this->email should be an unresolved User member, got %+v", refs[1])
+	}
+
+	for _, ref := range semanticProgram.UnresolvedRefs {
+		if ref.Name == "name" || ref.Name == "email" {
+			t.Errorf("member %s should not be recorded as a constant reference", ref.Name)
+		}
+	}
+}
+
+func TestMemberReferencesThroughParentClasses(t *testing.T) {
+	phpCode := `<?php
+namespace App;
+
+class A {
+    protected $x;
+}
+
+class B extends A {
+    public function read() {
+        return $this->x + $this->y;
+    }
+}
+
+class C extends Base {
+    public function read() {
+        return $this->anything;
+    }
+}
+?>`
+
+	semanticProgram, err := ParseWithSemantics(phpCode, "b.php")
+	if err != nil {
+		t.Fatalf("Failed to parse with semantics: %v", err)
+	}
+
+	resolved := map[string]bool{}
+	for _, ref := range semanticProgram.SymbolTable.MemberRefs {
+		resolved[ref.Class+"::"+ref.Member] = ref.Resolved
+	}
+
+	expected := map[string]bool{
+		"App\\B::x":        true,
+		"App\\B::y":        false,
+		"App\\C::anything": true,
+	}
+	for member, want := range expected {
+		got, ok := resolved[member]
+		if !ok {
+			t.Errorf("expected a member reference to %s. got=%v", member, resolved)
+			continue
+		}
+		if got != want {
+			t.Errorf("%s resolved=%t, want %t", member, got, want)
+		}
+	}
+
+	if !slices.Contains(semanticProgram.SymbolTable.GetMembers("App\\B"), "x") {
+		t.Errorf("expected inherited property x among members %v", semanticProgram.SymbolTable.GetMembers("App\\B"))
+	}
+}
+
+func TestKeyedForeachDeclaresPatternVariables(t *testing.T) {
+	phpCode := `<?php
+function totals($rows) {
+    foreach ($rows as $key => [$price, ['qty' => $qty]]) {
+        echo $key;
+    }
+}
+?>`
+
+	semanticProgram, err := ParseWithSemantics(phpCode, "totals.php")
+	if err != nil {
+		t.Fatalf("Failed to parse with semantics: %v", err)
+	}
+
+	var functionScope *Scope
+	for _, scope := range semanticProgram.SymbolTable.GlobalScope.Children {
+		if scope.Type == "function" && scope.Name == "totals" {
+			functionScope = scope
+		}
+	}
+	if functionScope == nil {
+		t.Fatalf("function scope for totals not found")
+	}
+
+	for _, name := range []string{"key", "price", "qty"} {
+		symbol, exists := functionScope.Symbols[name]
+		if !exists {
+			t.Errorf("variable $%s should be declared in the function scope", name)
+			continue
+		}
+		if symbol.Type != VARIABLE_SYMBOL {
+			t.Errorf("$%s should be a variable, got %s", name, symbol.Type)
+		}
+	}
+
+	for _, ref := range semanticProgram.UnresolvedRefs {
+		if ref.Name == "qty" {
+			t.Errorf("the 'qty' key should not be recorded as a reference")
+		}
+	}
+}
+
+func TestReferenceKinds(t *testing.T) {
+	phpCode := `<?php
+class Foo {
+    public static function bar() {}
+}
+class Baz extends Foo {}
+function make(Foo $foo): Foo {
+    Foo::bar();
+    return new Foo();
+}
+?>`
+
+	semanticProgram, err := ParseWithSemantics(phpCode, "kinds.php")
+	if err != nil {
+		t.Fatalf("Failed to parse with semantics: %v", err)
+	}
+
+	kinds := map[RefKind]int{}
+	for _, ref := range semanticProgram.AllReferences {
+		if ref.Name == "Foo" {
+			kinds[ref.RefKind]++
+		}
+	}
+
+	expected := map[RefKind]int{
+		INHERITANCE_REF:   1,
+		TYPE_HINT_REF:     2,
+		STATIC_ACCESS_REF: 1,
+		INSTANTIATION_REF: 1,
+	}
+	for kind, count := range expected {
+		if kinds[kind] != count {
+			t.Errorf("expected %d %s references to Foo, got=%d", count, kind, kinds[kind])
+		}
+	}
+
+	instantiations := semanticProgram.FindClassInstantiations("Foo")
+	if len(instantiations) != 1 {
+		t.Fatalf("expected 1 instantiation of Foo, got=%d", len(instantiations))
+	}
+	if instantiations[0].Line != 8 {
+		t.Errorf("instantiation should be on line 8, got=%d", instantiations[0].Line)
+	}
+
+	// AddReference keeps its original signature and records no kind
+	table := NewSymbolTable()
+	table.DeclareSymbol("Foo", CLASS_SYMBOL, "kinds.php", 2)
+	ref := table.AddReference("Foo", CLASS_SYMBOL, 3, 5)
+	if ref.ResolvedSymbol == nil || ref.RefKind != "" || ref.Column != 5 {
+		t.Errorf("AddReference recorded the wrong reference. got=%+v", ref)
+	}
+}
+
+func TestLooseComparisonLint(t *testing.T) {
+	phpCode := `<?php
+$x = 5;
+$name = "bob";
+if ($x == "abc") {
+    echo "never";
+}
+if ($x != "10") {
+    echo "numeric strings compare as numbers";
+}
+if ($name == "abc") {
+    echo "both strings";
+}
+?>`
+
+	l := New(phpCode)
+	p := NewParser(l)
+	program := p.ParseProgram()
+	if len(p.Errors()) != 0 {
+		t.Fatalf("parser errors: %v", p.Errors())
+	}
+
+	analyzer := NewSemanticAnalyzer()
+	analyzer.AnalyzeProgram(program, "compare.php")
+	if len(analyzer.GetWarnings()) != 0 {
+		t.Errorf("lint should be off by default, got=%v", analyzer.GetWarnings())
+	}
+
+	analyzer = NewSemanticAnalyzer()
+	analyzer.LintLooseComparisons = true
+	analyzer.AnalyzeProgram(program, "compare.php")
+
+	if len(analyzer.GetErrors()) != 0 {
+		t.Errorf("lints should report warnings, not errors. got=%v", analyzer.GetErrors())
+	}
+
+	warnings := analyzer.GetWarnings()
+	if len(warnings) != 1 {
+		t.Fatalf("expected 1 warning, got=%d (%v)", len(warnings), warnings)
+	}
+	if !containsError(warnings, `Loose comparison == between string "abc" and a number at line 4`) ||
+		!containsError(warnings, "use === instead") {
+		t.Errorf("unexpected warning: %q", warnings[0])
+	}
+}
+
+func TestEmptyStatementLint(t *testing.T) {
+	phpCode := `<?php
+$x = 5;;
+;;;
+function f() {
+    return 1;;
+}
+if ($x) {
+}
+?>`
+
+	l := New(phpCode)
+	p := NewParser(l)
+	program := p.ParseProgram()
+	if len(p.Errors()) != 0 {
+		t.Fatalf("parser errors: %v", p.Errors())
+	}
+
+	analyzer := NewSemanticAnalyzer()
+	analyzer.AnalyzeProgram(program, "empty.php")
+	if len(analyzer.GetWarnings()) != 0 {
+		t.Errorf("lint should be off by default, got=%v", analyzer.GetWarnings())
+	}
+
+	analyzer = NewSemanticAnalyzer()
+	analyzer.LintEmptyStatements = true
+	analyzer.AnalyzeProgram(program, "empty.php")
+
+	if len(analyzer.GetErrors()) != 0 {
+		t.Errorf("lints should report warnings, not errors. got=%v", analyzer.GetErrors())
+	}
+
+	warnings := analyzer.GetWarnings()
+	if len(warnings) != 2 {
+		t.Fatalf("expected 2 warnings, got=%d (%v)", len(warnings), warnings)
+	}
+	if !containsError(warnings, "Empty statement at line 2, column 8") ||
+		!containsError(warnings, "Empty statement at line 5, column 14") {
+		t.Errorf("unexpected warnings: %v", warnings)
+	}
+
+	semanticProgram, err := ParseWithSemanticsOptions(phpCode, "empty.php", AnalyzerOptions{LintEmptyStatements: true})
+	if err != nil {
+		t.Fatalf("Failed to parse with semantics: %v", err)
+	}
+	if len(semanticProgram.Warnings) != 2 {
+		t.Errorf("expected the 2 warnings on the semantic program, got=%v", semanticProgram.Warnings)
+	}
+}
+
+func TestPropertyShadowingLint(t *testing.T) {
+	phpCode := `<?php
+class User {
+    private $name;
+
+    public function __construct($name) {
+        $name = trim($name);
+        $this->name = $name;
+    }
+
+    public function rename($first) {
+        $name = $first;
+        $name = ucfirst($name);
+    }
+}
+?>`
+
+	l := New(phpCode)
+	p := NewParser(l)
+	program := p.ParseProgram()
+	if len(p.Errors()) != 0 {
+		t.Fatalf("parser errors: %v", p.Errors())
+	}
+
+	analyzer := NewSemanticAnalyzer()
+	analyzer.AnalyzeProgram(program, "user.php")
+	if len(analyzer.GetWarnings()) != 0 {
+		t.Errorf("lint should be off by default, got=%v", analyzer.GetWarnings())
+	}
+
+	analyzer = NewSemanticAnalyzer()
+	analyzer.LintPropertyShadowing = true
+	analyzer.AnalyzeProgram(program, "user.php")
+
+	if len(analyzer.GetErrors()) != 0 {
+		t.Errorf("lints should report warnings, not errors. got=%v", analyzer.GetErrors())
+	}
+
+	warnings := analyzer.GetWarnings()
+	if len(warnings) != 1 {
+		t.Fatalf("expected 1 warning, got=%d (%v)", len(warnings), warnings)
+	}
+	if !containsError(warnings, "Local variable $name in User::rename() shadows property $name at line 11") ||
+		!containsError(warnings, "did you mean $this->name?") {
+		t.Errorf("unexpected warning: %q", warnings[0])
+	}
+}
+
+func TestConstantArrayIndexing(t *testing.T) {
+	phpCode := `<?php
+const MAP = ['a' => 1];
+echo MAP['a'];
+?>`
+
+	semanticProgram, err := ParseWithSemantics(phpCode, "map.php")
+	if err != nil {
+		t.Fatalf("Failed to parse with semantics: %v", err)
+	}
+
+	echo, ok := semanticProgram.Statements[1].(*EchoStatement)
+	if !ok {
+		t.Fatalf("Statements[1] is not *EchoStatement. got=%T", semanticProgram.Statements[1])
+	}
+	index, ok := echo.Values[0].(*IndexExpression)
+	if !ok {
+		t.Fatalf("echo.Values[0] is not *IndexExpression. got=%T", echo.Values[0])
+	}
+	if ident, ok := index.Left.(*Identifier); !ok || ident.Value != "MAP" {
+		t.Errorf("index.Left is not Identifier MAP. got=%T (%v)", index.Left, index.Left)
+	}
+
+	found := false
+	for _, ref := range semanticProgram.AllReferences {
+		if ref.Name == "MAP" && ref.ResolvedSymbol != nil && ref.ResolvedSymbol.Type == CONSTANT_SYMBOL {
+			found = true
+		}
+	}
+	if !found {
+		t.Errorf("expected a resolved constant reference to MAP")
+	}
+}
+
+func TestMatchOnEnumCases(t *testing.T) {
+	phpCode := `<?php
+enum Status {
+    case Active;
+    case Inactive;
+}
+function label($status) {
+    return match($status) {
+        Status::Active => "on",
+        Status::Inactive, Status::Archived => "off",
+    };
+}
+?>`
+
+	semanticProgram, err := ParseWithSemantics(phpCode, "status.php")
+	if err != nil {
+		t.Fatalf("Failed to parse with semantics: %v", err)
+	}
+
+	resolved := map[string]bool{}
+	for _, ref := range semanticProgram.SymbolTable.MemberRefs {
+		if ref.Class != "Status" {
+			t.Errorf("member reference %s should be on Status, got=%s", ref.Member, ref.Class)
+		}
+		resolved[ref.Member] = ref.Resolved
+	}
+
+	expected := map[string]bool{"Active": true, "Inactive": true, "Archived": false}
+	for member, want := range expected {
+		got, ok := resolved[member]
+		if !ok {
+			t.Errorf("expected a member reference to Status::%s", member)
+			continue
+		}
+		if got != want {
+			t.Errorf("Status::%s resolved=%t, want %t", member, got, want)
+		}
+	}
+
+	for _, ref := range semanticProgram.UnresolvedRefs {
+		if ref.Name == "Active" || ref.Name == "Inactive" {
+			t.Errorf("enum case %s should not be referenced as a constant", ref.Name)
+		}
+	}
+}
+
+type facadeResolver map[string]string
+
+func (r facadeResolver) Resolve(name string, symbolType SymbolType, namespace string) *Symbol {
+	fqn, ok := r[name]
+	if !ok || symbolType != CLASS_SYMBOL {
+		return nil
+	}
+	return &Symbol{Name: name, FullyQualified: fqn, Type: CLASS_SYMBOL}
+}
+
+func TestExternalResolver(t *testing.T) {
+	phpCode := `<?php
+$value = Cache::get("key");
+$other = Missing::get("key");
+?>`
+
+	l := New(phpCode)
+	p := NewParser(l)
+	program := p.ParseProgram()
+	if len(p.Errors()) != 0 {
+		t.Fatalf("parser errors: %v", p.Errors())
+	}
+
+	analyzer := NewSemanticAnalyzerWithOptions(AnalyzerOptions{
+		ExternalResolver: facadeResolver{"Cache": "Illuminate\\Support\\Facades\\Cache"},
+	})
+	analyzer.AnalyzeProgram(program, "facade.php")
+
+	resolved := map[string]*Symbol{}
+	for _, ref := range analyzer.SymbolTable.References {
+		resolved[ref.Name] = ref.ResolvedSymbol
+	}
+
+	if symbol := resolved["Cache"]; symbol == nil || symbol.FullyQualified != "Illuminate\\Support\\Facades\\Cache" {
+		t.Errorf("expected Cache to resolve through the external resolver, got=%v", symbol)
+	}
+	if symbol, ok := resolved["Missing"]; !ok || symbol != nil {
+		t.Errorf("expected Missing to stay unresolved, got=%v", symbol)
+	}
+}
+
+func TestExternalResolverAfterDeclaredTypes(t *testing.T) {
+	phpCode := `<?php
+enum Status {
+    case Active;
+}
+$status = Status::Active;
+?>`
+
+	l := New(phpCode)
+	p := NewParser(l)
+	program := p.ParseProgram()
+	if len(p.Errors()) != 0 {
+		t.Fatalf("parser errors: %v", p.Errors())
+	}
+
+	// The resolver knows a class named Status, but the declared enum is
+	// still the better match even though enums are tried after classes
+	analyzer := NewSemanticAnalyzerWithOptions(AnalyzerOptions{
+		ExternalResolver: facadeResolver{"Status": "Vendor\\Status"},
+	})
+	analyzer.AnalyzeProgram(program, "status.php")
+
+	found := false
+	for _, ref := range analyzer.SymbolTable.References {
+		if ref.Name != "Status" {
+			continue
+		}
+		found = true
+		if ref.ResolvedSymbol == nil || ref.ResolvedSymbol.Type != ENUM_SYMBOL {
+			t.Errorf("expected Status to resolve to the declared enum, got=%v", ref.ResolvedSymbol)
+		}
+	}
+	if !found {
+		t.Errorf("no reference to Status recorded")
+	}
+}
+
+func TestNewSelfAndStatic(t *testing.T) {
+	phpCode := `<?php
+namespace App;
+
+class Model {
+    const TABLE = 'models';
+
+    public static function make() {
+        return new static();
+    }
+
+    public function copy() {
+        return new self();
+    }
+
+    public function table() {
+        return static::TABLE;
+    }
+}
+?>`
+
+	semanticProgram, err := ParseWithSemantics(phpCode, "model.php")
+	if err != nil {
+		t.Fatalf("Failed to parse with semantics: %v", err)
+	}
+	if len(semanticProgram.Errors) != 0 {
+		t.Fatalf("expected no errors, got %v", semanticProgram.Errors)
+	}
+
+	instantiations := semanticProgram.FindClassInstantiations("App\\Model")
+	if len(instantiations) != 2 {
+		t.Fatalf("expected 2 instantiations of App\\Model, got=%d", len(instantiations))
+	}
+
+	expected := []struct {
+		name      string
+		line      int
+		lateBound bool
+	}{
+		{"static", 8, true},
+		{"self", 12, false},
+	}
+	for i, want := range expected {
+		ref := instantiations[i]
+		if ref.Name != want.name || ref.Line != want.line {
+			t.Errorf("instantiation %d: expected %s at line %d, got %s at line %d",
+				i, want.name, want.line, ref.Name, ref.Line)
+		}
+		if ref.LateBound != want.lateBound {
+			t.Errorf("instantiation %d: expected LateBound=%t, got=%t", i, want.lateBound, ref.LateBound)
+		}
+	}
+
+	outside, err := ParseWithSemantics(`<?php function f() { return new self(); } ?>`, "outside.php")
+	if err != nil {
+		t.Fatalf("Failed to parse with semantics: %v", err)
+	}
+	if !containsError(outside.Errors, "Undefined class 'self'") {
+		t.Errorf("expected self outside a class to be undefined, got %v", outside.Errors)
+	}
+}
+
+func TestParentClassReferences(t *testing.T) {
+	phpCode := `<?php
+namespace App;
+
+class Base {
+    public function __construct() {}
+}
+
+class Child extends Base {
+    public function __construct() {
+        parent::__construct();
+    }
+
+    public function sibling() {
+        return new parent();
+    }
+}
+
+class Orphan {
+    public function make() {
+        return new parent();
+    }
+}
+?>`
+
+	semanticProgram, err := ParseWithSemantics(phpCode, "parent.php")
+	if err != nil {
+		t.Fatalf("Failed to parse with semantics: %v", err)
+	}
+
+	var resolved, unresolved []int
+	for _, ref := range semanticProgram.AllReferences {
+		if ref.Name != "parent" {
+			continue
+		}
+		if ref.ResolvedSymbol == nil {
+			unresolved = append(unresolved, ref.Line)
+		} else if ref.ResolvedSymbol.FullyQualified == "App\\Base" {
+			resolved = append(resolved, ref.Line)
+		} else {
+			t.Errorf("parent at line %d resolved to %s", ref.Line, ref.ResolvedSymbol.FullyQualified)
+		}
+	}
+
+	if len(resolved) != 2 || resolved[0] != 10 || resolved[1] != 14 {
+		t.Errorf("expected parent to resolve to App\\Base on lines 10 and 14, got=%v", resolved)
+	}
+	if len(unresolved) != 1 || unresolved[0] != 20 {
+		t.Errorf("expected parent without extends to stay unresolved on line 20, got=%v", unresolved)
+	}
+}
+
+func TestFullyQualifiedStaticAccessReferences(t *testing.T) {
+	phpCode := `<?php
+namespace App;
+class Config {
+    const database = ['host' => 'localhost'];
+}
+
+namespace Other;
+echo \App\Config::database['host'];
+echo \Vendor\Pkg\Util::VERSION;
+?>`
+
+	semanticProgram, err := ParseWithSemantics(phpCode, "config.php")
+	if err != nil {
+		t.Fatalf("Failed to parse with semantics: %v", err)
+	}
+
+	var refs []*SymbolReference
+	for _, ref := range semanticProgram.AllReferences {
+		if ref.RefKind == STATIC_ACCESS_REF {
+			refs = append(refs, ref)
+		}
+	}
+	if len(refs) != 2 {
+		t.Fatalf("expected 2 static access references, got=%d", len(refs))
+	}
+
+	if refs[0].Name != `\App\Config` {
+		t.Errorf("expected reference to \\App\\Config, got=%s", refs[0].Name)
+	}
+	if refs[0].ResolvedSymbol == nil || refs[0].ResolvedSymbol.FullyQualified != `App\Config` {
+		t.Errorf("\\App\\Config should resolve to App\\Config, got=%v", refs[0].ResolvedSymbol)
+	}
+
+	if refs[1].Name != `\Vendor\Pkg\Util` {
+		t.Errorf("expected reference to \\Vendor\\Pkg\\Util, got=%s", refs[1].Name)
+	}
+	if !containsError(semanticProgram.Errors, `Undefined class or enum '\Vendor\Pkg\Util'`) {
+		t.Errorf("expected the vendor class to be undefined, got %v", semanticProgram.Errors)
+	}
+}
+
+func TestInstanceofReferences(t *testing.T) {
+	phpCode := `<?php
+interface Shape {}
+class Circle implements Shape {
+    public function same($other) {
+        return $other instanceof static;
+    }
+}
+function check($s) {
+    return $s instanceof Shape;
+}
+?>`
+
+	semanticProgram, err := ParseWithSemantics(phpCode, "shapes.php")
+	if err != nil {
+		t.Fatalf("Failed to parse with semantics: %v", err)
+	}
+	if len(semanticProgram.Errors) != 0 {
+		t.Fatalf("expected no errors, got %v", semanticProgram.Errors)
+	}
+
+	resolved := map[string]string{}
+	for _, ref := range semanticProgram.AllReferences {
+		if ref.RefKind == INSTANCEOF_REF && ref.ResolvedSymbol != nil {
+			resolved[ref.Name] = ref.ResolvedSymbol.Name
+		}
+	}
+
+	if resolved["Shape"] != "Shape" {
+		t.Errorf("expected Shape to resolve to the interface, got=%q", resolved["Shape"])
+	}
+	if resolved["static"] != "Circle" {
+		t.Errorf("expected static to resolve to Circle, got=%q", resolved["static"])
+	}
+}
+
+func TestPromotedConstructorProperties(t *testing.T) {
+	phpCode := `<?php
+class Point {
+    public function __construct(private readonly int $x, public int $y = 0) {}
+
+    public function move() {
+        $this->x = 1;
+        $this->y = 2;
+    }
+}
+?>`
+
+	semanticProgram, err := ParseWithSemantics(phpCode, "point.php")
+	if err != nil {
+		t.Fatalf("Failed to parse with semantics: %v", err)
+	}
+
+	members := semanticProgram.SymbolTable.GetMembers("Point")
+	for _, expected := range []string{"x", "y"} {
+		if !slices.Contains(members, expected) {
+			t.Errorf("expected promoted property %s among members %v", expected, members)
+		}
+	}
+
+	if len(semanticProgram.Errors) != 1 ||
+		!containsError(semanticProgram.Errors, "Cannot modify readonly property Point::$x outside the constructor at line 6") {
+		t.Errorf("expected one readonly error for $x, got %v", semanticProgram.Errors)
+	}
+}
+
+func TestPromotedReadonlyPropertyWrite(t *testing.T) {
+	phpCode := `<?php
+class User {
+    public function __construct(public readonly int $id) {
+        $this->id = $id + 1;
+    }
+
+    public function reset() {
+        $this->id = 0;
+    }
+}
+?>`
+
+	semanticProgram, err := ParseWithSemantics(phpCode, "user.php")
+	if err != nil {
+		t.Fatalf("Failed to parse with semantics: %v", err)
+	}
+
+	if !slices.Contains(semanticProgram.SymbolTable.GetMembers("User"), "id") {
+		t.Errorf("expected promoted property id among members %v", semanticProgram.SymbolTable.GetMembers("User"))
+	}
+
+	if len(semanticProgram.Errors) != 1 ||
+		!containsError(semanticProgram.Errors, "Cannot modify readonly property User::$id outside the constructor at line 8") {
+		t.Errorf("expected one readonly error for $id in reset(), got %v", semanticProgram.Errors)
+	}
+}
+
+func TestAbstractMethodWithReturnType(t *testing.T) {
+	phpCode := `<?php
+abstract class Shape {
+    abstract public function area(): float;
+
+    public function describe(): string {
+        return "shape";
+    }
+}
+?>`
+
+	semanticProgram, err := ParseWithSemantics(phpCode, "shape.php")
+	if err != nil {
+		t.Fatalf("Failed to parse with semantics: %v", err)
+	}
+	if len(semanticProgram.Errors) != 0 {
+		t.Errorf("abstract methods have no body to check, got=%v", semanticProgram.Errors)
+	}
+}
+
+func TestNestedClosureCaptures(t *testing.T) {
+	phpCode := `<?php
+$rate = 1;
+$prefix = "global";
+$make = function ($base) {
+    $rate = $base * 2;
+    return function ($amount) use ($rate) {
+        return function () use ($amount, $rate, $prefix) {
+            return $amount * $rate;
+        };
+    };
+};
+?>`
+
+	semanticProgram, err := ParseWithSemantics(phpCode, "rates.php")
+	if err != nil {
+		t.Fatalf("Failed to parse with semantics: %v", err)
+	}
+
+	var captures []*SymbolReference
+	for _, ref := range semanticProgram.AllReferences {
+		if ref.RefKind == CAPTURE_REF {
+			captures = append(captures, ref)
+		}
+	}
+
+	tests := []struct {
+		name         string
+		line         int
+		resolvedLine int // 0 when the capture should stay unresolved
+	}{
+		{"rate", 6, 5},
+		{"amount", 7, 6},
+		{"rate", 7, 6},
+		{"prefix", 7, 0},
+	}
+
+	if len(captures) != len(tests) {
+		t.Fatalf("expected %d capture references, got %d", len(tests), len(captures))
+	}
+
+	for i, tt := range tests {
+		ref := captures[i]
+		if ref.Name != tt.name || ref.Line != tt.line {
+			t.Errorf("capture %d: expected $%s at line %d, got $%s at line %d", i, tt.name, tt.line, ref.Name, ref.Line)
+			continue
+		}
+		if tt.resolvedLine == 0 {
+			if ref.ResolvedSymbol != nil {
+				t.Errorf("capture of $%s should not see the global scope, resolved to line %d", tt.name, ref.ResolvedSymbol.Line)
+			}
+			continue
+		}
+		if ref.ResolvedSymbol == nil || ref.ResolvedSymbol.Line != tt.resolvedLine {
+			t.Errorf("capture of $%s at line %d should resolve to line %d, got %v", tt.name, tt.line, tt.resolvedLine, ref.ResolvedSymbol)
+		}
+	}
+
+	if !containsError(semanticProgram.Errors, "Undefined variable 'prefix' at line 7") {
+		t.Errorf("expected an undefined variable error for $prefix, got %v", semanticProgram.Errors)
+	}
+}
+
+func TestConditionalIncludes(t *testing.T) {
+	code := `<?php
+require_once 'bootstrap.php';
+if ($debug) {
+    require 'debug.php';
+} else {
+    $config = include __DIR__ . '/config.php';
+}
+foreach ($plugins as $plugin) {
+    include_once "plugins/$plugin.php";
+}
+?>`
+
+	semanticProgram, err := ParseWithSemantics(code, "index.php")
+	if err != nil {
+		t.Fatalf("Failed to parse: %v", err)
+	}
+
+	tests := []struct {
+		kind        string
+		path        string
+		literal     bool
+		conditional bool
+		line        int
+	}{
+		{"require_once", "bootstrap.php", true, false, 2},
+		{"require", "debug.php", true, true, 4},
+		{"include", "__DIR__ . '/config.php'", false, true, 6},
+		{"include_once", `"plugins/$plugin.php"`, false, true, 9},
+	}
+
+	if len(semanticProgram.Includes) != len(tests) {
+		t.Fatalf("expected %d includes, got %d", len(tests), len(semanticProgram.Includes))
+	}
+
+	for i, tt := range tests {
+		include := semanticProgram.Includes[i]
+		if include.Kind != tt.kind || include.Path != tt.path || include.Literal != tt.literal ||
+			include.Conditional != tt.conditional || include.Line != tt.line {
+			t.Errorf("include %d: expected %+v, got %+v", i, tt, *include)
+		}
+	}
+}
+
+func TestGlobalAndStaticOutsideFunction(t *testing.T) {
+	phpCode := `<?php
+global $config;
+static $count = 0;
+
+function load() {
+    global $config;
+    static $cache = [];
+    $f = function () {
+        static $calls = 0;
+    };
+}
+
+class Repo {
+    public function find() {
+        global $db;
+    }
+}
+?>`
+
+	result, err := ParseWithSemantics(phpCode, "scope.php")
+	if err != nil {
+		t.Fatalf("ParseWithSemantics failed: %v", err)
+	}
+
+	if len(result.Errors) != 2 {
+		t.Fatalf("expected 2 errors, got=%d (%v)", len(result.Errors), result.Errors)
+	}
+	if !containsError(result.Errors, "global has no effect outside a function at line 2, column 1") {
+		t.Errorf("missing error for top-level global: %v", result.Errors)
+	}
+	if !containsError(result.Errors, "static variables are only meaningful inside a function at line 3, column 1") {
+		t.Errorf("missing error for top-level static: %v", result.Errors)
+	}
+}
@@ -0,0 +1,139 @@
+package gophpparser
+
+import (
+	"encoding/json"
+	"reflect"
+)
+
+// symbolVersion is a declared symbol along with a fingerprint of its
+// declaration that ignores where in the file it sits
+type symbolVersion struct {
+	symbol      *Symbol
+	fingerprint string
+}
+
+// DiffSymbols compares two analyses of the same file and reports which
+// declared symbols were added, removed or changed, so an indexer can skip
+// the rest. Methods are compared on their own and reported with a fully
+// qualified name of the form Class::method; a class only counts as changed
+// when something other than its method bodies and method list changed.
+// Moving a declaration without editing it is not a change.
+func DiffSymbols(before, after *SemanticProgram) (added, removed, changed []*Symbol) {
+	beforeSymbols, beforeOrder := before.declaredSymbols()
+	afterSymbols, afterOrder := after.declaredSymbols()
+
+	for _, key := range afterOrder {
+		current := afterSymbols[key]
+		previous, existed := beforeSymbols[key]
+		if !existed {
+			added = append(added, current.symbol)
+		} else if previous.fingerprint != current.fingerprint {
+			changed = append(changed, current.symbol)
+		}
+	}
+	for _, key := range beforeOrder {
+		if _, exists := afterSymbols[key]; !exists {
+			removed = append(removed, beforeSymbols[key].symbol)
+		}
+	}
+
+	return added, removed, changed
+}
+
+// declaredSymbols collects the top-level declarations of the program and
+// the methods of its classes, traits and enums, keyed by fully qualified
+// name and listed in source order
+func (sp *SemanticProgram) declaredSymbols() (map[string]symbolVersion, []string) {
+	symbols := map[string]symbolVersion{}
+	var order []string
+	add := func(symbol *Symbol, fingerprint string) {
+		if _, exists := symbols[symbol.FullyQualified]; !exists {
+			order = append(order, symbol.FullyQualified)
+		}
+		symbols[symbol.FullyQualified] = symbolVersion{symbol: symbol, fingerprint: fingerprint}
+	}
+	addMethods := func(owner *Symbol, methods []*MethodDeclaration) {
+		for _, method := range methods {
+			add(&Symbol{
+				Name:           method.Name.Value,
+				FullyQualified: owner.FullyQualified + "::" + method.Name.Value,
+				Type:           FUNCTION_SYMBOL,
+				Namespace:      owner.Namespace,
+				File:           owner.File,
+				Line:           method.Token.Line,
+			}, declarationFingerprint(method))
+		}
+	}
+
+	namespace := ""
+	for _, stmt := range sp.Statements {
+		switch s := stmt.(type) {
+		case *NamespaceDeclaration:
+			namespace = s.Name.Value
+		case *ClassDeclaration:
+			symbol := sp.lookupDeclaredSymbol(s.Name.Value, CLASS_SYMBOL, namespace, s.Token.Line)
+			header := *s
+			header.Methods = nil
+			add(symbol, declarationFingerprint(&header))
+			addMethods(symbol, s.Methods)
+		case *TraitDeclaration:
+			symbol := sp.lookupDeclaredSymbol(s.Name.Value, TRAIT_SYMBOL, namespace, s.Token.Line)
+			header := *s
+			header.Methods = nil
+			add(symbol, declarationFingerprint(&header))
+			addMethods(symbol, s.Methods)
+		case *EnumDeclaration:
+			symbol := sp.lookupDeclaredSymbol(s.Name.Value, ENUM_SYMBOL, namespace, s.Token.Line)
+			header := *s
+			header.Methods = nil
+			add(symbol, declarationFingerprint(&header))
+			addMethods(symbol, s.Methods)
+		case *InterfaceDeclaration:
+			add(sp.lookupDeclaredSymbol(s.Name.Value, INTERFACE_SYMBOL, namespace, s.Token.Line), declarationFingerprint(s))
+		case *FunctionDeclaration:
+			add(sp.lookupDeclaredSymbol(s.Name.Value, FUNCTION_SYMBOL, namespace, s.Token.Line), declarationFingerprint(s))
+		case *ConstantDeclaration:
+			add(sp.lookupDeclaredSymbol(s.Name.Value, CONSTANT_SYMBOL, namespace, s.Token.Line), declarationFingerprint(s))
+		}
+	}
+
+	return symbols, order
+}
+
+// declarationFingerprint encodes a declaration the way ToJSON would, minus
+// its tokens, so it captures every detail of the code but not where it sits
+func declarationFingerprint(node Node) string {
+	data, _ := json.Marshal(withoutTokens(encodeValue(reflect.ValueOf(node), JSONOptions{})))
+	return string(data)
+}
+
+// withoutTokens removes the "token" fields from an encoded node
+func withoutTokens(value any) any {
+	switch v := value.(type) {
+	case map[string]any:
+		delete(v, "token")
+		for key, item := range v {
+			v[key] = withoutTokens(item)
+		}
+	case []any:
+		for i, item := range v {
+			v[i] = withoutTokens(item)
+		}
+	}
+	return value
+}
+
+// lookupDeclaredSymbol returns the symbol the analyzer declared for a
+// top-level name. The symbol table keys methods and locals by bare name
+// too, so a different kind of symbol may hold the slot; a fresh symbol
+// stands in then.
+func (sp *SemanticProgram) lookupDeclaredSymbol(name string, symbolType SymbolType, namespace string, line int) *Symbol {
+	fqn := name
+	if namespace != "" {
+		fqn = namespace + "\\" + name
+	}
+	if symbol, exists := sp.SymbolTable.AllSymbols[fqn]; exists && symbol.Type == symbolType && symbol.Line == line {
+		return symbol
+	}
+	return &Symbol{Name: name, FullyQualified: fqn, Type: symbolType, Namespace: namespace, Line: line}
+}
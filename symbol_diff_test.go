@@ -0,0 +1,122 @@
+package gophpparser
+
+import "testing"
+
+func TestDiffSymbols(t *testing.T) {
+	before := `<?php
+namespace App;
+
+function helper() { return 1; }
+
+class User {
+    private $name;
+
+    public function getName() {
+        return $this->name;
+    }
+
+    public function save() {
+        return true;
+    }
+}
+?>`
+
+	// save() changes and a line is inserted above the class, which moves
+	// every later declaration without changing it
+	after := `<?php
+namespace App;
+
+function helper() { return 1; }
+
+
+class User {
+    private $name;
+
+    public function getName() {
+        return $this->name;
+    }
+
+    public function save() {
+        return false;
+    }
+}
+?>`
+
+	beforeProgram, err := ParseWithSemantics(before, "User.php")
+	if err != nil {
+		t.Fatalf("Failed to parse with semantics: %v", err)
+	}
+	afterProgram, err := ParseWithSemantics(after, "User.php")
+	if err != nil {
+		t.Fatalf("Failed to parse with semantics: %v", err)
+	}
+
+	added, removed, changed := DiffSymbols(beforeProgram, afterProgram)
+	if len(added) != 0 || len(removed) != 0 {
+		t.Errorf("expected no added or removed symbols. got added=%v, removed=%v", added, removed)
+	}
+	if len(changed) != 1 {
+		t.Fatalf("expected 1 changed symbol, got=%d", len(changed))
+	}
+	if changed[0].FullyQualified != "App\\User::save" || changed[0].Line != 14 {
+		t.Errorf("wrong changed symbol. got=%s at line %d", changed[0].FullyQualified, changed[0].Line)
+	}
+
+	added, removed, changed = DiffSymbols(afterProgram, beforeProgram)
+	if len(added) != 0 || len(removed) != 0 || len(changed) != 1 {
+		t.Errorf("diff should be symmetric. got added=%d, removed=%d, changed=%d", len(added), len(removed), len(changed))
+	}
+
+	renamed, err := ParseWithSemantics(`<?php
+namespace App;
+
+function helperV2() { return 1; }
+?>`, "User.php")
+	if err != nil {
+		t.Fatalf("Failed to parse with semantics: %v", err)
+	}
+
+	added, removed, _ = DiffSymbols(beforeProgram, renamed)
+	if len(added) != 1 || added[0].FullyQualified != "App\\helperV2" {
+		t.Errorf("expected App\\helperV2 to be added. got=%v", added)
+	}
+	names := []string{}
+	for _, symbol := range removed {
+		names = append(names, symbol.FullyQualified)
+	}
+	expectedRemoved := []string{"App\\helper", "App\\User", "App\\User::getName", "App\\User::save"}
+	if len(names) != len(expectedRemoved) {
+		t.Fatalf("expected %v to be removed. got=%v", expectedRemoved, names)
+	}
+	for i, name := range expectedRemoved {
+		if names[i] != name {
+			t.Errorf("removed[%d] wrong. expected=%s, got=%s", i, name, names[i])
+		}
+	}
+}
+
+func TestDiffSymbolsSeesLiteralKinds(t *testing.T) {
+	tests := []struct {
+		before string
+		after  string
+	}{
+		{`function f() { return 1; }`, `function f() { return '1'; }`},
+		{`function f($x = 'a') {}`, `function f($x = a) {}`},
+	}
+
+	for _, tt := range tests {
+		beforeProgram, err := ParseWithSemantics("<?php "+tt.before, "f.php")
+		if err != nil {
+			t.Fatalf("Failed to parse with semantics: %v", err)
+		}
+		afterProgram, err := ParseWithSemantics("<?php "+tt.after, "f.php")
+		if err != nil {
+			t.Fatalf("Failed to parse with semantics: %v", err)
+		}
+
+		_, _, changed := DiffSymbols(beforeProgram, afterProgram)
+		if len(changed) != 1 || changed[0].FullyQualified != "f" {
+			t.Errorf("%s -> %s: expected f to change. got=%v", tt.before, tt.after, changed)
+		}
+	}
+}
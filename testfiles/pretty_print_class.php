@@ -0,0 +1,25 @@
+<?php
+namespace Geometry;
+use App\Contracts\Shape;
+class Polygon extends Shape implements Countable {
+  const SIDES=0;
+      private array $points=[];
+  protected static ?string $label=null;
+  public function __construct(private int $size,string $name='poly'){ parent::__construct($name); }
+  /**
+     * Sum of the side lengths
+     */
+  public function perimeter(): float {
+    $total=0;
+    for($i=0;$i<count($this->points);$i++){
+      $next=$this->points[($i+1)%count($this->points)];
+      $total+=$this->distance($this->points[$i],$next);
+    }
+    return $total;
+  }
+  public function count(): int { if($this->size>0){return $this->size;}else{return (1+2)*3;} }
+  public static function describe($shape){
+    $fn=fn($x)=>$x*2;
+    return match(true){ $shape instanceof self=>'polygon', default=>"shape {$shape->name}" };
+  }
+}
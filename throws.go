@@ -0,0 +1,308 @@
+package gophpparser
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+)
+
+// ThrowInfo describes one throw statement or throw expression
+type ThrowInfo struct {
+	ExceptionClass string `json:"exception_class,omitempty"` // Fully qualified when resolved; empty unless thrown with new
+	Function       string `json:"function,omitempty"`        // Enclosing function, Class::method or {closure}; empty at top level
+	Caught         bool   `json:"caught,omitempty"`          // A catch in the same function handles the class
+	Line           int    `json:"line"`
+	Column         int    `json:"column"`
+}
+
+// ThrowSites lists every throw in the program in source order. The
+// exception class is only known when the thrown value is a new expression;
+// rethrowing a variable leaves it empty.
+func (sp *SemanticProgram) ThrowSites() []ThrowInfo {
+	hierarchy := newExceptionHierarchy(sp.ClassHierarchy)
+	sites, _ := collectThrowSites(sp.Program)
+
+	infos := make([]ThrowInfo, 0, len(sites))
+	for _, site := range sites {
+		info := ThrowInfo{Function: site.Scope.Name, Line: site.Token.Line, Column: site.Token.Column}
+		if newExpr := thrownNewExpression(site.Value); newExpr != nil {
+			info.ExceptionClass = sp.resolveInstantiation(newExpr)
+			info.Caught = hierarchy.caught(newExpr.ClassName.Value, site.Catches)
+		}
+		infos = append(infos, info)
+	}
+
+	return infos
+}
+
+// resolveInstantiation returns the fully qualified class of a new
+// expression through the reference recorded for it, falling back to the
+// name as written
+func (sp *SemanticProgram) resolveInstantiation(expr *NewExpression) string {
+	for _, ref := range sp.AllReferences {
+		if ref.RefKind == INSTANTIATION_REF && ref.Line == expr.Token.Line &&
+			ref.Name == expr.ClassName.Value && ref.ResolvedSymbol != nil {
+			return ref.ResolvedSymbol.FullyQualified
+		}
+	}
+	return expr.ClassName.Value
+}
+
+// throwScope is a function-like declaration that throws are attributed to
+type throwScope struct {
+	Name string // As reported in ThrowInfo.Function
+	Decl Node   // FunctionDeclaration, MethodDeclaration, AnonymousFunction or ArrowFunction; nil at top level
+}
+
+// throwSite is a throw found by collectThrowSites
+type throwSite struct {
+	Token   Token
+	Value   Expression
+	Scope   *throwScope
+	Catches []*CatchClause // Clauses of the try blocks around the throw within its scope
+}
+
+// collectThrowSites finds every throw in the program in source order, along
+// with every function-like scope, including those that never throw
+func collectThrowSites(program *Program) ([]throwSite, []*throwScope) {
+	var sites []throwSite
+	var scopes []*throwScope
+
+	var collect func(root Node, class string, scope *throwScope, catches []*CatchClause)
+	enter := func(decl Node, class, name string) {
+		scope := &throwScope{Name: name, Decl: decl}
+		scopes = append(scopes, scope)
+		collect(decl, class, scope, nil)
+	}
+	collect = func(root Node, class string, scope *throwScope, catches []*CatchClause) {
+		Walk(root, func(node Node) bool {
+			if node == root {
+				return true
+			}
+			switch n := node.(type) {
+			case *ClassDeclaration:
+				collect(n, n.Name.Value, scope, catches)
+				return false
+			case *TraitDeclaration:
+				collect(n, n.Name.Value, scope, catches)
+				return false
+			case *EnumDeclaration:
+				collect(n, n.Name.Value, scope, catches)
+				return false
+			case *MethodDeclaration:
+				enter(n, class, class+"::"+n.Name.Value)
+				return false
+			case *FunctionDeclaration:
+				enter(n, class, n.Name.Value)
+				return false
+			case *AnonymousFunction, *ArrowFunction:
+				enter(n, class, "{closure}")
+				return false
+			case *TryStatement:
+				// Only the try body is covered by its own catch clauses
+				guarded := append(catches[:len(catches):len(catches)], n.Catches...)
+				collect(n.Body, class, scope, guarded)
+				for _, clause := range n.Catches {
+					collect(clause, class, scope, catches)
+				}
+				if n.Finally != nil {
+					collect(n.Finally, class, scope, catches)
+				}
+				return false
+			case *ThrowStatement:
+				sites = append(sites, throwSite{Token: n.Token, Value: n.Expression, Scope: scope, Catches: catches})
+			case *ThrowExpression:
+				sites = append(sites, throwSite{Token: n.Token, Value: n.Expression, Scope: scope, Catches: catches})
+			}
+			return true
+		})
+	}
+	collect(program, "", &throwScope{}, nil)
+
+	return sites, scopes
+}
+
+// thrownNewExpression returns the thrown value when it names its class
+// with new, or nil otherwise
+func thrownNewExpression(value Expression) *NewExpression {
+	newExpr, ok := value.(*NewExpression)
+	if !ok || newExpr.ClassName == nil {
+		return nil
+	}
+	return newExpr
+}
+
+// exceptionHierarchy maps lowercase short class names to the short names
+// of their parent class and interfaces. Namespaces are dropped, so two
+// classes sharing a short name are treated alike.
+type exceptionHierarchy map[string][]string
+
+func newExceptionHierarchy(classes map[string][]string) exceptionHierarchy {
+	hierarchy := exceptionHierarchy{}
+	for name, parents := range classes {
+		key := shortClassName(name)
+		for _, parent := range parents {
+			hierarchy[key] = append(hierarchy[key], shortClassName(parent))
+		}
+	}
+	return hierarchy
+}
+
+// isA reports whether class is ancestor or inherits from it, through the
+// declared classes and PHP's built-in exception classes
+func (h exceptionHierarchy) isA(class, ancestor string) bool {
+	ancestor = shortClassName(ancestor)
+	seen := map[string]bool{}
+	queue := []string{shortClassName(class)}
+	for len(queue) > 0 {
+		name := queue[0]
+		queue = queue[1:]
+		if name == ancestor {
+			return true
+		}
+		if seen[name] {
+			continue
+		}
+		seen[name] = true
+
+		queue = append(queue, h[name]...)
+		if parent, ok := builtinExceptionParents[name]; ok {
+			queue = append(queue, parent)
+		}
+	}
+	return false
+}
+
+// caught reports whether any of the catch clauses handles class
+func (h exceptionHierarchy) caught(class string, catches []*CatchClause) bool {
+	for _, clause := range catches {
+		for _, caughtType := range clause.ExceptionTypes {
+			if h.isA(class, caughtType.Value) {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+func shortClassName(name string) string {
+	if i := strings.LastIndex(name, "\\"); i >= 0 {
+		name = name[i+1:]
+	}
+	return strings.ToLower(name)
+}
+
+var throwsTagPattern = regexp.MustCompile(`@throws\s+([^\s*]+)`)
+
+// throwsTags returns the class names listed by the @throws tags of a
+// docblock, splitting union types such as A|B
+func throwsTags(docblock string) []string {
+	var names []string
+	for _, match := range throwsTagPattern.FindAllStringSubmatch(docblock, -1) {
+		for _, name := range strings.Split(match[1], "|") {
+			if name = strings.TrimPrefix(name, "\\"); name != "" {
+				names = append(names, name)
+			}
+		}
+	}
+	return names
+}
+
+// functionDocblocks maps the line of each function or method name to the
+// docblock written directly above the declaration. Modifiers may sit
+// between the two.
+func functionDocblocks(source string) map[int]string {
+	docblocks := map[int]string{}
+	docblock := ""
+	l := New(source)
+	for tok := l.NextToken(); tok.Type != EOF; tok = l.NextToken() {
+		switch tok.Type {
+		case DOCBLOCK:
+			docblock = tok.Literal
+			continue
+		case PUBLIC, PROTECTED, PRIVATE, STATIC, ABSTRACT, FINAL:
+			continue
+		case FUNCTION:
+			if docblock == "" {
+				break
+			}
+			name := l.NextToken()
+			if name.Type == BIT_AND {
+				name = l.NextToken()
+			}
+			if name.Type != LPAREN {
+				docblocks[name.Line] = docblock
+			}
+		}
+		docblock = ""
+	}
+	return docblocks
+}
+
+// checkThrowsDocs compares the @throws tags of each function and method
+// with the exceptions its body throws and does not catch itself. It is
+// advisory: exceptions that escape from called functions are not seen, so
+// a documented exception may well be thrown indirectly.
+func (sa *SemanticAnalyzer) checkThrowsDocs(program *Program) {
+	docblocks := functionDocblocks(program.source)
+	hierarchy := newExceptionHierarchy(sa.SymbolTable.ClassHierarchy)
+	sites, scopes := collectThrowSites(program)
+
+	for _, scope := range scopes {
+		var name *Identifier
+		switch decl := scope.Decl.(type) {
+		case *FunctionDeclaration:
+			name = decl.Name
+		case *MethodDeclaration:
+			name = decl.Name
+		default:
+			continue
+		}
+		documented := throwsTags(docblocks[name.Token.Line])
+
+		var thrown []string
+		for _, site := range sites {
+			newExpr := thrownNewExpression(site.Value)
+			if site.Scope != scope || newExpr == nil {
+				continue
+			}
+			class := newExpr.ClassName.Value
+			if hierarchy.caught(class, site.Catches) {
+				continue
+			}
+			thrown = append(thrown, class)
+
+			if !hierarchy.isAny(class, documented) {
+				sa.AddWarning(fmt.Sprintf("%s() throws %s at line %d, column %d but has no @throws tag for it",
+					scope.Name, class, site.Token.Line, site.Token.Column))
+			}
+		}
+
+		for _, tag := range documented {
+			if !hierarchy.anyIsA(thrown, tag) {
+				sa.AddWarning(fmt.Sprintf("%s() documents @throws %s but never throws it at line %d, column %d",
+					scope.Name, tag, name.Token.Line, name.Token.Column))
+			}
+		}
+	}
+}
+
+// isAny reports whether class is or inherits from any of ancestors
+func (h exceptionHierarchy) isAny(class string, ancestors []string) bool {
+	for _, ancestor := range ancestors {
+		if h.isA(class, ancestor) {
+			return true
+		}
+	}
+	return false
+}
+
+// anyIsA reports whether any of classes is or inherits from ancestor
+func (h exceptionHierarchy) anyIsA(classes []string, ancestor string) bool {
+	for _, class := range classes {
+		if h.isA(class, ancestor) {
+			return true
+		}
+	}
+	return false
+}
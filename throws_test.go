@@ -0,0 +1,122 @@
+package gophpparser
+
+import "testing"
+
+func TestThrowSites(t *testing.T) {
+	phpCode := `<?php
+namespace App;
+
+use Exception;
+
+class NotFound extends Exception {}
+
+function load($id) {
+    if ($id < 0) {
+        throw new \InvalidArgumentException("negative id");
+    }
+    throw new NotFound("missing");
+}
+
+class Repo {
+    public function find($id) {
+        $fallback = fn() => throw new NotFound("lazy");
+        try {
+            return load($id);
+        } catch (NotFound $e) {
+            throw $e;
+        }
+    }
+}
+?>`
+
+	semanticProgram, err := ParseWithSemantics(phpCode, "load.php")
+	if err != nil {
+		t.Fatalf("Failed to parse with semantics: %v", err)
+	}
+
+	sites := semanticProgram.ThrowSites()
+	expected := []ThrowInfo{
+		{ExceptionClass: "InvalidArgumentException", Function: "load", Line: 10},
+		{ExceptionClass: "App\\NotFound", Function: "load", Line: 12},
+		{ExceptionClass: "App\\NotFound", Function: "{closure}", Line: 17},
+		{ExceptionClass: "", Function: "Repo::find", Line: 21},
+	}
+
+	if len(sites) != len(expected) {
+		t.Fatalf("expected %d throw sites, got=%d: %+v", len(expected), len(sites), sites)
+	}
+	for i, want := range expected {
+		got := sites[i]
+		if got.ExceptionClass != want.ExceptionClass || got.Function != want.Function || got.Line != want.Line {
+			t.Errorf("sites[%d] wrong. expected=%+v, got=%+v", i, want, got)
+		}
+	}
+}
+
+func TestThrowsDocsLint(t *testing.T) {
+	phpCode := `<?php
+class NotFound extends Exception {}
+
+/**
+ * @throws NotFound
+ * @throws InvalidArgumentException
+ */
+function load($id) {
+    throw new NotFound("missing");
+}
+
+/**
+ * @throws LogicException
+ */
+function save($row) {
+    if (!$row) {
+        throw new InvalidArgumentException("empty row");
+    }
+    try {
+        write($row);
+    } catch (Exception $e) {
+        throw new RuntimeException("write failed");
+    }
+}
+
+function write($row) {
+    try {
+        throw new NotFound("disk");
+    } catch (NotFound $e) {
+        return false;
+    }
+}
+?>`
+
+	l := New(phpCode)
+	p := NewParser(l)
+	program := p.ParseProgram()
+	if len(p.Errors()) != 0 {
+		t.Fatalf("parser errors: %v", p.Errors())
+	}
+
+	analyzer := NewSemanticAnalyzer()
+	analyzer.AnalyzeProgram(program, "load.php")
+	if len(analyzer.GetWarnings()) != 0 {
+		t.Errorf("lint should be off by default, got=%v", analyzer.GetWarnings())
+	}
+
+	analyzer = NewSemanticAnalyzer()
+	analyzer.LintThrowsDocs = true
+	analyzer.AnalyzeProgram(program, "load.php")
+
+	if len(analyzer.GetErrors()) != 0 {
+		t.Errorf("lints should report warnings, not errors. got=%v", analyzer.GetErrors())
+	}
+
+	warnings := analyzer.GetWarnings()
+	if len(warnings) != 2 {
+		t.Fatalf("expected 2 warnings, got=%d (%v)", len(warnings), warnings)
+	}
+	if !containsError(warnings, "load() documents @throws InvalidArgumentException but never throws it at line 8") {
+		t.Errorf("expected documented-but-not-thrown warning, got=%v", warnings)
+	}
+	if !containsError(warnings, "save() throws RuntimeException at line 22") {
+		t.Errorf("expected thrown-but-not-documented warning, got=%v", warnings)
+	}
+}
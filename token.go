@@ -1,6 +1,9 @@
 package gophpparser
 
-import "fmt"
+import (
+	"fmt"
+	"strings"
+)
 
 type TokenType int
 
@@ -141,6 +144,39 @@ const (
 	// Comments
 	COMMENT      // /* */ or //
 	DOCBLOCK     // /** */
+	// Bitwise operators
+	BIT_AND     // &
+	BIT_OR      // |
+	BIT_XOR     // ^
+	BIT_NOT     // ~
+	SHIFT_LEFT  // <<
+	SHIFT_RIGHT // >>
+	READONLY
+	ENUM
+	CASE
+	AT // @
+	SWITCH
+	DEFAULT
+	POW             // **
+	POW_ASSIGN      // **=
+	ELLIPSIS        // ...
+	ATTRIBUTE_START // #[
+	// Compound assignment operators
+	PLUS_ASSIGN        // +=
+	MINUS_ASSIGN       // -=
+	MULTIPLY_ASSIGN    // *=
+	DIVIDE_ASSIGN      // /=
+	CONCAT_ASSIGN      // .=
+	MODULO_ASSIGN      // %=
+	BIT_AND_ASSIGN     // &=
+	BIT_OR_ASSIGN      // |=
+	BIT_XOR_ASSIGN     // ^=
+	SHIFT_LEFT_ASSIGN  // <<=
+	SHIFT_RIGHT_ASSIGN // >>=
+	// Word logical operators, which bind more loosely than assignment
+	LOGICAL_AND // and
+	LOGICAL_OR  // or
+	LOGICAL_XOR // xor
 )
 
 type Token struct {
@@ -148,7 +184,16 @@ type Token struct {
 	Literal  string
 	Line     int
 	Column   int
-	Position int
+	Position int // Byte offset of the first character
+	End      int // Byte offset just past the last character
+
+	// The parser fills these in for the token of a node, for SourceRange.
+	// closeEnd is the end of the ), ] or } that closes the node, which the
+	// AST does not keep; groupStart and groupEnd are the offsets of the
+	// parentheses around the node, if any.
+	closeEnd   int
+	groupStart int
+	groupEnd   int
 }
 
 var keywords = map[string]TokenType{
@@ -202,13 +247,27 @@ var keywords = map[string]TokenType{
 	"empty":        EMPTY,
 	"clone":        CLONE,
 	"instanceof":   INSTANCEOF,
+	"and":          LOGICAL_AND,
+	"or":           LOGICAL_OR,
+	"xor":          LOGICAL_XOR,
 	"match":        MATCH,
 	"include_once": INCLUDE_ONCE,
 	"require_once": REQUIRE_ONCE,
 	"fn":           ARROW_FUNCTION,
 	"declare":      DECLARE,
+	"readonly":     READONLY,
+	"enum":         ENUM,
+	"case":         CASE,
+	"switch":       SWITCH,
+	"default":      DEFAULT,
+	"__LINE__":     MAGIC_CONSTANT,
 	"__FILE__":     MAGIC_CONSTANT,
 	"__DIR__":      MAGIC_CONSTANT,
+	"__FUNCTION__": MAGIC_CONSTANT,
+	"__CLASS__":    MAGIC_CONSTANT,
+	"__TRAIT__":    MAGIC_CONSTANT,
+	"__METHOD__":   MAGIC_CONSTANT,
+	"__NAMESPACE__": MAGIC_CONSTANT,
 	// Built-in functions commonly used in Magento
 	"dirname":      IDENT,
 	"basename":     IDENT,
@@ -231,6 +290,12 @@ func LookupIdent(ident string) TokenType {
 	if tok, ok := keywords[ident]; ok {
 		return tok
 	}
+	// PHP keywords ignore case; the word operators at least must, or AND
+	// would silently split an expression into separate statements
+	switch lower := strings.ToLower(ident); lower {
+	case "and", "or", "xor":
+		return keywords[lower]
+	}
 	return IDENT
 }
 
@@ -400,6 +465,66 @@ func (t TokenType) String() string {
 		return "COMMENT"
 	case DOCBLOCK:
 		return "DOCBLOCK"
+	case BIT_AND:
+		return "BIT_AND"
+	case BIT_OR:
+		return "BIT_OR"
+	case BIT_XOR:
+		return "BIT_XOR"
+	case BIT_NOT:
+		return "BIT_NOT"
+	case SHIFT_LEFT:
+		return "SHIFT_LEFT"
+	case SHIFT_RIGHT:
+		return "SHIFT_RIGHT"
+	case READONLY:
+		return "READONLY"
+	case ENUM:
+		return "ENUM"
+	case CASE:
+		return "CASE"
+	case AT:
+		return "AT"
+	case SWITCH:
+		return "SWITCH"
+	case DEFAULT:
+		return "DEFAULT"
+	case POW:
+		return "POW"
+	case POW_ASSIGN:
+		return "POW_ASSIGN"
+	case ELLIPSIS:
+		return "ELLIPSIS"
+	case ATTRIBUTE_START:
+		return "ATTRIBUTE_START"
+	case PLUS_ASSIGN:
+		return "PLUS_ASSIGN"
+	case MINUS_ASSIGN:
+		return "MINUS_ASSIGN"
+	case MULTIPLY_ASSIGN:
+		return "MULTIPLY_ASSIGN"
+	case DIVIDE_ASSIGN:
+		return "DIVIDE_ASSIGN"
+	case CONCAT_ASSIGN:
+		return "CONCAT_ASSIGN"
+	case MODULO_ASSIGN:
+		return "MODULO_ASSIGN"
+	case BIT_AND_ASSIGN:
+		return "BIT_AND_ASSIGN"
+	case BIT_OR_ASSIGN:
+		return "BIT_OR_ASSIGN"
+	case BIT_XOR_ASSIGN:
+		return "BIT_XOR_ASSIGN"
+	case SHIFT_LEFT_ASSIGN:
+		return "SHIFT_LEFT_ASSIGN"
+	case SHIFT_RIGHT_ASSIGN:
+		return "SHIFT_RIGHT_ASSIGN"
+	case LOGICAL_AND:
+		return "LOGICAL_AND"
+	case LOGICAL_OR:
+		return "LOGICAL_OR"
+	case LOGICAL_XOR:
+		return "LOGICAL_XOR"
 	case NAMESPACE:
 		return "NAMESPACE"
 	case USE:
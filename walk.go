@@ -0,0 +1,334 @@
+package gophpparser
+
+import (
+	"reflect"
+	"sort"
+)
+
+// Walk traverses the AST rooted at node in depth-first order. It calls
+// visit for each node before its children and skips the children of any
+// node for which visit returns false. Nil children are not visited.
+func Walk(node Node, visit func(Node) bool) {
+	if isNilNode(node) || !visit(node) {
+		return
+	}
+	walkChildren(node, visit)
+}
+
+// walkChildren walks each child of node in source order
+func walkChildren(node Node, visit func(Node) bool) {
+	switch n := node.(type) {
+	case *Program:
+		walkStatements(n.Statements, visit)
+	case *ExpressionStatement:
+		Walk(n.Expression, visit)
+	case *AssignmentExpression:
+		Walk(n.Name, visit)
+		Walk(n.Target, visit)
+		Walk(n.Value, visit)
+	case *InfixExpression:
+		Walk(n.Left, visit)
+		Walk(n.Right, visit)
+	case *PrefixExpression:
+		Walk(n.Right, visit)
+	case *PostfixExpression:
+		Walk(n.Left, visit)
+	case *FunctionDeclaration:
+		Walk(n.Name, visit)
+		walkParameters(n.Parameters, visit)
+		Walk(n.ReturnType, visit)
+		Walk(n.Body, visit)
+	case *ReturnStatement:
+		Walk(n.ReturnValue, visit)
+	case *BlockStatement:
+		walkStatements(n.Statements, visit)
+	case *IfStatement:
+		Walk(n.Condition, visit)
+		Walk(n.Consequence, visit)
+		for _, elseIf := range n.ElseIfs {
+			Walk(elseIf.Condition, visit)
+			Walk(elseIf.Consequence, visit)
+		}
+		Walk(n.Alternative, visit)
+	case *EchoStatement:
+		walkExpressions(n.Values, visit)
+	case *UnsetStatement:
+		walkExpressions(n.Arguments, visit)
+	case *GlobalStatement:
+		for _, variable := range n.Variables {
+			Walk(variable, visit)
+		}
+	case *ListExpression:
+		for _, item := range n.Items {
+			if item != nil {
+				Walk(item.Key, visit)
+				Walk(item.Value, visit)
+			}
+		}
+	case *DestructuringAssignment:
+		Walk(n.Targets, visit)
+		Walk(n.Source, visit)
+	case *StaticVariableStatement:
+		for _, variable := range n.Variables {
+			Walk(variable.Variable, visit)
+			Walk(variable.Default, visit)
+		}
+	case *IssetExpression:
+		walkExpressions(n.Arguments, visit)
+	case *EmptyExpression:
+		walkExpressions(n.Arguments, visit)
+	case *CallExpression:
+		Walk(n.Function, visit)
+		walkExpressions(n.Arguments, visit)
+	case *ArrayLiteral:
+		walkExpressions(n.Elements, visit)
+	case *AssociativeArrayLiteral:
+		for _, pair := range n.Pairs {
+			Walk(pair.Key, visit)
+			Walk(pair.Value, visit)
+		}
+	case *InterpolatedString:
+		walkExpressions(n.Parts, visit)
+	case *ForStatement:
+		Walk(n.Init, visit)
+		Walk(n.Condition, visit)
+		Walk(n.Update, visit)
+		Walk(n.Body, visit)
+	case *IndexExpression:
+		Walk(n.Left, visit)
+		Walk(n.Index, visit)
+	case *WhileStatement:
+		Walk(n.Condition, visit)
+		Walk(n.Body, visit)
+	case *SwitchStatement:
+		Walk(n.Subject, visit)
+		for _, switchCase := range n.Cases {
+			Walk(switchCase.Condition, visit)
+			walkStatements(switchCase.Body, visit)
+		}
+	case *ForeachStatement:
+		Walk(n.Array, visit)
+		Walk(n.Key, visit)
+		Walk(n.Value, visit)
+		Walk(n.ValuePattern, visit)
+		Walk(n.Body, visit)
+	case *BreakStatement:
+		Walk(n.Level, visit)
+	case *ContinueStatement:
+		Walk(n.Level, visit)
+	case *ClassDeclaration:
+		Walk(n.Name, visit)
+		Walk(n.SuperClass, visit)
+		walkIdentifiers(n.Interfaces, visit)
+		for _, use := range n.TraitUses {
+			Walk(use, visit)
+		}
+		for _, constant := range n.Constants {
+			Walk(constant, visit)
+		}
+		for _, property := range n.Properties {
+			Walk(property, visit)
+		}
+		for _, method := range n.Methods {
+			Walk(method, visit)
+		}
+	case *PropertyDeclaration:
+		Walk(n.PropertyType, visit)
+		Walk(n.Name, visit)
+		Walk(n.Value, visit)
+	case *MethodDeclaration:
+		Walk(n.Name, visit)
+		walkParameters(n.Parameters, visit)
+		Walk(n.ReturnType, visit)
+		Walk(n.Body, visit)
+	case *InterfaceDeclaration:
+		Walk(n.Name, visit)
+		for _, method := range n.Methods {
+			Walk(method, visit)
+		}
+	case *InterfaceMethod:
+		Walk(n.Name, visit)
+		walkParameters(n.Parameters, visit)
+	case *TraitDeclaration:
+		Walk(n.Name, visit)
+		for _, constant := range n.Constants {
+			Walk(constant, visit)
+		}
+		for _, property := range n.Properties {
+			Walk(property, visit)
+		}
+		for _, method := range n.Methods {
+			Walk(method, visit)
+		}
+	case *TraitUse:
+		walkIdentifiers(n.Traits, visit)
+	case *EnumDeclaration:
+		Walk(n.Name, visit)
+		Walk(n.BackingType, visit)
+		walkIdentifiers(n.Interfaces, visit)
+		for _, use := range n.TraitUses {
+			Walk(use, visit)
+		}
+		for _, enumCase := range n.Cases {
+			Walk(enumCase, visit)
+		}
+		for _, constant := range n.Constants {
+			Walk(constant, visit)
+		}
+		for _, method := range n.Methods {
+			Walk(method, visit)
+		}
+	case *EnumCase:
+		Walk(n.Name, visit)
+		Walk(n.Value, visit)
+	case *ConstantDeclaration:
+		Walk(n.Name, visit)
+		Walk(n.Value, visit)
+	case *NewExpression:
+		Walk(n.ClassName, visit)
+		walkExpressions(n.Arguments, visit)
+	case *ObjectAccessExpression:
+		Walk(n.Object, visit)
+		Walk(n.Property, visit)
+	case *StaticAccessExpression:
+		Walk(n.Class, visit)
+		Walk(n.Property, visit)
+	case *NamespaceDeclaration:
+		Walk(n.Name, visit)
+	case *UseStatement:
+		Walk(n.Namespace, visit)
+		Walk(n.Alias, visit)
+	case *TryStatement:
+		Walk(n.Body, visit)
+		for _, catch := range n.Catches {
+			Walk(catch, visit)
+		}
+		Walk(n.Finally, visit)
+	case *CatchClause:
+		walkIdentifiers(n.ExceptionTypes, visit)
+		Walk(n.Variable, visit)
+		Walk(n.Body, visit)
+	case *ThrowStatement:
+		Walk(n.Expression, visit)
+	case *IncludeStatement:
+		Walk(n.Path, visit)
+	case *RequireStatement:
+		Walk(n.Path, visit)
+	case *IncludeExpression:
+		Walk(n.Path, visit)
+	case *RequireExpression:
+		Walk(n.Path, visit)
+	case *PrintExpression:
+		Walk(n.Value, visit)
+	case *TypeHint:
+		for _, member := range n.Union {
+			Walk(member, visit)
+		}
+		for _, member := range n.Intersection {
+			Walk(member, visit)
+		}
+	case *NullableType:
+		Walk(n.BaseType, visit)
+	case *AnonymousFunction:
+		walkParameters(n.Parameters, visit)
+		for _, useVar := range n.UseClause {
+			Walk(&Variable{Token: useVar.Token, Name: useVar.Name}, visit)
+		}
+		Walk(n.ReturnType, visit)
+		Walk(n.Body, visit)
+	case *NamespacedIdentifier:
+		walkIdentifiers(n.Namespace, visit)
+		Walk(n.Name, visit)
+	case *YieldExpression:
+		Walk(n.Key, visit)
+		Walk(n.Value, visit)
+	case *TernaryExpression:
+		Walk(n.Condition, visit)
+		Walk(n.TrueValue, visit)
+		Walk(n.FalseValue, visit)
+	case *CoalesceExpression:
+		Walk(n.Left, visit)
+		Walk(n.Right, visit)
+	case *InstanceofExpression:
+		Walk(n.Left, visit)
+		Walk(n.Class, visit)
+	case *CloneExpression:
+		Walk(n.Operand, visit)
+	case *CommaExpression:
+		for _, expr := range n.Expressions {
+			Walk(expr, visit)
+		}
+	case *ThrowExpression:
+		Walk(n.Expression, visit)
+	case *ArrowFunction:
+		walkParameters(n.Parameters, visit)
+		Walk(n.ReturnType, visit)
+		Walk(n.Body, visit)
+	case *MatchExpression:
+		Walk(n.Subject, visit)
+		for _, arm := range n.Arms {
+			walkExpressions(arm.Conditions, visit)
+			Walk(arm.Body, visit)
+		}
+	case *DeclareStatement:
+		names := make([]string, 0, len(n.Directives))
+		for name := range n.Directives {
+			names = append(names, name)
+		}
+		sort.Strings(names)
+		for _, name := range names {
+			Walk(n.Directives[name], visit)
+		}
+		Walk(n.Body, visit)
+	// The semantic wrappers share the children of the node they embed
+	case *SemanticNewExpression:
+		walkChildren(n.NewExpression, visit)
+	case *SemanticCallExpression:
+		walkChildren(n.CallExpression, visit)
+	case *SemanticStaticAccess:
+		walkChildren(n.StaticAccessExpression, visit)
+	}
+}
+
+// isNilNode reports whether node is nil or a typed nil pointer, which a
+// failed parse can leave behind in an interface field
+func isNilNode(node Node) bool {
+	if node == nil {
+		return true
+	}
+	value := reflect.ValueOf(node)
+	return value.Kind() == reflect.Pointer && value.IsNil()
+}
+
+func walkStatements(statements []Statement, visit func(Node) bool) {
+	for _, stmt := range statements {
+		Walk(stmt, visit)
+	}
+}
+
+func walkExpressions(expressions []Expression, visit func(Node) bool) {
+	for _, expr := range expressions {
+		Walk(expr, visit)
+	}
+}
+
+func walkIdentifiers(identifiers []*Identifier, visit func(Node) bool) {
+	for _, identifier := range identifiers {
+		Walk(identifier, visit)
+	}
+}
+
+// walkParameters visits the attributes, type, variable and default of each
+// parameter. Parameter is not itself a Node, so its name is visited as a
+// Variable.
+func walkParameters(parameters []*Parameter, visit func(Node) bool) {
+	for _, param := range parameters {
+		for _, attribute := range param.Attributes {
+			Walk(attribute.Name, visit)
+			walkExpressions(attribute.Arguments, visit)
+		}
+		Walk(param.TypeHint, visit)
+		Walk(&Variable{Token: param.Token, Name: param.Name}, visit)
+		Walk(param.Default, visit)
+	}
+}
@@ -0,0 +1,114 @@
+package gophpparser
+
+import "testing"
+
+func TestWalkCountsVariables(t *testing.T) {
+	input := `<?php
+function total(#[Limit($max)] array $items, $tax = 0) {
+    $sum = 0;
+    foreach ($items as $key => $item) {
+        $sum += $item['price'] ?? $default;
+    }
+    $map = ['sum' => $sum, $key => [$tax]];
+    [$first, $third] = $items;
+    return match (true) {
+        $sum > 100 => fn($x) => $x * $tax,
+        default => $sum,
+    };
+}
+?>`
+
+	l := New(input)
+	p := NewParser(l)
+	program := p.ParseProgram()
+
+	if len(p.Errors()) != 0 {
+		t.Errorf("parser has %d errors", len(p.Errors()))
+		for _, err := range p.Errors() {
+			t.Errorf("parser error: %q", err)
+		}
+		return
+	}
+
+	counts := map[string]int{}
+	Walk(program, func(node Node) bool {
+		if variable, ok := node.(*Variable); ok {
+			counts[variable.Name]++
+		}
+		return true
+	})
+
+	expected := map[string]int{
+		"max":     1, // Attribute argument
+		"items":   3, // Parameter, foreach subject and destructuring source
+		"tax":     3, // Parameter, nested array element and arrow function body
+		"sum":     5,
+		"key":     2,
+		"item":    2,
+		"default": 1,
+		"map":     1,
+		"first":   1,
+		"third":   1,
+		"x":       2,
+	}
+	for name, want := range expected {
+		if counts[name] != want {
+			t.Errorf("$%s visited %d times, want %d", name, counts[name], want)
+		}
+	}
+	for name := range counts {
+		if _, ok := expected[name]; !ok {
+			t.Errorf("unexpected variable $%s visited", name)
+		}
+	}
+}
+
+func TestWalkSkipsChildrenWhenVisitReturnsFalse(t *testing.T) {
+	input := `<?php
+$a = 1;
+function f($b) {
+    $c = $b;
+}
+?>`
+
+	program, err := Parse(input)
+	if err != nil {
+		t.Fatalf("parse error: %v", err)
+	}
+
+	var names []string
+	Walk(program, func(node Node) bool {
+		if variable, ok := node.(*Variable); ok {
+			names = append(names, variable.Name)
+		}
+		_, isFunction := node.(*FunctionDeclaration)
+		return !isFunction
+	})
+
+	if len(names) != 1 || names[0] != "a" {
+		t.Errorf("expected only $a outside the function to be visited. got=%v", names)
+	}
+}
+
+func TestWalkSemanticWrappers(t *testing.T) {
+	call := &CallExpression{
+		Function:  &Identifier{Value: "strlen"},
+		Arguments: []Expression{&Variable{Name: "s"}},
+	}
+
+	var visited []string
+	Walk(&SemanticCallExpression{CallExpression: call}, func(node Node) bool {
+		visited = append(visited, node.Type())
+		return true
+	})
+
+	expected := []string{"SemanticCallExpression", "Identifier", "Variable"}
+	if len(visited) != len(expected) {
+		t.Fatalf("visited %v, want %v", visited, expected)
+	}
+	for i := range expected {
+		if visited[i] != expected[i] {
+			t.Errorf("visited[%d] = %s, want %s", i, visited[i], expected[i])
+		}
+	}
+}